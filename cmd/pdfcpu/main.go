@@ -29,9 +29,17 @@ import (
 
 var (
 	fileStats, mode, selectedPages string
-	upw, opw, key, perm            string
+	fromFile, selectedFromPages    string
+	before                         int
+	threshold                      float64
+	dryRun                         bool
+	hidden, noPrint, annots        bool
+	upw, opw, key, perm, format    string
+	cert, certKey, certPW, trust   string
+	tsa                            string
 	verbose, veryVerbose           bool
-	quiet                          bool
+	quiet, bookmarks               bool
+	reverse, fill                  bool
 	needStackTrace                 = true
 	cmdMap                         CommandMap
 )
@@ -48,7 +56,7 @@ func initFlags() {
 	flag.StringVar(&fileStats, "stats", "", statsUsage)
 	flag.StringVar(&fileStats, "s", "", statsUsage)
 
-	modeUsage := "validate: strict|relaxed; extract: image|font|content|page; encrypt: rc4|aes"
+	modeUsage := "validate: strict|relaxed; split: span|bookmark|pages|size; merge: concat|zip; extract: image|font|content|page; encrypt: rc4|aes"
 	flag.StringVar(&mode, "mode", "", modeUsage)
 	flag.StringVar(&mode, "m", "", modeUsage)
 
@@ -63,6 +71,60 @@ func initFlags() {
 	flag.StringVar(&selectedPages, "pages", "", selectedPagesUsage)
 	flag.StringVar(&selectedPages, "p", "", selectedPagesUsage)
 
+	bookmarksUsage := "merge: create a bookmark for each merged file"
+	flag.BoolVar(&bookmarks, "bookmarks", false, bookmarksUsage)
+	flag.BoolVar(&bookmarks, "b", false, bookmarksUsage)
+
+	reverseUsage := "merge zip: take the pages of the second file in reverse order"
+	flag.BoolVar(&reverse, "reverse", false, reverseUsage)
+	flag.BoolVar(&reverse, "r", false, reverseUsage)
+
+	fillUsage := "merge zip: pad the shorter file with blank pages"
+	flag.BoolVar(&fill, "fill", false, fillUsage)
+	flag.BoolVar(&fill, "f", false, fillUsage)
+
+	fromUsage := "pages insert: insert pages from this file"
+	flag.StringVar(&fromFile, "from", "", fromUsage)
+
+	selUsage := "pages insert: pages of the -from file to insert"
+	flag.StringVar(&selectedFromPages, "sel", "", selUsage)
+
+	beforeUsage := "pages insert: insert before this page"
+	flag.IntVar(&before, "before", 0, beforeUsage)
+
+	thresholdUsage := "pages removeblank: minimum share of white pixels of a blank image"
+	flag.Float64Var(&threshold, "threshold", pdfcpu.DefaultBlankThreshold, thresholdUsage)
+
+	dryRunUsage := "pages removeblank: list blank pages only"
+	flag.BoolVar(&dryRun, "dryrun", false, dryRunUsage)
+
+	hiddenUsage := "flatten annotations: include hidden annotations"
+	flag.BoolVar(&hidden, "hidden", false, hiddenUsage)
+
+	noPrintUsage := "flatten annotations: include annotations not meant to be printed"
+	flag.BoolVar(&noPrint, "noprint", false, noPrintUsage)
+
+	annotsUsage := "form flatten: also flatten all other annotations"
+	flag.BoolVar(&annots, "annots", false, annotsUsage)
+
+	formatUsage := "form import, export: json|fdf|xfdf"
+	flag.StringVar(&format, "format", "", formatUsage)
+
+	certUsage := "sign, decrypt: PKCS#12 (.p12, .pfx) or PEM certificate file, encrypt: comma separated list of recipient certificate files"
+	flag.StringVar(&cert, "cert", "", certUsage)
+
+	certKeyUsage := "sign, decrypt: PEM private key file"
+	flag.StringVar(&certKey, "certkey", "", certKeyUsage)
+
+	certPWUsage := "sign, decrypt: PKCS#12 password"
+	flag.StringVar(&certPW, "certpw", "", certPWUsage)
+
+	trustUsage := "signatures verify: comma separated list of PEM or DER files holding trusted certificates"
+	flag.StringVar(&trust, "trust", "", trustUsage)
+
+	tsaUsage := "timestamp: URL of an RFC 3161 time-stamp authority"
+	flag.StringVar(&tsa, "tsa", "", tsaUsage)
+
 	flag.BoolVar(&quiet, "quiet", false, "")
 	flag.BoolVar(&quiet, "q", false, "")
 
@@ -76,6 +138,43 @@ func initFlags() {
 
 func initCommandMap() {
 
+	annotsCmdMap := NewCommandMap()
+	for k, v := range map[string]Command{
+		"list":   {handleListAnnotationsCommand, nil, "", ""},
+		"add":    {handleAddAnnotationsCommand, nil, "", ""},
+		"remove": {handleRemoveAnnotationsCommand, nil, "", ""},
+	} {
+		annotsCmdMap.Register(k, v)
+	}
+
+	flattenCmdMap := NewCommandMap()
+	for k, v := range map[string]Command{
+		"annotations": {handleFlattenAnnotationsCommand, nil, "", ""},
+	} {
+		flattenCmdMap.Register(k, v)
+	}
+
+	formCmdMap := NewCommandMap()
+	for k, v := range map[string]Command{
+		"list":    {handleListFormFieldsCommand, nil, "", ""},
+		"fill":    {handleFillFormCommand, nil, "", ""},
+		"export":  {handleExportFormCommand, nil, "", ""},
+		"flatten": {handleFlattenFormCommand, nil, "", ""},
+		"import":  {handleImportFormCommand, nil, "", ""},
+		"create":  {handleCreateFormFieldsCommand, nil, "", ""},
+	} {
+		formCmdMap.Register(k, v)
+	}
+
+	signaturesCmdMap := NewCommandMap()
+	for k, v := range map[string]Command{
+		"list":   {handleListSignaturesCommand, nil, "", ""},
+		"verify": {handleVerifySignaturesCommand, nil, "", ""},
+		"ltv":    {handleAddLTVCommand, nil, "", ""},
+	} {
+		signaturesCmdMap.Register(k, v)
+	}
+
 	attachCmdMap := NewCommandMap()
 	for k, v := range map[string]Command{
 		"list":    {handleListAttachmentsCommand, nil, "", ""},
@@ -86,6 +185,15 @@ func initCommandMap() {
 		attachCmdMap.Register(k, v)
 	}
 
+	boxesCmdMap := NewCommandMap()
+	for k, v := range map[string]Command{
+		"list":   {handleListBoxesCommand, nil, "", ""},
+		"add":    {handleAddBoxesCommand, nil, "", ""},
+		"remove": {handleRemoveBoxesCommand, nil, "", ""},
+	} {
+		boxesCmdMap.Register(k, v)
+	}
+
 	permissionsCmdMap := NewCommandMap()
 	for k, v := range map[string]Command{
 		"list": {handleListPermissionsCommand, nil, "", ""},
@@ -96,8 +204,10 @@ func initCommandMap() {
 
 	pagesCmdMap := NewCommandMap()
 	for k, v := range map[string]Command{
-		"insert": {handleInsertPagesCommand, nil, "", ""},
-		"remove": {handleRemovePagesCommand, nil, "", ""},
+		"arrange":     {handleArrangePagesCommand, nil, "", ""},
+		"insert":      {handleInsertPagesCommand, nil, "", ""},
+		"remove":      {handleRemovePagesCommand, nil, "", ""},
+		"removeblank": {handleRemoveBlankPagesCommand, nil, "", ""},
 	} {
 		pagesCmdMap.Register(k, v)
 	}
@@ -105,12 +215,19 @@ func initCommandMap() {
 	cmdMap = NewCommandMap()
 
 	for k, v := range map[string]Command{
+		"annotations": {nil, annotsCmdMap, usageAnnots, usageLongAnnots},
 		"attachments": {nil, attachCmdMap, usageAttach, usageLongAttach},
+		"booklet":     {handleBookletCommand, nil, usageBooklet, usageLongBooklet},
+		"boxes":       {nil, boxesCmdMap, usageBoxes, usageLongBoxes},
 		"changeopw":   {handleChangeOwnerPasswordCommand, nil, usageChangeOwnerPW, usageLongChangeUserPW},
 		"changeupw":   {handleChangeUserPasswordCommand, nil, usageChangeUserPW, usageLongChangeUserPW},
+		"crop":        {handleCropCommand, nil, usageCrop, usageLongCrop},
+		"cut":         {handleCutCommand, nil, usageCut, usageLongCut},
 		"decrypt":     {handleDecryptCommand, nil, usageDecrypt, usageLongDecrypt},
 		"encrypt":     {handleEncryptCommand, nil, usageEncrypt, usageLongEncrypt},
 		"extract":     {handleExtractCommand, nil, usageExtract, usageLongExtract},
+		"flatten":     {nil, flattenCmdMap, usageFlatten, usageLongFlatten},
+		"form":        {nil, formCmdMap, usageForm, usageLongForm},
 		"grid":        {handleGridCommand, nil, usageGrid, usageLongGrid},
 		"help":        {printHelp, nil, "", ""},
 		"info":        {handleInfoCommand, nil, usageInfo, usageLongInfo},
@@ -122,9 +239,14 @@ func initCommandMap() {
 		"pages":       {nil, pagesCmdMap, usagePages, usageLongPages},
 		"paper":       {printPaperSizes, nil, usagePaper, usageLongPaper},
 		"permissions": {nil, permissionsCmdMap, usagePerm, usageLongPerm},
+		"poster":      {handlePosterCommand, nil, usagePoster, usageLongPoster},
+		"resize":      {handleResizeCommand, nil, usageResize, usageLongResize},
 		"rotate":      {handleRotateCommand, nil, usageRotate, usageLongRotate},
+		"sign":        {handleSignCommand, nil, usageSign, usageLongSign},
+		"signatures":  {nil, signaturesCmdMap, usageSignatures, usageLongSignatures},
 		"split":       {handleSplitCommand, nil, usageSplit, usageLongSplit},
 		"stamp":       {handleAddStampsCommand, nil, usageStamp, usageLongStamp},
+		"timestamp":   {handleTimeStampCommand, nil, usageTimeStamp, usageLongTimeStamp},
 		"trim":        {handleTrimCommand, nil, usageTrim, usageLongTrim},
 		"validate":    {handleValidateCommand, nil, usageValidate, usageLongValidate},
 		"watermark":   {handleAddWatermarksCommand, nil, usageWatermark, usageLongWatermark},
@@ -27,6 +27,7 @@ import (
 	"github.com/denisbetsi/pdfcpu/pkg/api"
 	"github.com/denisbetsi/pdfcpu/pkg/cli"
 	"github.com/denisbetsi/pdfcpu/pkg/pdfcpu"
+	"github.com/denisbetsi/pdfcpu/pkg/pdfcpu/cms"
 )
 
 func hasPdfExtension(filename string) bool {
@@ -119,8 +120,46 @@ func handleOptimizeCommand(conf *pdfcpu.Configuration) {
 	process(cli.OptimizeCommand(inFile, outFile, conf))
 }
 
+func splitModeCompletion(modePrefix string) string {
+	var modeStr string
+	for _, mode := range []string{"span", "bookmark", "pages", "size"} {
+		if !strings.HasPrefix(mode, modePrefix) {
+			continue
+		}
+		if len(modeStr) > 0 {
+			return ""
+		}
+		modeStr = mode
+	}
+	return modeStr
+}
+
+// parseByteSize parses a size in bytes with an optional k, kb, m or mb suffix.
+func parseByteSize(s string) (int64, error) {
+	s = strings.ToLower(s)
+	f := int64(1)
+	for _, u := range []struct {
+		suffix string
+		f      int64
+	}{{"kb", 1024}, {"k", 1024}, {"mb", 1024 * 1024}, {"m", 1024 * 1024}} {
+		if strings.HasSuffix(s, u.suffix) {
+			s, f = strings.TrimSuffix(s, u.suffix), u.f
+			break
+		}
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return i * f, nil
+}
+
 func handleSplitCommand(conf *pdfcpu.Configuration) {
-	if len(flag.Args()) < 2 || len(flag.Args()) > 3 || selectedPages != "" {
+	if mode == "" {
+		mode = "span"
+	}
+	mode = splitModeCompletion(mode)
+	if len(flag.Args()) < 2 || mode == "" || selectedPages != "" {
 		fmt.Fprintf(os.Stderr, "%s\n\n", usageSplit)
 		os.Exit(1)
 	}
@@ -130,39 +169,146 @@ func handleSplitCommand(conf *pdfcpu.Configuration) {
 
 	outDir := flag.Arg(1)
 
-	span := 1
-	var err error
-	if len(flag.Args()) == 3 {
-		span, err = strconv.Atoi(flag.Arg(2))
-		if err != nil || span < 1 {
-			fmt.Fprintln(os.Stderr, "split: span is a numeric value >= 1")
+	var cmd *cli.Command
+
+	switch mode {
+
+	case "span":
+		if len(flag.Args()) > 3 {
+			fmt.Fprintf(os.Stderr, "%s\n\n", usageSplit)
+			os.Exit(1)
+		}
+		span := 1
+		var err error
+		if len(flag.Args()) == 3 {
+			span, err = strconv.Atoi(flag.Arg(2))
+			if err != nil || span < 1 {
+				fmt.Fprintln(os.Stderr, "split: span is a numeric value >= 1")
+				os.Exit(1)
+			}
+		}
+		cmd = cli.SplitCommand(inFile, outDir, span, conf)
+
+	case "bookmark":
+		if len(flag.Args()) > 2 {
+			fmt.Fprintf(os.Stderr, "%s\n\n", usageSplit)
+			os.Exit(1)
+		}
+		cmd = cli.SplitByBookmarkCommand(inFile, outDir, conf)
+
+	case "pages":
+		if len(flag.Args()) < 3 {
+			fmt.Fprintf(os.Stderr, "%s\n\n", usageSplit)
+			os.Exit(1)
+		}
+		pageNrs := []int{}
+		for _, arg := range flag.Args()[2:] {
+			i, err := strconv.Atoi(arg)
+			if err != nil || i < 1 {
+				fmt.Fprintln(os.Stderr, "split: pageNr is a numeric value >= 1")
+				os.Exit(1)
+			}
+			pageNrs = append(pageNrs, i)
+		}
+		cmd = cli.SplitByPageNrCommand(inFile, outDir, pageNrs, conf)
+
+	case "size":
+		if len(flag.Args()) != 3 {
+			fmt.Fprintf(os.Stderr, "%s\n\n", usageSplit)
 			os.Exit(1)
 		}
+		maxSize, err := parseByteSize(flag.Arg(2))
+		if err != nil || maxSize < 1 {
+			fmt.Fprintln(os.Stderr, "split: maxSize is a numeric value >= 1, optionally followed by k(b) or m(b)")
+			os.Exit(1)
+		}
+		cmd = cli.SplitBySizeCommand(inFile, outDir, maxSize, conf)
+
 	}
 
-	process(cli.SplitCommand(inFile, outDir, span, conf))
+	process(cmd)
+}
+
+func mergeModeCompletion(modePrefix string) string {
+	var modeStr string
+	for _, mode := range []string{"concat", "zip"} {
+		if !strings.HasPrefix(mode, modePrefix) {
+			continue
+		}
+		if len(modeStr) > 0 {
+			return ""
+		}
+		modeStr = mode
+	}
+	return modeStr
 }
 
 func handleMergeCommand(conf *pdfcpu.Configuration) {
-	if len(flag.Args()) < 3 || selectedPages != "" {
+	if mode == "" {
+		mode = "concat"
+	}
+	mode = mergeModeCompletion(mode)
+	if len(flag.Args()) < 3 || mode == "" || selectedPages != "" ||
+		(mode == "zip" && len(flag.Args()) != 3) ||
+		(mode == "concat" && (reverse || fill)) {
 		fmt.Fprintf(os.Stderr, "%s\n\n", usageMerge)
 		os.Exit(1)
 	}
 
 	filesIn := []string{}
+	pageSelections := [][]string{}
+	selected := false
 	outFile := ""
 	for i, arg := range flag.Args() {
-		ensurePdfExtension(arg)
 		if i == 0 {
+			ensurePdfExtension(arg)
 			outFile = arg
 			continue
 		}
-		filesIn = append(filesIn, arg)
+		fileName, pages := splitMergeArg(arg)
+		ensurePdfExtension(fileName)
+		filesIn = append(filesIn, fileName)
+		pageSelections = append(pageSelections, pages)
+		if pages != nil {
+			selected = true
+		}
+	}
+
+	conf.CreateBookmarks = bookmarks
+
+	if mode == "zip" {
+		if selected {
+			fmt.Fprintf(os.Stderr, "%s\n\n", usageMerge)
+			os.Exit(1)
+		}
+		process(cli.MergeZipCommand(filesIn[0], filesIn[1], outFile, reverse, fill, conf))
+		return
+	}
+
+	if selected {
+		process(cli.MergeSelectedPagesCommand(filesIn, pageSelections, outFile, conf))
+		return
 	}
 
 	process(cli.MergeCommand(filesIn, outFile, conf))
 }
 
+// splitMergeArg splits a merge inFile argument of the form file.pdf:pageSelection.
+func splitMergeArg(arg string) (string, []string) {
+	i := strings.LastIndex(arg, ":")
+	if i < 0 || !hasPdfExtension(arg[:i]) {
+		return arg, nil
+	}
+
+	pages, err := api.ParsePageSelection(arg[i+1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "problem with page selection of %s: %v\n", arg[:i], err)
+		os.Exit(1)
+	}
+
+	return arg[:i], pages
+}
+
 func extractModeCompletion(modePrefix string) string {
 	var modeStr string
 	for _, mode := range []string{"image", "font", "page", "content", "meta"} {
@@ -246,6 +392,310 @@ func handleTrimCommand(conf *pdfcpu.Configuration) {
 	process(cli.TrimCommand(inFile, outFile, pages, conf))
 }
 
+func handleListAnnotationsCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) != 1 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageAnnotsList)
+		os.Exit(1)
+	}
+
+	pages, err := api.ParsePageSelection(selectedPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "problem with flag selectedPages: %v\n", err)
+		os.Exit(1)
+	}
+
+	inFile := flag.Arg(0)
+	ensurePdfExtension(inFile)
+
+	process(cli.ListAnnotationsCommand(inFile, pages, conf))
+}
+
+func handleAddAnnotationsCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) < 2 || len(flag.Args()) > 3 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageAnnotsAdd)
+		os.Exit(1)
+	}
+
+	pages, err := api.ParsePageSelection(selectedPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "problem with flag selectedPages: %v\n", err)
+		os.Exit(1)
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+	aa, err := pdfcpu.ParseAnnotationsJSON(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+
+	inFile := flag.Arg(1)
+	ensurePdfExtension(inFile)
+	outFile := ""
+	if len(flag.Args()) == 3 {
+		outFile = flag.Arg(2)
+		ensurePdfExtension(outFile)
+	}
+
+	process(cli.AddAnnotationsCommand(inFile, outFile, pages, aa, conf))
+}
+
+func handleRemoveAnnotationsCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) == 0 || len(flag.Args()) > 3 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageAnnotsRemove)
+		os.Exit(1)
+	}
+
+	pages, err := api.ParsePageSelection(selectedPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "problem with flag selectedPages: %v\n", err)
+		os.Exit(1)
+	}
+
+	args := flag.Args()
+
+	var types, ids []string
+
+	if !hasPdfExtension(args[0]) {
+		// pdfcpu annotations remove filter inFile [outFile]
+		for _, s := range strings.Split(args[0], ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			if t, err := pdfcpu.AnnotationType(s); err == nil {
+				types = append(types, t)
+				continue
+			}
+			ids = append(ids, s)
+		}
+		args = args[1:]
+	}
+
+	if len(args) == 0 || len(args) > 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageAnnotsRemove)
+		os.Exit(1)
+	}
+
+	inFile := args[0]
+	ensurePdfExtension(inFile)
+	outFile := ""
+	if len(args) == 2 {
+		outFile = args[1]
+		ensurePdfExtension(outFile)
+	}
+
+	process(cli.RemoveAnnotationsCommand(inFile, outFile, pages, types, ids, conf))
+}
+
+func handleFlattenAnnotationsCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) == 0 || len(flag.Args()) > 3 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageFlattenAnnots)
+		os.Exit(1)
+	}
+
+	pages, err := api.ParsePageSelection(selectedPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "problem with flag selectedPages: %v\n", err)
+		os.Exit(1)
+	}
+
+	f := &pdfcpu.Flatten{Hidden: hidden, NoPrint: noPrint}
+
+	args := flag.Args()
+
+	if !hasPdfExtension(args[0]) {
+		// pdfcpu flatten annotations types inFile [outFile]
+		for _, s := range strings.Split(args[0], ",") {
+			t, err := pdfcpu.AnnotationType(strings.TrimSpace(s))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", err)
+				os.Exit(1)
+			}
+			f.Types = append(f.Types, t)
+		}
+		args = args[1:]
+	}
+
+	if len(args) == 0 || len(args) > 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageFlattenAnnots)
+		os.Exit(1)
+	}
+
+	inFile := args[0]
+	ensurePdfExtension(inFile)
+	outFile := ""
+	if len(args) == 2 {
+		outFile = args[1]
+		ensurePdfExtension(outFile)
+	}
+
+	process(cli.FlattenAnnotationsCommand(inFile, outFile, pages, f, conf))
+}
+
+func handleListFormFieldsCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) != 1 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageFormList)
+		os.Exit(1)
+	}
+
+	inFile := flag.Arg(0)
+	ensurePdfExtension(inFile)
+
+	process(cli.ListFormFieldsCommand(inFile, conf))
+}
+
+func handleFillFormCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) < 2 || len(flag.Args()) > 3 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageFormFill)
+		os.Exit(1)
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+	fvs, err := pdfcpu.ParseFormValuesJSON(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+
+	inFile := flag.Arg(1)
+	ensurePdfExtension(inFile)
+	outFile := ""
+	if len(flag.Args()) == 3 {
+		outFile = flag.Arg(2)
+		ensurePdfExtension(outFile)
+	}
+
+	process(cli.FillFormCommand(inFile, outFile, fvs, conf))
+}
+
+func handleExportFormCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) == 0 || len(flag.Args()) > 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageFormExport)
+		os.Exit(1)
+	}
+
+	inFile := flag.Arg(0)
+	ensurePdfExtension(inFile)
+
+	f := format
+	outFile := ""
+	if len(flag.Args()) == 2 {
+		outFile = flag.Arg(1)
+		if f == "" {
+			f = formDataFormat(outFile)
+		}
+	}
+	if f == "" {
+		f = "json"
+	}
+	if f != "json" && f != "fdf" && f != "xfdf" {
+		fmt.Fprintf(os.Stderr, "unsupported format: %s, use json, fdf or xfdf\n", f)
+		os.Exit(1)
+	}
+	if outFile == "" {
+		outFile = strings.TrimSuffix(inFile, filepath.Ext(inFile)) + "." + f
+	}
+
+	process(cli.ExportFormCommand(inFile, outFile, f, conf))
+}
+
+// formDataFormat returns the form data format implied by the extension of fileName.
+func formDataFormat(fileName string) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".fdf":
+		return "fdf"
+	case ".xfdf":
+		return "xfdf"
+	case ".json":
+		return "json"
+	}
+	return ""
+}
+
+func handleImportFormCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) < 2 || len(flag.Args()) > 3 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageFormImport)
+		os.Exit(1)
+	}
+
+	dataFile := flag.Arg(0)
+	f := format
+	if f == "" {
+		f = formDataFormat(dataFile)
+	}
+	if f != "fdf" && f != "xfdf" {
+		fmt.Fprintf(os.Stderr, "%s: unsupported format, use fdf or xfdf (form fill imports json)\n", dataFile)
+		os.Exit(1)
+	}
+
+	inFile := flag.Arg(1)
+	ensurePdfExtension(inFile)
+	outFile := ""
+	if len(flag.Args()) == 3 {
+		outFile = flag.Arg(2)
+		ensurePdfExtension(outFile)
+	}
+
+	process(cli.ImportFormCommand(inFile, dataFile, outFile, f, conf))
+}
+
+func handleCreateFormFieldsCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) < 2 || len(flag.Args()) > 3 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageFormCreate)
+		os.Exit(1)
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+	fss, err := pdfcpu.ParseFieldSpecsJSON(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+
+	inFile := flag.Arg(1)
+	ensurePdfExtension(inFile)
+	outFile := ""
+	if len(flag.Args()) == 3 {
+		outFile = flag.Arg(2)
+		ensurePdfExtension(outFile)
+	}
+
+	process(cli.CreateFormFieldsCommand(inFile, outFile, fss, conf))
+}
+
+func handleFlattenFormCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) == 0 || len(flag.Args()) > 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageFormFlatten)
+		os.Exit(1)
+	}
+
+	inFile := flag.Arg(0)
+	ensurePdfExtension(inFile)
+	outFile := ""
+	if len(flag.Args()) == 2 {
+		outFile = flag.Arg(1)
+		ensurePdfExtension(outFile)
+	}
+
+	process(cli.FlattenFormCommand(inFile, outFile, annots, conf))
+}
+
 func handleListAttachmentsCommand(conf *pdfcpu.Configuration) {
 	if len(flag.Args()) != 1 || selectedPages != "" {
 		fmt.Fprintf(os.Stderr, "usage: %s\n", usageAttachList)
@@ -379,6 +829,13 @@ func handleDecryptCommand(conf *pdfcpu.Configuration) {
 		os.Exit(1)
 	}
 
+	if cert != "" {
+		if err := conf.ReadRecipientCredentials(cert, certKey, certPW); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+	}
+
 	inFile := flag.Arg(0)
 	ensurePdfExtension(inFile)
 
@@ -436,7 +893,7 @@ func handleEncryptCommand(conf *pdfcpu.Configuration) {
 		os.Exit(1)
 	}
 
-	if conf.OwnerPW == "" {
+	if conf.OwnerPW == "" && cert == "" {
 		fmt.Fprintln(os.Stderr, "missing non-empty owner password!")
 		fmt.Fprintf(os.Stderr, "%s\n\n", usageEncrypt)
 		os.Exit(1)
@@ -453,6 +910,15 @@ func handleEncryptCommand(conf *pdfcpu.Configuration) {
 		conf.Permissions = pdfcpu.PermissionsAll
 	}
 
+	if cert != "" {
+		rr, err := pdfcpu.ParseRecipients(cert, conf.Permissions)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		conf.Recipients = rr
+	}
+
 	inFile := flag.Arg(0)
 	ensurePdfExtension(inFile)
 
@@ -625,6 +1091,16 @@ func handleInsertPagesCommand(conf *pdfcpu.Configuration) {
 		ensurePdfExtension(outFile)
 	}
 
+	if fromFile != "" {
+		handleInsertPagesFromCommand(inFile, outFile, conf)
+		return
+	}
+
+	if selectedFromPages != "" || before != 0 {
+		fmt.Fprintf(os.Stderr, "%s\n\n", usagePagesInsert)
+		os.Exit(1)
+	}
+
 	pages, err := api.ParsePageSelection(selectedPages)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "problem with flag selectedPages: %v\n", err)
@@ -634,38 +1110,164 @@ func handleInsertPagesCommand(conf *pdfcpu.Configuration) {
 	process(cli.InsertPagesCommand(inFile, outFile, pages, conf))
 }
 
-func handleRemovePagesCommand(conf *pdfcpu.Configuration) {
-	if len(flag.Args()) == 0 || len(flag.Args()) > 2 || selectedPages == "" {
-		fmt.Fprintf(os.Stderr, "%s\n\n", usagePagesRemove)
+func handleInsertPagesFromCommand(inFile, outFile string, conf *pdfcpu.Configuration) {
+	if selectedPages != "" || before < 0 {
+		fmt.Fprintf(os.Stderr, "%s\n\n", usagePagesInsert)
+		os.Exit(1)
+	}
+
+	ensurePdfExtension(fromFile)
+
+	pages, err := api.ParsePageSelection(selectedFromPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "problem with flag sel: %v\n", err)
+		os.Exit(1)
+	}
+
+	process(cli.InsertPagesFromCommand(inFile, fromFile, outFile, pages, before, conf))
+}
+
+func handleArrangePagesCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) < 2 || len(flag.Args()) > 3 || selectedPages != "" {
+		fmt.Fprintf(os.Stderr, "%s\n\n", usagePagesArrange)
+		os.Exit(1)
+	}
+
+	order, err := api.ParsePageOrder(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	inFile := flag.Arg(1)
+	ensurePdfExtension(inFile)
+	outFile := ""
+	if len(flag.Args()) == 3 {
+		outFile = flag.Arg(2)
+		ensurePdfExtension(outFile)
+	}
+
+	process(cli.ArrangePagesCommand(inFile, outFile, order, conf))
+}
+
+func handleRemoveBlankPagesCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) == 0 || len(flag.Args()) > 2 || (dryRun && len(flag.Args()) == 2) {
+		fmt.Fprintf(os.Stderr, "%s\n\n", usagePagesRemoveBlank)
+		os.Exit(1)
+	}
+
+	inFile := flag.Arg(0)
+	ensurePdfExtension(inFile)
+	outFile := ""
+	if len(flag.Args()) == 2 {
+		outFile = flag.Arg(1)
+		ensurePdfExtension(outFile)
+	}
+
+	pages, err := api.ParsePageSelection(selectedPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "problem with flag selectedPages: %v\n", err)
+		os.Exit(1)
+	}
+
+	if threshold <= 0 || threshold > 1 {
+		fmt.Fprintf(os.Stderr, "threshold: please provide a value 0.0 < threshold <= 1.0\n")
+		os.Exit(1)
+	}
+
+	process(cli.RemoveBlankPagesCommand(inFile, outFile, pages, threshold, dryRun, conf))
+}
+
+func handleRemovePagesCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) == 0 || len(flag.Args()) > 2 || selectedPages == "" {
+		fmt.Fprintf(os.Stderr, "%s\n\n", usagePagesRemove)
+		os.Exit(1)
+	}
+
+	inFile := flag.Arg(0)
+	ensurePdfExtension(inFile)
+	outFile := ""
+	if len(flag.Args()) == 2 {
+		outFile = flag.Arg(1)
+		ensurePdfExtension(outFile)
+	}
+
+	pages, err := api.ParsePageSelection(selectedPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "problem with flag selectedPages: %v\n", err)
+		os.Exit(1)
+	}
+	if pages == nil {
+		fmt.Fprintf(os.Stderr, "missing page selection\n")
+		os.Exit(1)
+	}
+
+	process(cli.RemovePagesCommand(inFile, outFile, pages, conf))
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+func handleResizeCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) < 2 || len(flag.Args()) > 3 {
+		fmt.Fprintf(os.Stderr, "%s\n\n", usageResize)
+		os.Exit(1)
+	}
+
+	pages, err := api.ParsePageSelection(selectedPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "problem with flag selectedPages: %v\n", err)
+		os.Exit(1)
+	}
+
+	res, err := pdfcpu.ParseResizeConfig(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
 		os.Exit(1)
 	}
 
-	inFile := flag.Arg(0)
+	inFile := flag.Arg(1)
 	ensurePdfExtension(inFile)
 	outFile := ""
-	if len(flag.Args()) == 2 {
-		outFile = flag.Arg(1)
+	if len(flag.Args()) == 3 {
+		outFile = flag.Arg(2)
 		ensurePdfExtension(outFile)
 	}
 
+	process(cli.ResizeCommand(inFile, outFile, pages, res, conf))
+}
+
+func handleCutCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) < 2 || len(flag.Args()) > 3 {
+		fmt.Fprintf(os.Stderr, "%s\n\n", usageCut)
+		os.Exit(1)
+	}
+
 	pages, err := api.ParsePageSelection(selectedPages)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "problem with flag selectedPages: %v\n", err)
 		os.Exit(1)
 	}
-	if pages == nil {
-		fmt.Fprintf(os.Stderr, "missing page selection\n")
+
+	cut, err := pdfcpu.ParseCutConfig(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
 		os.Exit(1)
 	}
 
-	process(cli.RemovePagesCommand(inFile, outFile, pages, conf))
-}
-
-func abs(i int) int {
-	if i < 0 {
-		return -i
+	inFile := flag.Arg(1)
+	ensurePdfExtension(inFile)
+	outFile := ""
+	if len(flag.Args()) == 3 {
+		outFile = flag.Arg(2)
+		ensurePdfExtension(outFile)
 	}
-	return i
+
+	process(cli.CutCommand(inFile, outFile, pages, cut, conf))
 }
 
 func handleRotateCommand(conf *pdfcpu.Configuration) {
@@ -788,6 +1390,72 @@ func handleNUpCommand(conf *pdfcpu.Configuration) {
 	process(cli.NUpCommand(inFiles, outFile, pages, nup, conf))
 }
 
+func handleBookletCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) < 2 || len(flag.Args()) > 3 {
+		fmt.Fprintf(os.Stderr, "%s\n\n", usageBooklet)
+		os.Exit(1)
+	}
+
+	pages, err := api.ParsePageSelection(selectedPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "problem with flag selectedPages: %v\n", err)
+		os.Exit(1)
+	}
+
+	// pdfcpu booklet [description] outFile inFile
+	args := flag.Args()
+	desc := ""
+	if len(args) == 3 {
+		desc, args = args[0], args[1:]
+	}
+
+	b, err := pdfcpu.ParseBookletConfig(desc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	outFile := args[0]
+	ensurePdfExtension(outFile)
+	inFile := args[1]
+	ensurePdfExtension(inFile)
+
+	process(cli.BookletCommand(inFile, outFile, pages, b, conf))
+}
+
+func handlePosterCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) < 2 || len(flag.Args()) > 3 {
+		fmt.Fprintf(os.Stderr, "%s\n\n", usagePoster)
+		os.Exit(1)
+	}
+
+	pages, err := api.ParsePageSelection(selectedPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "problem with flag selectedPages: %v\n", err)
+		os.Exit(1)
+	}
+
+	// pdfcpu poster [description] outFile inFile
+	args := flag.Args()
+	desc := ""
+	if len(args) == 3 {
+		desc, args = args[0], args[1:]
+	}
+
+	p, err := pdfcpu.ParsePosterConfig(desc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	outFile := args[0]
+	ensurePdfExtension(outFile)
+	inFile := args[1]
+	ensurePdfExtension(inFile)
+
+	process(cli.PosterCommand(inFile, outFile, pages, p, conf))
+}
+
 func handleGridCommand(conf *pdfcpu.Configuration) {
 	if len(flag.Args()) < 4 {
 		fmt.Fprintf(os.Stderr, "%s\n\n", usageGrid)
@@ -836,3 +1504,255 @@ func handleInfoCommand(conf *pdfcpu.Configuration) {
 
 	process(cli.InfoCommand(inFile, conf))
 }
+
+func handleListBoxesCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) == 0 || len(flag.Args()) > 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageBoxesList)
+		os.Exit(1)
+	}
+
+	pages, err := api.ParsePageSelection(selectedPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "problem with flag selectedPages: %v\n", err)
+		os.Exit(1)
+	}
+
+	var boxes []string
+
+	inFile := flag.Arg(0)
+	if len(flag.Args()) == 2 {
+		// pdfcpu boxes list boxTypes inFile
+		if boxes, err = pdfcpu.ParseBoxTypes(flag.Arg(0)); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		inFile = flag.Arg(1)
+	}
+	ensurePdfExtension(inFile)
+
+	process(cli.ListBoxesCommand(inFile, pages, boxes, conf))
+}
+
+func handleAddBoxesCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) < 2 || len(flag.Args()) > 3 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageBoxesAdd)
+		os.Exit(1)
+	}
+
+	pages, err := api.ParsePageSelection(selectedPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "problem with flag selectedPages: %v\n", err)
+		os.Exit(1)
+	}
+
+	pb, err := pdfcpu.ParsePageBoundaries(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	inFile := flag.Arg(1)
+	ensurePdfExtension(inFile)
+	outFile := ""
+	if len(flag.Args()) == 3 {
+		outFile = flag.Arg(2)
+		ensurePdfExtension(outFile)
+	}
+
+	process(cli.AddBoxesCommand(inFile, outFile, pages, pb, conf))
+}
+
+func handleCropCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) < 1 || len(flag.Args()) > 3 {
+		fmt.Fprintf(os.Stderr, "%s\n\n", usageCrop)
+		os.Exit(1)
+	}
+
+	pages, err := api.ParsePageSelection(selectedPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "problem with flag selectedPages: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The description is optional and never ends with .pdf.
+	args := flag.Args()
+	desc := ""
+	if !hasPdfExtension(args[0]) {
+		desc, args = args[0], args[1:]
+	}
+
+	if len(args) == 0 || len(args) > 2 {
+		fmt.Fprintf(os.Stderr, "%s\n\n", usageCrop)
+		os.Exit(1)
+	}
+
+	box, err := pdfcpu.ParseCropBox(desc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	inFile := args[0]
+	ensurePdfExtension(inFile)
+	outFile := ""
+	if len(args) == 2 {
+		outFile = args[1]
+		ensurePdfExtension(outFile)
+	}
+
+	process(cli.CropCommand(inFile, outFile, pages, box, conf))
+}
+
+func handleRemoveBoxesCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) < 2 || len(flag.Args()) > 3 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageBoxesRemove)
+		os.Exit(1)
+	}
+
+	pages, err := api.ParsePageSelection(selectedPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "problem with flag selectedPages: %v\n", err)
+		os.Exit(1)
+	}
+
+	boxes, err := pdfcpu.ParseBoxTypes(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	inFile := flag.Arg(1)
+	ensurePdfExtension(inFile)
+	outFile := ""
+	if len(flag.Args()) == 3 {
+		outFile = flag.Arg(2)
+		ensurePdfExtension(outFile)
+	}
+
+	process(cli.RemoveBoxesCommand(inFile, outFile, pages, boxes, conf))
+}
+
+func handleSignCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) < 1 || len(flag.Args()) > 3 || cert == "" {
+		fmt.Fprintf(os.Stderr, "%s\n\n", usageSign)
+		os.Exit(1)
+	}
+
+	// The description is optional and never ends with .pdf.
+	args := flag.Args()
+	desc := ""
+	if !hasPdfExtension(args[0]) {
+		desc, args = args[0], args[1:]
+	}
+
+	if len(args) == 0 || len(args) > 2 {
+		fmt.Fprintf(os.Stderr, "%s\n\n", usageSign)
+		os.Exit(1)
+	}
+
+	sign, err := pdfcpu.ParseSignConfig(desc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	if err := sign.ReadCredentials(cert, certKey, certPW); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	inFile := args[0]
+	ensurePdfExtension(inFile)
+	outFile := ""
+	if len(args) == 2 {
+		outFile = args[1]
+		ensurePdfExtension(outFile)
+	}
+
+	process(cli.SignCommand(inFile, outFile, sign, conf))
+}
+
+func handleListSignaturesCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) != 1 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageSignaturesList)
+		os.Exit(1)
+	}
+
+	inFile := flag.Arg(0)
+	ensurePdfExtension(inFile)
+
+	process(cli.ListSignaturesCommand(inFile, conf))
+}
+
+func handleVerifySignaturesCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) != 1 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageSignaturesVerify)
+		os.Exit(1)
+	}
+
+	if trust != "" {
+		pool, err := pdfcpu.ReadTrustedCerts(strings.Split(trust, ",")...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		conf.TrustedCerts = pool
+	}
+
+	inFile := flag.Arg(0)
+	ensurePdfExtension(inFile)
+
+	process(cli.VerifySignaturesCommand(inFile, conf))
+}
+
+func handleAddLTVCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) < 1 || len(flag.Args()) > 3 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageSignaturesLTV)
+		os.Exit(1)
+	}
+
+	// The description is optional and never ends with .pdf.
+	args := flag.Args()
+	desc := ""
+	if !hasPdfExtension(args[0]) {
+		desc, args = args[0], args[1:]
+	}
+
+	if len(args) == 0 || len(args) > 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n", usageSignaturesLTV)
+		os.Exit(1)
+	}
+
+	ltv, err := pdfcpu.ParseLTVConfig(desc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	inFile := args[0]
+	ensurePdfExtension(inFile)
+	outFile := ""
+	if len(args) == 2 {
+		outFile = args[1]
+		ensurePdfExtension(outFile)
+	}
+
+	process(cli.AddLTVCommand(inFile, outFile, ltv, conf))
+}
+
+func handleTimeStampCommand(conf *pdfcpu.Configuration) {
+	if len(flag.Args()) < 1 || len(flag.Args()) > 2 || tsa == "" {
+		fmt.Fprintf(os.Stderr, "%s\n\n", usageTimeStamp)
+		os.Exit(1)
+	}
+
+	inFile := flag.Arg(0)
+	ensurePdfExtension(inFile)
+	outFile := ""
+	if len(flag.Args()) == 2 {
+		outFile = flag.Arg(1)
+		ensurePdfExtension(outFile)
+	}
+
+	process(cli.TimeStampCommand(inFile, outFile, cms.HTTPTSAClient{URL: tsa}, conf))
+}
@@ -55,9 +55,13 @@ func (m CommandMap) Register(cmdStr string, cmd Command) {
 	m[cmdStr] = &cmd
 }
 
-// Handle applies command completion and if successful
-// executes the resulting command.
-func (m CommandMap) Handle(cmdPrefix string, command string, conf *pdfcpu.Configuration) (string, error) {
+// complete returns the command cmdPrefix is an unambiguous prefix of.
+// An exact match takes precedence, eg. "remove" vs. "removeblank".
+func (m CommandMap) complete(cmdPrefix string) (string, error) {
+
+	if _, ok := m[cmdPrefix]; ok {
+		return cmdPrefix, nil
+	}
 
 	var cmdStr string
 
@@ -67,13 +71,25 @@ func (m CommandMap) Handle(cmdPrefix string, command string, conf *pdfcpu.Config
 			continue
 		}
 		if len(cmdStr) > 0 {
-			return command, errAmbiguousCmd
+			return "", errAmbiguousCmd
 		}
 		cmdStr = k
 	}
 
 	if cmdStr == "" {
-		return command, errUnknownCmd
+		return "", errUnknownCmd
+	}
+
+	return cmdStr, nil
+}
+
+// Handle applies command completion and if successful
+// executes the resulting command.
+func (m CommandMap) Handle(cmdPrefix string, command string, conf *pdfcpu.Configuration) (string, error) {
+
+	cmdStr, err := m.complete(cmdPrefix)
+	if err != nil {
+		return command, err
 	}
 
 	parseFlags(m[cmdStr])
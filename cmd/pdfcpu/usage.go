@@ -25,12 +25,19 @@ Usage:
    
 The commands are:
 
+   annotations list, add, remove annotations
    attachments list, add, remove, extract embedded file attachments
+   booklet     arrange pages onto sheets for folding into a booklet
+   boxes       list, add, remove page boundaries for selected pages
    changeopw   change owner password
    changeupw   change user password
+   crop        set cropbox of selected pages to margins or content bounding box
+   cut         cut selected pages into sub pages
    decrypt     remove password protection
    encrypt     set password protection		
    extract     extract images, fonts, content, pages, metadata
+   flatten     draw annotations into the page content
+   form        list, fill, export, import, flatten, create form fields
    grid        rearrange pages or images for enhanced browsing experience
    import      import/convert images to PDF
    info        print file info
@@ -40,9 +47,14 @@ The commands are:
    pages       insert, remove selected pages
    paper       print list of supported paper sizes
    permissions list, set user access permissions
+   poster      tile selected pages across multiple sheets
+   resize      scale selected pages to a paper size or page dimensions
    rotate      rotate pages
-   split       split multi-page PDF into several PDFs according to split span
+   sign        digitally sign PDF
+   signatures  list, verify digital signatures, add validation data
+   split       split multi-page PDF into several PDFs by span, bookmarks, pages or file size
    stamp       add text, image or PDF stamp to selected pages
+   timestamp   add a document time-stamp
    trim        create trimmed version of selected pages
    validate    validate PDF against PDF 32000-1:2008 (PDF 1.7)
    version     print version
@@ -82,26 +94,60 @@ verbose, v ... turn on logging
     inFile ... input pdf file
    outFile ... output pdf file (default: inFile-new.pdf)`
 
-	usageSplit     = "usage: pdfcpu split [-v(erbose)|vv] [-q(uiet)] [-upw userpw] [-opw ownerpw] inFile outDir [span]"
-	usageLongSplit = `Generate a set of PDFs for the input file in outDir according to given span value.
+	usageSplit     = "usage: pdfcpu split [-v(erbose)|vv] [-q(uiet)] [-mode span|bookmark|pages|size] [-upw userpw] [-opw ownerpw] inFile outDir [span|pageNr...|maxSize]"
+	usageLongSplit = `Generate a set of PDFs for the input file in outDir according to the split mode.
 
 verbose, v ... turn on logging
         vv ... verbose logging
   quiet, q ... disable output
+      mode ... split mode (default: span)
        upw ... user password
        opw ... owner password
     inFile ... input pdf file
     outDir ... output directory
-      span ... split span in pages (default: 1)`
+      span ... split span in pages (default: 1)
+    pageNr ... page number starting a new file
+   maxSize ... maximum file size in bytes, optionally followed by k(b) or m(b)
 
-	usageMerge     = "usage: pdfcpu merge [-v(erbose)|vv] [-q(uiet)] outFile inFile..."
+ The split modes are:
+
+     span ... split into files of span pages each
+ bookmark ... split at each top level bookmark, files are named after the bookmark title
+    pages ... split before each given page number
+     size ... split into files not exceeding maxSize, a single page exceeding maxSize goes into a file of its own
+
+e.g. pdfcpu split in.pdf out 2
+     pdfcpu split -mode bookmark in.pdf out
+     pdfcpu split -mode pages in.pdf out 3 7 12
+     pdfcpu split -mode size in.pdf out 5mb`
+
+	usageMerge     = "usage: pdfcpu merge [-v(erbose)|vv] [-q(uiet)] [-mode concat|zip] [-r(everse)] [-f(ill)] [-b(ookmarks)] outFile inFile[:selectedPages]..."
 	usageLongMerge = `Concatenate a sequence of PDFs/inFiles into outFile.
 
-verbose, v ... turn on logging
-        vv ... verbose logging
-  quiet, q ... disable output
-   outFile ... output pdf file
-   inFiles ... a list of at least 2 pdf files subject to concatenation.`
+   verbose, v ... turn on logging
+           vv ... verbose logging
+     quiet, q ... disable output
+         mode ... merge mode (defaults to concat)
+   reverse, r ... zip: take the pages of the second inFile in reverse order
+      fill, f ... zip: pad the shorter inFile with blank pages instead of failing
+ bookmarks, b ... create a top level bookmark for each inFile pointing to its first page.
+                  The bookmark title is the document title or else the file name.
+                  Existing bookmarks are nested underneath.
+      outFile ... output pdf file
+      inFiles ... a list of at least 2 pdf files subject to concatenation.
+
+In concat mode each inFile may be followed by a colon and a page selection
+restricting the pages to be merged (see pdfcpu help extract for the syntax):
+
+   pdfcpu merge out.pdf a.pdf:1-3 b.pdf:even c.pdf
+
+The merge modes are:
+
+ concat ... (default) concatenate inFiles
+    zip ... interleave the pages of exactly 2 inFiles,
+            eg. the front and back sides of a duplex job scanned in 2 passes:
+
+            pdfcpu merge -mode zip -reverse out.pdf fronts.pdf backs.pdf`
 
 	usagePageSelection = `'-pages' selects pages for processing and is a comma separated list of expressions:
 
@@ -157,6 +203,161 @@ verbose, v ... turn on logging
    
 ` + usagePageSelection
 
+	usageFlattenAnnots = "pdfcpu flatten annotations [-v(erbose)|vv] [-q(uiet)] [-pages selectedPages] [-hidden] [-noprint] [-upw userpw] [-opw ownerpw] [types] inFile [outFile]"
+
+	usageFlatten = "usage: " + usageFlattenAnnots
+
+	usageLongFlatten = `Draw the appearance of annotations into the page content and remove the annotations.
+
+    verbose, v ... turn on logging
+            vv ... verbose logging
+      quiet, q ... disable output
+         pages ... selected pages
+        hidden ... also flatten hidden annotations
+       noprint ... also flatten annotations not meant to be printed
+           upw ... user password
+           opw ... owner password
+         types ... comma separated list of annotation types, eg. Text,Stamp,FreeText
+        inFile ... input pdf file
+       outFile ... output pdf file
+
+   By default only annotations that are visible and meant to be printed get flattened.
+   Annotations without appearance stream (eg. most links) and form field widgets are left untouched.
+   Popups go along with the annotation they belong to.
+
+   e.g. pdfcpu flatten annotations in.pdf out.pdf
+        pdfcpu flatten annotations -pages 1-3 Stamp,Ink in.pdf
+        pdfcpu flatten annotations -noprint in.pdf`
+
+	usageFormList    = "pdfcpu form list    [-v(erbose)|vv] [-q(uiet)] [-upw userpw] [-opw ownerpw] inFile"
+	usageFormFill    = "pdfcpu form fill    [-v(erbose)|vv] [-q(uiet)] [-upw userpw] [-opw ownerpw] jsonFile inFile [outFile]"
+	usageFormExport  = "pdfcpu form export  [-v(erbose)|vv] [-q(uiet)] [-format json|fdf|xfdf] [-upw userpw] [-opw ownerpw] inFile [dataFile]"
+	usageFormImport  = "pdfcpu form import  [-v(erbose)|vv] [-q(uiet)] [-format fdf|xfdf] [-upw userpw] [-opw ownerpw] dataFile inFile [outFile]"
+	usageFormFlatten = "pdfcpu form flatten [-v(erbose)|vv] [-q(uiet)] [-annots] [-upw userpw] [-opw ownerpw] inFile [outFile]"
+	usageFormCreate  = "pdfcpu form create  [-v(erbose)|vv] [-q(uiet)] [-upw userpw] [-opw ownerpw] jsonFile inFile [outFile]"
+
+	usageForm = "usage: " + usageFormList +
+		"\n       " + usageFormFill +
+		"\n       " + usageFormExport +
+		"\n       " + usageFormImport +
+		"\n       " + usageFormFlatten +
+		"\n       " + usageFormCreate
+
+	usageLongForm = `Manage interactive forms (AcroForms).
+
+    verbose, v ... turn on logging
+            vv ... verbose logging
+      quiet, q ... disable output
+        annots ... also flatten all other annotations
+        format ... json, fdf or xfdf, defaults to the extension of dataFile
+           upw ... user password
+           opw ... owner password
+      jsonFile ... JSON file containing field values or field specs
+      dataFile ... JSON, FDF or XFDF file
+        inFile ... input pdf file
+       outFile ... output pdf file
+
+   list prints type, name, value, options and flags of each form field.
+
+   fill sets field values by fully qualified field name:
+
+         text field ... string
+           checkbox ... true, false or the name of the on state
+        radio group ... the export value of a button
+     combo/list box ... an option (export value or display text), list boxes also take an array
+
+   The appearance of filled fields gets regenerated.
+   jsonFile may contain an array of value sets in which case one file per set gets written
+   named outFile_1.pdf, outFile_2.pdf ... (inFile_1.pdf ... without outFile).
+
+   export writes the field values as JSON suitable for form fill or as FDF/XFDF including annotations.
+   dataFile defaults to inFile with the extension of the format, json if not given.
+
+   import fills the form with the field values of an FDF or XFDF file and adds its annotations.
+
+   flatten draws all visible and printable fields into the page content and removes the form making the file non-editable.
+   Hidden and non printing fields get discarded.
+   Missing appearances of text and choice fields get generated. Other annotations are kept unless -annots is given.
+
+   create adds new fields along with their widgets and appearances, jsonFile holds an array of field specs:
+
+           page ... page number
+           rect ... [llx, lly, urx, ury]
+           name ... partial field name, radio buttons sharing a name make up a group
+           type ... Text, CheckBox, RadioGroup, ComboBox or ListBox
+        default ... default value as for fill
+ font, fontSize ... a standard font (Helvetica) and size (0 = auto)
+        options ... choices, the on state of a check box, the export value of a radio button
+                    optional: color, align, maxLen, flags, tooltip, borderColor, backgroundColor
+
+   e.g. {"Name": "Jane Doe", "Subscribe": true, "Payment": "card2", "Colors": ["r", "b"]}
+        [{"page": 1, "rect": [100, 700, 300, 720], "name": "Name", "type": "Text", "fontSize": 12}]
+
+   e.g. pdfcpu form list in.pdf
+        pdfcpu form fill values.json in.pdf out.pdf
+        pdfcpu form export in.pdf values.json
+        pdfcpu form export -format xfdf in.pdf
+        pdfcpu form import data.fdf in.pdf out.pdf
+        pdfcpu form flatten filled.pdf final.pdf
+        pdfcpu form create fields.json in.pdf out.pdf`
+
+	usageAnnotsList   = "pdfcpu annotations list   [-v(erbose)|vv] [-q(uiet)] [-pages selectedPages] [-upw userpw] [-opw ownerpw] inFile"
+	usageAnnotsAdd    = "pdfcpu annotations add    [-v(erbose)|vv] [-q(uiet)] [-pages selectedPages] [-upw userpw] [-opw ownerpw] jsonFile inFile [outFile]"
+	usageAnnotsRemove = "pdfcpu annotations remove [-v(erbose)|vv] [-q(uiet)] [-pages selectedPages] [-upw userpw] [-opw ownerpw] [filter] inFile [outFile]"
+
+	usageAnnots = "usage: " + usageAnnotsList +
+		"\n       " + usageAnnotsAdd +
+		"\n       " + usageAnnotsRemove
+
+	usageLongAnnots = `Manage annotations like comments, highlights, stamps and links.
+
+    verbose, v ... turn on logging
+            vv ... verbose logging
+      quiet, q ... disable output
+         pages ... selected pages
+           upw ... user password
+           opw ... owner password
+      jsonFile ... JSON file containing an annotation or an array of annotations
+        filter ... comma separated list of annotation types and/or ids
+        inFile ... input pdf file
+       outFile ... output pdf file
+
+   An annotation id is either the annotation name (NM) or the object number as listed.
+   Without filter all annotations of the selected pages get removed except widgets belonging to form fields.
+   Popups and replies go along with the annotation they belong to.
+
+   An annotation is added to the page it specifies, otherwise to all selected pages.
+   Supported annotation types:
+
+      Text, FreeText, Stamp, Link, Line, Square, Circle, Polygon, PolyLine,
+      Highlight, Underline, Squiggly, StrikeOut, Caret, Ink
+
+   Rect, color and border apply to all types, in addition:
+
+             Text ... icon (Note, Comment, Help, ...), open
+            Stamp ... icon (Approved, Draft, Confidential, ...)
+             Link ... uri or dest (page number)
+         FreeText ... fontSize
+             Line ... line [x1 y1 x2 y2], interiorColor
+   Square, Circle ... interiorColor
+Polygon, PolyLine ... vertices [x1 y1 x2 y2 ...], interiorColor
+ Highlight et al. ... quadPoints [x1 y1 ... x4 y4 ...], defaults to rect
+              Ink ... inkList [[x1 y1 x2 y2 ...] ...]
+
+   Markup annotations (all but Link) also take title (the author) and subject.
+   Rect may be omitted for annotations defined by points (line, vertices, quadPoints, inkList).
+
+   e.g. [
+          {"page": 1, "type": "Text", "rect": [50, 700, 70, 720], "contents": "Please review", "title": "Jane", "color": [1, 1, 0]},
+          {"type": "Highlight", "rect": [72, 600, 300, 615], "color": [1, 1, 0], "contents": "Check figures"}
+        ]
+
+   e.g. pdfcpu annotations list in.pdf
+        pdfcpu annotations add -pages 1 notes.json in.pdf out.pdf
+        pdfcpu annotations remove in.pdf out.pdf
+        pdfcpu annotations remove -pages 2-3 Text,Highlight in.pdf
+        pdfcpu annotations remove 12,note-7 in.pdf`
+
 	usageAttachList    = "pdfcpu attachments list    [-v(erbose)|vv] [-q(uiet)] [-upw userpw] [-opw ownerpw] inFile"
 	usageAttachAdd     = "pdfcpu attachments add     [-v(erbose)|vv] [-q(uiet)] [-upw userpw] [-opw ownerpw] inFile file..."
 	usageAttachRemove  = "pdfcpu attachments remove  [-v(erbose)|vv] [-q(uiet)] [-upw userpw] [-opw ownerpw] inFile [file...]"
@@ -177,6 +378,176 @@ verbose, v ... turn on logging
     inFile ... input pdf file
     outDir ... output directory`
 
+	usageSign     = "usage: pdfcpu sign [-v(erbose)|vv] [-q(uiet)] -cert certFile [-certkey keyFile] [-certpw password] [-upw userpw] [-opw ownerpw] [description] inFile [outFile]"
+	usageLongSign = `Digitally sign inFile. The signature is appended as an incremental update.
+
+    verbose, v ... turn on logging
+            vv ... verbose logging
+      quiet, q ... disable output
+          cert ... PKCS#12 file (.p12, .pfx) or PEM certificate file incl. optional chain
+       certkey ... PEM private key file, defaults to cert
+        certpw ... PKCS#12 password
+           upw ... user password
+           opw ... owner password
+   description ... signature configuration
+        inFile ... input pdf file
+       outFile ... output pdf file
+
+   <description> is a comma separated configuration string containing these optional entries:
+
+      (defaults: "page:1, format:pkcs7")
+
+      field:    the name of the signature field, defaults to Signature1, Signature2..
+      page:     the page displaying the signature widget
+      rect:     llx lly urx ury, the position of a visible signature, omit for an invisible signature
+      reason:   the reason for signing
+      location: the location of signing
+      contact:  contact info of the signer
+      name:     the name of the signer, defaults to the common name of the signer certificate
+      format:   pkcs7 ... adbe.pkcs7.detached
+                cades ... ETSI.CAdES.detached (PAdES baseline)
+
+   Encrypted files cannot be signed.
+
+   e.g. pdfcpu sign -cert signer.p12 -certpw secret in.pdf out.pdf
+        pdfcpu sign -cert signer.pem -certkey signer.key "reason: Approved, location: Berlin" in.pdf
+        pdfcpu sign -cert signer.p12 -certpw secret "page: 2, rect: 50 50 250 100, format: cades" in.pdf
+`
+
+	usageTimeStamp     = "usage: pdfcpu timestamp [-v(erbose)|vv] [-q(uiet)] -tsa url inFile [outFile]"
+	usageLongTimeStamp = `Add a document time-stamp (ETSI.RFC3161) obtained from an RFC 3161 time-stamp authority to inFile.
+The time-stamp is appended as an incremental update and covers all prior revisions including their signatures.
+
+    verbose, v ... turn on logging
+            vv ... verbose logging
+      quiet, q ... disable output
+           tsa ... URL of the time-stamp authority, credentials for basic authentication may be part of the URL
+        inFile ... input pdf file
+       outFile ... output pdf file
+
+   Encrypted files cannot be time-stamped.
+
+   e.g. pdfcpu timestamp -tsa http://timestamp.example.com signed.pdf
+        pdfcpu timestamp -tsa http://timestamp.example.com signed.pdf stamped.pdf
+`
+
+	usageSignaturesList   = "pdfcpu signatures list   [-v(erbose)|vv] [-q(uiet)] [-upw userpw] [-opw ownerpw] inFile"
+	usageSignaturesVerify = "pdfcpu signatures verify [-v(erbose)|vv] [-q(uiet)] [-trust certFiles] [-upw userpw] [-opw ownerpw] inFile"
+	usageSignaturesLTV    = "pdfcpu signatures ltv    [-v(erbose)|vv] [-q(uiet)] [description] inFile [outFile]"
+
+	usageSignatures = "usage: " + usageSignaturesList +
+		"\n       " + usageSignaturesVerify +
+		"\n       " + usageSignaturesLTV
+
+	usageLongSignatures = `Report and verify digital signatures.
+
+    verbose, v ... turn on logging
+            vv ... verbose logging
+      quiet, q ... disable output
+         trust ... comma separated list of PEM or DER files holding trusted certificates, defaults to the system roots
+           upw ... user password
+           opw ... owner password
+   description ... ltv configuration
+        inFile ... input pdf file
+       outFile ... output pdf file
+
+   list prints for each signed signature field the signer certificate, the signing time,
+   the ByteRange and whether the file has been modified by incremental updates after signing.
+
+   verify additionally checks the message digest and the signature value of each signature
+   and whether the signer certificate chains up to a trusted certificate at signing time.
+   Exits with an error if any signature is invalid.
+
+   ltv appends a document security store (DSS) holding the certificate chains and the revocation data
+   of all signatures as an incremental update enabling long-term validation.
+   <description> is a comma separated configuration string containing these optional entries:
+
+      certs: CA and OCSP responder certificates not embedded in the signatures (PEM or DER files)
+      crls:  certificate revocation lists (PEM or DER files)
+      ocsps: DER encoded OCSP responses
+
+   Multiple files per entry are separated by space.
+
+   e.g. pdfcpu signatures list signed.pdf
+        pdfcpu signatures verify -trust ca.pem signed.pdf
+        pdfcpu signatures ltv "certs: ca.pem, crls: ca.crl, ocsps: signer.ocsp" signed.pdf
+`
+
+	usageCrop     = "usage: pdfcpu crop [-v(erbose)|vv] [-q(uiet)] [-pages selectedPages] [-upw userpw] [-opw ownerpw] [description] inFile [outFile]"
+	usageLongCrop = `Set the CropBox of selected pages.
+
+    verbose, v ... turn on logging
+            vv ... verbose logging
+      quiet, q ... disable output
+         pages ... selected pages
+           upw ... user password
+           opw ... owner password
+   description ... auto (default) or box definition
+        inFile ... input pdf file
+       outFile ... output pdf file
+
+   A box is defined by one of:
+
+                        auto ... bounding box of the visible page content (text, paths, images)
+           [llx lly urx ury] ... absolute rectangle in user units (72 dpi)
+     top [right bottom left] ... margins relative to the MediaBox in user units or % of the MediaBox dimensions,
+                                 1 value applies to all sides, 2 values: vertical horizontal
+                  paper size ... eg. A4, A4L, Letter - centered within the MediaBox, see pdfcpu paper
+
+   The CropBox is clipped to the MediaBox.
+   Pages without visible content are left unchanged.
+   White fills are ignored when detecting the content bounding box.
+   Full page images like scanned pages are taken into account with their full extent.
+
+   e.g. pdfcpu crop in.pdf out.pdf
+        pdfcpu crop -pages 2- "10 20" in.pdf
+        pdfcpu crop "[20 20 575 822]" in.pdf out.pdf
+        pdfcpu crop A5 in.pdf
+
+` + usagePageSelection
+
+	usageBoxesList   = "pdfcpu boxes list   [-v(erbose)|vv] [-q(uiet)] [-pages selectedPages] [-upw userpw] [-opw ownerpw] [boxTypes] inFile"
+	usageBoxesAdd    = "pdfcpu boxes add    [-v(erbose)|vv] [-q(uiet)] [-pages selectedPages] [-upw userpw] [-opw ownerpw] description inFile [outFile]"
+	usageBoxesRemove = "pdfcpu boxes remove [-v(erbose)|vv] [-q(uiet)] [-pages selectedPages] [-upw userpw] [-opw ownerpw] boxTypes inFile [outFile]"
+
+	usageBoxes = "usage: " + usageBoxesList +
+		"\n       " + usageBoxesAdd +
+		"\n       " + usageBoxesRemove
+
+	usageLongBoxes = `Manage page boundaries.
+
+    verbose, v ... turn on logging
+            vv ... verbose logging
+      quiet, q ... disable output
+         pages ... selected pages
+           upw ... user password
+           opw ... owner password
+      boxTypes ... comma separated list of box types: media, crop, trim, bleed, art
+   description ... comma separated list of box definitions: boxType: box
+        inFile ... input pdf file
+       outFile ... output pdf file
+
+   Box types may be abbreviated by their first letter: m, c, t, b, a
+
+   A box is defined by one of:
+
+         [llx lly urx ury] ... absolute rectangle in user units (72 dpi)
+   top [right bottom left] ... margins relative to the MediaBox in user units or % of the MediaBox dimensions,
+                               1 value applies to all sides, 2 values: vertical horizontal
+                  paper size ... eg. A4, A4L, Letter - centered within the MediaBox, see pdfcpu paper
+
+   All boxes other than the MediaBox are clipped to the MediaBox.
+   Removed boxes fall back to their default: the CropBox defaults to the MediaBox,
+   the TrimBox, BleedBox and ArtBox default to the CropBox.
+
+   e.g. pdfcpu boxes list in.pdf
+        pdfcpu boxes list trim,bleed in.pdf
+        pdfcpu boxes add "trim: 10, bleed: 1%" in.pdf out.pdf
+        pdfcpu boxes add -pages 1-3 "t: A5, a: [20 20 400 580]" in.pdf
+        pdfcpu boxes remove t,b,a in.pdf
+
+` + usagePageSelection
+
 	usagePermList = "pdfcpu permissions list [-v(erbose)|vv] [-q(uiet)] [-upw userpw] [-opw ownerpw] inFile"
 	usagePermSet  = "pdfcpu permissions set  [-v(erbose)|vv] [-q(uiet)] [-perm none|all] [-upw userpw] -opw ownerpw inFile"
 
@@ -193,8 +564,9 @@ verbose, v ... turn on logging
        opw ... owner password
     inFile ... input pdf file`
 
-	usageEncrypt     = "usage: pdfcpu encrypt [-v(erbose)|vv] [-q(uiet)] [-mode rc4|aes] [-key 40|128|256] [perm none|all] [-upw userpw] -opw ownerpw inFile [outFile]"
-	usageLongEncrypt = `Setup password protection based on user and owner password.
+	usageEncrypt     = "usage: pdfcpu encrypt [-v(erbose)|vv] [-q(uiet)] [-mode rc4|aes] [-key 40|128|256] [perm none|all] [-upw userpw] -opw ownerpw | -cert certFiles inFile [outFile]"
+	usageLongEncrypt = `Setup password protection based on user and owner password
+or public-key encryption for a list of recipient certificates.
 
 verbose, v ... turn on logging
         vv ... verbose logging
@@ -204,17 +576,28 @@ verbose, v ... turn on logging
       perm ... user access permissions
        upw ... user password
        opw ... owner password (must not be empty!)
+      cert ... comma separated list of PEM or DER recipient certificate files replacing the passwords,
+               append :none or :all to a file name for recipient specific permissions
     inFile ... input pdf file
-   outFile ... output pdf file`
+   outFile ... output pdf file
 
-	usageDecrypt     = "usage: pdfcpu decrypt [-v(erbose)|vv] [-q(uiet)] [-upw userpw] [-opw ownerpw] inFile [outFile]"
-	usageLongDecrypt = `Remove password protection and reset permissions.
+   Public-key encryption (Adobe.PubSec) supports RSA recipient certificates only.
+   Each recipient opens the file using its own private key and gets its own permissions.
+
+   e.g. pdfcpu encrypt -opw secret in.pdf out.pdf
+        pdfcpu encrypt -cert alice.pem,bob.pem:all -perm none in.pdf out.pdf`
+
+	usageDecrypt     = "usage: pdfcpu decrypt [-v(erbose)|vv] [-q(uiet)] [-upw userpw] [-opw ownerpw] [-cert certFile [-certkey keyFile] [-certpw password]] inFile [outFile]"
+	usageLongDecrypt = `Remove password protection or public-key encryption and reset permissions.
 
 verbose, v ... turn on logging
         vv ... verbose logging
   quiet, q ... disable output
        upw ... user password
        opw ... owner password
+      cert ... recipient credentials: PKCS#12 file (.p12, .pfx) or PEM certificate file
+   certkey ... PEM private key file, defaults to cert
+    certpw ... PKCS#12 password
     inFile ... input pdf file
    outFile ... output pdf file`
 
@@ -337,11 +720,18 @@ description ... dimensions, format, position, offset, scale factor
        'd:300 600, p:bl, o:20 20, s:1.0 abs' ... render the image anchored to bottom left corner with offset 20,20 and abs. scaling 1.0.
        'p:full'                              ... render the image to a page with corresponding dimensions.`
 
-	usagePagesInsert = "pdfcpu pages insert [-v(erbose)|vv] [-q(uiet)] [-pages selectedPages] [-upw userpw] [-opw ownerpw] inFile [outFile]"
+	usagePagesInsert = "pdfcpu pages insert [-v(erbose)|vv] [-q(uiet)] [-pages selectedPages] [-upw userpw] [-opw ownerpw] inFile [outFile]" +
+		"\n       pdfcpu pages insert [-v(erbose)|vv] [-q(uiet)] -from fromFile [-sel selectedPages] [-before page] [-upw userpw] [-opw ownerpw] inFile [outFile]"
 	usagePagesRemove = "pdfcpu pages remove [-v(erbose)|vv] [-q(uiet)] -pages selectedPages  [-upw userpw] [-opw ownerpw] inFile [outFile]"
 
+	usagePagesRemoveBlank = "pdfcpu pages removeblank [-v(erbose)|vv] [-q(uiet)] [-pages selectedPages] [-threshold t] [-dryrun] [-upw userpw] [-opw ownerpw] inFile [outFile]"
+
+	usagePagesArrange = "pdfcpu pages arrange [-v(erbose)|vv] [-q(uiet)] [-upw userpw] [-opw ownerpw] order inFile [outFile]"
+
 	usagePages = "usage: " + usagePagesInsert +
-		"\n       " + usagePagesRemove
+		"\n       " + usagePagesRemove +
+		"\n       " + usagePagesRemoveBlank +
+		"\n       " + usagePagesArrange
 
 	usageLongPages = `Manage pages.
 
@@ -349,11 +739,136 @@ description ... dimensions, format, position, offset, scale factor
          vv ... verbose logging
    quiet, q ... disable output
       pages ... selected pages
+       from ... insert: insert pages from this pdf file instead of blank pages
+        sel ... insert: selected pages of fromFile (defaults to all pages)
+     before ... insert: insert before this page (defaults to appending)
+  threshold ... removeblank: minimum share of white pixels of an image considered blank (defaults to 0.995)
+     dryrun ... removeblank: list the blank pages without removing them
         upw ... user password
         opw ... owner password
+      order ... page order expression
      inFile ... input pdf file
     outFile ... output pdf file
 
+    <order> is a comma separated list of pages and page ranges defining the resulting page sequence:
+
+           # ... page #, l denotes the last page
+         #-# ... page range, descending if the second page comes first eg. l-1
+          #- ... page # until the last page
+          -# ... first page until page #
+    even/odd ... all even/odd pages
+
+    Pages may be repeated, pages not mentioned get dropped.
+    Repeated pages are copies including their annotations.
+
+    Alternatively <order> is one of:
+
+      reverse ... reverse page order
+    duplicate ... repeat each page once
+      collate ... interleave front and back sides of a duplex job scanned in 2 passes,
+                  the back sides coming in reverse order after the front sides
+
+    e.g. pdfcpu pages arrange 3,1,2,5-l,4 in.pdf out.pdf
+         pdfcpu pages arrange reverse in.pdf
+         pdfcpu pages arrange collate scan.pdf out.pdf
+
+    Without -from, insert adds a blank page before each selected page.
+    With -from, insert migrates the selected pages of fromFile:
+
+    e.g. pdfcpu pages insert -from cover.pdf -before 1 in.pdf out.pdf
+         pdfcpu pages insert -from appendix.pdf -sel 2-4 -before 5 in.pdf out.pdf
+
+    removeblank removes pages drawing nothing visible apart from near-white images,
+    eg. the blank back sides of a duplex scan. A pixel counts as white if its luminance
+    is at least 90%, an image is near-white if the share of its white pixels is at least threshold:
+
+    e.g. pdfcpu pages removeblank -dryrun scan.pdf
+         pdfcpu pages removeblank -threshold 0.98 scan.pdf out.pdf
+
+` + usagePageSelection
+
+	usageResize     = "usage: pdfcpu resize [-v(erbose)|vv] [-q(uiet)] [-pages selectedPages] [-upw userpw] [-opw ownerpw] description inFile [outFile]"
+	usageLongResize = `Resize selected pages to a paper size or page dimensions.
+The page content is scaled and centered on the new page.
+
+ verbose, v ... turn on logging
+         vv ... verbose logging
+   quiet, q ... disable output
+      pages ... selected pages
+        upw ... user password
+        opw ... owner password
+description ... paper size or configuration string
+     inFile ... input pdf file
+    outFile ... output pdf file
+
+    <description> is a paper size like A4L or a comma separated configuration string containing:
+
+    optional entries:
+
+        (defaults: f:A4, m:fit)
+
+    d: dimensions (width,height) in user units eg. '400 200'
+
+    f: form/paper size, eg. A4, Letter, Legal...
+                           Please refer to "pdfcpu help paper" for a comprehensive list of defined paper sizes.
+                           Appended 'L' enforces landscape mode. (eg. A3L)
+                           Appended 'P' enforces portrait mode. (eg. TabloidP)
+                           Only one of dimensions or format is allowed.
+
+    m: mode, one of fit     ... scale preserving the aspect ratio, the content fits the page (=default)
+                    fill    ... scale preserving the aspect ratio, the content covers the page
+                    stretch ... scale width and height independently
+
+    c: background color, 3 intensities 0.0 <= i <= 1.0 eg. '1 1 1', none(=default)
+
+    The visible region (CropBox) of each page gets resized.
+    Annotations, TrimBox, BleedBox and ArtBox are adjusted accordingly.
+    For rotated pages width and height are swapped.
+
+    e.g. pdfcpu resize A4 in.pdf out.pdf
+         pdfcpu resize -pages 1-3 "f: LetterL, m: fill" in.pdf
+         pdfcpu resize "d: 500 800, m: stretch, c: 0.9 0.9 0.9" in.pdf out.pdf
+
+` + usagePageSelection
+
+	usageCut     = "usage: pdfcpu cut [-v(erbose)|vv] [-q(uiet)] [-pages selectedPages] [-upw userpw] [-opw ownerpw] description inFile [outFile]"
+	usageLongCut = `Cut selected pages into sub pages along horizontal and vertical cuts.
+Each sub page replaces the original page and shows its share of the original content.
+
+ verbose, v ... turn on logging
+         vv ... verbose logging
+   quiet, q ... disable output
+      pages ... selected pages
+        upw ... user password
+        opw ... owner password
+description ... cuts and order
+     inFile ... input pdf file
+    outFile ... output pdf file
+
+    <description> is a comma separated configuration string containing:
+
+    at least one of:
+
+    h: horizontal cuts, fractions of the page height measured from the top eg. '.5' or '.33 .66'
+
+    v: vertical cuts, fractions of the page width measured from the left eg. '.5'
+
+    optional entries:
+
+        (defaults: o:ltr)
+
+    o: order of the sub pages within a row, one of ltr ... left to right (=default)
+                                                   rtl ... right to left
+
+    Cuts refer to the visible region (CropBox) of the page as displayed.
+    Sub pages are ordered in rows from top to bottom.
+    Annotations intersecting a sub page get copied to it.
+    Links, bookmarks and named destinations pointing to a cut page lead to its first sub page.
+
+    e.g. pdfcpu cut "v: .5" in.pdf out.pdf
+         pdfcpu cut -pages 2- "v: .5, o: rtl" in.pdf
+         pdfcpu cut "h: .33 .66, v: .5" in.pdf out.pdf
+
 ` + usagePageSelection
 
 	usageRotate     = "usage: pdfcpu rotate [-v(erbose)|vv] [-q(uiet)] [-pages selectedPages] [-upw userpw] [-opw ownerpw] inFile rotation"
@@ -368,6 +883,96 @@ description ... dimensions, format, position, offset, scale factor
      inFile ... input pdf file
    rotation ... a multiple of 90 degrees for clockwise rotation.
 
+` + usagePageSelection
+
+	usageBooklet     = "usage: pdfcpu booklet [-v(erbose)|vv] [-q(uiet)] [-pages selectedPages] [-upw userpw] [-opw ownerpw] [description] outFile inFile"
+	usageLongBooklet = `Arrange selected pages 2-up onto both sides of landscape sheets for printing a booklet.
+Pages are reordered for folding and padded with blank pages to a multiple of 4.
+
+ verbose, v ... turn on logging
+         vv ... verbose logging
+   quiet, q ... disable output
+      pages ... selected pages
+        upw ... user password
+        opw ... owner password
+description ... sheet size, binding, signatures
+    outFile ... output pdf file
+     inFile ... input pdf file
+
+    <description> is a comma separated configuration string containing:
+
+    optional entries:
+
+        (defaults: f:A4, t:saddle, s:0, r:off, b:off, m:0)
+
+    d: sheet dimensions (width,height) in user units eg. '842 595'
+
+    f: sheet form/paper size, eg. A4, Letter, Legal...
+                           Please refer to "pdfcpu help paper" for a comprehensive list of defined paper sizes.
+                           Sheets are always used in landscape mode.
+                           Only one of dimensions or format is allowed.
+
+    t: binding type, one of saddle  ... nested sheets folded and stitched along the fold (=default)
+                            perfect ... stacked sheets cut along the fold and glued along the spine
+
+    s: signature size, the number of sheets per signature for saddle stitch binding,
+                           0 ... one signature for all sheets (=default)
+
+    r: rotate back sides by 180 degrees for duplex printers flipping on the long edge, on/off
+
+    b: border, on/off
+
+    m: margin in user units
+
+    e.g. pdfcpu booklet out.pdf in.pdf
+         pdfcpu booklet -pages 1-32 "f: A3, s: 4" out.pdf in.pdf
+         pdfcpu booklet "t: perfect, r: on" out.pdf in.pdf
+
+` + usagePageSelection
+
+	usagePoster     = "usage: pdfcpu poster [-v(erbose)|vv] [-q(uiet)] [-pages selectedPages] [-upw userpw] [-opw ownerpw] [description] outFile inFile"
+	usageLongPoster = `Tile selected pages across as many sheets as needed for printing large pages on small paper.
+This is the inverse of nup.
+
+ verbose, v ... turn on logging
+         vv ... verbose logging
+   quiet, q ... disable output
+      pages ... selected pages
+        upw ... user password
+        opw ... owner password
+description ... sheet size, scale, margin, overlap, crop marks, labels
+    outFile ... output pdf file
+     inFile ... input pdf file
+
+    <description> is a comma separated configuration string containing:
+
+    optional entries:
+
+        (defaults: f:A4, s:1, m:18, o:0, c:off, l:off)
+
+    d: sheet dimensions (width,height) in user units eg. '595 842'
+
+    f: sheet form/paper size, eg. A4, Letter, Legal...
+                           Please refer to "pdfcpu help paper" for a comprehensive list of defined paper sizes.
+                           The sheet orientation resulting in fewer sheets is chosen.
+                           Only one of dimensions or format is allowed.
+
+    s: scale factor applied to the page before tiling, eg. 0.5
+
+    m: unprintable sheet margin in user units, crop marks and labels go here
+
+    o: overlap of adjacent tiles in user units
+
+    c: crop marks at the tile corners, on/off
+
+    l: labels with page, row and column of each tile, on/off
+
+    Tiles are arranged in rows from top to bottom, each row from left to right.
+
+    e.g. pdfcpu poster out.pdf drawing.pdf
+         pdfcpu poster "f: Letter, o: 20, c: on, l: on" out.pdf drawing.pdf
+         pdfcpu poster -pages 3 "s: 0.5, m: 10" out.pdf timeline.pdf
+
 ` + usagePageSelection
 
 	usageNUp     = "usage: pdfcpu nup [-v(erbose)|vv] [-q(uiet)] [-pages selectedPages] [description] outFile n inFile|imageFiles..."
@@ -0,0 +1,350 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	pdf "github.com/denisbetsi/pdfcpu/pkg/pdfcpu"
+	"github.com/pkg/errors"
+)
+
+// Annotations returns the annotations of selected pages of rs.
+func Annotations(rs io.ReadSeeker, selectedPages []string, conf *pdf.Configuration) ([]pdf.Annotation, error) {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.LISTANNOTATIONS
+
+	ctx, _, _, err := readAndValidate(rs, conf, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := pagesForPageSelection(ctx.PageCount, selectedPages, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return pdf.Annotations(ctx, pages)
+}
+
+// AnnotationsFile returns the annotations of selected pages of inFile.
+func AnnotationsFile(inFile string, selectedPages []string, conf *pdf.Configuration) ([]pdf.Annotation, error) {
+	f, err := os.Open(inFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Annotations(f, selectedPages, conf)
+}
+
+// ListAnnotations returns a list of the annotations of selected pages of rs.
+func ListAnnotations(rs io.ReadSeeker, selectedPages []string, conf *pdf.Configuration) ([]string, error) {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.LISTANNOTATIONS
+
+	ctx, _, _, err := readAndValidate(rs, conf, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := pagesForPageSelection(ctx.PageCount, selectedPages, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return pdf.ListAnnotations(ctx, pages)
+}
+
+// ListAnnotationsFile returns a list of the annotations of selected pages of inFile.
+func ListAnnotationsFile(inFile string, selectedPages []string, conf *pdf.Configuration) ([]string, error) {
+	f, err := os.Open(inFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ListAnnotations(f, selectedPages, conf)
+}
+
+// AddAnnotations adds annotations to rs and writes the result to w.
+// Annotations without a page number get added to all selected pages.
+func AddAnnotations(rs io.ReadSeeker, w io.Writer, selectedPages []string, aa []pdf.Annotation, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.ADDANNOTATIONS
+
+	if len(aa) == 0 {
+		return errors.New("missing annotations")
+	}
+
+	fromStart := time.Now()
+	ctx, durRead, durVal, durOpt, err := readValidateAndOptimize(rs, conf, fromStart)
+	if err != nil {
+		return err
+	}
+
+	from := time.Now()
+	pages, err := pagesForPageSelection(ctx.PageCount, selectedPages, false)
+	if err != nil {
+		return err
+	}
+
+	if err = pdf.AddAnnotations(ctx, aa, pages); err != nil {
+		return err
+	}
+
+	log.Stats.Printf("XRefTable:\n%s\n", ctx)
+	durAnnots := time.Since(from).Seconds()
+	fromWrite := time.Now()
+
+	if conf.ValidationMode != pdf.ValidationNone {
+		if err = ValidateContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err = WriteContext(ctx, w); err != nil {
+		return err
+	}
+
+	durWrite := durAnnots + time.Since(fromWrite).Seconds()
+	durTotal := time.Since(fromStart).Seconds()
+	logOperationStats(ctx, "add annotations, write", durRead, durVal, durOpt, durWrite, durTotal)
+
+	return nil
+}
+
+// AddAnnotationsFile adds annotations to inFile and writes the result to outFile.
+func AddAnnotationsFile(inFile, outFile string, selectedPages []string, aa []pdf.Annotation, conf *pdf.Configuration) (err error) {
+	var f1, f2 *os.File
+
+	if f1, err = os.Open(inFile); err != nil {
+		return err
+	}
+
+	tmpFile := inFile + ".tmp"
+	if outFile != "" && inFile != outFile {
+		tmpFile = outFile
+	}
+	if f2, err = os.Create(tmpFile); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f2.Close()
+			f1.Close()
+			if outFile == "" || inFile == outFile {
+				os.Remove(tmpFile)
+			}
+			return
+		}
+		if err = f2.Close(); err != nil {
+			return
+		}
+		if err = f1.Close(); err != nil {
+			return
+		}
+		if outFile == "" || inFile == outFile {
+			if err = os.Rename(tmpFile, inFile); err != nil {
+				return
+			}
+		}
+	}()
+
+	return AddAnnotations(f1, f2, selectedPages, aa, conf)
+}
+
+// RemoveAnnotations removes the annotations of selected pages of rs of one of types or with one of ids and writes the result to w.
+// An id matches the annotation name (NM) or its object number. Without types and ids all annotations get removed
+// with the exception of widget annotations belonging to form fields.
+func RemoveAnnotations(rs io.ReadSeeker, w io.Writer, selectedPages []string, types, ids []string, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.REMOVEANNOTATIONS
+
+	fromStart := time.Now()
+	ctx, durRead, durVal, durOpt, err := readValidateAndOptimize(rs, conf, fromStart)
+	if err != nil {
+		return err
+	}
+
+	from := time.Now()
+	pages, err := pagesForPageSelection(ctx.PageCount, selectedPages, true)
+	if err != nil {
+		return err
+	}
+
+	if _, err = pdf.RemoveAnnotations(ctx, pages, types, ids); err != nil {
+		return err
+	}
+
+	log.Stats.Printf("XRefTable:\n%s\n", ctx)
+	durAnnots := time.Since(from).Seconds()
+	fromWrite := time.Now()
+
+	if conf.ValidationMode != pdf.ValidationNone {
+		if err = ValidateContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err = WriteContext(ctx, w); err != nil {
+		return err
+	}
+
+	durWrite := durAnnots + time.Since(fromWrite).Seconds()
+	durTotal := time.Since(fromStart).Seconds()
+	logOperationStats(ctx, "remove annotations, write", durRead, durVal, durOpt, durWrite, durTotal)
+
+	return nil
+}
+
+// RemoveAnnotationsFile removes the annotations of selected pages of inFile of one of types or with one of ids and writes the result to outFile.
+func RemoveAnnotationsFile(inFile, outFile string, selectedPages []string, types, ids []string, conf *pdf.Configuration) (err error) {
+	var f1, f2 *os.File
+
+	if f1, err = os.Open(inFile); err != nil {
+		return err
+	}
+
+	tmpFile := inFile + ".tmp"
+	if outFile != "" && inFile != outFile {
+		tmpFile = outFile
+	}
+	if f2, err = os.Create(tmpFile); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f2.Close()
+			f1.Close()
+			if outFile == "" || inFile == outFile {
+				os.Remove(tmpFile)
+			}
+			return
+		}
+		if err = f2.Close(); err != nil {
+			return
+		}
+		if err = f1.Close(); err != nil {
+			return
+		}
+		if outFile == "" || inFile == outFile {
+			if err = os.Rename(tmpFile, inFile); err != nil {
+				return
+			}
+		}
+	}()
+
+	return RemoveAnnotations(f1, f2, selectedPages, types, ids, conf)
+}
+
+// FlattenAnnotations draws the appearance of the annotations of selected pages of rs into the page content,
+// removes the annotations and writes the result to w. Widget annotations belonging to form fields are left untouched.
+func FlattenAnnotations(rs io.ReadSeeker, w io.Writer, selectedPages []string, f *pdf.Flatten, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.FLATTENANNOTATIONS
+
+	fromStart := time.Now()
+	ctx, durRead, durVal, durOpt, err := readValidateAndOptimize(rs, conf, fromStart)
+	if err != nil {
+		return err
+	}
+
+	from := time.Now()
+	pages, err := pagesForPageSelection(ctx.PageCount, selectedPages, true)
+	if err != nil {
+		return err
+	}
+
+	if _, err = pdf.FlattenAnnotations(ctx, pages, f); err != nil {
+		return err
+	}
+
+	log.Stats.Printf("XRefTable:\n%s\n", ctx)
+	durAnnots := time.Since(from).Seconds()
+	fromWrite := time.Now()
+
+	if conf.ValidationMode != pdf.ValidationNone {
+		if err = ValidateContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err = WriteContext(ctx, w); err != nil {
+		return err
+	}
+
+	durWrite := durAnnots + time.Since(fromWrite).Seconds()
+	durTotal := time.Since(fromStart).Seconds()
+	logOperationStats(ctx, "flatten annotations, write", durRead, durVal, durOpt, durWrite, durTotal)
+
+	return nil
+}
+
+// FlattenAnnotationsFile flattens the annotations of selected pages of inFile and writes the result to outFile.
+func FlattenAnnotationsFile(inFile, outFile string, selectedPages []string, f *pdf.Flatten, conf *pdf.Configuration) (err error) {
+	var f1, f2 *os.File
+
+	if f1, err = os.Open(inFile); err != nil {
+		return err
+	}
+
+	tmpFile := inFile + ".tmp"
+	if outFile != "" && inFile != outFile {
+		tmpFile = outFile
+	}
+	if f2, err = os.Create(tmpFile); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f2.Close()
+			f1.Close()
+			if outFile == "" || inFile == outFile {
+				os.Remove(tmpFile)
+			}
+			return
+		}
+		if err = f2.Close(); err != nil {
+			return
+		}
+		if err = f1.Close(); err != nil {
+			return
+		}
+		if outFile == "" || inFile == outFile {
+			if err = os.Rename(tmpFile, inFile); err != nil {
+				return
+			}
+		}
+	}()
+
+	return FlattenAnnotations(f1, f2, selectedPages, f, conf)
+}
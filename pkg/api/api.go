@@ -473,24 +473,18 @@ func spanFileName(fileName string, from, thru int) string {
 	return fn + "-" + strconv.Itoa(thru) + ".pdf"
 }
 
-func writeSpan(ctx *pdf.Context, from, thru int, outDir, fileName string) error {
-	ctx.ResetWriteContext()
-	w := ctx.Write
-	w.SelectedPages = selectedPageRange(from, thru)
-	w.DirName = outDir
-	w.FileName = spanFileName(fileName, from, thru)
-	// TODO Use io.Writer
-	return pdf.Write(ctx)
+func writeSpan(ctx *pdf.Context, from, thru int, fileName string, wf WriterFunc) error {
+	return writePages(ctx, selectedPageRange(from, thru), spanFileName(fileName, from, thru), wf)
 }
 
-func writePDFSequence(ctx *pdf.Context, span int, outDir, fileName string) error {
+func writePDFSequence(ctx *pdf.Context, span int, fileName string, wf WriterFunc) error {
 	for i := 0; i < ctx.PageCount/span; i++ {
 
 		start := i * span
 		from := start + 1
 		thru := start + span
 
-		if err := writeSpan(ctx, from, thru, outDir, fileName); err != nil {
+		if err := writeSpan(ctx, from, thru, fileName, wf); err != nil {
 			return err
 		}
 
@@ -502,7 +496,7 @@ func writePDFSequence(ctx *pdf.Context, span int, outDir, fileName string) error
 		from := start + 1
 		thru := start + ctx.PageCount%span
 
-		if err := writeSpan(ctx, from, thru, outDir, fileName); err != nil {
+		if err := writeSpan(ctx, from, thru, fileName, wf); err != nil {
 			return err
 		}
 
@@ -511,9 +505,10 @@ func writePDFSequence(ctx *pdf.Context, span int, outDir, fileName string) error
 	return nil
 }
 
-// Split generates a sequence of PDF files in outDir for the PDF stream read from rs obeying given split span.
+// SplitToWriters generates a sequence of PDFs for the PDF stream read from rs obeying given split span.
+// Each PDF is written to the writer returned by wf for a name derived from fileName.
 // The default span 1 creates a sequence of single page PDFs.
-func Split(rs io.ReadSeeker, outDir, fileName string, span int, conf *pdf.Configuration) error {
+func SplitToWriters(rs io.ReadSeeker, fileName string, span int, wf WriterFunc, conf *pdf.Configuration) error {
 	if conf == nil {
 		conf = pdf.NewDefaultConfiguration()
 	}
@@ -528,7 +523,7 @@ func Split(rs io.ReadSeeker, outDir, fileName string, span int, conf *pdf.Config
 
 	fromWrite := time.Now()
 
-	if err = writePDFSequence(ctx, span, outDir, fileName); err != nil {
+	if err = writePDFSequence(ctx, span, fileName, wf); err != nil {
 		return err
 	}
 
@@ -539,6 +534,22 @@ func Split(rs io.ReadSeeker, outDir, fileName string, span int, conf *pdf.Config
 	return nil
 }
 
+// SplitToReaders generates a sequence of in-memory PDFs for the PDF stream read from rs obeying given split span.
+// The default span 1 creates a sequence of single page PDFs.
+func SplitToReaders(rs io.ReadSeeker, fileName string, span int, conf *pdf.Configuration) ([]NamedReader, error) {
+	c := &readerCollector{}
+	if err := SplitToWriters(rs, fileName, span, c.writer, conf); err != nil {
+		return nil, err
+	}
+	return c.rr, nil
+}
+
+// Split generates a sequence of PDF files in outDir for the PDF stream read from rs obeying given split span.
+// The default span 1 creates a sequence of single page PDFs.
+func Split(rs io.ReadSeeker, outDir, fileName string, span int, conf *pdf.Configuration) error {
+	return SplitToWriters(rs, fileName, span, dirWriterFunc(outDir), conf)
+}
+
 // SplitFile generates a sequence of PDF files in outDir for inFile obeying given split span.
 // The default span 1 creates a sequence of single page PDFs.
 func SplitFile(inFile, outDir string, span int, conf *pdf.Configuration) error {
@@ -899,6 +910,148 @@ func NUpFile(inFiles []string, outFile string, selectedPages []string, nup *pdf.
 	return NUp(f1, f2, inFiles, selectedPages, nup, conf)
 }
 
+// Booklet imposes selected pages of rs 2-up onto both sides of sheets in booklet order and writes the result to w.
+func Booklet(rs io.ReadSeeker, w io.Writer, selectedPages []string, b *pdf.Booklet, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.BOOKLET
+
+	if b == nil {
+		b = pdf.DefaultBookletConfig()
+	}
+
+	log.Info.Printf("%s", b)
+
+	ctx, _, _, err := readAndValidate(rs, conf, time.Now())
+	if err != nil {
+		return err
+	}
+
+	pages, err := pagesForPageSelection(ctx.PageCount, selectedPages, true)
+	if err != nil {
+		return err
+	}
+
+	// New pages get added to ctx while old pages get deleted.
+	if err = pdf.BookletFromPDF(ctx, pages, b); err != nil {
+		return err
+	}
+
+	if conf.ValidationMode != pdf.ValidationNone {
+		if err = ValidateContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err = WriteContext(ctx, w); err != nil {
+		return err
+	}
+
+	log.Stats.Printf("XRefTable:\n%s\n", ctx)
+
+	return nil
+}
+
+// BookletFile imposes selected pages of inFile as a booklet and writes the result to outFile.
+func BookletFile(inFile, outFile string, selectedPages []string, b *pdf.Booklet, conf *pdf.Configuration) (err error) {
+	var f1, f2 *os.File
+
+	if f1, err = os.Open(inFile); err != nil {
+		return err
+	}
+
+	if f2, err = os.Create(outFile); err != nil {
+		f1.Close()
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f2.Close()
+			f1.Close()
+			return
+		}
+		if err = f2.Close(); err != nil {
+			return
+		}
+		err = f1.Close()
+	}()
+
+	return Booklet(f1, f2, selectedPages, b, conf)
+}
+
+// Poster tiles each selected page of rs across as many sheets as needed and writes the result to w.
+func Poster(rs io.ReadSeeker, w io.Writer, selectedPages []string, p *pdf.Poster, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.POSTER
+
+	if p == nil {
+		p = pdf.DefaultPosterConfig()
+	}
+
+	log.Info.Printf("%s", p)
+
+	ctx, _, _, err := readAndValidate(rs, conf, time.Now())
+	if err != nil {
+		return err
+	}
+
+	pages, err := pagesForPageSelection(ctx.PageCount, selectedPages, true)
+	if err != nil {
+		return err
+	}
+
+	// New pages get added to ctx while old pages get deleted.
+	if err = pdf.PosterFromPDF(ctx, pages, p); err != nil {
+		return err
+	}
+
+	if conf.ValidationMode != pdf.ValidationNone {
+		if err = ValidateContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err = WriteContext(ctx, w); err != nil {
+		return err
+	}
+
+	log.Stats.Printf("XRefTable:\n%s\n", ctx)
+
+	return nil
+}
+
+// PosterFile tiles each selected page of inFile across as many sheets as needed and writes the result to outFile.
+func PosterFile(inFile, outFile string, selectedPages []string, p *pdf.Poster, conf *pdf.Configuration) (err error) {
+	var f1, f2 *os.File
+
+	if f1, err = os.Open(inFile); err != nil {
+		return err
+	}
+
+	if f2, err = os.Create(outFile); err != nil {
+		f1.Close()
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f2.Close()
+			f1.Close()
+			return
+		}
+		if err = f2.Close(); err != nil {
+			return
+		}
+		err = f1.Close()
+	}()
+
+	return Poster(f1, f2, selectedPages, p, conf)
+}
+
 // ImportImages appends PDF pages containing images to rs and writes the result to w.
 // If rs == nil a new PDF file will be written to w.
 func ImportImages(rs io.ReadSeeker, w io.Writer, imgs []io.Reader, imp *pdf.Import, conf *pdf.Configuration) error {
@@ -1111,6 +1264,88 @@ func InsertPagesFile(inFile, outFile string, selectedPages []string, conf *pdf.C
 	return InsertPages(f1, f2, selectedPages, conf)
 }
 
+// InsertPagesFrom inserts the selected pages of rsFrom into rs before page before and writes the result to w.
+// A nil selection inserts all pages of rsFrom, before == 0 appends the pages.
+func InsertPagesFrom(rs, rsFrom io.ReadSeeker, w io.Writer, selectedPages []string, before int, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.INSERTPAGES
+
+	fromStart := time.Now()
+	ctx, _, _, err := readAndValidate(rs, conf, fromStart)
+	if err != nil {
+		return err
+	}
+
+	ctxFrom, _, _, err := readAndValidate(rsFrom, conf, fromStart)
+	if err != nil {
+		return err
+	}
+
+	pages, err := pagesForPageSelection(ctxFrom.PageCount, selectedPages, true)
+	if err != nil {
+		return err
+	}
+
+	if before == 0 {
+		before = ctx.PageCount + 1
+	}
+
+	if err = pdf.InsertPagesFrom(ctxFrom, ctx, pages, before); err != nil {
+		return err
+	}
+
+	return writeMergedContext(ctx, w)
+}
+
+// InsertPagesFromFile inserts the selected pages of fromFile into inFile before page before and writes the result to outFile.
+func InsertPagesFromFile(inFile, fromFile, outFile string, selectedPages []string, before int, conf *pdf.Configuration) (err error) {
+	var f0, f1, f2 *os.File
+
+	if f0, err = os.Open(fromFile); err != nil {
+		return err
+	}
+	defer f0.Close()
+
+	if f1, err = os.Open(inFile); err != nil {
+		return err
+	}
+
+	tmpFile := inFile + ".tmp"
+	if outFile != "" && inFile != outFile {
+		tmpFile = outFile
+	}
+	if f2, err = os.Create(tmpFile); err != nil {
+		f1.Close()
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f2.Close()
+			f1.Close()
+			if outFile == "" || inFile == outFile {
+				os.Remove(tmpFile)
+			}
+			return
+		}
+		if err = f2.Close(); err != nil {
+			return
+		}
+		if err = f1.Close(); err != nil {
+			return
+		}
+		if outFile == "" || inFile == outFile {
+			if err = os.Rename(tmpFile, inFile); err != nil {
+				return
+			}
+		}
+	}()
+
+	return InsertPagesFrom(f1, f0, f2, selectedPages, before, conf)
+}
+
 // RemovePages removes selected pages from rs and writes the result to w.
 func RemovePages(rs io.ReadSeeker, w io.Writer, selectedPages []string, conf *pdf.Configuration) error {
 	if conf == nil {
@@ -1192,33 +1427,171 @@ func RemovePagesFile(inFile, outFile string, selectedPages []string, conf *pdf.C
 	return RemovePages(f1, f2, selectedPages, conf)
 }
 
-// appendTo appends inFile to ctxDest's page tree.
-func appendTo(rs io.ReadSeeker, ctxDest *pdf.Context) error {
+// ArrangePages reorders, repeats or drops pages of rs according to a page order expression and writes the result to w.
+func ArrangePages(rs io.ReadSeeker, w io.Writer, order []string, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.ARRANGEPAGES
+
+	fromStart := time.Now()
+	ctx, durRead, durVal, durOpt, err := readValidateAndOptimize(rs, conf, fromStart)
+	if err != nil {
+		return err
+	}
+
+	fromWrite := time.Now()
+
+	pageNrs, err := pageOrder(ctx.PageCount, order)
+	if err != nil {
+		return err
+	}
+
+	if len(pageNrs) == 0 {
+		return errors.New("operation invalid")
+	}
+
+	if err = ctx.ArrangePages(pageNrs); err != nil {
+		return err
+	}
+
+	// Get rid of references to dropped pages.
+	ctx.PageCount = len(pageNrs)
+	ctx.Write.SelectedPages = pdf.IntSet{}
+	for i := 1; i <= ctx.PageCount; i++ {
+		ctx.Write.SelectedPages[i] = true
+	}
+
+	if err = WriteContext(ctx, w); err != nil {
+		return err
+	}
+
+	durWrite := time.Since(fromWrite).Seconds()
+	durTotal := time.Since(fromStart).Seconds()
+	logOperationStats(ctx, "arrange pages, write", durRead, durVal, durOpt, durWrite, durTotal)
+
+	return nil
+}
+
+// ArrangePagesFile reorders, repeats or drops pages of inFile according to a page order expression and writes the result to outFile.
+func ArrangePagesFile(inFile, outFile string, order []string, conf *pdf.Configuration) (err error) {
+	var f1, f2 *os.File
+
+	if f1, err = os.Open(inFile); err != nil {
+		return err
+	}
+
+	tmpFile := inFile + ".tmp"
+	if outFile != "" && inFile != outFile {
+		tmpFile = outFile
+	}
+	if f2, err = os.Create(tmpFile); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f2.Close()
+			f1.Close()
+			if outFile == "" || inFile == outFile {
+				os.Remove(tmpFile)
+			}
+			return
+		}
+		if err = f2.Close(); err != nil {
+			return
+		}
+		if err = f1.Close(); err != nil {
+			return
+		}
+		if outFile == "" || inFile == outFile {
+			if err = os.Rename(tmpFile, inFile); err != nil {
+				return
+			}
+		}
+	}()
+
+	return ArrangePages(f1, f2, order, conf)
+}
+
+// MergeSource pairs a PDF stream with a selection of its pages to be merged.
+// A nil PageSelection selects all pages.
+type MergeSource struct {
+	io.ReadSeeker
+	PageSelection []string
+}
+
+// trimMergeSource reduces ctx to the pages selected by pageSelection.
+func trimMergeSource(ctx *pdf.Context, pageSelection []string) error {
+	if len(pageSelection) == 0 {
+		return nil
+	}
+
+	pages, err := pagesForPageSelection(ctx.PageCount, pageSelection, false)
+	if err != nil {
+		return err
+	}
+
+	return pdf.TrimPages(ctx, pages)
+}
+
+// appendTo appends the selected pages of rs to ctxDest's page tree.
+func appendTo(rs io.ReadSeeker, pageSelection []string, ctxDest *pdf.Context) error {
 	ctxSource, _, _, err := readAndValidate(rs, ctxDest.Configuration, time.Now())
 	if err != nil {
 		return err
 	}
 
+	ctxSource.Read.FileName = fileName(rs)
+
+	if err = trimMergeSource(ctxSource, pageSelection); err != nil {
+		return err
+	}
+
 	// Merge the source context into the dest context.
 	return pdf.MergeXRefTables(ctxSource, ctxDest)
 }
 
+// fileName returns the name of the file underlying rs if available.
+func fileName(rs io.ReadSeeker) string {
+	if f, ok := rs.(interface{ Name() string }); ok {
+		return f.Name()
+	}
+	return ""
+}
+
 // ReadSeekerCloser combines io.ReadSeeker and io.Closer
 type ReadSeekerCloser interface {
 	io.ReadSeeker
 	io.Closer
 }
 
-// Merge merges a sequence of PDF streams and writes the result to w.
-func Merge(rsc []io.ReadSeeker, w io.Writer, conf *pdf.Configuration) error {
-	if conf == nil {
-		conf = pdf.NewDefaultConfiguration()
+func mergeSources(rsc []io.ReadSeeker) []MergeSource {
+	ss := make([]MergeSource, len(rsc))
+	for i, rs := range rsc {
+		ss[i] = MergeSource{ReadSeeker: rs}
 	}
-	conf.Cmd = pdf.MERGE
+	return ss
+}
 
-	ctxDest, _, _, err := readAndValidate(rsc[0], conf, time.Now())
+// mergedContext merges a sequence of PDF streams into the context of the first one.
+// It returns the merged context along with the number of pages contributed by each stream.
+func mergedContext(ss []MergeSource, conf *pdf.Configuration) (*pdf.Context, []int, error) {
+	ctxDest, _, _, err := readAndValidate(ss[0], conf, time.Now())
 	if err != nil {
-		return err
+		return nil, nil, err
+	}
+
+	ctxDest.Read.FileName = fileName(ss[0].ReadSeeker)
+
+	if err = trimMergeSource(ctxDest, ss[0].PageSelection); err != nil {
+		return nil, nil, err
+	}
+
+	if conf.CreateBookmarks {
+		if err = pdf.CreateMergeOutlines(ctxDest); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	if ctxDest.XRefTable.Version() < pdf.V15 {
@@ -1227,25 +1600,43 @@ func Merge(rsc []io.ReadSeeker, w io.Writer, conf *pdf.Configuration) error {
 		log.Stats.Println("Ensure V1.5 for writing object & xref streams")
 	}
 
+	pageCounts := []int{}
+
+	irs, err := ctxDest.PageIndRefs()
+	if err != nil {
+		return nil, nil, err
+	}
+	n := len(irs)
+	pageCounts = append(pageCounts, n)
+
 	// Repeatedly merge files into fileDest's xref table.
-	for _, f := range rsc[1:] {
-		err = appendTo(f, ctxDest)
+	for _, src := range ss[1:] {
+		err = appendTo(src.ReadSeeker, src.PageSelection, ctxDest)
 		if err != nil {
-			return err
+			return nil, nil, err
+		}
+		if irs, err = ctxDest.PageIndRefs(); err != nil {
+			return nil, nil, err
 		}
+		pageCounts = append(pageCounts, len(irs)-n)
+		n = len(irs)
 	}
 
-	if err = OptimizeContext(ctxDest); err != nil {
+	return ctxDest, pageCounts, nil
+}
+
+func writeMergedContext(ctxDest *pdf.Context, w io.Writer) error {
+	if err := OptimizeContext(ctxDest); err != nil {
 		return err
 	}
 
-	if conf.ValidationMode != pdf.ValidationNone {
-		if err = ValidateContext(ctxDest); err != nil {
+	if ctxDest.Configuration.ValidationMode != pdf.ValidationNone {
+		if err := ValidateContext(ctxDest); err != nil {
 			return err
 		}
 	}
 
-	if err = WriteContext(ctxDest, w); err != nil {
+	if err := WriteContext(ctxDest, w); err != nil {
 		return err
 	}
 
@@ -1254,9 +1645,111 @@ func Merge(rsc []io.ReadSeeker, w io.Writer, conf *pdf.Configuration) error {
 	return nil
 }
 
+// Merge merges a sequence of PDF streams and writes the result to w.
+// If conf.CreateBookmarks is set, each stream contributes a top level bookmark pointing to its first page.
+// The bookmark title is taken from the document title or else the file name.
+// Any existing outlines are nested underneath.
+func Merge(rsc []io.ReadSeeker, w io.Writer, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.MERGE
+
+	ctxDest, _, err := mergedContext(mergeSources(rsc), conf)
+	if err != nil {
+		return err
+	}
+
+	return writeMergedContext(ctxDest, w)
+}
+
+// MergeSources merges the selected pages of a sequence of PDF streams and writes the result to w.
+// Objects only used by pages not selected do not make it into the result.
+func MergeSources(ss []MergeSource, w io.Writer, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.MERGE
+
+	if len(ss) == 0 {
+		return errors.New("missing merge sources")
+	}
+
+	ctxDest, _, err := mergedContext(ss, conf)
+	if err != nil {
+		return err
+	}
+
+	return writeMergedContext(ctxDest, w)
+}
+
+// zipPageNrs returns the page sequence interleaving n1 pages with the n2 pages following them.
+// 0 denotes a blank page filling in for a missing page.
+func zipPageNrs(n1, n2 int, reverse, fill bool) ([]int, error) {
+	if n1 != n2 && !fill {
+		return nil, errors.Errorf("zip: page count mismatch: %d != %d", n1, n2)
+	}
+
+	n := n1
+	if n2 > n {
+		n = n2
+	}
+
+	pp := []int{}
+
+	for i := 0; i < n; i++ {
+		p := 0
+		if i < n1 {
+			p = i + 1
+		}
+		pp = append(pp, p)
+
+		p = 0
+		if i < n2 {
+			p = n1 + 1 + i
+			if reverse {
+				p = n1 + n2 - i
+			}
+		}
+		pp = append(pp, p)
+	}
+
+	return pp, nil
+}
+
+// MergeZip interleaves the pages of rs1 and rs2 and writes the result to w.
+// This is useful for merging the front and back sides of a duplex job scanned in two passes.
+// If reverse is set the pages of rs2 are taken in reverse order.
+// For inputs of unequal length fill pads the shorter input with blank pages, otherwise an error is returned.
+func MergeZip(rs1, rs2 io.ReadSeeker, w io.Writer, reverse, fill bool, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.MERGE
+
+	ctxDest, pageCounts, err := mergedContext(mergeSources([]io.ReadSeeker{rs1, rs2}), conf)
+	if err != nil {
+		return err
+	}
+
+	pageNrs, err := zipPageNrs(pageCounts[0], pageCounts[1], reverse, fill)
+	if err != nil {
+		return err
+	}
+
+	if err = ctxDest.ArrangePages(pageNrs); err != nil {
+		return err
+	}
+
+	ctxDest.PageCount = len(pageNrs)
+
+	return writeMergedContext(ctxDest, w)
+}
+
 // MergeFile merges a sequence of inFiles and writes the result to outFile.
 // This operation corresponds to file concatenation in the order specified by inFiles.
 // The first entry of inFiles serves as the destination context where all remaining files get merged into.
+// Set conf.CreateBookmarks to get a top level bookmark for each of inFiles.
 func MergeFile(inFiles []string, outFile string, conf *pdf.Configuration) error {
 	ff := []*os.File(nil)
 	for _, f := range inFiles {
@@ -1285,6 +1778,62 @@ func MergeFile(inFiles []string, outFile string, conf *pdf.Configuration) error
 	return Merge(rs, f, conf)
 }
 
+// MergeSourcesFile merges the selected pages of a sequence of inFiles and writes the result to outFile.
+// pageSelections[i] selects the pages of inFiles[i], nil selects all pages.
+func MergeSourcesFile(inFiles []string, pageSelections [][]string, outFile string, conf *pdf.Configuration) error {
+	if len(pageSelections) != len(inFiles) {
+		return errors.New("merge: page selection count mismatch")
+	}
+
+	ff := []*os.File(nil)
+	defer func() {
+		for _, f := range ff {
+			f.Close()
+		}
+	}()
+
+	ss := make([]MergeSource, len(inFiles))
+	for i, fn := range inFiles {
+		f, err := os.Open(fn)
+		if err != nil {
+			return err
+		}
+		ff = append(ff, f)
+		ss[i] = MergeSource{ReadSeeker: f, PageSelection: pageSelections[i]}
+	}
+
+	f, err := os.Create(outFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return MergeSources(ss, f, conf)
+}
+
+// MergeZipFile interleaves the pages of inFile1 and inFile2 and writes the result to outFile.
+func MergeZipFile(inFile1, inFile2, outFile string, reverse, fill bool, conf *pdf.Configuration) error {
+	f1, err := os.Open(inFile1)
+	if err != nil {
+		return err
+	}
+	defer f1.Close()
+
+	f2, err := os.Open(inFile2)
+	if err != nil {
+		return err
+	}
+	defer f2.Close()
+
+	f, err := os.Create(outFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return MergeZip(f1, f2, f, reverse, fill, conf)
+}
+
 // Info returns information about rs.
 func Info(rs io.ReadSeeker, conf *pdf.Configuration) ([]string, error) {
 	if conf == nil {
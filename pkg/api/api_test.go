@@ -17,16 +17,22 @@ limitations under the License.
 package api
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/denisbetsi/pdfcpu/pkg/pdfcpu"
 	pdf "github.com/denisbetsi/pdfcpu/pkg/pdfcpu"
+	"github.com/denisbetsi/pdfcpu/pkg/pdfcpu/cms"
+	"github.com/denisbetsi/pdfcpu/pkg/pdfcpu/fdf"
+	"github.com/pkg/errors"
 )
 
 var inDir, outDir, resDir string
@@ -135,6 +141,75 @@ func TestTrim(t *testing.T) {
 	}
 }
 
+func outlineDestsWithin(ctx *pdf.Context, ir *pdf.IndirectRef, pages pdf.IntSet) (int, error) {
+	c := 0
+	for ir != nil {
+		d, err := ctx.DereferenceDict(*ir)
+		if err != nil {
+			return 0, err
+		}
+		o := d["Dest"]
+		if a, err := ctx.DereferenceDict(d["A"]); err == nil && a != nil {
+			o = a["D"]
+		}
+		if a, err := ctx.ResolveDestination(o); err == nil && len(a) > 0 {
+			if pageRef, ok := a[0].(pdf.IndirectRef); ok && !pages[pageRef.ObjectNumber.Value()] {
+				return 0, fmt.Errorf("outline item %s points to missing page", ir)
+			}
+		}
+		i, err := outlineDestsWithin(ctx, d.IndirectRefEntry("First"), pages)
+		if err != nil {
+			return 0, err
+		}
+		c += 1 + i
+		ir = d.IndirectRefEntry("Next")
+	}
+	return c, nil
+}
+
+func TestTrimKeepsOutlines(t *testing.T) {
+	msg := "TestTrimKeepsOutlines"
+	fileName := "TheGoProgrammingLanguageCh1.pdf"
+	inFile := filepath.Join(inDir, fileName)
+	outFile := filepath.Join(outDir, fileName)
+
+	// Outline items pointing to removed pages are pruned.
+	if err := TrimFile(inFile, outFile, []string{"15-21"}, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if err := ValidateFile(outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	ctx, err := ReadContextFile(outFile)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	irs, err := ctx.PageIndRefs()
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	pages := pdf.IntSet{}
+	for _, ir := range irs {
+		pages[ir.ObjectNumber.Value()] = true
+	}
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	d, err := ctx.DereferenceDict(rootDict["Outlines"])
+	if err != nil || d == nil {
+		t.Fatalf("%s: missing outlines %v\n", msg, err)
+	}
+	c, err := outlineDestsWithin(ctx, d.IndirectRefEntry("First"), pages)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if c == 0 {
+		t.Fatalf("%s: missing outline items\n", msg)
+	}
+}
+
 func TestSplit(t *testing.T) {
 	msg := "TestSplit"
 	fileName := "Acroforms2.pdf"
@@ -144,96 +219,1490 @@ func TestSplit(t *testing.T) {
 	if err := SplitFile(inFile, outDir, 1, nil); err != nil {
 		t.Fatalf("%s: %v\n", msg, err)
 	}
-}
+}
+
+func TestSplitToReaders(t *testing.T) {
+	msg := "TestSplitToReaders"
+	fileName := "Acroforms2.pdf"
+	inFile := filepath.Join(inDir, fileName)
+
+	f, err := os.Open(inFile)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	defer f.Close()
+
+	// Split inFile into in-memory PDFs of 2 pages each.
+	rr, err := SplitToReaders(f, fileName, 2, nil)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	n, err := PageCount(inFile)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if want := (n + 1) / 2; len(rr) != want {
+		t.Fatalf("%s: want %d readers, got %d\n", msg, want, len(rr))
+	}
+
+	for _, r := range rr {
+		bb, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: %v\n", msg, err)
+		}
+		if err = Validate(bytes.NewReader(bb), nil); err != nil {
+			t.Fatalf("%s: %s: %v\n", msg, r.Name, err)
+		}
+	}
+}
+
+func TestSplitByBookmark(t *testing.T) {
+	msg := "TestSplitByBookmark"
+	fileName := "TheGoProgrammingLanguageCh1.pdf"
+	inFile := filepath.Join(inDir, fileName)
+
+	dir, err := ioutil.TempDir(outDir, "bookmark")
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	// Create a file for each top level bookmark of inFile in dir.
+	if err := SplitByBookmarkFile(inFile, dir, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	ff, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if len(ff) < 2 {
+		t.Fatalf("%s: want more than 1 file, got %d\n", msg, len(ff))
+	}
+}
+
+func TestSplitByPageNr(t *testing.T) {
+	msg := "TestSplitByPageNr"
+	fileName := "TheGoProgrammingLanguageCh1.pdf"
+	inFile := filepath.Join(inDir, fileName)
+
+	dir, err := ioutil.TempDir(outDir, "pages")
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	// Create files for the pages 1-2, 3-6 and 7-end of inFile in dir.
+	if err := SplitByPageNrFile(inFile, dir, []int{7, 3}, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	for _, fn := range []string{"TheGoProgrammingLanguageCh1_1-2.pdf", "TheGoProgrammingLanguageCh1_3-6.pdf", "TheGoProgrammingLanguageCh1_7-59.pdf"} {
+		if err := ValidateFile(filepath.Join(dir, fn), nil); err != nil {
+			t.Fatalf("%s: %v\n", msg, err)
+		}
+	}
+}
+
+func TestSplitBySize(t *testing.T) {
+	msg := "TestSplitBySize"
+	fileName := "TheGoProgrammingLanguageCh1.pdf"
+	inFile := filepath.Join(inDir, fileName)
+	maxSize := int64(200 * 1024)
+
+	dir, err := ioutil.TempDir(outDir, "size")
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	// Create files of inFile in dir not exceeding maxSize bytes.
+	if err := SplitBySizeFile(inFile, dir, maxSize, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	ff, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if len(ff) < 2 {
+		t.Fatalf("%s: want more than 1 file, got %d\n", msg, len(ff))
+	}
+
+	for _, f := range ff {
+		n, err := PageCount(filepath.Join(dir, f.Name()))
+		if err != nil {
+			t.Fatalf("%s: %v\n", msg, err)
+		}
+		if f.Size() > maxSize && n > 1 {
+			t.Fatalf("%s: %s has %d pages and exceeds %d bytes\n", msg, f.Name(), n, maxSize)
+		}
+	}
+}
+
+func TestSplitByPageNrToReaders(t *testing.T) {
+	msg := "TestSplitByPageNrToReaders"
+	fileName := "TheGoProgrammingLanguageCh1.pdf"
+	inFile := filepath.Join(inDir, fileName)
+
+	f, err := os.Open(inFile)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	defer f.Close()
+
+	// Split inFile into in-memory PDFs for the pages 1-2, 3-6 and 7-end.
+	rr, err := SplitByPageNrToReaders(f, fileName, []int{7, 3}, nil)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	want := []string{"TheGoProgrammingLanguageCh1_1-2.pdf", "TheGoProgrammingLanguageCh1_3-6.pdf", "TheGoProgrammingLanguageCh1_7-59.pdf"}
+	if len(rr) != len(want) {
+		t.Fatalf("%s: want %d readers, got %d\n", msg, len(want), len(rr))
+	}
+
+	for i, r := range rr {
+		if r.Name != want[i] {
+			t.Fatalf("%s: want %s, got %s\n", msg, want[i], r.Name)
+		}
+		bb, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: %v\n", msg, err)
+		}
+		if err = Validate(bytes.NewReader(bb), nil); err != nil {
+			t.Fatalf("%s: %s: %v\n", msg, r.Name, err)
+		}
+	}
+}
+
+func TestRotate(t *testing.T) {
+	msg := "TestRotate"
+	fileName := "Acroforms2.pdf"
+	inFile := filepath.Join(inDir, fileName)
+	outFile := filepath.Join(outDir, fileName)
+
+	// Rotate all pages of inFile, clockwise by 90 degrees and write the result to outFile.
+	if err := RotateFile(inFile, outFile, 90, nil, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	// Rotate the first page of inFile by 180 degrees.
+	// If you want to modify the original file, pass an empty string for outFile.
+	inFile = outFile
+	if err := RotateFile(inFile, "", 180, []string{"1"}, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	msg := "TestMerge"
+	inFiles := []string{
+		filepath.Join(inDir, "Acroforms2.pdf"),
+		filepath.Join(inDir, "adobe_errata.pdf"),
+	}
+	outFile := filepath.Join(outDir, "test.pdf")
+
+	// Merge inFiles by concatenation in the order specified and write the result to outFile.
+	if err := MergeFile(inFiles, outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+}
+
+func TestMergeZip(t *testing.T) {
+	msg := "TestMergeZip"
+	inFile1 := filepath.Join(inDir, "CenterOfWhy.pdf")
+	inFile2 := filepath.Join(inDir, "go.pdf")
+	outFile := filepath.Join(outDir, "test.pdf")
+
+	// The inFiles differ in length.
+	if err := MergeZipFile(inFile1, inFile2, outFile, true, false, nil); err == nil {
+		t.Fatalf("%s: missing page count mismatch error\n", msg)
+	}
+
+	// Pad the shorter inFile with blank pages.
+	if err := MergeZipFile(inFile1, inFile2, outFile, true, true, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if err := ValidateFile(outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	n1, err := PageCount(inFile1)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	n, err := PageCount(outFile)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if n != 2*n1 {
+		t.Fatalf("%s: pageCount want:%d got:%d\n", msg, 2*n1, n)
+	}
+}
+
+func TestMergeSelectedPages(t *testing.T) {
+	msg := "TestMergeSelectedPages"
+	inFiles := []string{
+		filepath.Join(inDir, "Acroforms2.pdf"),
+		filepath.Join(inDir, "CenterOfWhy.pdf"),
+		filepath.Join(inDir, "go.pdf"),
+	}
+	pageSelections := [][]string{{"1"}, {"even"}, nil}
+	outFile := filepath.Join(outDir, "test.pdf")
+
+	if err := MergeSourcesFile(inFiles, pageSelections, outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if err := ValidateFile(outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	n2, err := PageCount(inFiles[1])
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	n3, err := PageCount(inFiles[2])
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	n, err := PageCount(outFile)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if want := 1 + n2/2 + n3; n != want {
+		t.Fatalf("%s: pageCount want:%d got:%d\n", msg, want, n)
+	}
+}
+
+func TestZipPageNrs(t *testing.T) {
+	for _, tt := range []struct {
+		n1, n2        int
+		reverse, fill bool
+		want          []int
+	}{
+		{3, 3, false, false, []int{1, 4, 2, 5, 3, 6}},
+		{3, 3, true, false, []int{1, 6, 2, 5, 3, 4}},
+		{3, 2, false, true, []int{1, 4, 2, 5, 3, 0}},
+		{1, 2, true, true, []int{1, 3, 0, 2}},
+	} {
+		got, err := zipPageNrs(tt.n1, tt.n2, tt.reverse, tt.fill)
+		if err != nil {
+			t.Fatalf("TestZipPageNrs: %v\n", err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("TestZipPageNrs %d %d: want %v, got %v\n", tt.n1, tt.n2, tt.want, got)
+		}
+	}
+}
+
+func TestMergeWithBookmarks(t *testing.T) {
+	msg := "TestMergeWithBookmarks"
+	inFiles := []string{
+		filepath.Join(inDir, "Acroforms2.pdf"),
+		filepath.Join(inDir, "adobe_errata.pdf"),
+		filepath.Join(inDir, "CenterOfWhy.pdf"),
+	}
+	outFile := filepath.Join(outDir, "test.pdf")
+
+	// Merge inFiles and create a top level bookmark for each of them.
+	conf := pdf.NewDefaultConfiguration()
+	conf.CreateBookmarks = true
+	if err := MergeFile(inFiles, outFile, conf); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	ctx, err := ReadContextFile(outFile)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	o, found := rootDict.Find("Outlines")
+	if !found {
+		t.Fatalf("%s: missing outlines\n", msg)
+	}
+	d, err := ctx.DereferenceDict(o)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if c := d.IntEntry("Count"); c == nil || *c != len(inFiles) {
+		t.Fatalf("%s: outline count want:%d got:%v\n", msg, len(inFiles), c)
+	}
+}
+
+func TestInsertRemovePages(t *testing.T) {
+	msg := "TestInsertRemovePages"
+	inFile := filepath.Join(inDir, "Acroforms2.pdf")
+	outFile := filepath.Join(outDir, "test.pdf")
+
+	n1, err := PageCount(inFile)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, inFile, err)
+	}
+
+	// Insert an empty page before pages 1 and 2.
+	if err := InsertPagesFile(inFile, outFile, []string{"-2"}, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if err := ValidateFile(outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	n2, err := PageCount(outFile)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if n2 != n1+2 {
+		t.Fatalf("%s %s: pageCount want:%d got:%d\n", msg, inFile, n1+2, n2)
+	}
+
+	// 	// Remove pages 1 and 2.
+	if err := RemovePagesFile(outFile, "", []string{"-2"}, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if err := ValidateFile(outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	n2, err = PageCount(outFile)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, inFile, err)
+	}
+	if n1 != n2 {
+		t.Fatalf("%s %s: pageCount want:%d got:%d\n", msg, inFile, n1, n2)
+	}
+}
+
+func TestInsertPagesFrom(t *testing.T) {
+	msg := "TestInsertPagesFrom"
+	inFile := filepath.Join(inDir, "CenterOfWhy.pdf")
+	fromFile := filepath.Join(inDir, "go.pdf")
+	outFile := filepath.Join(outDir, "test.pdf")
+
+	n1, err := PageCount(inFile)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, inFile, err)
+	}
+
+	for _, tt := range []struct {
+		selectedPages []string
+		before        int
+		want          int
+	}{
+		{[]string{"2-4"}, 5, n1 + 3},  // Insert an appendix.
+		{[]string{"1"}, 1, n1 + 1},    // Add a cover sheet.
+		{[]string{"odd"}, 0, n1 + 12}, // Append.
+	} {
+		if err := InsertPagesFromFile(inFile, fromFile, outFile, tt.selectedPages, tt.before, nil); err != nil {
+			t.Fatalf("%s %s: %v\n", msg, outFile, err)
+		}
+		if err := ValidateFile(outFile, nil); err != nil {
+			t.Fatalf("%s: %v\n", msg, err)
+		}
+		n2, err := PageCount(outFile)
+		if err != nil {
+			t.Fatalf("%s %s: %v\n", msg, outFile, err)
+		}
+		if n2 != tt.want {
+			t.Fatalf("%s %s: pageCount want:%d got:%d\n", msg, outFile, tt.want, n2)
+		}
+	}
+
+	// There is no page 100.
+	if err := InsertPagesFromFile(inFile, fromFile, outFile, nil, 100, nil); err == nil {
+		t.Fatalf("%s: missing invalid page number error\n", msg)
+	}
+}
+
+func TestRemoveBlankPages(t *testing.T) {
+	msg := "TestRemoveBlankPages"
+	inFile := filepath.Join(inDir, "go.pdf")
+	outFile := filepath.Join(outDir, "test.pdf")
+
+	n1, err := PageCount(inFile)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, inFile, err)
+	}
+
+	// Insert an empty page before pages 1 and 2.
+	if err := InsertPagesFile(inFile, outFile, []string{"-2"}, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+
+	blank, err := BlankPagesFile(outFile, nil, pdf.DefaultBlankThreshold, nil)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if want := []int{1, 3}; !reflect.DeepEqual(blank, want) {
+		t.Fatalf("%s %s: blank pages want:%v got:%v\n", msg, outFile, want, blank)
+	}
+
+	if _, err = RemoveBlankPagesFile(outFile, "", nil, pdf.DefaultBlankThreshold, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if err := ValidateFile(outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	n2, err := PageCount(outFile)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if n1 != n2 {
+		t.Fatalf("%s %s: pageCount want:%d got:%d\n", msg, outFile, n1, n2)
+	}
+
+	// The only page of this scan is a near-white image.
+	inFile = filepath.Join(inDir, "blank-scan.pdf")
+	blank, err = BlankPagesFile(inFile, nil, pdf.DefaultBlankThreshold, nil)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, inFile, err)
+	}
+	if want := []int{1}; !reflect.DeepEqual(blank, want) {
+		t.Fatalf("%s %s: blank pages want:%v got:%v\n", msg, inFile, want, blank)
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	msg := "TestAnnotations"
+	inFile := filepath.Join(inDir, "go.pdf")
+	outFile := filepath.Join(outDir, "annotated.pdf")
+
+	js := `[
+		{"page": 1, "type": "Text", "id": "note-1", "rect": [50, 700, 70, 720], "contents": "Please review", "title": "Reviewer"},
+		{"type": "Highlight", "rect": [72, 600, 300, 615], "color": [1, 1, 0]},
+		{"page": 2, "type": "Line", "line": [10, 10, 200, 200]}
+	]`
+
+	aa, err := pdf.ParseAnnotationsJSON(strings.NewReader(js))
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	if err := AddAnnotationsFile(inFile, outFile, []string{"1-2"}, aa, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if err := ValidateFile(outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	countTypes := func(selectedPages []string) map[string]int {
+		t.Helper()
+		aa, err := AnnotationsFile(outFile, selectedPages, nil)
+		if err != nil {
+			t.Fatalf("%s %s: %v\n", msg, outFile, err)
+		}
+		m := map[string]int{}
+		for _, a := range aa {
+			m[a.Type]++
+		}
+		return m
+	}
+
+	if want, got := map[string]int{"Text": 1, "Highlight": 2, "Line": 1}, countTypes(nil); !reflect.DeepEqual(want, got) {
+		t.Fatalf("%s %s: annotations want:%v got:%v\n", msg, outFile, want, got)
+	}
+
+	// Remove the note by its id and the Highlight on page 2.
+	if err := RemoveAnnotationsFile(outFile, "", nil, nil, []string{"note-1"}, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if err := RemoveAnnotationsFile(outFile, "", []string{"2"}, []string{"Highlight"}, nil, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if want, got := map[string]int{"Highlight": 1, "Line": 1}, countTypes(nil); !reflect.DeepEqual(want, got) {
+		t.Fatalf("%s %s: annotations want:%v got:%v\n", msg, outFile, want, got)
+	}
+
+	// Remove all remaining annotations.
+	if err := RemoveAnnotationsFile(outFile, "", nil, nil, nil, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if got := countTypes(nil); len(got) > 0 {
+		t.Fatalf("%s %s: unexpected annotations: %v\n", msg, outFile, got)
+	}
+	if err := ValidateFile(outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+}
+
+func TestFlattenAnnotations(t *testing.T) {
+	msg := "TestFlattenAnnotations"
+	inFile := filepath.Join(inDir, "annotTest.pdf")
+	outFile := filepath.Join(outDir, "flattened.pdf")
+
+	countTypes := func(fileName string) map[string]int {
+		t.Helper()
+		aa, err := AnnotationsFile(fileName, nil, nil)
+		if err != nil {
+			t.Fatalf("%s %s: %v\n", msg, fileName, err)
+		}
+		m := map[string]int{}
+		for _, a := range aa {
+			m[a.Type]++
+		}
+		return m
+	}
+
+	m := countTypes(inFile)
+
+	// Flatten stamps only.
+	if err := FlattenAnnotationsFile(inFile, outFile, nil, &pdf.Flatten{Types: []string{"Stamp"}}, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if err := ValidateFile(outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	m1 := countTypes(outFile)
+	if m1["Stamp"] != 0 || m1["FreeText"] != m["FreeText"] {
+		t.Fatalf("%s %s: annotations before:%v after:%v\n", msg, outFile, m, m1)
+	}
+
+	// Flatten all annotations having an appearance.
+	if err := FlattenAnnotationsFile(inFile, outFile, nil, nil, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if err := ValidateFile(outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	for _, typ := range []string{"Stamp", "FreeText", "Line", "Square"} {
+		if n := countTypes(outFile)[typ]; n > 0 {
+			t.Fatalf("%s %s: %d %s annotations left\n", msg, outFile, n, typ)
+		}
+	}
+}
+
+func TestFlattenAddedAnnotations(t *testing.T) {
+	msg := "TestFlattenAddedAnnotations"
+	inFile := filepath.Join(inDir, "go.pdf")
+	outFile := filepath.Join(outDir, "annotatedFlattened.pdf")
+
+	js := `[
+		{"type": "Square", "rect": [50, 600, 150, 650], "color": [1, 0, 0], "interiorColor": [0, 0, 1]},
+		{"type": "Circle", "rect": [200, 600, 300, 650], "border": 2},
+		{"type": "Line", "line": [50, 550, 300, 580], "color": [0, 0.5, 0]},
+		{"type": "Polygon", "vertices": [50, 450, 100, 520, 150, 450], "interiorColor": [1, 1, 0]},
+		{"type": "PolyLine", "vertices": [200, 450, 250, 520, 300, 450]},
+		{"type": "Highlight", "rect": [72, 400, 300, 415]},
+		{"type": "Underline", "rect": [72, 380, 300, 395]},
+		{"type": "Squiggly", "rect": [72, 360, 300, 375]},
+		{"type": "StrikeOut", "rect": [72, 340, 300, 355]},
+		{"type": "Ink", "inkList": [[50, 300, 80, 320, 110, 300], [50, 280, 110, 280]]},
+		{"type": "FreeText", "rect": [200, 250, 400, 320], "contents": "Grüße aus Zürich, a free text annotation wrapping lines", "fontSize": 10}
+	]`
+
+	aa, err := pdf.ParseAnnotationsJSON(strings.NewReader(js))
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	if err := AddAnnotationsFile(inFile, outFile, []string{"1"}, aa, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+
+	// All added annotations come with an appearance and get flattened.
+	if err := FlattenAnnotationsFile(outFile, "", nil, nil, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if err := ValidateFile(outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	aa, err = AnnotationsFile(outFile, []string{"1"}, nil)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	for _, a := range aa {
+		t.Fatalf("%s %s: %s annotation left\n", msg, outFile, a.Type)
+	}
+}
+
+// fieldDict returns the dict of the terminal field named name.
+func fieldDict(ctx *pdf.Context, name string) (pdf.Dict, error) {
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return nil, err
+	}
+	af, err := ctx.DereferenceDict(rootDict["AcroForm"])
+	if err != nil || af == nil {
+		return nil, fmt.Errorf("missing AcroForm %v", err)
+	}
+	fields, err := ctx.DereferenceArray(af["Fields"])
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range fields {
+		d, err := ctx.DereferenceDict(o)
+		if err != nil || d == nil {
+			continue
+		}
+		if s, ok := d["T"].(pdf.StringLiteral); ok && s.Value() == name {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("field %s not found", name)
+}
+
+// checkFieldFontEncoding verifies all fonts of the normal appearance of the field named name use WinAnsiEncoding.
+func checkFieldFontEncoding(fileName, name string) error {
+	ctx, err := ReadContextFile(fileName)
+	if err != nil {
+		return err
+	}
+	d, err := fieldDict(ctx, name)
+	if err != nil {
+		return err
+	}
+	ap, err := ctx.DereferenceDict(d["AP"])
+	if err != nil || ap == nil {
+		return fmt.Errorf("field %s: missing appearance %v", name, err)
+	}
+	sd, err := ctx.DereferenceStreamDict(ap["N"])
+	if err != nil || sd == nil {
+		return fmt.Errorf("field %s: missing normal appearance %v", name, err)
+	}
+	res, err := ctx.DereferenceDict(sd.Dict["Resources"])
+	if err != nil || res == nil {
+		return fmt.Errorf("field %s: missing resources %v", name, err)
+	}
+	fonts, err := ctx.DereferenceDict(res["Font"])
+	if err != nil || len(fonts) == 0 {
+		return fmt.Errorf("field %s: missing fonts %v", name, err)
+	}
+	for k, o := range fonts {
+		fd, err := ctx.DereferenceDict(o)
+		if err != nil || fd == nil {
+			return fmt.Errorf("field %s: font %s: %v", name, k, err)
+		}
+		if n := fd.NameEntry("Encoding"); n == nil || *n != "WinAnsiEncoding" {
+			return fmt.Errorf("field %s: font %s is not WinAnsi encoded", name, k)
+		}
+	}
+	return nil
+}
+
+func TestFillForm(t *testing.T) {
+	msg := "TestFillForm"
+	inFile := filepath.Join(outDir, "form.pdf")
+	outFile := filepath.Join(outDir, "formFilled.pdf")
+
+	xRefTable, err := pdf.CreateAcroFormDemoXRef()
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if err := CreatePDFFile(xRefTable, inFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	js := `{
+		"inputField": "Jörg (Müller)",
+		"CheckBox": false,
+		"Credit card": "card2",
+		"ComboBox": "Blue",
+		"ListBox": ["g", "r"]
+	}`
+
+	vv, err := pdf.ParseFormValuesJSON(strings.NewReader(js))
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	if err := FillFormFile(inFile, outFile, vv[0], nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if err := ValidateFile(outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	// Non ASCII text gets rendered using a WinAnsi encoded font.
+	if err := checkFieldFontEncoding(outFile, "inputField"); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+
+	fields, err := FormFieldsFile(outFile, nil)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+
+	got := map[string]string{}
+	for _, f := range fields {
+		got[f.Name] = f.Value
+		if f.Type == pdf.FieldListBox {
+			got[f.Name] = strings.Join(f.Values, ",")
+		}
+	}
+
+	want := map[string]string{
+		"inputField":  "Jörg (Müller)",
+		"CheckBox":    "Off",
+		"Credit card": "card2",
+		"ComboBox":    "b",
+		"ListBox":     "g,r",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("%s %s: field %s want:%q got:%q\n", msg, outFile, k, v, got[k])
+		}
+	}
+
+	// The exported values fill the original form identically.
+	var buf bytes.Buffer
+	f, err := os.Open(outFile)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	defer f.Close()
+	if err := ExportForm(f, &buf, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	vv, err = pdf.ParseFormValuesJSON(&buf)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if err := FillFormFile(inFile, outFile, vv[0], nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	fields1, err := FormFieldsFile(outFile, nil)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if !reflect.DeepEqual(fields, fields1) {
+		t.Fatalf("%s %s: fields want:%v got:%v\n", msg, outFile, fields, fields1)
+	}
+
+	if err := FillFormFile(inFile, outFile, pdf.FormValues{"ComboBox": "Yellow"}, nil); err == nil {
+		t.Fatalf("%s %s: invalid option accepted\n", msg, outFile)
+	}
+}
+
+func TestFlattenForm(t *testing.T) {
+	msg := "TestFlattenForm"
+	inFile := filepath.Join(outDir, "formFlatten.pdf")
+	outFile := filepath.Join(outDir, "formFlattened.pdf")
+
+	xRefTable, err := pdf.CreateAcroFormDemoXRef()
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if err := CreatePDFFile(xRefTable, inFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	if err := FillFormFile(inFile, "", pdf.FormValues{"inputField": "Flattened"}, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, inFile, err)
+	}
+
+	// Only printable widgets get flattened, non printing widgets are discarded.
+	ctx, err := ReadContextFile(inFile)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, inFile, err)
+	}
+	d, err := fieldDict(ctx, "inputField")
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, inFile, err)
+	}
+	d.Update("F", pdf.Integer(4))
+	n, err := pdf.FlattenForm(ctx, false)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, inFile, err)
+	}
+	if n != 1 {
+		t.Fatalf("%s %s: want 1 flattened widget, got %d\n", msg, inFile, n)
+	}
+
+	if err := FlattenFormFile(inFile, outFile, false, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if err := ValidateFile(outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	fields, err := FormFieldsFile(outFile, nil)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if len(fields) > 0 {
+		t.Fatalf("%s: want no form fields, got %d\n", msg, len(fields))
+	}
+
+	aa, err := AnnotationsFile(outFile, nil, nil)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	for _, a := range aa {
+		if a.Type == "Widget" {
+			t.Fatalf("%s: widget left on page %d\n", msg, a.PageNr)
+		}
+	}
+
+	// There is no form left to be flattened.
+	if err := FlattenFormFile(outFile, "", false, nil); err == nil {
+		t.Fatalf("%s: want error flattening file without form\n", msg)
+	}
+}
+
+func TestFormData(t *testing.T) {
+	msg := "TestFormData"
+	inFile := filepath.Join(outDir, "formData.pdf")
+	filledFile := filepath.Join(outDir, "formDataFilled.pdf")
+
+	xRefTable, err := pdf.CreateAcroFormDemoXRef()
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if err := CreatePDFFile(xRefTable, inFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	fv := pdf.FormValues{"inputField": "Jörg", "CheckBox": false, "Credit card": "card2", "ListBox": []string{"g"}}
+	if err := FillFormFile(inFile, filledFile, fv, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, filledFile, err)
+	}
+
+	aa, err := pdf.ParseAnnotationsJSON(strings.NewReader(`{"page": 1, "type": "Text", "id": "note-1", "rect": [50, 700, 70, 720], "contents": "Approved"}`))
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if err := AddAnnotationsFile(filledFile, "", nil, aa, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, filledFile, err)
+	}
+
+	values := func(fileName string) map[string]string {
+		t.Helper()
+		ff, err := FormFieldsFile(fileName, nil)
+		if err != nil {
+			t.Fatalf("%s %s: %v\n", msg, fileName, err)
+		}
+		m := map[string]string{}
+		for _, f := range ff {
+			m[f.Name] = fmt.Sprintf("%s %v", f.Value, f.Values)
+		}
+		return m
+	}
+
+	want := values(filledFile)
+
+	for _, format := range []string{FormDataFDF, FormDataXFDF} {
+
+		dataFile := filepath.Join(outDir, "formData."+format)
+		outFile := filepath.Join(outDir, "formDataImported_"+format+".pdf")
+
+		if err := ExportFormDataFile(filledFile, dataFile, format, nil); err != nil {
+			t.Fatalf("%s %s: %v\n", msg, dataFile, err)
+		}
+		if err := ImportFormDataFile(inFile, dataFile, outFile, format, nil); err != nil {
+			t.Fatalf("%s %s: %v\n", msg, outFile, err)
+		}
+		if err := ValidateFile(outFile, nil); err != nil {
+			t.Fatalf("%s: %v\n", msg, err)
+		}
+
+		if got := values(outFile); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s %s: want %v, got %v\n", msg, format, want, got)
+		}
+
+		aa, err := AnnotationsFile(outFile, nil, nil)
+		if err != nil {
+			t.Fatalf("%s %s: %v\n", msg, outFile, err)
+		}
+		found := false
+		for _, a := range aa {
+			if a.Type == "Text" && a.ID == "note-1" && a.Contents == "Approved" {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("%s %s: missing imported annotation\n", msg, format)
+		}
+	}
+
+	// Hierarchical field names, hex strings, indirect objects and streams.
+	s := `%FDF-1.2
+1 0 obj
+<< /FDF << /Fields [ << /T (a) /Kids [ << /T (b) /V <FEFF004A00F6> >> << /T (c) /V 2 0 R >> ] >> ] /Annots [ 3 0 R ] >> >>
+endobj
+2 0 obj
+/Off
+endobj
+3 0 obj
+<< /Type /Annot /Subtype /Square /Page 1 /Rect [ 10 10 50 50 ] /AP << /N 4 0 R >> >>
+endobj
+4 0 obj
+<< /Length 8 >>
+stream
+0 0 m S
+endstream
+endobj
+trailer
+<< /Root 1 0 R >>
+%%EOF`
+
+	data, err := fdf.Read(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if len(data.Fields) != 2 || data.Fields[0].Name != "a.b" || data.Fields[1].Name != "a.c" {
+		t.Fatalf("%s: unexpected fields: %v\n", msg, data.Fields)
+	}
+	if len(data.Annots) != 1 || data.Annots[0].PageNr != 2 {
+		t.Fatalf("%s: unexpected annotations: %v\n", msg, data.Annots)
+	}
+	if _, found := data.Annots[0].Dict.Find("AP"); found {
+		t.Fatalf("%s: appearance streams are not supported\n", msg)
+	}
+
+	// Imported annotations get added to an indirect Annots array and refer to their page.
+	ctx, err := ReadContextFile(filledFile)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, filledFile, err)
+	}
+	pd, _, err := ctx.PageDict(1)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	arr, err := ctx.DereferenceArray(pd["Annots"])
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	annotsIndRef, err := ctx.IndRefForNewObject(arr)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	pd.Update("Annots", *annotsIndRef)
+
+	data.Fields = nil
+	data.Annots[0].PageNr = 1
+	if err := fdf.Import(ctx, data); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	if ir, ok := pd["Annots"].(pdf.IndirectRef); !ok || ir != *annotsIndRef {
+		t.Fatalf("%s: want indirect Annots %s, got %v\n", msg, annotsIndRef, pd["Annots"])
+	}
+	arr1, err := ctx.DereferenceArray(pd["Annots"])
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if len(arr1) != len(arr)+1 {
+		t.Fatalf("%s: want %d annotations, got %d\n", msg, len(arr)+1, len(arr1))
+	}
+	d, err := ctx.DereferenceDict(arr1[len(arr1)-1])
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	pageIndRefs, err := ctx.PageIndRefs()
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if ir := d.IndirectRefEntry("P"); ir == nil || *ir != pageIndRefs[0] {
+		t.Fatalf("%s: want /P %s, got %v\n", msg, pageIndRefs[0], d["P"])
+	}
+}
+
+func TestCreateFormFields(t *testing.T) {
+	msg := "TestCreateFormFields"
+	inFile := filepath.Join(inDir, "T6.pdf")
+	outFile := filepath.Join(outDir, "formCreated.pdf")
+
+	js := `[
+		{"page": 1, "rect": [100, 700, 300, 720], "name": "Name", "type": "Text", "default": "Jane", "font": "Courier", "fontSize": 12, "borderColor": [0, 0, 1]},
+		{"page": 1, "rect": [100, 670, 115, 685], "name": "Agree", "type": "CheckBox", "default": true},
+		{"page": 1, "rect": [100, 640, 115, 655], "name": "Size", "type": "RadioGroup", "options": ["S"]},
+		{"page": 1, "rect": [120, 640, 135, 655], "name": "Size", "type": "RadioGroup", "options": ["L"], "default": "L"},
+		{"page": 2, "rect": [100, 600, 200, 620], "name": "Color", "type": "ComboBox", "options": ["Red", "Blue"], "default": "Blue"}
+	]`
+
+	fss, err := pdf.ParseFieldSpecsJSON(strings.NewReader(js))
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	if err := CreateFormFieldsFile(inFile, outFile, fss, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if err := ValidateFile(outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	fields, err := FormFieldsFile(outFile, nil)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+
+	got := map[string]string{}
+	for _, f := range fields {
+		got[f.Name] = f.Type + ":" + f.Value
+	}
+
+	want := map[string]string{
+		"Name":  pdf.FieldText + ":Jane",
+		"Agree": pdf.FieldCheckBox + ":Yes",
+		"Size":  pdf.FieldRadioGroup + ":L",
+		"Color": pdf.FieldComboBox + ":Blue",
+	}
+
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: field %s: want %q, got %q\n", msg, k, v, got[k])
+		}
+	}
+
+	// Field names must be unique.
+	if err := CreateFormFieldsFile(outFile, "", fss[:1], nil); err == nil {
+		t.Fatalf("%s: want error for duplicate field\n", msg)
+	}
+}
+
+func TestArrangePages(t *testing.T) {
+	msg := "TestArrangePages"
+	inFile := filepath.Join(inDir, "TheGoProgrammingLanguageCh1.pdf")
+	outFile := filepath.Join(outDir, "arranged.pdf")
+
+	n, err := PageCount(inFile)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	for _, tt := range []struct {
+		order     string
+		pageCount int
+	}{
+		{"3,1,2,5-l,4", n},
+		{"reverse", n},
+		{"duplicate", 2 * n},
+		{"1,1,l-l", 3},
+	} {
+		order, err := ParsePageOrder(tt.order)
+		if err != nil {
+			t.Fatalf("%s %s: %v\n", msg, tt.order, err)
+		}
+		if err := ArrangePagesFile(inFile, outFile, order, nil); err != nil {
+			t.Fatalf("%s %s: %v\n", msg, tt.order, err)
+		}
+		if err := ValidateFile(outFile, nil); err != nil {
+			t.Fatalf("%s %s: %v\n", msg, tt.order, err)
+		}
+		n1, err := PageCount(outFile)
+		if err != nil {
+			t.Fatalf("%s %s: %v\n", msg, tt.order, err)
+		}
+		if n1 != tt.pageCount {
+			t.Fatalf("%s %s: pageCount want:%d got:%d\n", msg, tt.order, tt.pageCount, n1)
+		}
+	}
+	// Repeated pages keep their annotations.
+	inFile = filepath.Join(inDir, "annotTest.pdf")
+	outFile = filepath.Join(outDir, "annotTestArranged.pdf")
+
+	if err := ArrangePagesFile(inFile, outFile, []string{"1", "1"}, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if err := ValidateFile(outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	aa, err := AnnotationsFile(outFile, nil, nil)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	c := map[int]int{}
+	for _, a := range aa {
+		c[a.PageNr]++
+	}
+	if c[1] == 0 || c[1] != c[2] {
+		t.Fatalf("%s: want the same annotations on both pages, got %v\n", msg, c)
+	}
+}
+
+func testAddWatermarks(t *testing.T, msg, inFile, outFile string, selectedPages []string, wmConf string, onTop bool) {
+	t.Helper()
+	inFile = filepath.Join(inDir, inFile)
+	outFile = filepath.Join(outDir, outFile)
+	wm, err := pdf.ParseWatermarkDetails(wmConf, onTop)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if err := AddWatermarksFile(inFile, outFile, selectedPages, wm, nil); err != nil {
+		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	}
+	if err := ValidateFile(outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+}
+
+func TestBoxes(t *testing.T) {
+	msg := "TestBoxes"
+	inFile := filepath.Join(inDir, "go.pdf")
+	outFile := filepath.Join(outDir, "goBoxes.pdf")
+
+	pb, err := pdf.ParsePageBoundaries("trim: 10, bleed: 1%, art: A5")
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	// Set TrimBox, BleedBox and ArtBox for the first 2 pages.
+	if err := AddBoxesFile(inFile, outFile, []string{"1-2"}, pb, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	ss, err := ListBoxesFile(outFile, []string{"1"}, []string{"TrimBox"}, nil)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if len(ss) != 2 || !strings.Contains(ss[1], "(10.00, 10.00, 783.80, 585.20)") {
+		t.Fatalf("%s: unexpected TrimBox: %v\n", msg, ss)
+	}
+
+	// Remove the TrimBox of page 2.
+	if err := RemoveBoxesFile(outFile, "", []string{"2"}, []string{"TrimBox"}, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	ss, err = ListBoxesFile(outFile, []string{"2"}, []string{"TrimBox"}, nil)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if len(ss) != 2 || !strings.Contains(ss[1], "(default)") {
+		t.Fatalf("%s: unexpected TrimBox: %v\n", msg, ss)
+	}
+
+	// The MediaBox may not be removed.
+	if err := RemoveBoxesFile(outFile, "", nil, []string{"MediaBox"}, nil); err == nil {
+		t.Fatalf("%s: removing the MediaBox should fail\n", msg)
+	}
+}
+
+func TestCrop(t *testing.T) {
+	msg := "TestCrop"
+	inFile := filepath.Join(inDir, "testImage.pdf")
+	outFile := filepath.Join(outDir, "testImageCropped.pdf")
+
+	// Crop to the bounding box of the page content.
+	if err := CropFile(inFile, outFile, []string{"1"}, nil, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	ss, err := ListBoxesFile(outFile, []string{"1"}, []string{"MediaBox", "CropBox"}, nil)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	// The image is centered on the page, so the CropBox must not start at the MediaBox origin.
+	if len(ss) != 3 || !strings.HasPrefix(strings.TrimSpace(ss[2]), "CropBox") || strings.Contains(ss[2], "(0.00, 0.00,") {
+		t.Fatalf("%s: CropBox not set: %v\n", msg, ss)
+	}
+
+	// Crop using margins.
+	b, err := pdf.ParseCropBox("10% 20")
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if err := CropFile(inFile, outFile, nil, b, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+}
+
+func TestResize(t *testing.T) {
+	msg := "TestResize"
+	inFile := filepath.Join(inDir, "annotTest.pdf")
+	outFile := filepath.Join(outDir, "annotTestResized.pdf")
+
+	res, err := pdf.ParseResizeConfig("f: A4L, m: fill, c: 1 1 1")
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	// Resize all pages including their annotations.
+	if err := ResizeFile(inFile, outFile, nil, res, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	ss, err := ListBoxesFile(outFile, []string{"1"}, []string{"MediaBox"}, nil)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if len(ss) != 2 || !strings.Contains(ss[1], "(0.00, 0.00, 842.00, 595.00)") {
+		t.Fatalf("%s: unexpected MediaBox: %v\n", msg, ss)
+	}
+}
+
+func TestResizeDestinations(t *testing.T) {
+	msg := "TestResizeDestinations"
+	inFile := filepath.Join(inDir, "annotTest.pdf")
+
+	ctx, err := ReadContextFile(inFile)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	irs, err := ctx.PageIndRefs()
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	pageDict, _, err := ctx.PageDict(1)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	// A link to page 1 along with named destinations.
+	xyz := pdf.Array{irs[0], pdf.Name("XYZ"), pdf.Float(100), pdf.Float(200), nil}
+	fitR := pdf.Array{irs[0], pdf.Name("FitR"), pdf.Float(0), pdf.Float(0), pdf.Float(595.44), pdf.Float(841.92)}
+	fitH := pdf.Array{irs[0], pdf.Name("FitH"), pdf.Float(841.92)}
+
+	link := pdf.Dict(map[string]pdf.Object{
+		"Type":    pdf.Name("Annot"),
+		"Subtype": pdf.Name("Link"),
+		"Rect":    pdf.Array{pdf.Float(0), pdf.Float(0), pdf.Float(10), pdf.Float(10)},
+		"A":       pdf.Dict(map[string]pdf.Object{"S": pdf.Name("GoTo"), "D": fitH}),
+	})
+	linkIndRef, err := ctx.IndRefForNewObject(link)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	annots, err := ctx.DereferenceArray(pageDict["Annots"])
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	pageDict.Update("Annots", append(annots, *linkIndRef))
+
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	rootDict.Update("Dests", pdf.Dict(map[string]pdf.Object{
+		"xyz":  xyz,
+		"fitR": pdf.Dict(map[string]pdf.Object{"D": fitR}),
+	}))
+
+	// Shrink A4 to A5 by a factor of 420/595.44 centered vertically.
+	res, err := pdf.ParseResizeConfig("f: A5, m: fit")
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if err := pdf.ResizePages(ctx, pdf.IntSet{1: true}, res); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	s := 420 / 595.44
+	ty := (595 - 841.92*s) / 2
+
+	for _, tt := range []struct {
+		a    pdf.Array
+		i    int
+		want float64
+	}{
+		{xyz, 2, 100 * s},
+		{xyz, 3, 200*s + ty},
+		{fitR, 3, ty},
+		{fitR, 4, 420},
+		{fitH, 2, 841.92*s + ty},
+	} {
+		f, ok := tt.a[tt.i].(pdf.Float)
+		if !ok || math.Abs(f.Value()-tt.want) > 0.01 {
+			t.Fatalf("%s: %v[%d]: want %.2f\n", msg, tt.a, tt.i, tt.want)
+		}
+	}
+	if xyz[4] != nil {
+		t.Fatalf("%s: XYZ zoom: want null, got %v\n", msg, xyz[4])
+	}
+}
+
+func TestBooklet(t *testing.T) {
+	msg := "TestBooklet"
+	inFile := filepath.Join(inDir, "CenterOfWhy.pdf")
+	outFile := filepath.Join(outDir, "CenterOfWhyBooklet.pdf")
+
+	for _, tt := range []struct {
+		desc      string
+		pageCount int
+	}{
+		{"", 6},                   // 10 pages padded to 12 make 3 sheets.
+		{"f: A3, s: 1, r: on", 6}, // 3 signatures of 1 sheet.
+		{"t: perfect, b: on", 6},  // 3 sheets cut along the fold.
+	} {
+		b, err := pdf.ParseBookletConfig(tt.desc)
+		if err != nil {
+			t.Fatalf("%s: %v\n", msg, err)
+		}
+
+		if err := BookletFile(inFile, outFile, []string{"1-10"}, b, nil); err != nil {
+			t.Fatalf("%s %s: %v\n", msg, tt.desc, err)
+		}
+
+		n, err := PageCount(outFile)
+		if err != nil {
+			t.Fatalf("%s: %v\n", msg, err)
+		}
+		if n != tt.pageCount {
+			t.Fatalf("%s %s: want %d pages, got %d\n", msg, tt.desc, tt.pageCount, n)
+		}
+	}
+
+	// A selection of no existing pages.
+	if err := BookletFile(inFile, outFile, []string{"100-"}, nil, nil); err == nil {
+		t.Fatalf("%s: want error for empty selection\n", msg)
+	}
+}
+
+func TestPoster(t *testing.T) {
+	msg := "TestPoster"
+	inFile := filepath.Join(inDir, "go.pdf")
+	outFile := filepath.Join(outDir, "goPoster.pdf")
+
+	// Scale page 1 (794 x 595) by 2 and tile it across A4 sheets.
+	p, err := pdf.ParsePosterConfig("s: 2, c: on, l: on")
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	if err := PosterFile(inFile, outFile, []string{"1"}, p, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	n, err := PageCount(outFile)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if n != 6 {
+		t.Fatalf("%s: want 6 sheets, got %d\n", msg, n)
+	}
+
+	// All tiles share the resources of page 1.
+	ctx, err := ReadContextFile(outFile)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	irs := pdf.IntSet{}
+	for i := 1; i <= n; i++ {
+		d, _, err := ctx.PageDict(i)
+		if err != nil {
+			t.Fatalf("%s: %v\n", msg, err)
+		}
+		ir := d.IndirectRefEntry("Resources")
+		if ir == nil {
+			t.Fatalf("%s: sheet %d: missing indirect resources\n", msg, i)
+		}
+		irs[ir.ObjectNumber.Value()] = true
+	}
+	if len(irs) != 1 {
+		t.Fatalf("%s: want 1 resources dict, got %d\n", msg, len(irs))
+	}
+}
+
+func TestCut(t *testing.T) {
+	msg := "TestCut"
+	inFile := filepath.Join(inDir, "CenterOfWhy.pdf")
+	outFile := filepath.Join(outDir, "CenterOfWhyCut.pdf")
+
+	n1, err := PageCount(inFile)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	cut, err := pdf.ParseCutConfig("h: .5, v: .5, o: rtl")
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
 
-func TestRotate(t *testing.T) {
-	msg := "TestRotate"
-	fileName := "Acroforms2.pdf"
-	inFile := filepath.Join(inDir, fileName)
-	outFile := filepath.Join(outDir, fileName)
+	// Cut pages 1 and 2 into quarters.
+	if err := CutFile(inFile, outFile, []string{"1-2"}, cut, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
 
-	// Rotate all pages of inFile, clockwise by 90 degrees and write the result to outFile.
-	if err := RotateFile(inFile, outFile, 90, nil, nil); err != nil {
+	n2, err := PageCount(outFile)
+	if err != nil {
 		t.Fatalf("%s: %v\n", msg, err)
 	}
+	if n2 != n1+6 {
+		t.Fatalf("%s: want %d pages, got %d\n", msg, n1+6, n2)
+	}
+	// Cut a page with annotations into an upper and a lower half.
+	inFile = filepath.Join(inDir, "annotTest.pdf")
+	outFile = filepath.Join(outDir, "annotTestCut.pdf")
 
-	// Rotate the first page of inFile by 180 degrees.
-	// If you want to modify the original file, pass an empty string for outFile.
-	inFile = outFile
-	if err := RotateFile(inFile, "", 180, []string{"1"}, nil); err != nil {
+	cut, err = pdf.ParseCutConfig("h: .5")
+	if err != nil {
 		t.Fatalf("%s: %v\n", msg, err)
 	}
-}
 
-func TestMerge(t *testing.T) {
-	msg := "TestMerge"
-	inFiles := []string{
-		filepath.Join(inDir, "Acroforms2.pdf"),
-		filepath.Join(inDir, "adobe_errata.pdf"),
+	if err := CutFile(inFile, outFile, []string{"1"}, cut, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
 	}
-	outFile := filepath.Join(outDir, "test.pdf")
 
-	// Merge inFiles by concatenation in the order specified and write the result to outFile.
-	if err := MergeFile(inFiles, outFile, nil); err != nil {
+	aa, err := AnnotationsFile(outFile, []string{"1-2"}, nil)
+	if err != nil {
 		t.Fatalf("%s: %v\n", msg, err)
 	}
+
+	// Annotations within the upper half stay on the upper sub page, full page annotations go to both.
+	m := map[string][]int{}
+	for _, a := range aa {
+		m[a.Contents] = append(m[a.Contents], a.PageNr)
+	}
+	if pp := m["note"]; len(pp) != 1 || pp[0] != 1 {
+		t.Fatalf("%s: want note on page 1, got %v\n", msg, pp)
+	}
+	if pp := m["hkjhkjh"]; len(pp) != 2 {
+		t.Fatalf("%s: want hkjhkjh on both sub pages, got %v\n", msg, pp)
+	}
 }
 
-func TestInsertRemovePages(t *testing.T) {
-	msg := "TestInsertRemovePages"
-	inFile := filepath.Join(inDir, "Acroforms2.pdf")
-	outFile := filepath.Join(outDir, "test.pdf")
+func TestCutKeepsOutlines(t *testing.T) {
+	msg := "TestCutKeepsOutlines"
+	fileName := "TheGoProgrammingLanguageCh1.pdf"
+	inFile := filepath.Join(inDir, fileName)
+	outFile := filepath.Join(outDir, "TheGoProgrammingLanguageCh1Cut.pdf")
 
-	n1, err := PageCount(inFile)
+	cut, err := pdf.ParseCutConfig("h: .5")
 	if err != nil {
-		t.Fatalf("%s %s: %v\n", msg, inFile, err)
+		t.Fatalf("%s: %v\n", msg, err)
 	}
 
-	// Insert an empty page before pages 1 and 2.
-	if err := InsertPagesFile(inFile, outFile, []string{"-2"}, nil); err != nil {
-		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	// Outline items pointing to cut pages get redirected to their first sub page.
+	if err := CutFile(inFile, outFile, nil, cut, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
 	}
 	if err := ValidateFile(outFile, nil); err != nil {
 		t.Fatalf("%s: %v\n", msg, err)
 	}
-	n2, err := PageCount(outFile)
+
+	ctx, err := ReadContextFile(outFile)
 	if err != nil {
-		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+		t.Fatalf("%s: %v\n", msg, err)
 	}
-	if n2 != n1+2 {
-		t.Fatalf("%s %s: pageCount want:%d got:%d\n", msg, inFile, n1+2, n2)
+	irs, err := ctx.PageIndRefs()
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
 	}
-
-	// 	// Remove pages 1 and 2.
-	if err := RemovePagesFile(outFile, "", []string{"-2"}, nil); err != nil {
-		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+	pages := pdf.IntSet{}
+	for _, ir := range irs {
+		pages[ir.ObjectNumber.Value()] = true
 	}
-	if err := ValidateFile(outFile, nil); err != nil {
+	rootDict, err := ctx.Catalog()
+	if err != nil {
 		t.Fatalf("%s: %v\n", msg, err)
 	}
-	n2, err = PageCount(outFile)
+	d, err := ctx.DereferenceDict(rootDict["Outlines"])
+	if err != nil || d == nil {
+		t.Fatalf("%s: missing outlines %v\n", msg, err)
+	}
+	c, err := outlineDestsWithin(ctx, d.IndirectRefEntry("First"), pages)
 	if err != nil {
-		t.Fatalf("%s %s: %v\n", msg, inFile, err)
+		t.Fatalf("%s: %v\n", msg, err)
 	}
-	if n1 != n2 {
-		t.Fatalf("%s %s: pageCount want:%d got:%d\n", msg, inFile, n1, n2)
+	if c == 0 {
+		t.Fatalf("%s: missing outline items\n", msg)
 	}
-}
 
-func testAddWatermarks(t *testing.T, msg, inFile, outFile string, selectedPages []string, wmConf string, onTop bool) {
-	t.Helper()
-	inFile = filepath.Join(inDir, inFile)
-	outFile = filepath.Join(outDir, outFile)
-	wm, err := pdf.ParseWatermarkDetails(wmConf, onTop)
+	// The replaced original pages must not be written.
+	fi1, err := os.Stat(inFile)
 	if err != nil {
-		t.Fatalf("%s %s: %v\n", msg, outFile, err)
-	}
-	if err := AddWatermarksFile(inFile, outFile, selectedPages, wm, nil); err != nil {
-		t.Fatalf("%s %s: %v\n", msg, outFile, err)
+		t.Fatalf("%s: %v\n", msg, err)
 	}
-	if err := ValidateFile(outFile, nil); err != nil {
+	fi2, err := os.Stat(outFile)
+	if err != nil {
 		t.Fatalf("%s: %v\n", msg, err)
 	}
+	if fi2.Size() > fi1.Size()*3/2 {
+		t.Fatalf("%s: output size %d exceeds input size %d\n", msg, fi2.Size(), fi1.Size())
+	}
 }
 
 func TestAddWatermarks(t *testing.T) {
@@ -645,6 +2114,36 @@ func TestExtractImagesCommand(t *testing.T) {
 	}
 }
 
+func TestExtractImagesToReaders(t *testing.T) {
+	msg := "TestExtractImagesToReaders"
+	inFile := filepath.Join(inDir, "testImage.pdf")
+
+	f, err := os.Open(inFile)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	defer f.Close()
+
+	// Extract images for all pages into memory.
+	rr, err := ExtractImagesToReaders(f, nil, nil)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if len(rr) == 0 {
+		t.Fatalf("%s: no images extracted\n", msg)
+	}
+
+	for _, r := range rr {
+		bb, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: %v\n", msg, err)
+		}
+		if len(bb) == 0 {
+			t.Fatalf("%s: %s is empty\n", msg, r.Name)
+		}
+	}
+}
+
 func TestExtractFontsCommand(t *testing.T) {
 	msg := "TestExtractFontsCommand"
 
@@ -692,3 +2191,310 @@ func TestExtractMetadataCommand(t *testing.T) {
 		t.Fatalf("%s %s: %v\n", msg, inFile, err)
 	}
 }
+
+func TestSign(t *testing.T) {
+	msg := "TestSign"
+	inFile := filepath.Join(inDir, "T6.pdf")
+	p12File := filepath.Join(inDir, "sign", "signer.p12")
+
+	orig, err := ioutil.ReadFile(inFile)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	for _, tt := range []struct {
+		outFile string
+		desc    string
+	}{
+		{"signedInvisible.pdf", ""},
+		{"signedVisible.pdf", "page: 2, rect: 50 50 250 100, reason: Approved, location: Berlin"},
+		{"signedCAdES.pdf", "field: Approval, format: cades"},
+	} {
+		outFile := filepath.Join(outDir, tt.outFile)
+
+		s, err := pdf.ParseSignConfig(tt.desc)
+		if err != nil {
+			t.Fatalf("%s %s: %v\n", msg, tt.outFile, err)
+		}
+		if err := s.ReadCredentials(p12File, "", "test"); err != nil {
+			t.Fatalf("%s %s: %v\n", msg, tt.outFile, err)
+		}
+
+		if err := SignFile(inFile, outFile, s, nil); err != nil {
+			t.Fatalf("%s %s: %v\n", msg, tt.outFile, err)
+		}
+		if err := ValidateFile(outFile, nil); err != nil {
+			t.Fatalf("%s %s: %v\n", msg, tt.outFile, err)
+		}
+
+		b, err := ioutil.ReadFile(outFile)
+		if err != nil {
+			t.Fatalf("%s: %v\n", msg, err)
+		}
+		if !bytes.HasPrefix(b, orig) {
+			t.Fatalf("%s %s: not an incremental update\n", msg, tt.outFile)
+		}
+		if !bytes.Contains(b, []byte("/SubFilter/"+s.SubFilter())) {
+			t.Fatalf("%s %s: missing /SubFilter %s\n", msg, tt.outFile, s.SubFilter())
+		}
+
+		fields, err := FormFieldsFile(outFile, nil)
+		if err != nil {
+			t.Fatalf("%s %s: %v\n", msg, tt.outFile, err)
+		}
+		if len(fields) != 1 || fields[0].Type != pdf.FieldSignature {
+			t.Fatalf("%s %s: want 1 signature field, got %v\n", msg, tt.outFile, fields)
+		}
+	}
+}
+
+func TestVerifySignatures(t *testing.T) {
+	msg := "TestVerifySignatures"
+	inFile := filepath.Join(inDir, "T6.pdf")
+	signedFile := filepath.Join(outDir, "signedTwice.pdf")
+	tamperedFile := filepath.Join(outDir, "signedTampered.pdf")
+
+	roots, err := pdf.ReadTrustedCerts(filepath.Join(inDir, "sign", "ca.pem"))
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	// Sign twice using RSA and ECDSA credentials.
+	// The contact info mimics a Contents placeholder.
+	s1, _ := pdf.ParseSignConfig("reason: Approved, contact: /Contents<00>, format: cades")
+	if err := s1.ReadCredentials(filepath.Join(inDir, "sign", "signer.p12"), "", "test"); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if err := SignFile(inFile, signedFile, s1, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	s2, _ := pdf.ParseSignConfig("")
+	if err := s2.ReadCredentials(filepath.Join(inDir, "sign", "ec.pem"), filepath.Join(inDir, "sign", "ec.key"), ""); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if err := SignFile(signedFile, "", s2, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	conf := pdf.NewDefaultConfiguration()
+	conf.TrustedCerts = roots
+	sigs, err := VerifySignaturesFile(signedFile, conf)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if len(sigs) != 2 {
+		t.Fatalf("%s: want 2 signatures, got %d\n", msg, len(sigs))
+	}
+	for i, s := range sigs {
+		if s.Err != nil || !s.Verified || !s.Trusted {
+			t.Fatalf("%s: %s: %v\n", msg, s.FieldName, s.Err)
+		}
+		if s.Modified() != (i == 0) {
+			t.Fatalf("%s: %s: modified=%t\n", msg, s.FieldName, s.Modified())
+		}
+	}
+	if sigs[0].Signer.Subject.CommonName != "Jane Doe" || sigs[1].Signer.Subject.CommonName != "John Roe" {
+		t.Fatalf("%s: unexpected signers\n", msg)
+	}
+
+	// Untrusted without the test CA.
+	sigs, err = VerifySignaturesFile(signedFile, nil)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if s := sigs[0]; !s.Verified || s.Trusted || s.Err == nil {
+		t.Fatalf("%s: %s: want intact but untrusted\n", msg, s.FieldName)
+	}
+
+	// Tamper with the signed reason.
+	b, err := ioutil.ReadFile(signedFile)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	b = bytes.Replace(b, []byte("(Approved)"), []byte("(Rejected)"), 1)
+	if err := ioutil.WriteFile(tamperedFile, b, os.ModePerm); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	sigs, err = VerifySignaturesFile(tamperedFile, conf)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	// The second signature covers the first revision too.
+	for _, s := range sigs {
+		if s.Err != cms.ErrDigestMismatch {
+			t.Fatalf("%s: %s: want digest mismatch, got %v\n", msg, s.FieldName, s.Err)
+		}
+	}
+
+	// Inbound documents get checked automatically.
+	conf = pdf.NewDefaultConfiguration()
+	conf.VerifySignatures = true
+	conf.TrustedCerts = roots
+	if _, err := ListFormFieldsFile(signedFile, conf); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if _, err := ListFormFieldsFile(tamperedFile, conf); errors.Cause(err) != cms.ErrDigestMismatch {
+		t.Fatalf("%s: want digest mismatch, got %v\n", msg, err)
+	}
+}
+
+func TestTimeStampAndLTV(t *testing.T) {
+	msg := "TestTimeStampAndLTV"
+	inFile := filepath.Join(inDir, "T6.pdf")
+	outFile := filepath.Join(outDir, "signedStamped.pdf")
+	signDir := filepath.Join(inDir, "sign")
+
+	certPEM, _ := ioutil.ReadFile(filepath.Join(signDir, "tsa.pem"))
+	keyPEM, _ := ioutil.ReadFile(filepath.Join(signDir, "tsa.key"))
+	tsaSigner, err := cms.ParsePEM(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	tsa := &cms.LocalTSA{Signer: tsaSigner}
+
+	s, _ := pdf.ParseSignConfig("format: cades")
+	if err := s.ReadCredentials(filepath.Join(signDir, "signer.p12"), "", "test"); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if err := SignFile(inFile, outFile, s, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if err := TimeStampFile(outFile, "", tsa, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	ltv, err := pdf.ParseLTVConfig(fmt.Sprintf("certs: %s, crls: %s, ocsps: %s",
+		filepath.Join(signDir, "ca.pem"), filepath.Join(signDir, "ca.crl"), filepath.Join(signDir, "signer.ocsp")))
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if err := AddLTVFile(outFile, "", ltv, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if err := ValidateFile(outFile, nil); err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+
+	conf := pdf.NewDefaultConfiguration()
+	conf.TrustedCerts, err = pdf.ReadTrustedCerts(filepath.Join(signDir, "ca.pem"))
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	sigs, err := VerifySignaturesFile(outFile, conf)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	if len(sigs) != 2 {
+		t.Fatalf("%s: want 2 signatures, got %d\n", msg, len(sigs))
+	}
+	for _, s := range sigs {
+		if s.Err != nil || !s.Verified || !s.Trusted || !s.Modified() {
+			t.Fatalf("%s: %s: %v\n", msg, s.FieldName, s.Err)
+		}
+	}
+	if ts := sigs[1]; ts.Type != "DocTimeStamp" || ts.SubFilter != pdf.SubFilterRFC3161 || ts.Signer.Subject.CommonName != "pdfcpu Test TSA" {
+		t.Fatalf("%s: unexpected time-stamp: %s %s\n", msg, ts.Type, ts.SubFilter)
+	}
+
+	// The DSS holds one VRI entry per signature.
+	ctx, err := ReadContextFile(outFile)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	rootDict, _ := ctx.Catalog()
+	dss, _ := ctx.DereferenceDict(rootDict["DSS"])
+	vri, _ := ctx.DereferenceDict(dss["VRI"])
+	if len(vri) != 2 || len(dss.ArrayEntry("OCSPs")) != 1 || len(dss.ArrayEntry("CRLs")) != 1 {
+		t.Fatalf("%s: unexpected DSS: %s\n", msg, dss)
+	}
+}
+
+func testPubSecEncryption(t *testing.T, fileName string, alg string, keyLength int) {
+	msg := "testPubSecEncryption"
+
+	signDir := filepath.Join(inDir, "sign")
+	inFile := filepath.Join(inDir, fileName)
+	outFile := filepath.Join(outDir, "testPubSec.pdf")
+
+	confForRecipient := func(cert, key string) *pdfcpu.Configuration {
+		conf := pdfcpu.NewDefaultConfiguration()
+		if err := conf.ReadRecipientCredentials(filepath.Join(signDir, cert), filepath.Join(signDir, key), ""); err != nil {
+			t.Fatalf("%s: %v\n", msg, err)
+		}
+		return conf
+	}
+
+	// Encrypt file for Jane Doe with no permissions and for the TSA with all permissions.
+	conf := confForAlgorithm(alg == "aes", keyLength, "", "")
+	rr, err := pdfcpu.ParseRecipients(filepath.Join(signDir, "signer.pem")+", "+filepath.Join(signDir, "tsa.pem")+":all", pdfcpu.PermissionsNone)
+	if err != nil {
+		t.Fatalf("%s: %v\n", msg, err)
+	}
+	conf.Recipients = rr
+	if err := EncryptFile(inFile, outFile, conf); err != nil {
+		t.Fatalf("%s: encrypt %s: %v\n", msg, outFile, err)
+	}
+
+	// Opening the encrypted file w/o credentials should fail.
+	if list, err := ListPermissionsFile(outFile, nil); err == nil {
+		t.Fatalf("%s: list permissions w/o credentials %s: %v\n", msg, outFile, list)
+	}
+
+	// Opening the encrypted file as non recipient should fail.
+	if list, err := ListPermissionsFile(outFile, confForRecipient("ca.pem", "ca.key")); err == nil {
+		t.Fatalf("%s: list permissions as non recipient %s: %v\n", msg, outFile, list)
+	}
+
+	// Each recipient gets its own permissions.
+	list, err := ListPermissionsFile(outFile, confForRecipient("signer.pem", "signer.key"))
+	if err != nil {
+		t.Fatalf("%s: list permissions %s: %v\n", msg, outFile, err)
+	}
+	ensurePermissionsNone(t, list)
+
+	if list, err = ListPermissionsFile(outFile, confForRecipient("tsa.pem", "tsa.key")); err != nil {
+		t.Fatalf("%s: list permissions %s: %v\n", msg, outFile, err)
+	}
+	ensurePermissionsAll(t, list)
+
+	// Setting permissions of a public-key encrypted file should fail.
+	conf = confForRecipient("tsa.pem", "tsa.key")
+	conf.Permissions = pdfcpu.PermissionsNone
+	if err = SetPermissionsFile(outFile, "", conf); err == nil {
+		t.Fatalf("%s: set permissions for %s\n", msg, outFile)
+	}
+
+	// Decrypt file.
+	if err = DecryptFile(outFile, "", confForRecipient("signer.pem", "signer.key")); err != nil {
+		t.Fatalf("%s: decrypt %s: %v\n", msg, outFile, err)
+	}
+
+	// Validate decrypted file.
+	if err = ValidateFile(outFile, nil); err != nil {
+		t.Fatalf("%s: validate %s: %v\n", msg, outFile, err)
+	}
+}
+
+func TestPubSecEncryption(t *testing.T) {
+	for _, fileName := range []string{
+		"5116.DCT_Filter.pdf",
+		"networkProgr.pdf",
+	} {
+		testPubSecEncryption(t, fileName, "rc4", 40)
+		testPubSecEncryption(t, fileName, "rc4", 128)
+		testPubSecEncryption(t, fileName, "aes", 128)
+		testPubSecEncryption(t, fileName, "aes", 256)
+	}
+
+	// Only RSA recipients are supported.
+	conf := confForAlgorithm(true, 256, "", "")
+	rr, err := pdfcpu.ParseRecipients(filepath.Join(inDir, "sign", "ec.pem"), pdfcpu.PermissionsAll)
+	if err != nil {
+		t.Fatalf("TestPubSecEncryption: %v\n", err)
+	}
+	conf.Recipients = rr
+	if err := EncryptFile(filepath.Join(inDir, "5116.DCT_Filter.pdf"), filepath.Join(outDir, "testPubSec.pdf"), conf); err == nil {
+		t.Fatalf("TestPubSecEncryption: encrypt for EC recipient should fail\n")
+	}
+}
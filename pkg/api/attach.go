@@ -216,8 +216,9 @@ func RemoveAttachmentsFile(inFile, outFile string, files []string, conf *pdf.Con
 	return RemoveAttachments(f1, f2, files, conf)
 }
 
-// ExtractAttachments extracts embedded files from a PDF context read from rs into outDir.
-func ExtractAttachments(rs io.ReadSeeker, outDir string, fileNames []string, conf *pdf.Configuration) error {
+// ExtractAttachmentsToWriters extracts embedded files from a PDF context read from rs.
+// Each file is written to the writer returned by wf for its file name.
+func ExtractAttachmentsToWriters(rs io.ReadSeeker, fileNames []string, wf WriterFunc, conf *pdf.Configuration) error {
 	if conf == nil {
 		conf = pdf.NewDefaultConfiguration()
 	}
@@ -230,11 +231,18 @@ func ExtractAttachments(rs io.ReadSeeker, outDir string, fileNames []string, con
 
 	fromWrite := time.Now()
 
-	ctx.Write.DirName = outDir
-	if err = pdf.AttachExtract(ctx, stringSet(fileNames)); err != nil {
+	aa, err := pdf.AttachExtract(ctx, stringSet(fileNames))
+	if err != nil {
 		return err
 	}
 
+	for _, a := range aa {
+		log.Info.Printf("writing %s\n", a.FileName)
+		if err = writeNamed(wf, a.FileName, a); err != nil {
+			return err
+		}
+	}
+
 	durWrite := time.Since(fromWrite).Seconds()
 	durTotal := time.Since(fromStart).Seconds()
 	log.Stats.Printf("XRefTable:\n%s\n", ctx)
@@ -243,6 +251,20 @@ func ExtractAttachments(rs io.ReadSeeker, outDir string, fileNames []string, con
 	return nil
 }
 
+// ExtractAttachmentsToReaders extracts embedded files from a PDF context read from rs into memory.
+func ExtractAttachmentsToReaders(rs io.ReadSeeker, fileNames []string, conf *pdf.Configuration) ([]NamedReader, error) {
+	c := &readerCollector{}
+	if err := ExtractAttachmentsToWriters(rs, fileNames, c.writer, conf); err != nil {
+		return nil, err
+	}
+	return c.rr, nil
+}
+
+// ExtractAttachments extracts embedded files from a PDF context read from rs into outDir.
+func ExtractAttachments(rs io.ReadSeeker, outDir string, fileNames []string, conf *pdf.Configuration) error {
+	return ExtractAttachmentsToWriters(rs, fileNames, dirWriterFunc(outDir), conf)
+}
+
 // ExtractAttachmentsFile extracts embedded files from a PDF context read from inFile into outDir.
 func ExtractAttachmentsFile(inFile, outDir string, fileNames []string, conf *pdf.Configuration) error {
 	f, err := os.Open(inFile)
@@ -0,0 +1,185 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	pdf "github.com/denisbetsi/pdfcpu/pkg/pdfcpu"
+	"github.com/pkg/errors"
+)
+
+func blankPages(ctx *pdf.Context, selectedPages []string, threshold float64) ([]int, error) {
+	pages, err := pagesForPageSelection(ctx.PageCount, selectedPages, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return pdf.BlankPages(ctx, pages, threshold)
+}
+
+// BlankPages returns the numbers of the blank pages among the selected pages of rs.
+// Images count as blank if the share of their white pixels is at least threshold.
+func BlankPages(rs io.ReadSeeker, selectedPages []string, threshold float64, conf *pdf.Configuration) ([]int, error) {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.LISTBLANKPAGES
+
+	ctx, _, _, err := readAndValidate(rs, conf, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return blankPages(ctx, selectedPages, threshold)
+}
+
+// BlankPagesFile returns the numbers of the blank pages among the selected pages of inFile.
+func BlankPagesFile(inFile string, selectedPages []string, threshold float64, conf *pdf.Configuration) ([]int, error) {
+	f, err := os.Open(inFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return BlankPages(f, selectedPages, threshold, conf)
+}
+
+// ListBlankPagesFile returns a list of the blank pages among the selected pages of inFile.
+func ListBlankPagesFile(inFile string, selectedPages []string, threshold float64, conf *pdf.Configuration) ([]string, error) {
+	blank, err := BlankPagesFile(inFile, selectedPages, threshold, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(blank) == 0 {
+		return []string{"no blank pages"}, nil
+	}
+
+	ss := []string{"blank pages:"}
+	for _, i := range blank {
+		ss = append(ss, fmt.Sprintf("page %d", i))
+	}
+
+	return ss, nil
+}
+
+// RemoveBlankPages removes the blank pages among the selected pages of rs and writes the result to w.
+// Images count as blank if the share of their white pixels is at least threshold.
+// It returns the numbers of the removed pages.
+func RemoveBlankPages(rs io.ReadSeeker, w io.Writer, selectedPages []string, threshold float64, conf *pdf.Configuration) ([]int, error) {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.REMOVEBLANKPAGES
+
+	fromStart := time.Now()
+	ctx, durRead, durVal, durOpt, err := readValidateAndOptimize(rs, conf, fromStart)
+	if err != nil {
+		return nil, err
+	}
+
+	from := time.Now()
+	blank, err := blankPages(ctx, selectedPages, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(blank) == ctx.PageCount {
+		return nil, errors.New("removeblank: all pages are blank")
+	}
+
+	if len(blank) > 0 {
+		keep := pdf.IntSet{}
+		for i := 1; i <= ctx.PageCount; i++ {
+			keep[i] = true
+		}
+		for _, i := range blank {
+			delete(keep, i)
+		}
+		if err = pdf.TrimPages(ctx, keep); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Info.Printf("removeblank: removed %d pages\n", len(blank))
+	log.Stats.Printf("XRefTable:\n%s\n", ctx)
+	durRemove := time.Since(from).Seconds()
+	fromWrite := time.Now()
+
+	if conf.ValidationMode != pdf.ValidationNone {
+		if err = ValidateContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = WriteContext(ctx, w); err != nil {
+		return nil, err
+	}
+
+	durWrite := durRemove + time.Since(fromWrite).Seconds()
+	durTotal := time.Since(fromStart).Seconds()
+	logOperationStats(ctx, "remove blank pages, write", durRead, durVal, durOpt, durWrite, durTotal)
+
+	return blank, nil
+}
+
+// RemoveBlankPagesFile removes the blank pages among the selected pages of inFile and writes the result to outFile.
+// It returns the numbers of the removed pages.
+func RemoveBlankPagesFile(inFile, outFile string, selectedPages []string, threshold float64, conf *pdf.Configuration) (blank []int, err error) {
+	var f1, f2 *os.File
+
+	if f1, err = os.Open(inFile); err != nil {
+		return nil, err
+	}
+
+	tmpFile := inFile + ".tmp"
+	if outFile != "" && inFile != outFile {
+		tmpFile = outFile
+	}
+	if f2, err = os.Create(tmpFile); err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			f2.Close()
+			f1.Close()
+			if outFile == "" || inFile == outFile {
+				os.Remove(tmpFile)
+			}
+			return
+		}
+		if err = f2.Close(); err != nil {
+			return
+		}
+		if err = f1.Close(); err != nil {
+			return
+		}
+		if outFile == "" || inFile == outFile {
+			if err = os.Rename(tmpFile, inFile); err != nil {
+				return
+			}
+		}
+	}()
+
+	return RemoveBlankPages(f1, f2, selectedPages, threshold, conf)
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	pdf "github.com/denisbetsi/pdfcpu/pkg/pdfcpu"
+	"github.com/pkg/errors"
+)
+
+// ListBoxes returns a list of page boundaries for selected pages of rs.
+// If no boxes are specified all page boundaries are listed.
+func ListBoxes(rs io.ReadSeeker, selectedPages []string, boxes []string, conf *pdf.Configuration) ([]string, error) {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.LISTBOXES
+
+	ctx, _, _, _, err := readValidateAndOptimize(rs, conf, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := pagesForPageSelection(ctx.PageCount, selectedPages, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return pdf.ListBoxes(ctx, pages, boxes)
+}
+
+// ListBoxesFile returns a list of page boundaries for selected pages of inFile.
+func ListBoxesFile(inFile string, selectedPages []string, boxes []string, conf *pdf.Configuration) ([]string, error) {
+	f, err := os.Open(inFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ListBoxes(f, selectedPages, boxes, conf)
+}
+
+// AddBoxes sets page boundaries for selected pages of rs and writes the result to w.
+func AddBoxes(rs io.ReadSeeker, w io.Writer, selectedPages []string, pb pdf.PageBoundaries, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.ADDBOXES
+
+	if len(pb) == 0 {
+		return errors.New("missing page boundaries")
+	}
+
+	fromStart := time.Now()
+	ctx, durRead, durVal, durOpt, err := readValidateAndOptimize(rs, conf, fromStart)
+	if err != nil {
+		return err
+	}
+
+	from := time.Now()
+	pages, err := pagesForPageSelection(ctx.PageCount, selectedPages, true)
+	if err != nil {
+		return err
+	}
+
+	if err = pdf.AddBoxes(ctx, pages, pb); err != nil {
+		return err
+	}
+
+	log.Stats.Printf("XRefTable:\n%s\n", ctx)
+	durBoxes := time.Since(from).Seconds()
+	fromWrite := time.Now()
+
+	if conf.ValidationMode != pdf.ValidationNone {
+		if err = ValidateContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err = WriteContext(ctx, w); err != nil {
+		return err
+	}
+
+	durWrite := durBoxes + time.Since(fromWrite).Seconds()
+	durTotal := time.Since(fromStart).Seconds()
+	logOperationStats(ctx, "add boxes, write", durRead, durVal, durOpt, durWrite, durTotal)
+
+	return nil
+}
+
+// AddBoxesFile sets page boundaries for selected pages of inFile and writes the result to outFile.
+func AddBoxesFile(inFile, outFile string, selectedPages []string, pb pdf.PageBoundaries, conf *pdf.Configuration) (err error) {
+	var f1, f2 *os.File
+
+	if f1, err = os.Open(inFile); err != nil {
+		return err
+	}
+
+	tmpFile := inFile + ".tmp"
+	if outFile != "" && inFile != outFile {
+		tmpFile = outFile
+	}
+	if f2, err = os.Create(tmpFile); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f2.Close()
+			f1.Close()
+			if outFile == "" || inFile == outFile {
+				os.Remove(tmpFile)
+			}
+			return
+		}
+		if err = f2.Close(); err != nil {
+			return
+		}
+		if err = f1.Close(); err != nil {
+			return
+		}
+		if outFile == "" || inFile == outFile {
+			if err = os.Rename(tmpFile, inFile); err != nil {
+				return
+			}
+		}
+	}()
+
+	return AddBoxes(f1, f2, selectedPages, pb, conf)
+}
+
+// RemoveBoxes removes page boundaries from selected pages of rs and writes the result to w.
+func RemoveBoxes(rs io.ReadSeeker, w io.Writer, selectedPages []string, boxes []string, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.REMOVEBOXES
+
+	if len(boxes) == 0 {
+		return errors.New("missing box types")
+	}
+
+	fromStart := time.Now()
+	ctx, durRead, durVal, durOpt, err := readValidateAndOptimize(rs, conf, fromStart)
+	if err != nil {
+		return err
+	}
+
+	from := time.Now()
+	pages, err := pagesForPageSelection(ctx.PageCount, selectedPages, true)
+	if err != nil {
+		return err
+	}
+
+	if err = pdf.RemoveBoxes(ctx, pages, boxes); err != nil {
+		return err
+	}
+
+	log.Stats.Printf("XRefTable:\n%s\n", ctx)
+	durBoxes := time.Since(from).Seconds()
+	fromWrite := time.Now()
+
+	if conf.ValidationMode != pdf.ValidationNone {
+		if err = ValidateContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err = WriteContext(ctx, w); err != nil {
+		return err
+	}
+
+	durWrite := durBoxes + time.Since(fromWrite).Seconds()
+	durTotal := time.Since(fromStart).Seconds()
+	logOperationStats(ctx, "remove boxes, write", durRead, durVal, durOpt, durWrite, durTotal)
+
+	return nil
+}
+
+// RemoveBoxesFile removes page boundaries from selected pages of inFile and writes the result to outFile.
+func RemoveBoxesFile(inFile, outFile string, selectedPages []string, boxes []string, conf *pdf.Configuration) (err error) {
+	var f1, f2 *os.File
+
+	if f1, err = os.Open(inFile); err != nil {
+		return err
+	}
+
+	tmpFile := inFile + ".tmp"
+	if outFile != "" && inFile != outFile {
+		tmpFile = outFile
+	}
+	if f2, err = os.Create(tmpFile); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f2.Close()
+			f1.Close()
+			if outFile == "" || inFile == outFile {
+				os.Remove(tmpFile)
+			}
+			return
+		}
+		if err = f2.Close(); err != nil {
+			return
+		}
+		if err = f1.Close(); err != nil {
+			return
+		}
+		if outFile == "" || inFile == outFile {
+			if err = os.Rename(tmpFile, inFile); err != nil {
+				return
+			}
+		}
+	}()
+
+	return RemoveBoxes(f1, f2, selectedPages, boxes, conf)
+}
@@ -46,11 +46,11 @@ func imageObjNrs(ctx *pdf.Context, page int) []int {
 	return o
 }
 
-func imageFilenameWithoutExtension(dir, resID string, pageNr, objNr int) string {
-	return filepath.Join(dir, fmt.Sprintf("%s_%d_%d", resID, pageNr, objNr))
+func imageFileName(resID string, pageNr, objNr int, ext string) string {
+	return fmt.Sprintf("%s_%d_%d.%s", resID, pageNr, objNr, ext)
 }
 
-func doExtractImages(ctx *pdf.Context, selectedPages pdf.IntSet) error {
+func doExtractImages(ctx *pdf.Context, selectedPages pdf.IntSet, wf WriterFunc) error {
 
 	visited := pdf.IntSet{}
 
@@ -77,13 +77,19 @@ func doExtractImages(ctx *pdf.Context, selectedPages pdf.IntSet) error {
 					continue
 				}
 
-				filename := imageFilenameWithoutExtension(ctx.Write.DirName, io.ResourceNames[0], pageNr, objNr)
-
-				_, err = pdf.WriteImage(ctx.XRefTable, filename, io.ImageDict, objNr)
+				r, ext, err := pdf.RenderImage(ctx.XRefTable, io.ImageDict, objNr)
 				if err != nil {
 					return err
 				}
 
+				if r == nil {
+					continue
+				}
+
+				if err = writeNamed(wf, imageFileName(io.ResourceNames[0], pageNr, objNr, ext), r); err != nil {
+					return err
+				}
+
 			}
 
 		}
@@ -93,8 +99,9 @@ func doExtractImages(ctx *pdf.Context, selectedPages pdf.IntSet) error {
 	return nil
 }
 
-// ExtractImages dumps embedded image resources from rs into outDir for selected pages.
-func ExtractImages(rs io.ReadSeeker, outDir string, selectedPages []string, conf *pdf.Configuration) error {
+// ExtractImagesToWriters extracts embedded image resources from rs for selected pages.
+// Each image is written to the writer returned by wf for a name derived from its resource name, page and object number.
+func ExtractImagesToWriters(rs io.ReadSeeker, selectedPages []string, wf WriterFunc, conf *pdf.Configuration) error {
 	if conf == nil {
 		conf = pdf.NewDefaultConfiguration()
 	}
@@ -111,8 +118,7 @@ func ExtractImages(rs io.ReadSeeker, outDir string, selectedPages []string, conf
 		return err
 	}
 
-	ctx.Write.DirName = outDir
-	if err = doExtractImages(ctx, pages); err != nil {
+	if err = doExtractImages(ctx, pages, wf); err != nil {
 		return err
 	}
 
@@ -124,6 +130,20 @@ func ExtractImages(rs io.ReadSeeker, outDir string, selectedPages []string, conf
 	return nil
 }
 
+// ExtractImagesToReaders extracts embedded image resources from rs into memory for selected pages.
+func ExtractImagesToReaders(rs io.ReadSeeker, selectedPages []string, conf *pdf.Configuration) ([]NamedReader, error) {
+	c := &readerCollector{}
+	if err := ExtractImagesToWriters(rs, selectedPages, c.writer, conf); err != nil {
+		return nil, err
+	}
+	return c.rr, nil
+}
+
+// ExtractImages dumps embedded image resources from rs into outDir for selected pages.
+func ExtractImages(rs io.ReadSeeker, outDir string, selectedPages []string, conf *pdf.Configuration) error {
+	return ExtractImagesToWriters(rs, selectedPages, dirWriterFunc(outDir), conf)
+}
+
 // ExtractImagesFile dumps embedded image resources from inFile into outDir for selected pages.
 func ExtractImagesFile(inFile, outDir string, selectedPages []string, conf *pdf.Configuration) error {
 	f, err := os.Open(inFile)
@@ -238,21 +258,10 @@ func singlePageFileName(fileName string, pageNr int) string {
 	return fn + "_" + strconv.Itoa(pageNr) + ".pdf"
 }
 
-func writeSinglePagePDF(ctx *pdf.Context, pageNr int, outDir string) error {
-	ctx.ResetWriteContext()
-	w := ctx.Write
-	w.SelectedPages[pageNr] = true
-	w.DirName = outDir + "/"
-	w.FileName = singlePageFileName("fn", pageNr)
-	log.CLI.Printf("writing %s ...\n", w.DirName+w.FileName)
-	return pdf.Write(ctx)
-}
-
-func writeSinglePagePDFs(ctx *pdf.Context, selectedPages pdf.IntSet, outDir string) error {
+func writeSinglePagePDFs(ctx *pdf.Context, selectedPages pdf.IntSet, wf WriterFunc) error {
 	for i, v := range selectedPages {
 		if v {
-			err := writeSinglePagePDF(ctx, i, outDir)
-			if err != nil {
+			if err := writePages(ctx, pdf.IntSet{i: true}, singlePageFileName("fn", i), wf); err != nil {
 				return err
 			}
 		}
@@ -260,12 +269,13 @@ func writeSinglePagePDFs(ctx *pdf.Context, selectedPages pdf.IntSet, outDir stri
 	return nil
 }
 
-// ExtractPages generates single page PDF files from rs in outDir for selected pages.
-func ExtractPages(rs io.ReadSeeker, outDir string, selectedPages []string, conf *pdf.Configuration) error {
+// ExtractPagesToWriters generates single page PDFs from rs for selected pages.
+// Each PDF is written to the writer returned by wf for a name derived from its page number.
+func ExtractPagesToWriters(rs io.ReadSeeker, selectedPages []string, wf WriterFunc, conf *pdf.Configuration) error {
 	if conf == nil {
 		conf = pdf.NewDefaultConfiguration()
-		conf.Cmd = pdf.EXTRACTPAGES
 	}
+	conf.Cmd = pdf.EXTRACTPAGES
 
 	fromStart := time.Now()
 	ctx, durRead, durVal, durOpt, err := readValidateAndOptimize(rs, conf, fromStart)
@@ -279,7 +289,7 @@ func ExtractPages(rs io.ReadSeeker, outDir string, selectedPages []string, conf
 		return err
 	}
 
-	if err = writeSinglePagePDFs(ctx, pages, outDir); err != nil {
+	if err = writeSinglePagePDFs(ctx, pages, wf); err != nil {
 		return err
 	}
 
@@ -291,6 +301,20 @@ func ExtractPages(rs io.ReadSeeker, outDir string, selectedPages []string, conf
 	return nil
 }
 
+// ExtractPagesToReaders generates in-memory single page PDFs from rs for selected pages.
+func ExtractPagesToReaders(rs io.ReadSeeker, selectedPages []string, conf *pdf.Configuration) ([]NamedReader, error) {
+	c := &readerCollector{}
+	if err := ExtractPagesToWriters(rs, selectedPages, c.writer, conf); err != nil {
+		return nil, err
+	}
+	return c.rr, nil
+}
+
+// ExtractPages generates single page PDF files from rs in outDir for selected pages.
+func ExtractPages(rs io.ReadSeeker, outDir string, selectedPages []string, conf *pdf.Configuration) error {
+	return ExtractPagesToWriters(rs, selectedPages, dirWriterFunc(outDir), conf)
+}
+
 // ExtractPagesFile generates single page PDF files from inFile in outDir for selected pages.
 func ExtractPagesFile(inFile, outDir string, selectedPages []string, conf *pdf.Configuration) error {
 	f, err := os.Open(inFile)
@@ -0,0 +1,541 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	pdf "github.com/denisbetsi/pdfcpu/pkg/pdfcpu"
+	"github.com/denisbetsi/pdfcpu/pkg/pdfcpu/fdf"
+	"github.com/pkg/errors"
+)
+
+// FormFields returns the terminal form fields of rs.
+func FormFields(rs io.ReadSeeker, conf *pdf.Configuration) ([]pdf.Field, error) {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.LISTFORMFIELDS
+
+	ctx, _, _, err := readAndValidate(rs, conf, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return pdf.FormFields(ctx)
+}
+
+// FormFieldsFile returns the terminal form fields of inFile.
+func FormFieldsFile(inFile string, conf *pdf.Configuration) ([]pdf.Field, error) {
+	f, err := os.Open(inFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return FormFields(f, conf)
+}
+
+// ListFormFields returns a list of the terminal form fields of rs.
+func ListFormFields(rs io.ReadSeeker, conf *pdf.Configuration) ([]string, error) {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.LISTFORMFIELDS
+
+	ctx, _, _, err := readAndValidate(rs, conf, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return pdf.ListFormFields(ctx)
+}
+
+// ListFormFieldsFile returns a list of the terminal form fields of inFile.
+func ListFormFieldsFile(inFile string, conf *pdf.Configuration) ([]string, error) {
+	f, err := os.Open(inFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ListFormFields(f, conf)
+}
+
+// ExportForm writes the values of the form fields of rs as JSON to w.
+// The result may be used for filling the form.
+func ExportForm(rs io.ReadSeeker, w io.Writer, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.EXPORTFORM
+
+	ctx, _, _, err := readAndValidate(rs, conf, time.Now())
+	if err != nil {
+		return err
+	}
+
+	fv, err := pdf.ExportFormValues(ctx)
+	if err != nil {
+		return err
+	}
+
+	bb, err := json.MarshalIndent(fv, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(bb, '\n'))
+
+	return err
+}
+
+// ExportFormFile writes the values of the form fields of inFile as JSON to outFile.
+func ExportFormFile(inFile, outFile string, conf *pdf.Configuration) (err error) {
+	var f1, f2 *os.File
+
+	if f1, err = os.Open(inFile); err != nil {
+		return err
+	}
+	defer f1.Close()
+
+	if f2, err = os.Create(outFile); err != nil {
+		return err
+	}
+
+	defer func() {
+		if cerr := f2.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	return ExportForm(f1, f2, conf)
+}
+
+// FillForm sets the values of the form fields of rs, updates their appearances and writes the result to w.
+func FillForm(rs io.ReadSeeker, w io.Writer, fv pdf.FormValues, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.FILLFORM
+
+	if len(fv) == 0 {
+		return errors.New("missing form values")
+	}
+
+	fromStart := time.Now()
+	ctx, durRead, durVal, durOpt, err := readValidateAndOptimize(rs, conf, fromStart)
+	if err != nil {
+		return err
+	}
+
+	from := time.Now()
+
+	if err = pdf.FillForm(ctx, fv); err != nil {
+		return err
+	}
+
+	log.Stats.Printf("XRefTable:\n%s\n", ctx)
+	durFill := time.Since(from).Seconds()
+	fromWrite := time.Now()
+
+	if conf.ValidationMode != pdf.ValidationNone {
+		if err = ValidateContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err = WriteContext(ctx, w); err != nil {
+		return err
+	}
+
+	durWrite := durFill + time.Since(fromWrite).Seconds()
+	durTotal := time.Since(fromStart).Seconds()
+	logOperationStats(ctx, "fill form, write", durRead, durVal, durOpt, durWrite, durTotal)
+
+	return nil
+}
+
+// FillFormFile fills the form fields of inFile and writes the result to outFile.
+func FillFormFile(inFile, outFile string, fv pdf.FormValues, conf *pdf.Configuration) (err error) {
+	var f1, f2 *os.File
+
+	if f1, err = os.Open(inFile); err != nil {
+		return err
+	}
+
+	tmpFile := inFile + ".tmp"
+	if outFile != "" && inFile != outFile {
+		tmpFile = outFile
+	}
+	if f2, err = os.Create(tmpFile); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f2.Close()
+			f1.Close()
+			if outFile == "" || inFile == outFile {
+				os.Remove(tmpFile)
+			}
+			return
+		}
+		if err = f2.Close(); err != nil {
+			return
+		}
+		if err = f1.Close(); err != nil {
+			return
+		}
+		if outFile == "" || inFile == outFile {
+			if err = os.Rename(tmpFile, inFile); err != nil {
+				return
+			}
+		}
+	}()
+
+	return FillForm(f1, f2, fv, conf)
+}
+
+// CreateFormFields adds the form fields specified by fss to rs, generates their appearances and writes the result to w.
+func CreateFormFields(rs io.ReadSeeker, w io.Writer, fss []pdf.FieldSpec, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.CREATEFORMFIELDS
+
+	fromStart := time.Now()
+	ctx, durRead, durVal, durOpt, err := readValidateAndOptimize(rs, conf, fromStart)
+	if err != nil {
+		return err
+	}
+
+	from := time.Now()
+
+	if err = pdf.CreateFormFields(ctx, fss); err != nil {
+		return err
+	}
+
+	log.Stats.Printf("XRefTable:\n%s\n", ctx)
+	durCreate := time.Since(from).Seconds()
+	fromWrite := time.Now()
+
+	if conf.ValidationMode != pdf.ValidationNone {
+		if err = ValidateContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err = WriteContext(ctx, w); err != nil {
+		return err
+	}
+
+	durWrite := durCreate + time.Since(fromWrite).Seconds()
+	durTotal := time.Since(fromStart).Seconds()
+	logOperationStats(ctx, "create form fields, write", durRead, durVal, durOpt, durWrite, durTotal)
+
+	return nil
+}
+
+// CreateFormFieldsFile adds the form fields specified by fss to inFile and writes the result to outFile.
+func CreateFormFieldsFile(inFile, outFile string, fss []pdf.FieldSpec, conf *pdf.Configuration) (err error) {
+	var f1, f2 *os.File
+
+	if f1, err = os.Open(inFile); err != nil {
+		return err
+	}
+
+	tmpFile := inFile + ".tmp"
+	if outFile != "" && inFile != outFile {
+		tmpFile = outFile
+	}
+	if f2, err = os.Create(tmpFile); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f2.Close()
+			f1.Close()
+			if outFile == "" || inFile == outFile {
+				os.Remove(tmpFile)
+			}
+			return
+		}
+		if err = f2.Close(); err != nil {
+			return
+		}
+		if err = f1.Close(); err != nil {
+			return
+		}
+		if outFile == "" || inFile == outFile {
+			if err = os.Rename(tmpFile, inFile); err != nil {
+				return
+			}
+		}
+	}()
+
+	return CreateFormFields(f1, f2, fss, conf)
+}
+
+// FlattenForm draws the form fields of rs into the page content, removes the form and writes the result to w.
+// With annots set all other annotations get flattened as well.
+func FlattenForm(rs io.ReadSeeker, w io.Writer, annots bool, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.FLATTENFORM
+
+	fromStart := time.Now()
+	ctx, durRead, durVal, durOpt, err := readValidateAndOptimize(rs, conf, fromStart)
+	if err != nil {
+		return err
+	}
+
+	from := time.Now()
+
+	if _, err = pdf.FlattenForm(ctx, annots); err != nil {
+		return err
+	}
+
+	log.Stats.Printf("XRefTable:\n%s\n", ctx)
+	durFlatten := time.Since(from).Seconds()
+	fromWrite := time.Now()
+
+	if conf.ValidationMode != pdf.ValidationNone {
+		if err = ValidateContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err = WriteContext(ctx, w); err != nil {
+		return err
+	}
+
+	durWrite := durFlatten + time.Since(fromWrite).Seconds()
+	durTotal := time.Since(fromStart).Seconds()
+	logOperationStats(ctx, "flatten form, write", durRead, durVal, durOpt, durWrite, durTotal)
+
+	return nil
+}
+
+// FlattenFormFile flattens the form of inFile and writes the result to outFile.
+func FlattenFormFile(inFile, outFile string, annots bool, conf *pdf.Configuration) (err error) {
+	var f1, f2 *os.File
+
+	if f1, err = os.Open(inFile); err != nil {
+		return err
+	}
+
+	tmpFile := inFile + ".tmp"
+	if outFile != "" && inFile != outFile {
+		tmpFile = outFile
+	}
+	if f2, err = os.Create(tmpFile); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f2.Close()
+			f1.Close()
+			if outFile == "" || inFile == outFile {
+				os.Remove(tmpFile)
+			}
+			return
+		}
+		if err = f2.Close(); err != nil {
+			return
+		}
+		if err = f1.Close(); err != nil {
+			return
+		}
+		if outFile == "" || inFile == outFile {
+			if err = os.Rename(tmpFile, inFile); err != nil {
+				return
+			}
+		}
+	}()
+
+	return FlattenForm(f1, f2, annots, conf)
+}
+
+// Form data formats supported by ExportFormData and ImportFormData.
+const (
+	FormDataFDF  = "fdf"
+	FormDataXFDF = "xfdf"
+)
+
+func exportFormData(rs io.ReadSeeker, w io.Writer, format, fileName string, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.EXPORTFORM
+
+	if format != FormDataFDF && format != FormDataXFDF {
+		return errors.Errorf("unsupported form data format: %s", format)
+	}
+
+	ctx, _, _, err := readAndValidate(rs, conf, time.Now())
+	if err != nil {
+		return err
+	}
+
+	data, err := fdf.Export(ctx)
+	if err != nil {
+		return err
+	}
+	data.File = fileName
+
+	if format == FormDataXFDF {
+		return fdf.WriteXFDF(w, data)
+	}
+
+	return fdf.Write(w, data)
+}
+
+// ExportFormData writes the form field values and annotations of rs to w in FDF or XFDF format.
+func ExportFormData(rs io.ReadSeeker, w io.Writer, format string, conf *pdf.Configuration) error {
+	return exportFormData(rs, w, format, "", conf)
+}
+
+// ExportFormDataFile writes the form field values and annotations of inFile to outFile in FDF or XFDF format.
+func ExportFormDataFile(inFile, outFile, format string, conf *pdf.Configuration) (err error) {
+	var f1, f2 *os.File
+
+	if f1, err = os.Open(inFile); err != nil {
+		return err
+	}
+	defer f1.Close()
+
+	if f2, err = os.Create(outFile); err != nil {
+		return err
+	}
+
+	defer func() {
+		if cerr := f2.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	return exportFormData(f1, f2, format, filepath.Base(inFile), conf)
+}
+
+// ImportFormData fills the form fields of rs with the values read from r in FDF or XFDF format,
+// adds the annotations read and writes the result to w.
+func ImportFormData(rs io.ReadSeeker, w io.Writer, r io.Reader, format string, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.IMPORTFORM
+
+	var data *fdf.Data
+	var err error
+
+	switch format {
+	case FormDataFDF:
+		data, err = fdf.Read(r)
+	case FormDataXFDF:
+		data, err = fdf.ReadXFDF(r)
+	default:
+		err = errors.Errorf("unsupported form data format: %s", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	fromStart := time.Now()
+	ctx, durRead, durVal, durOpt, err := readValidateAndOptimize(rs, conf, fromStart)
+	if err != nil {
+		return err
+	}
+
+	from := time.Now()
+
+	if err = fdf.Import(ctx, data); err != nil {
+		return err
+	}
+
+	log.Stats.Printf("XRefTable:\n%s\n", ctx)
+	durImport := time.Since(from).Seconds()
+	fromWrite := time.Now()
+
+	if conf.ValidationMode != pdf.ValidationNone {
+		if err = ValidateContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err = WriteContext(ctx, w); err != nil {
+		return err
+	}
+
+	durWrite := durImport + time.Since(fromWrite).Seconds()
+	durTotal := time.Since(fromStart).Seconds()
+	logOperationStats(ctx, "import form data, write", durRead, durVal, durOpt, durWrite, durTotal)
+
+	return nil
+}
+
+// ImportFormDataFile imports the form field values and annotations of dataFile into inFile and writes the result to outFile.
+func ImportFormDataFile(inFile, dataFile, outFile, format string, conf *pdf.Configuration) (err error) {
+	var f0, f1, f2 *os.File
+
+	if f0, err = os.Open(dataFile); err != nil {
+		return err
+	}
+	defer f0.Close()
+
+	if f1, err = os.Open(inFile); err != nil {
+		return err
+	}
+
+	tmpFile := inFile + ".tmp"
+	if outFile != "" && inFile != outFile {
+		tmpFile = outFile
+	}
+	if f2, err = os.Create(tmpFile); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f2.Close()
+			f1.Close()
+			if outFile == "" || inFile == outFile {
+				os.Remove(tmpFile)
+			}
+			return
+		}
+		if err = f2.Close(); err != nil {
+			return
+		}
+		if err = f1.Close(); err != nil {
+			return
+		}
+		if outFile == "" || inFile == outFile {
+			if err = os.Rename(tmpFile, inFile); err != nil {
+				return
+			}
+		}
+	}()
+
+	return ImportFormData(f1, f2, f0, format, conf)
+}
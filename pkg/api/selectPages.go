@@ -27,6 +27,7 @@ import (
 
 var (
 	selectedPagesRegExp *regexp.Regexp
+	pageOrderRegExp     *regexp.Regexp
 )
 
 func setupRegExpForPageSelection() *regexp.Regexp {
@@ -37,8 +38,16 @@ func setupRegExpForPageSelection() *regexp.Regexp {
 	return re
 }
 
+func setupRegExpForPageOrder() *regexp.Regexp {
+	e := "(\\d+|l)(-(\\d+|l)?)?|-(\\d+|l)|\\Qeven\\E|\\Qodd\\E"
+	exp := "^(" + e + ")(,(" + e + "))*$|^\\Qreverse\\E$|^\\Qduplicate\\E$|^\\Qcollate\\E$"
+	re, _ := regexp.Compile(exp)
+	return re
+}
+
 func init() {
 	selectedPagesRegExp = setupRegExpForPageSelection()
+	pageOrderRegExp = setupRegExpForPageOrder()
 }
 
 // ParsePageSelection ensures a correct page selection expression.
@@ -272,3 +281,138 @@ func pagesForPageSelection(pageCount int, pageSelection []string, ensureAllforNo
 	}
 	return m, nil
 }
+
+// ParsePageOrder ensures a correct page order expression.
+func ParsePageOrder(s string) ([]string, error) {
+
+	// Ensure valid comma separated expression of: { # | #-# | #- | -# | even | odd }*
+	// or one of the keywords reverse, duplicate, collate.
+	//
+	// 'l' may be used instead of # to denote the last page.
+	// Ranges may be descending, pages may be repeated.
+	//
+	// Reorder pages: "3,1,2,5-l,4"
+	// Reverse pages: "l-1" or "reverse"
+	//
+	// The order expression is evaluated strictly from left to right.
+	//
+
+	if !pageOrderRegExp.MatchString(s) {
+		return nil, errors.Errorf("order \"%s\" => syntax error\n", s)
+	}
+
+	return strings.Split(s, ","), nil
+}
+
+func orderPageNr(s string, pageCount int) (int, error) {
+	if s == "l" {
+		return pageCount, nil
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+
+	if i < 1 || i > pageCount {
+		return 0, errors.Errorf("order: page %d out of range 1-%d", i, pageCount)
+	}
+
+	return i, nil
+}
+
+func pageRange(from, thru int) []int {
+	pp := []int{}
+	if from <= thru {
+		for i := from; i <= thru; i++ {
+			pp = append(pp, i)
+		}
+		return pp
+	}
+	for i := from; i >= thru; i-- {
+		pp = append(pp, i)
+	}
+	return pp
+}
+
+// collatedPages interleaves the front and back sides of a duplex job scanned in two passes.
+// The back sides are expected in reverse order as a result of flipping the stack.
+func collatedPages(pageCount int) ([]int, error) {
+	if pageCount%2 > 0 {
+		return nil, errors.Errorf("order: collate needs an even page count, got %d", pageCount)
+	}
+
+	pp := []int{}
+	for i := 1; i <= pageCount/2; i++ {
+		pp = append(pp, i, pageCount+1-i)
+	}
+
+	return pp, nil
+}
+
+// pageOrder returns the sequence of page numbers for a page order expression.
+func pageOrder(pageCount int, order []string) ([]int, error) {
+
+	if len(order) == 1 {
+		switch order[0] {
+		case "reverse":
+			return pageRange(pageCount, 1), nil
+		case "duplicate":
+			pp := []int{}
+			for i := 1; i <= pageCount; i++ {
+				pp = append(pp, i, i)
+			}
+			return pp, nil
+		case "collate":
+			return collatedPages(pageCount)
+		}
+	}
+
+	pp := []int{}
+
+	for _, v := range order {
+
+		if v == "even" || v == "odd" {
+			i := 1
+			if v == "even" {
+				i = 2
+			}
+			for ; i <= pageCount; i += 2 {
+				pp = append(pp, i)
+			}
+			continue
+		}
+
+		from, thru := 1, pageCount
+
+		// -# ... all pages up to and including #
+		// #- ... all pages from # until the end
+		// #-# ... a page range, descending if thru < from
+		// # ... a specific page
+
+		pr := strings.Split(v, "-")
+
+		var err error
+
+		if pr[0] != "" {
+			if from, err = orderPageNr(pr[0], pageCount); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(pr) == 1 {
+			pp = append(pp, from)
+			continue
+		}
+
+		if pr[1] != "" {
+			if thru, err = orderPageNr(pr[1], pageCount); err != nil {
+				return nil, err
+			}
+		}
+
+		pp = append(pp, pageRange(from, thru)...)
+	}
+
+	return pp, nil
+}
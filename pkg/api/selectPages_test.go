@@ -17,6 +17,7 @@ limitations under the License.
 package api
 
 import (
+	"reflect"
 	"regexp"
 	"testing"
 
@@ -127,3 +128,44 @@ func TestSelectedPages(t *testing.T) {
 	testSelectedPages("4-", pageCount, "00011", t)
 	testSelectedPages("5-", pageCount, "00001", t)
 }
+
+func testPageOrder(s string, pageCount int, want []int, t *testing.T) {
+	order, err := ParsePageOrder(s)
+	if err != nil {
+		t.Fatalf("testPageOrder(%s) %v\n", s, err)
+	}
+
+	got, err := pageOrder(pageCount, order)
+	if err != nil {
+		t.Fatalf("testPageOrder(%s) %v\n", s, err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("testPageOrder(%s) expected:%v got:%v\n", s, want, got)
+	}
+}
+
+func TestPageOrder(t *testing.T) {
+	pageCount := 6
+
+	testPageOrder("3,1,2,5-l,4", pageCount, []int{3, 1, 2, 5, 6, 4}, t)
+	testPageOrder("l-1", pageCount, []int{6, 5, 4, 3, 2, 1}, t)
+	testPageOrder("reverse", pageCount, []int{6, 5, 4, 3, 2, 1}, t)
+	testPageOrder("duplicate", 3, []int{1, 1, 2, 2, 3, 3}, t)
+	testPageOrder("collate", pageCount, []int{1, 6, 2, 5, 3, 4}, t)
+	testPageOrder("-2,5-,odd", pageCount, []int{1, 2, 5, 6, 1, 3, 5}, t)
+	testPageOrder("2,2,even", pageCount, []int{2, 2, 2, 4, 6}, t)
+
+	for _, s := range []string{"", "1,", "reverse,1", "0-x"} {
+		if _, err := ParsePageOrder(s); err == nil {
+			t.Errorf("ParsePageOrder(%s): want syntax error\n", s)
+		}
+	}
+
+	for _, s := range []string{"0", "7", "2-9"} {
+		order, _ := ParsePageOrder(s)
+		if _, err := pageOrder(pageCount, order); err == nil {
+			t.Errorf("pageOrder(%s): want range error\n", s)
+		}
+	}
+}
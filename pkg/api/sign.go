@@ -0,0 +1,272 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	pdf "github.com/denisbetsi/pdfcpu/pkg/pdfcpu"
+	"github.com/denisbetsi/pdfcpu/pkg/pdfcpu/cms"
+)
+
+// appendUpdate reads rs and writes its unmodified content followed by the incremental update of f to w.
+func appendUpdate(rs io.ReadSeeker, w io.Writer, conf *pdf.Configuration, op string, f func(ctx *pdf.Context, b []byte, w io.Writer) error) error {
+	fromStart := time.Now()
+
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadAll(rs)
+	if err != nil {
+		return err
+	}
+
+	ctx, durRead, durVal, err := readAndValidate(bytes.NewReader(b), conf, fromStart)
+	if err != nil {
+		return err
+	}
+
+	fromWrite := time.Now()
+
+	if err = f(ctx, b, w); err != nil {
+		return err
+	}
+
+	durWrite := time.Since(fromWrite).Seconds()
+	durTotal := time.Since(fromStart).Seconds()
+	pdf.TimingStats(op+", write", durRead, durVal, 0, durWrite, durTotal)
+
+	return nil
+}
+
+// Sign digitally signs rs using s and writes the result to w.
+// The signature gets appended to the unmodified content of rs as an incremental update.
+func Sign(rs io.ReadSeeker, w io.Writer, s *pdf.Sign, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.SIGN
+
+	return appendUpdate(rs, w, conf, "sign", func(ctx *pdf.Context, b []byte, w io.Writer) error {
+		return pdf.AddSignature(ctx, b, w, s)
+	})
+}
+
+// SignFile digitally signs inFile using s and writes the result to outFile.
+func SignFile(inFile, outFile string, s *pdf.Sign, conf *pdf.Configuration) (err error) {
+	var f1, f2 *os.File
+
+	if f1, err = os.Open(inFile); err != nil {
+		return err
+	}
+
+	tmpFile := inFile + ".tmp"
+	if outFile != "" && inFile != outFile {
+		tmpFile = outFile
+	}
+	if f2, err = os.Create(tmpFile); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f2.Close()
+			f1.Close()
+			if outFile == "" || inFile == outFile {
+				os.Remove(tmpFile)
+			}
+			return
+		}
+		if err = f2.Close(); err != nil {
+			return
+		}
+		if err = f1.Close(); err != nil {
+			return
+		}
+		if outFile == "" || inFile == outFile {
+			if err = os.Rename(tmpFile, inFile); err != nil {
+				return
+			}
+		}
+	}()
+
+	return Sign(f1, f2, s, conf)
+}
+
+// ListSignatures returns a report of the signatures of rs.
+func ListSignatures(rs io.ReadSeeker, conf *pdf.Configuration) ([]string, error) {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.LISTSIGNATURES
+
+	ctx, _, _, err := readAndValidate(rs, conf, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return pdf.ListSignatures(ctx, false, nil)
+}
+
+// ListSignaturesFile returns a report of the signatures of inFile.
+func ListSignaturesFile(inFile string, conf *pdf.Configuration) ([]string, error) {
+	f, err := os.Open(inFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ListSignatures(f, conf)
+}
+
+// VerifySignatures verifies the signatures of rs against conf.TrustedCerts.
+// The verification result of each signature is recorded in its Err, Verified and Trusted fields.
+func VerifySignatures(rs io.ReadSeeker, conf *pdf.Configuration) ([]*pdf.Signature, error) {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.VERIFYSIGNATURES
+
+	ctx, _, _, err := readAndValidate(rs, conf, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return pdf.VerifySignatures(ctx, conf.TrustedCerts)
+}
+
+// VerifySignaturesFile verifies the signatures of inFile against conf.TrustedCerts.
+func VerifySignaturesFile(inFile string, conf *pdf.Configuration) ([]*pdf.Signature, error) {
+	f, err := os.Open(inFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return VerifySignatures(f, conf)
+}
+
+// TimeStamp adds a document time-stamp obtained from tsa to rs and writes the result to w.
+// The time-stamp gets appended to the unmodified content of rs as an incremental update.
+func TimeStamp(rs io.ReadSeeker, w io.Writer, tsa cms.TSAClient, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.TIMESTAMP
+
+	return appendUpdate(rs, w, conf, "timestamp", func(ctx *pdf.Context, b []byte, w io.Writer) error {
+		return pdf.AddSignature(ctx, b, w, &pdf.Sign{DocTimeStamp: true, TSA: tsa})
+	})
+}
+
+// TimeStampFile adds a document time-stamp obtained from tsa to inFile and writes the result to outFile.
+func TimeStampFile(inFile, outFile string, tsa cms.TSAClient, conf *pdf.Configuration) (err error) {
+	var f1, f2 *os.File
+
+	if f1, err = os.Open(inFile); err != nil {
+		return err
+	}
+
+	tmpFile := inFile + ".tmp"
+	if outFile != "" && inFile != outFile {
+		tmpFile = outFile
+	}
+	if f2, err = os.Create(tmpFile); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f2.Close()
+			f1.Close()
+			if outFile == "" || inFile == outFile {
+				os.Remove(tmpFile)
+			}
+			return
+		}
+		if err = f2.Close(); err != nil {
+			return
+		}
+		if err = f1.Close(); err != nil {
+			return
+		}
+		if outFile == "" || inFile == outFile {
+			if err = os.Rename(tmpFile, inFile); err != nil {
+				return
+			}
+		}
+	}()
+
+	return TimeStamp(f1, f2, tsa, conf)
+}
+
+// AddLTV adds a document security store holding the validation data for the signatures of rs to rs
+// and writes the result to w. ltv supplies revocation data and certificates not embedded in the signatures.
+func AddLTV(rs io.ReadSeeker, w io.Writer, ltv *pdf.LTV, conf *pdf.Configuration) error {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.ADDLTV
+
+	return appendUpdate(rs, w, conf, "ltv", func(ctx *pdf.Context, b []byte, w io.Writer) error {
+		return pdf.AddLTV(ctx, b, w, ltv)
+	})
+}
+
+// AddLTVFile adds a document security store holding the validation data for the signatures of inFile and writes the result to outFile.
+func AddLTVFile(inFile, outFile string, ltv *pdf.LTV, conf *pdf.Configuration) (err error) {
+	var f1, f2 *os.File
+
+	if f1, err = os.Open(inFile); err != nil {
+		return err
+	}
+
+	tmpFile := inFile + ".tmp"
+	if outFile != "" && inFile != outFile {
+		tmpFile = outFile
+	}
+	if f2, err = os.Create(tmpFile); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f2.Close()
+			f1.Close()
+			if outFile == "" || inFile == outFile {
+				os.Remove(tmpFile)
+			}
+			return
+		}
+		if err = f2.Close(); err != nil {
+			return
+		}
+		if err = f1.Close(); err != nil {
+			return
+		}
+		if outFile == "" || inFile == outFile {
+			if err = os.Rename(tmpFile, inFile); err != nil {
+				return
+			}
+		}
+	}()
+
+	return AddLTV(f1, f2, ltv, conf)
+}
@@ -0,0 +1,302 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	pdf "github.com/denisbetsi/pdfcpu/pkg/pdfcpu"
+	"github.com/pkg/errors"
+)
+
+func readForSplit(rs io.ReadSeeker, conf *pdf.Configuration) (*pdf.Context, error) {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.SPLIT
+
+	ctx, _, _, _, err := readValidateAndOptimize(rs, conf, time.Now())
+	return ctx, err
+}
+
+// bookmarkFileName returns a file name for a bookmark title.
+func bookmarkFileName(title string) string {
+	fn := strings.Map(func(r rune) rune {
+		if r < 32 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, title)
+	return strings.Trim(fn, " .")
+}
+
+// SplitByBookmarkToWriters generates a PDF for each top level bookmark of the PDF stream read from rs.
+// Each PDF is named after its bookmark title, holds all pages up to the next top level bookmark
+// and is written to the writer returned by wf for its name.
+// Any pages preceding the first top level bookmark go into a separate PDF.
+func SplitByBookmarkToWriters(rs io.ReadSeeker, fileName string, wf WriterFunc, conf *pdf.Configuration) error {
+	ctx, err := readForSplit(rs, conf)
+	if err != nil {
+		return err
+	}
+
+	bms, err := pdf.TopLevelBookmarks(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(bms) == 0 {
+		return errors.New("split: no bookmarks available")
+	}
+
+	if bms[0].PageFrom > 1 {
+		if err = writeSpan(ctx, 1, bms[0].PageFrom-1, fileName, wf); err != nil {
+			return err
+		}
+	}
+
+	used := map[string]bool{}
+
+	for i, bm := range bms {
+		fn := bookmarkFileName(bm.Title)
+		if fn == "" || used[fn] {
+			fn = strings.TrimSuffix(spanFileName(fileName, bm.PageFrom, bm.PageThru), ".pdf")
+		}
+		used[fn] = true
+		log.Info.Printf("split: bookmark %d: %s -> %s\n", i+1, bm.Title, fn)
+
+		if err = writePages(ctx, selectedPageRange(bm.PageFrom, bm.PageThru), fn+".pdf", wf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SplitByBookmarkToReaders generates an in-memory PDF for each top level bookmark of the PDF stream read from rs.
+func SplitByBookmarkToReaders(rs io.ReadSeeker, fileName string, conf *pdf.Configuration) ([]NamedReader, error) {
+	c := &readerCollector{}
+	if err := SplitByBookmarkToWriters(rs, fileName, c.writer, conf); err != nil {
+		return nil, err
+	}
+	return c.rr, nil
+}
+
+// SplitByBookmark generates a PDF file in outDir for each top level bookmark of the PDF stream read from rs.
+// Each file is named after its bookmark title and holds all pages up to the next top level bookmark.
+// Any pages preceding the first top level bookmark go into a separate file.
+func SplitByBookmark(rs io.ReadSeeker, outDir, fileName string, conf *pdf.Configuration) error {
+	return SplitByBookmarkToWriters(rs, fileName, dirWriterFunc(outDir), conf)
+}
+
+// SplitByBookmarkFile generates a PDF file in outDir for each top level bookmark of inFile.
+func SplitByBookmarkFile(inFile, outDir string, conf *pdf.Configuration) error {
+	f, err := os.Open(inFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return SplitByBookmark(f, outDir, filepath.Base(inFile), conf)
+}
+
+// SplitByPageNrToWriters generates a sequence of PDFs for the PDF stream read from rs.
+// A new PDF starts at each of pageNrs.
+// Each PDF is written to the writer returned by wf for a name derived from fileName.
+func SplitByPageNrToWriters(rs io.ReadSeeker, fileName string, pageNrs []int, wf WriterFunc, conf *pdf.Configuration) error {
+	ctx, err := readForSplit(rs, conf)
+	if err != nil {
+		return err
+	}
+
+	pp := []int{}
+	for _, p := range pageNrs {
+		if p < 1 || p > ctx.PageCount {
+			return errors.Errorf("split: invalid page number %d, must be within 1..%d", p, ctx.PageCount)
+		}
+		if p > 1 {
+			pp = append(pp, p)
+		}
+	}
+	sort.Ints(pp)
+
+	from := 1
+	for _, p := range append(pp, ctx.PageCount+1) {
+		if p == from {
+			continue
+		}
+		if err = writeSpan(ctx, from, p-1, fileName, wf); err != nil {
+			return err
+		}
+		from = p
+	}
+
+	return nil
+}
+
+// SplitByPageNrToReaders generates a sequence of in-memory PDFs for the PDF stream read from rs.
+// A new PDF starts at each of pageNrs.
+func SplitByPageNrToReaders(rs io.ReadSeeker, fileName string, pageNrs []int, conf *pdf.Configuration) ([]NamedReader, error) {
+	c := &readerCollector{}
+	if err := SplitByPageNrToWriters(rs, fileName, pageNrs, c.writer, conf); err != nil {
+		return nil, err
+	}
+	return c.rr, nil
+}
+
+// SplitByPageNr generates a sequence of PDF files in outDir for the PDF stream read from rs.
+// A new file starts at each of pageNrs.
+func SplitByPageNr(rs io.ReadSeeker, outDir, fileName string, pageNrs []int, conf *pdf.Configuration) error {
+	return SplitByPageNrToWriters(rs, fileName, pageNrs, dirWriterFunc(outDir), conf)
+}
+
+// SplitByPageNrFile generates a sequence of PDF files in outDir for inFile.
+// A new file starts at each of pageNrs.
+func SplitByPageNrFile(inFile, outDir string, pageNrs []int, conf *pdf.Configuration) error {
+	f, err := os.Open(inFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return SplitByPageNr(f, outDir, filepath.Base(inFile), pageNrs, conf)
+}
+
+// spanBytes returns the PDF for the pages from thru thru.
+func spanBytes(ctx *pdf.Context, from, thru int) ([]byte, error) {
+	var buf bytes.Buffer
+	ctx.ResetWriteContext()
+	ctx.Write.SelectedPages = selectedPageRange(from, thru)
+	ctx.Write.Writer = bufio.NewWriter(&buf)
+	ctx.Write.Quiet = true
+	if err := pdf.Write(ctx); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// largestSpan returns the last page of the largest span starting at page from whose PDF does not exceed maxSize bytes.
+// A single page span is returned regardless of its size.
+func largestSpan(ctx *pdf.Context, from int, maxSize int64) (int, error) {
+	thru := from
+	bb, err := spanBytes(ctx, from, thru)
+	if err != nil {
+		return 0, err
+	}
+	if int64(len(bb)) > maxSize {
+		log.Info.Printf("split: page %d exceeds %d bytes\n", from, maxSize)
+		return thru, nil
+	}
+
+	// Grow the span exponentially, then narrow it down by binary search.
+	lo, hi := thru, 0
+	for step := 1; hi == 0; step *= 2 {
+		t := from + step
+		if t > ctx.PageCount {
+			t = ctx.PageCount
+		}
+		if t == lo {
+			return lo, nil
+		}
+		b, err := spanBytes(ctx, from, t)
+		if err != nil {
+			return 0, err
+		}
+		if int64(len(b)) > maxSize {
+			hi = t
+			break
+		}
+		lo = t
+	}
+
+	for hi-lo > 1 {
+		t := (lo + hi) / 2
+		b, err := spanBytes(ctx, from, t)
+		if err != nil {
+			return 0, err
+		}
+		if int64(len(b)) > maxSize {
+			hi = t
+			continue
+		}
+		lo = t
+	}
+
+	return lo, nil
+}
+
+// SplitBySizeToWriters generates a sequence of PDFs for the PDF stream read from rs.
+// Each PDF holds as many pages as possible without exceeding maxSize bytes
+// and is written to the writer returned by wf for a name derived from fileName.
+// A single page exceeding maxSize ends up in a PDF of its own.
+func SplitBySizeToWriters(rs io.ReadSeeker, fileName string, maxSize int64, wf WriterFunc, conf *pdf.Configuration) error {
+	if maxSize <= 0 {
+		return errors.New("split: maxSize must be > 0")
+	}
+
+	ctx, err := readForSplit(rs, conf)
+	if err != nil {
+		return err
+	}
+
+	for from := 1; from <= ctx.PageCount; {
+		thru, err := largestSpan(ctx, from, maxSize)
+		if err != nil {
+			return err
+		}
+		if err = writeSpan(ctx, from, thru, fileName, wf); err != nil {
+			return err
+		}
+		from = thru + 1
+	}
+
+	return nil
+}
+
+// SplitBySizeToReaders generates a sequence of in-memory PDFs for the PDF stream read from rs.
+// Each PDF holds as many pages as possible without exceeding maxSize bytes.
+func SplitBySizeToReaders(rs io.ReadSeeker, fileName string, maxSize int64, conf *pdf.Configuration) ([]NamedReader, error) {
+	c := &readerCollector{}
+	if err := SplitBySizeToWriters(rs, fileName, maxSize, c.writer, conf); err != nil {
+		return nil, err
+	}
+	return c.rr, nil
+}
+
+// SplitBySize generates a sequence of PDF files in outDir for the PDF stream read from rs.
+// Each file holds as many pages as possible without exceeding maxSize bytes.
+// A single page exceeding maxSize ends up in a file of its own.
+func SplitBySize(rs io.ReadSeeker, outDir, fileName string, maxSize int64, conf *pdf.Configuration) error {
+	return SplitBySizeToWriters(rs, fileName, maxSize, dirWriterFunc(outDir), conf)
+}
+
+// SplitBySizeFile generates a sequence of PDF files in outDir for inFile.
+// Each file holds as many pages as possible without exceeding maxSize bytes.
+func SplitBySizeFile(inFile, outDir string, maxSize int64, conf *pdf.Configuration) error {
+	f, err := os.Open(inFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return SplitBySize(f, outDir, filepath.Base(inFile), maxSize, conf)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	pdf "github.com/denisbetsi/pdfcpu/pkg/pdfcpu"
+)
+
+// WriterFunc returns a writer for an output named name.
+// Operations producing multiple outputs call it once per output and close the writer when done.
+type WriterFunc func(name string) (io.WriteCloser, error)
+
+// NamedReader is an output of an operation producing multiple outputs along with its suggested name.
+type NamedReader struct {
+	io.Reader
+	Name string
+}
+
+// dirWriterFunc returns a WriterFunc creating files in dir.
+func dirWriterFunc(dir string) WriterFunc {
+	return func(name string) (io.WriteCloser, error) {
+		return os.Create(filepath.Join(dir, name))
+	}
+}
+
+type nopCloseBuffer struct {
+	bytes.Buffer
+}
+
+func (b *nopCloseBuffer) Close() error {
+	return nil
+}
+
+// readerCollector buffers all outputs written via its writer func.
+type readerCollector struct {
+	rr []NamedReader
+}
+
+func (c *readerCollector) writer(name string) (io.WriteCloser, error) {
+	b := &nopCloseBuffer{}
+	c.rr = append(c.rr, NamedReader{Reader: &b.Buffer, Name: name})
+	return b, nil
+}
+
+// writeNamed copies r to the writer wf returns for name.
+func writeNamed(wf WriterFunc, name string, r io.Reader) (err error) {
+	w, err := wf(name)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			w.Close()
+			return
+		}
+		err = w.Close()
+	}()
+
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// writePages writes a PDF for selected pages of ctx to the writer wf returns for name.
+func writePages(ctx *pdf.Context, selectedPages pdf.IntSet, name string, wf WriterFunc) (err error) {
+	w, err := wf(name)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			w.Close()
+			return
+		}
+		err = w.Close()
+	}()
+
+	ctx.ResetWriteContext()
+	ctx.Write.SelectedPages = selectedPages
+	ctx.Write.FileName = name
+	ctx.Write.Writer = bufio.NewWriter(w)
+
+	return pdf.Write(ctx)
+}
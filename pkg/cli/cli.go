@@ -18,6 +18,10 @@ limitations under the License.
 package cli
 
 import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
 	"github.com/denisbetsi/pdfcpu/pkg/api"
 	pdf "github.com/denisbetsi/pdfcpu/pkg/pdfcpu"
 	"github.com/pkg/errors"
@@ -74,8 +78,16 @@ func SetPermissions(cmd *Command) ([]string, error) {
 	return nil, api.SetPermissionsFile(*cmd.InFile, *cmd.OutFile, cmd.Conf)
 }
 
-// Split inFile into single page PDFs and write result files to outDir.
+// Split inFile according to the split mode and write result files to outDir.
 func Split(cmd *Command) ([]string, error) {
+	switch cmd.SplitMode {
+	case SplitBookmark:
+		return nil, api.SplitByBookmarkFile(*cmd.InFile, *cmd.OutDir, cmd.Conf)
+	case SplitPageNrs:
+		return nil, api.SplitByPageNrFile(*cmd.InFile, *cmd.OutDir, cmd.PageNrs, cmd.Conf)
+	case SplitSize:
+		return nil, api.SplitBySizeFile(*cmd.InFile, *cmd.OutDir, cmd.MaxSize, cmd.Conf)
+	}
 	return nil, api.SplitFile(*cmd.InFile, *cmd.OutDir, cmd.Span, cmd.Conf)
 }
 
@@ -94,6 +106,16 @@ func AddWatermarks(cmd *Command) ([]string, error) {
 	return nil, api.AddWatermarksFile(*cmd.InFile, *cmd.OutFile, cmd.PageSelection, cmd.Watermark, cmd.Conf)
 }
 
+// Booklet imposes selected pages of inFile as a booklet and writes the result to outFile.
+func Booklet(cmd *Command) ([]string, error) {
+	return nil, api.BookletFile(*cmd.InFile, *cmd.OutFile, cmd.PageSelection, cmd.Booklet, cmd.Conf)
+}
+
+// Poster tiles selected pages of inFile across multiple sheets and writes the result to outFile.
+func Poster(cmd *Command) ([]string, error) {
+	return nil, api.PosterFile(*cmd.InFile, *cmd.OutFile, cmd.PageSelection, cmd.Poster, cmd.Conf)
+}
+
 // NUp renders selected PDF pages or image files to outFile in n-up fashion.
 func NUp(cmd *Command) ([]string, error) {
 	return nil, api.NUpFile(cmd.InFiles, *cmd.OutFile, cmd.PageSelection, cmd.NUp, cmd.Conf)
@@ -107,17 +129,44 @@ func ImportImages(cmd *Command) ([]string, error) {
 }
 
 // InsertPages inserts a blank page before each selected page.
+// If cmd.FromFile is set, the selected pages of FromFile get inserted before page cmd.Before instead.
 func InsertPages(cmd *Command) ([]string, error) {
+	if cmd.FromFile != nil {
+		return nil, api.InsertPagesFromFile(*cmd.InFile, *cmd.FromFile, *cmd.OutFile, cmd.PageSelection, cmd.Before, cmd.Conf)
+	}
 	return nil, api.InsertPagesFile(*cmd.InFile, *cmd.OutFile, cmd.PageSelection, cmd.Conf)
 }
 
+// RemoveBlankPages removes blank pages among selected pages.
+// In dry run mode the blank pages are listed only.
+func RemoveBlankPages(cmd *Command) ([]string, error) {
+	if cmd.DryRun {
+		return api.ListBlankPagesFile(*cmd.InFile, cmd.PageSelection, cmd.Threshold, cmd.Conf)
+	}
+	_, err := api.RemoveBlankPagesFile(*cmd.InFile, *cmd.OutFile, cmd.PageSelection, cmd.Threshold, cmd.Conf)
+	return nil, err
+}
+
 // RemovePages removes selected pages.
 func RemovePages(cmd *Command) ([]string, error) {
 	return nil, api.RemovePagesFile(*cmd.InFile, *cmd.OutFile, cmd.PageSelection, cmd.Conf)
 }
 
+// ArrangePages reorders, repeats or drops pages of inFile and writes the result to outFile.
+func ArrangePages(cmd *Command) ([]string, error) {
+	return nil, api.ArrangePagesFile(*cmd.InFile, *cmd.OutFile, cmd.PageOrder, cmd.Conf)
+}
+
 // Merge merges inFiles in the order specified and writes the result to outFile.
+// Page selections restrict the pages taken from each inFile.
+// In zip mode the pages of 2 inFiles get interleaved.
 func Merge(cmd *Command) ([]string, error) {
+	if cmd.MergeMode == MergeZip {
+		return nil, api.MergeZipFile(cmd.InFiles[0], cmd.InFiles[1], *cmd.OutFile, cmd.Reverse, cmd.Fill, cmd.Conf)
+	}
+	if cmd.PageSelections != nil {
+		return nil, api.MergeSourcesFile(cmd.InFiles, cmd.PageSelections, *cmd.OutFile, cmd.Conf)
+	}
 	return nil, api.MergeFile(cmd.InFiles, *cmd.OutFile, cmd.Conf)
 }
 
@@ -170,3 +219,151 @@ func ExtractAttachments(cmd *Command) ([]string, error) {
 func Info(cmd *Command) ([]string, error) {
 	return api.InfoFile(*cmd.InFile, cmd.Conf)
 }
+
+// ListBoxes returns a list of page boundaries for selected pages of inFile.
+func ListBoxes(cmd *Command) ([]string, error) {
+	return api.ListBoxesFile(*cmd.InFile, cmd.PageSelection, cmd.Boxes, cmd.Conf)
+}
+
+// AddBoxes sets page boundaries for selected pages of inFile and writes the result to outFile.
+func AddBoxes(cmd *Command) ([]string, error) {
+	return nil, api.AddBoxesFile(*cmd.InFile, *cmd.OutFile, cmd.PageSelection, cmd.PageBoundaries, cmd.Conf)
+}
+
+// RemoveBoxes removes page boundaries from selected pages of inFile and writes the result to outFile.
+func RemoveBoxes(cmd *Command) ([]string, error) {
+	return nil, api.RemoveBoxesFile(*cmd.InFile, *cmd.OutFile, cmd.PageSelection, cmd.Boxes, cmd.Conf)
+}
+
+// Crop sets the CropBox of selected pages of inFile and writes the result to outFile.
+func Crop(cmd *Command) ([]string, error) {
+	return nil, api.CropFile(*cmd.InFile, *cmd.OutFile, cmd.PageSelection, cmd.Box, cmd.Conf)
+}
+
+// Resize resizes selected pages of inFile and writes the result to outFile.
+func Resize(cmd *Command) ([]string, error) {
+	return nil, api.ResizeFile(*cmd.InFile, *cmd.OutFile, cmd.PageSelection, cmd.Resize, cmd.Conf)
+}
+
+// Cut cuts selected pages of inFile into sub pages and writes the result to outFile.
+func Cut(cmd *Command) ([]string, error) {
+	return nil, api.CutFile(*cmd.InFile, *cmd.OutFile, cmd.PageSelection, cmd.Cut, cmd.Conf)
+}
+
+// ListAnnotations returns a list of the annotations of selected pages of inFile.
+func ListAnnotations(cmd *Command) ([]string, error) {
+	return api.ListAnnotationsFile(*cmd.InFile, cmd.PageSelection, cmd.Conf)
+}
+
+// AddAnnotations adds annotations to selected pages of inFile and writes the result to outFile.
+func AddAnnotations(cmd *Command) ([]string, error) {
+	return nil, api.AddAnnotationsFile(*cmd.InFile, *cmd.OutFile, cmd.PageSelection, cmd.Annotations, cmd.Conf)
+}
+
+// RemoveAnnotations removes annotations of selected pages of inFile and writes the result to outFile.
+func RemoveAnnotations(cmd *Command) ([]string, error) {
+	return nil, api.RemoveAnnotationsFile(*cmd.InFile, *cmd.OutFile, cmd.PageSelection, cmd.AnnotTypes, cmd.AnnotIDs, cmd.Conf)
+}
+
+// FlattenAnnotations draws the annotations of selected pages of inFile into the page content and writes the result to outFile.
+func FlattenAnnotations(cmd *Command) ([]string, error) {
+	return nil, api.FlattenAnnotationsFile(*cmd.InFile, *cmd.OutFile, cmd.PageSelection, cmd.Flatten, cmd.Conf)
+}
+
+// ListFormFields returns a list of the form fields of inFile.
+func ListFormFields(cmd *Command) ([]string, error) {
+	return api.ListFormFieldsFile(*cmd.InFile, cmd.Conf)
+}
+
+// FillForm fills the form fields of inFile and writes the result to outFile.
+// For more than one set of values the files outFile_1.pdf, outFile_2.pdf ... get written.
+func FillForm(cmd *Command) ([]string, error) {
+
+	if len(cmd.FormValues) == 1 {
+		return nil, api.FillFormFile(*cmd.InFile, *cmd.OutFile, cmd.FormValues[0], cmd.Conf)
+	}
+
+	outFile := *cmd.OutFile
+	if outFile == "" {
+		outFile = *cmd.InFile
+	}
+	outFile = strings.TrimSuffix(outFile, filepath.Ext(outFile))
+
+	for i, fv := range cmd.FormValues {
+		if err := api.FillFormFile(*cmd.InFile, fmt.Sprintf("%s_%d.pdf", outFile, i+1), fv, cmd.Conf); err != nil {
+			return nil, errors.Wrapf(err, "form values #%d", i+1)
+		}
+	}
+
+	return nil, nil
+}
+
+// ExportForm writes the form field values of inFile to outFile as JSON, FDF or XFDF.
+func ExportForm(cmd *Command) ([]string, error) {
+	if cmd.Format == "" || cmd.Format == "json" {
+		return nil, api.ExportFormFile(*cmd.InFile, *cmd.OutFile, cmd.Conf)
+	}
+	return nil, api.ExportFormDataFile(*cmd.InFile, *cmd.OutFile, cmd.Format, cmd.Conf)
+}
+
+// FlattenForm draws the form fields of inFile into the page content and removes the form.
+func FlattenForm(cmd *Command) ([]string, error) {
+	return nil, api.FlattenFormFile(*cmd.InFile, *cmd.OutFile, cmd.FlattenAnnots, cmd.Conf)
+}
+
+// ImportForm imports the form field values and annotations of an FDF or XFDF file into inFile.
+func ImportForm(cmd *Command) ([]string, error) {
+	return nil, api.ImportFormDataFile(*cmd.InFile, *cmd.DataFile, *cmd.OutFile, cmd.Format, cmd.Conf)
+}
+
+// CreateFormFields adds the form fields specified by cmd.FieldSpecs to inFile.
+func CreateFormFields(cmd *Command) ([]string, error) {
+	return nil, api.CreateFormFieldsFile(*cmd.InFile, *cmd.OutFile, cmd.FieldSpecs, cmd.Conf)
+}
+
+// Sign digitally signs inFile.
+func Sign(cmd *Command) ([]string, error) {
+	return nil, api.SignFile(*cmd.InFile, *cmd.OutFile, cmd.Sign, cmd.Conf)
+}
+
+// TimeStamp adds a document time-stamp to inFile.
+func TimeStamp(cmd *Command) ([]string, error) {
+	return nil, api.TimeStampFile(*cmd.InFile, *cmd.OutFile, cmd.TSA, cmd.Conf)
+}
+
+// AddLTV adds validation data for the signatures of inFile.
+func AddLTV(cmd *Command) ([]string, error) {
+	return nil, api.AddLTVFile(*cmd.InFile, *cmd.OutFile, cmd.LTV, cmd.Conf)
+}
+
+// ListSignatures returns a report of the signatures of inFile.
+func ListSignatures(cmd *Command) ([]string, error) {
+	return api.ListSignaturesFile(*cmd.InFile, cmd.Conf)
+}
+
+// VerifySignatures returns a report of the verified signatures of inFile.
+// Returns an error including the report if any signature is invalid.
+func VerifySignatures(cmd *Command) ([]string, error) {
+	sigs, err := api.VerifySignaturesFile(*cmd.InFile, cmd.Conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sigs) == 0 {
+		return []string{"no signatures"}, nil
+	}
+
+	ss, invalid := []string{}, 0
+	for _, s := range sigs {
+		ss = append(ss, s.String())
+		if s.Err != nil {
+			invalid++
+		}
+	}
+
+	if invalid > 0 {
+		return nil, errors.Errorf("%s\n%d of %d signature(s) invalid", strings.Join(ss, "\n"), invalid, len(sigs))
+	}
+
+	return ss, nil
+}
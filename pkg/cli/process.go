@@ -20,29 +20,81 @@ import (
 	"io"
 
 	pdf "github.com/denisbetsi/pdfcpu/pkg/pdfcpu"
+	"github.com/denisbetsi/pdfcpu/pkg/pdfcpu/cms"
 	"github.com/pkg/errors"
 )
 
+// SplitMode represents the criterion for splitting a file.
+type SplitMode int
+
+// The available split modes.
+const (
+	SplitSpan     SplitMode = iota // Split into files of span pages each.
+	SplitBookmark                  // Split at each top level bookmark.
+	SplitPageNrs                   // Split before each of a list of pages.
+	SplitSize                      // Split into files not exceeding a maximum size.
+)
+
+// MergeMode represents the way files get merged.
+type MergeMode int
+
+// The available merge modes.
+const (
+	MergeConcat MergeMode = iota // Concatenate files.
+	MergeZip                     // Interleave the pages of 2 files.
+)
+
 // Command represents an execution context.
 type Command struct {
-	Mode          pdf.CommandMode    // VALIDATE  OPTIMIZE  SPLIT  MERGE  EXTRACT  TRIM  LISTATT ADDATT REMATT EXTATT  ENCRYPT  DECRYPT  CHANGEUPW  CHANGEOPW LISTP ADDP  WATERMARK  IMPORT  INSERTP REMOVEP ROTATE  NUP
-	InFile        *string            //    *         *        *      -       *      *      *       *       *      *       *        *         *          *       *     *       *         -       *       *       *     -
-	InFiles       []string           //    -         -        -      *       -      -      -       *       *      *       -        -         -          -       -     -       -         *       -       -       -     *
-	InDir         *string            //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
-	OutFile       *string            //    -         *        -      *       -      *      -       -       -      -       *        *         *          *       -     -       *         *       *       *       -     *
-	OutDir        *string            //    -         -        *      -       *      -      -       -       -      *       -        -         -          -       -     -       -         -       -       -       -     -
-	PageSelection []string           //    -         -        -      -       *      *      -       -       -      -       -        -         -          -       -     -       *         -       *       *       -     *
-	Conf          *pdf.Configuration //    *         *        *      *       *      *      *       *       *      *       *        *         *          *       *     *       *         *       *       *       *     *
-	PWOld         *string            //    -         -        -      -       -      -      -       -       -      -       -        -         *          *       -     -       -         -       -       -       -     -
-	PWNew         *string            //    -         -        -      -       -      -      -       -       -      -       -        -         *          *       -     -       -         -       -       -       -     -
-	Watermark     *pdf.Watermark     //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
-	Span          int                //    -         -        *      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
-	Import        *pdf.Import        //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         *       -       -       -     -
-	Rotation      int                //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       *     -
-	NUp           *pdf.NUp           //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     *
-	Input         io.ReadSeeker
-	Inputs        []io.ReadSeeker
-	Output        io.Writer
+	Mode           pdf.CommandMode    // VALIDATE  OPTIMIZE  SPLIT  MERGE  EXTRACT  TRIM  LISTATT ADDATT REMATT EXTATT  ENCRYPT  DECRYPT  CHANGEUPW  CHANGEOPW LISTP ADDP  WATERMARK  IMPORT  INSERTP REMOVEP ROTATE  NUP
+	InFile         *string            //    *         *        *      -       *      *      *       *       *      *       *        *         *          *       *     *       *         -       *       *       *     -
+	InFiles        []string           //    -         -        -      *       -      -      -       *       *      *       -        -         -          -       -     -       -         *       -       -       -     *
+	InDir          *string            //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	OutFile        *string            //    -         *        -      *       -      *      -       -       -      -       *        *         *          *       -     -       *         *       *       *       -     *
+	OutDir         *string            //    -         -        *      -       *      -      -       -       -      *       -        -         -          -       -     -       -         -       -       -       -     -
+	PageSelection  []string           //    -         -        -      -       *      *      -       -       -      -       -        -         -          -       -     -       *         -       *       *       -     *
+	PageOrder      []string           //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	Conf           *pdf.Configuration //    *         *        *      *       *      *      *       *       *      *       *        *         *          *       *     *       *         *       *       *       *     *
+	PWOld          *string            //    -         -        -      -       -      -      -       -       -      -       -        -         *          *       -     -       -         -       -       -       -     -
+	PWNew          *string            //    -         -        -      -       -      -      -       -       -      -       -        -         *          *       -     -       -         -       -       -       -     -
+	Watermark      *pdf.Watermark     //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	Span           int                //    -         -        *      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	SplitMode      SplitMode          //    -         -        *      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	PageNrs        []int              //    -         -        *      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	MaxSize        int64              //    -         -        *      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	MergeMode      MergeMode          //    -         -        -      *       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	Reverse        bool               //    -         -        -      *       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	Fill           bool               //    -         -        -      *       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	PageSelections [][]string         //    -         -        -      *       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	FromFile       *string            //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       *       -       -     -
+	Before         int                //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       *       -       -     -
+	Threshold      float64            //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	DryRun         bool               //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	Import         *pdf.Import        //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         *       -       -       -     -
+	Rotation       int                //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       *     -
+	Boxes          []string           //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	PageBoundaries pdf.PageBoundaries //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	Box            *pdf.Box           //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	Resize         *pdf.Resize        //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	NUp            *pdf.NUp           //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     *
+	Booklet        *pdf.Booklet       //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	Poster         *pdf.Poster        //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	Cut            *pdf.Cut           //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	Annotations    []pdf.Annotation   //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	AnnotTypes     []string           //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	AnnotIDs       []string           //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	Flatten        *pdf.Flatten       //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	FormValues     []pdf.FormValues   //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	FlattenAnnots  bool               //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	DataFile       *string            //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	FieldSpecs     []pdf.FieldSpec    //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	Format         string             //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	Sign           *pdf.Sign          //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	TSA            cms.TSAClient      //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	LTV            *pdf.LTV           //    -         -        -      -       -      -      -       -       -      -       -        -         -          -       -     -       -         -       -       -       -     -
+	Input          io.ReadSeeker
+	Inputs         []io.ReadSeeker
+	Output         io.Writer
 }
 
 var cmdMap = map[pdf.CommandMode]func(cmd *Command) ([]string, error){
@@ -70,9 +122,34 @@ var cmdMap = map[pdf.CommandMode]func(cmd *Command) ([]string, error){
 	pdf.IMPORTIMAGES:       ImportImages,
 	pdf.INSERTPAGES:        InsertPages,
 	pdf.REMOVEPAGES:        RemovePages,
+	pdf.ARRANGEPAGES:       ArrangePages,
+	pdf.REMOVEBLANKPAGES:   RemoveBlankPages,
 	pdf.ROTATE:             Rotate,
 	pdf.NUP:                NUp,
 	pdf.INFO:               Info,
+	pdf.LISTBOXES:          processBoxes,
+	pdf.ADDBOXES:           processBoxes,
+	pdf.REMOVEBOXES:        processBoxes,
+	pdf.CROP:               Crop,
+	pdf.RESIZE:             Resize,
+	pdf.BOOKLET:            Booklet,
+	pdf.POSTER:             Poster,
+	pdf.CUT:                Cut,
+	pdf.LISTANNOTATIONS:    processAnnotations,
+	pdf.ADDANNOTATIONS:     processAnnotations,
+	pdf.REMOVEANNOTATIONS:  processAnnotations,
+	pdf.FLATTENANNOTATIONS: FlattenAnnotations,
+	pdf.LISTFORMFIELDS:     processForm,
+	pdf.FILLFORM:           processForm,
+	pdf.EXPORTFORM:         processForm,
+	pdf.FLATTENFORM:        processForm,
+	pdf.IMPORTFORM:         processForm,
+	pdf.CREATEFORMFIELDS:   processForm,
+	pdf.SIGN:               Sign,
+	pdf.LISTSIGNATURES:     processSignatures,
+	pdf.VERIFYSIGNATURES:   processSignatures,
+	pdf.TIMESTAMP:          TimeStamp,
+	pdf.ADDLTV:             processSignatures,
 }
 
 // Process executes a pdfcpu command.
@@ -132,6 +209,50 @@ func SplitCommand(inFile, dirNameOut string, span int, conf *pdf.Configuration)
 		Conf:   conf}
 }
 
+// SplitByBookmarkCommand creates a new command to split a file at each top level bookmark.
+func SplitByBookmarkCommand(inFile, dirNameOut string, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.SPLIT
+	return &Command{
+		Mode:      pdf.SPLIT,
+		InFile:    &inFile,
+		OutDir:    &dirNameOut,
+		SplitMode: SplitBookmark,
+		Conf:      conf}
+}
+
+// SplitByPageNrCommand creates a new command to split a file before each of pageNrs.
+func SplitByPageNrCommand(inFile, dirNameOut string, pageNrs []int, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.SPLIT
+	return &Command{
+		Mode:      pdf.SPLIT,
+		InFile:    &inFile,
+		OutDir:    &dirNameOut,
+		SplitMode: SplitPageNrs,
+		PageNrs:   pageNrs,
+		Conf:      conf}
+}
+
+// SplitBySizeCommand creates a new command to split a file into files not exceeding maxSize bytes.
+func SplitBySizeCommand(inFile, dirNameOut string, maxSize int64, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.SPLIT
+	return &Command{
+		Mode:      pdf.SPLIT,
+		InFile:    &inFile,
+		OutDir:    &dirNameOut,
+		SplitMode: SplitSize,
+		MaxSize:   maxSize,
+		Conf:      conf}
+}
+
 // MergeCommand creates a new command to merge files.
 func MergeCommand(inFiles []string, outFile string, conf *pdf.Configuration) *Command {
 	if conf == nil {
@@ -145,6 +266,37 @@ func MergeCommand(inFiles []string, outFile string, conf *pdf.Configuration) *Co
 		Conf:    conf}
 }
 
+// MergeSelectedPagesCommand creates a new command to merge the selected pages of files.
+// pageSelections[i] selects the pages of inFiles[i], nil selects all pages.
+func MergeSelectedPagesCommand(inFiles []string, pageSelections [][]string, outFile string, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.MERGE
+	return &Command{
+		Mode:           pdf.MERGE,
+		InFiles:        inFiles,
+		PageSelections: pageSelections,
+		OutFile:        &outFile,
+		Conf:           conf}
+}
+
+// MergeZipCommand creates a new command to merge 2 files by interleaving their pages.
+func MergeZipCommand(inFile1, inFile2, outFile string, reverse, fill bool, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.MERGE
+	return &Command{
+		Mode:      pdf.MERGE,
+		InFiles:   []string{inFile1, inFile2},
+		OutFile:   &outFile,
+		MergeMode: MergeZip,
+		Reverse:   reverse,
+		Fill:      fill,
+		Conf:      conf}
+}
+
 // ExtractImagesCommand creates a new command to extract embedded images.
 // (experimental
 func ExtractImagesCommand(inFile string, outDir string, pageSelection []string, conf *pdf.Configuration) *Command {
@@ -384,6 +536,79 @@ func processAttachments(cmd *Command) (out []string, err error) {
 	return out, err
 }
 
+func processBoxes(cmd *Command) (out []string, err error) {
+	switch cmd.Mode {
+
+	case pdf.LISTBOXES:
+		out, err = ListBoxes(cmd)
+
+	case pdf.ADDBOXES:
+		out, err = AddBoxes(cmd)
+
+	case pdf.REMOVEBOXES:
+		out, err = RemoveBoxes(cmd)
+	}
+
+	return out, err
+}
+
+func processAnnotations(cmd *Command) (out []string, err error) {
+	switch cmd.Mode {
+
+	case pdf.LISTANNOTATIONS:
+		out, err = ListAnnotations(cmd)
+
+	case pdf.ADDANNOTATIONS:
+		out, err = AddAnnotations(cmd)
+
+	case pdf.REMOVEANNOTATIONS:
+		out, err = RemoveAnnotations(cmd)
+	}
+
+	return out, err
+}
+
+func processForm(cmd *Command) (out []string, err error) {
+	switch cmd.Mode {
+
+	case pdf.LISTFORMFIELDS:
+		out, err = ListFormFields(cmd)
+
+	case pdf.FILLFORM:
+		out, err = FillForm(cmd)
+
+	case pdf.EXPORTFORM:
+		out, err = ExportForm(cmd)
+
+	case pdf.FLATTENFORM:
+		out, err = FlattenForm(cmd)
+
+	case pdf.IMPORTFORM:
+		out, err = ImportForm(cmd)
+
+	case pdf.CREATEFORMFIELDS:
+		out, err = CreateFormFields(cmd)
+	}
+
+	return out, err
+}
+
+func processSignatures(cmd *Command) (out []string, err error) {
+	switch cmd.Mode {
+
+	case pdf.LISTSIGNATURES:
+		out, err = ListSignatures(cmd)
+
+	case pdf.VERIFYSIGNATURES:
+		out, err = VerifySignatures(cmd)
+
+	case pdf.ADDLTV:
+		out, err = AddLTV(cmd)
+	}
+
+	return out, err
+}
+
 func processEncryption(cmd *Command) (out []string, err error) {
 	switch cmd.Mode {
 
@@ -459,6 +684,42 @@ func InsertPagesCommand(inFile, outFile string, pageSelection []string, conf *pd
 		Conf:          conf}
 }
 
+// InsertPagesFromCommand creates a new command to insert selected pages of fromFile before page before.
+func InsertPagesFromCommand(inFile, fromFile, outFile string, pageSelection []string, before int, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.INSERTPAGES
+	return &Command{
+		Mode:          pdf.INSERTPAGES,
+		InFile:        &inFile,
+		FromFile:      &fromFile,
+		OutFile:       &outFile,
+		PageSelection: pageSelection,
+		Before:        before,
+		Conf:          conf}
+}
+
+// RemoveBlankPagesCommand creates a new command to remove blank pages among selected pages.
+// In dry run mode the blank pages are listed only.
+func RemoveBlankPagesCommand(inFile, outFile string, pageSelection []string, threshold float64, dryRun bool, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.REMOVEBLANKPAGES
+	if dryRun {
+		conf.Cmd = pdf.LISTBLANKPAGES
+	}
+	return &Command{
+		Mode:          pdf.REMOVEBLANKPAGES,
+		InFile:        &inFile,
+		OutFile:       &outFile,
+		PageSelection: pageSelection,
+		Threshold:     threshold,
+		DryRun:        dryRun,
+		Conf:          conf}
+}
+
 // RemovePagesCommand creates a new command to remove selected pages.
 func RemovePagesCommand(inFile, outFile string, pageSelection []string, conf *pdf.Configuration) *Command {
 	if conf == nil {
@@ -473,6 +734,20 @@ func RemovePagesCommand(inFile, outFile string, pageSelection []string, conf *pd
 		Conf:          conf}
 }
 
+// ArrangePagesCommand creates a new command to reorder, repeat or drop pages.
+func ArrangePagesCommand(inFile, outFile string, pageOrder []string, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.ARRANGEPAGES
+	return &Command{
+		Mode:      pdf.ARRANGEPAGES,
+		InFile:    &inFile,
+		OutFile:   &outFile,
+		PageOrder: pageOrder,
+		Conf:      conf}
+}
+
 // RotateCommand creates a new command to rotate pages.
 func RotateCommand(inFile, outFile string, rotation int, pageSelection []string, conf *pdf.Configuration) *Command {
 	if conf == nil {
@@ -503,6 +778,36 @@ func NUpCommand(inFiles []string, outFile string, pageSelection []string, nUp *p
 		Conf:          conf}
 }
 
+// BookletCommand creates a new command to impose selected pages of inFile as a booklet.
+func BookletCommand(inFile, outFile string, pageSelection []string, b *pdf.Booklet, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.BOOKLET
+	return &Command{
+		Mode:          pdf.BOOKLET,
+		InFile:        &inFile,
+		OutFile:       &outFile,
+		PageSelection: pageSelection,
+		Booklet:       b,
+		Conf:          conf}
+}
+
+// PosterCommand creates a new command to tile selected pages of inFile across multiple sheets.
+func PosterCommand(inFile, outFile string, pageSelection []string, p *pdf.Poster, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.POSTER
+	return &Command{
+		Mode:          pdf.POSTER,
+		InFile:        &inFile,
+		OutFile:       &outFile,
+		PageSelection: pageSelection,
+		Poster:        p,
+		Conf:          conf}
+}
+
 // InfoCommand creates a new command to output information about inFile.
 func InfoCommand(inFile string, conf *pdf.Configuration) *Command {
 	if conf == nil {
@@ -514,3 +819,304 @@ func InfoCommand(inFile string, conf *pdf.Configuration) *Command {
 		InFile: &inFile,
 		Conf:   conf}
 }
+
+// ListBoxesCommand creates a new command to list page boundaries for selected pages.
+func ListBoxesCommand(inFile string, pageSelection []string, boxes []string, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.LISTBOXES
+	return &Command{
+		Mode:          pdf.LISTBOXES,
+		InFile:        &inFile,
+		PageSelection: pageSelection,
+		Boxes:         boxes,
+		Conf:          conf}
+}
+
+// AddBoxesCommand creates a new command to set page boundaries for selected pages.
+func AddBoxesCommand(inFile, outFile string, pageSelection []string, pb pdf.PageBoundaries, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.ADDBOXES
+	return &Command{
+		Mode:           pdf.ADDBOXES,
+		InFile:         &inFile,
+		OutFile:        &outFile,
+		PageSelection:  pageSelection,
+		PageBoundaries: pb,
+		Conf:           conf}
+}
+
+// RemoveBoxesCommand creates a new command to remove page boundaries from selected pages.
+func RemoveBoxesCommand(inFile, outFile string, pageSelection []string, boxes []string, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.REMOVEBOXES
+	return &Command{
+		Mode:          pdf.REMOVEBOXES,
+		InFile:        &inFile,
+		OutFile:       &outFile,
+		PageSelection: pageSelection,
+		Boxes:         boxes,
+		Conf:          conf}
+}
+
+// CropCommand creates a new command to set the CropBox of selected pages.
+// A nil box crops to the bounding box of the visible page content.
+func CropCommand(inFile, outFile string, pageSelection []string, box *pdf.Box, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.CROP
+	return &Command{
+		Mode:          pdf.CROP,
+		InFile:        &inFile,
+		OutFile:       &outFile,
+		PageSelection: pageSelection,
+		Box:           box,
+		Conf:          conf}
+}
+
+// ResizeCommand creates a new command to resize selected pages.
+func ResizeCommand(inFile, outFile string, pageSelection []string, res *pdf.Resize, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.RESIZE
+	return &Command{
+		Mode:          pdf.RESIZE,
+		InFile:        &inFile,
+		OutFile:       &outFile,
+		PageSelection: pageSelection,
+		Resize:        res,
+		Conf:          conf}
+}
+
+// CutCommand creates a new command to cut selected pages into sub pages.
+func CutCommand(inFile, outFile string, pageSelection []string, cut *pdf.Cut, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.CUT
+	return &Command{
+		Mode:          pdf.CUT,
+		InFile:        &inFile,
+		OutFile:       &outFile,
+		PageSelection: pageSelection,
+		Cut:           cut,
+		Conf:          conf}
+}
+
+// ListAnnotationsCommand creates a new command to list the annotations of selected pages.
+func ListAnnotationsCommand(inFile string, pageSelection []string, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.LISTANNOTATIONS
+	return &Command{
+		Mode:          pdf.LISTANNOTATIONS,
+		InFile:        &inFile,
+		PageSelection: pageSelection,
+		Conf:          conf}
+}
+
+// AddAnnotationsCommand creates a new command to add annotations to selected pages.
+func AddAnnotationsCommand(inFile, outFile string, pageSelection []string, aa []pdf.Annotation, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.ADDANNOTATIONS
+	return &Command{
+		Mode:          pdf.ADDANNOTATIONS,
+		InFile:        &inFile,
+		OutFile:       &outFile,
+		PageSelection: pageSelection,
+		Annotations:   aa,
+		Conf:          conf}
+}
+
+// RemoveAnnotationsCommand creates a new command to remove annotations of selected pages
+// matching annotation types and ids.
+func RemoveAnnotationsCommand(inFile, outFile string, pageSelection []string, types, ids []string, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.REMOVEANNOTATIONS
+	return &Command{
+		Mode:          pdf.REMOVEANNOTATIONS,
+		InFile:        &inFile,
+		OutFile:       &outFile,
+		PageSelection: pageSelection,
+		AnnotTypes:    types,
+		AnnotIDs:      ids,
+		Conf:          conf}
+}
+
+// FlattenAnnotationsCommand creates a new command to flatten the annotations of selected pages.
+func FlattenAnnotationsCommand(inFile, outFile string, pageSelection []string, f *pdf.Flatten, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.FLATTENANNOTATIONS
+	return &Command{
+		Mode:          pdf.FLATTENANNOTATIONS,
+		InFile:        &inFile,
+		OutFile:       &outFile,
+		PageSelection: pageSelection,
+		Flatten:       f,
+		Conf:          conf}
+}
+
+// ListFormFieldsCommand creates a new command to list the form fields of a file.
+func ListFormFieldsCommand(inFile string, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.LISTFORMFIELDS
+	return &Command{
+		Mode:   pdf.LISTFORMFIELDS,
+		InFile: &inFile,
+		Conf:   conf}
+}
+
+// FillFormCommand creates a new command to fill the form fields of a file.
+// For more than one set of values one output file per set gets written.
+func FillFormCommand(inFile, outFile string, fvs []pdf.FormValues, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.FILLFORM
+	return &Command{
+		Mode:       pdf.FILLFORM,
+		InFile:     &inFile,
+		OutFile:    &outFile,
+		FormValues: fvs,
+		Conf:       conf}
+}
+
+// ExportFormCommand creates a new command to export the form field values of a file.
+// format is one of json, fdf, xfdf. FDF and XFDF also cover annotations.
+func ExportFormCommand(inFile, outFile, format string, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.EXPORTFORM
+	return &Command{
+		Mode:    pdf.EXPORTFORM,
+		InFile:  &inFile,
+		OutFile: &outFile,
+		Format:  format,
+		Conf:    conf}
+}
+
+// FlattenFormCommand creates a new command to flatten the form of a file.
+func FlattenFormCommand(inFile, outFile string, annots bool, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.FLATTENFORM
+	return &Command{
+		Mode:          pdf.FLATTENFORM,
+		InFile:        &inFile,
+		OutFile:       &outFile,
+		FlattenAnnots: annots,
+		Conf:          conf}
+}
+
+// ImportFormCommand creates a new command to import form field values and annotations from an FDF or XFDF file.
+func ImportFormCommand(inFile, dataFile, outFile, format string, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.IMPORTFORM
+	return &Command{
+		Mode:     pdf.IMPORTFORM,
+		InFile:   &inFile,
+		DataFile: &dataFile,
+		OutFile:  &outFile,
+		Format:   format,
+		Conf:     conf}
+}
+
+// CreateFormFieldsCommand creates a new command to add form fields to a file.
+func CreateFormFieldsCommand(inFile, outFile string, fss []pdf.FieldSpec, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.CREATEFORMFIELDS
+	return &Command{
+		Mode:       pdf.CREATEFORMFIELDS,
+		InFile:     &inFile,
+		OutFile:    &outFile,
+		FieldSpecs: fss,
+		Conf:       conf}
+}
+
+// SignCommand creates a new command to digitally sign a file.
+func SignCommand(inFile, outFile string, s *pdf.Sign, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.SIGN
+	return &Command{
+		Mode:    pdf.SIGN,
+		InFile:  &inFile,
+		OutFile: &outFile,
+		Sign:    s,
+		Conf:    conf}
+}
+
+// ListSignaturesCommand creates a new command to list the signatures of a file.
+func ListSignaturesCommand(inFile string, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.LISTSIGNATURES
+	return &Command{
+		Mode:   pdf.LISTSIGNATURES,
+		InFile: &inFile,
+		Conf:   conf}
+}
+
+// VerifySignaturesCommand creates a new command to verify the signatures of a file against conf.TrustedCerts.
+func VerifySignaturesCommand(inFile string, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.VERIFYSIGNATURES
+	return &Command{
+		Mode:   pdf.VERIFYSIGNATURES,
+		InFile: &inFile,
+		Conf:   conf}
+}
+
+// TimeStampCommand creates a new command to add a document time-stamp obtained from tsa to a file.
+func TimeStampCommand(inFile, outFile string, tsa cms.TSAClient, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.TIMESTAMP
+	return &Command{
+		Mode:    pdf.TIMESTAMP,
+		InFile:  &inFile,
+		OutFile: &outFile,
+		TSA:     tsa,
+		Conf:    conf}
+}
+
+// AddLTVCommand creates a new command to add validation data for the signatures of a file.
+func AddLTVCommand(inFile, outFile string, ltv *pdf.LTV, conf *pdf.Configuration) *Command {
+	if conf == nil {
+		conf = pdf.NewDefaultConfiguration()
+	}
+	conf.Cmd = pdf.ADDLTV
+	return &Command{
+		Mode:    pdf.ADDLTV,
+		InFile:  &inFile,
+		OutFile: &outFile,
+		LTV:     ltv,
+		Conf:    conf}
+}
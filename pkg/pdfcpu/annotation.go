@@ -0,0 +1,895 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// Annotation represents a page annotation, see 12.5 Annotations.
+// The JSON representation is used for adding annotations.
+type Annotation struct {
+	PageNr   int        `json:"page,omitempty"`     // The page the annotation is on.
+	ObjNr    int        `json:"-"`                  // 0 for annotations not being indirect objects.
+	Type     string     `json:"type"`               // The annotation subtype, eg. Text, Link, Highlight.
+	ID       string     `json:"id,omitempty"`       // NM, the annotation name.
+	Rect     [4]float64 `json:"rect"`               // llx, lly, urx, ury in user space.
+	Contents string     `json:"contents,omitempty"` // The text to be displayed.
+	Color    []float64  `json:"color,omitempty"`    // C, 0 (transparent), 1 (gray), 3 (RGB) or 4 (CMYK) components.
+	Border   *float64   `json:"border,omitempty"`   // Border width, defaults to 1.
+	Flags    int        `json:"flags,omitempty"`    // F, defaults to 4 (Print) for new annotations.
+	Modified string     `json:"-"`                  // M
+
+	// Markup annotations
+	Title   string `json:"title,omitempty"`   // T, the author.
+	Subject string `json:"subject,omitempty"` // Subj
+
+	// Text, Stamp
+	Icon string `json:"icon,omitempty"` // Name, eg. Note, Comment, Approved, Draft.
+	Open bool   `json:"open,omitempty"` // Text only.
+
+	// Link
+	URI  string `json:"uri,omitempty"`
+	Dest int    `json:"dest,omitempty"` // The destination page number.
+
+	// FreeText
+	FontSize float64 `json:"fontSize,omitempty"`
+
+	// Line
+	Line []float64 `json:"line,omitempty"` // L: x1 y1 x2 y2
+
+	// Square, Circle, Line, Polygon, PolyLine
+	InteriorColor []float64 `json:"interiorColor,omitempty"` // IC
+
+	// Polygon, PolyLine
+	Vertices []float64 `json:"vertices,omitempty"`
+
+	// Highlight, Underline, Squiggly, StrikeOut, defaults to Rect.
+	QuadPoints []float64 `json:"quadPoints,omitempty"`
+
+	// Ink
+	InkList [][]float64 `json:"inkList,omitempty"`
+}
+
+// annotTypes maps annotation subtypes to whether they are markup annotations, see Table 169.
+var annotTypes = map[string]bool{
+	"Text":           true,
+	"Link":           false,
+	"FreeText":       true,
+	"Line":           true,
+	"Square":         true,
+	"Circle":         true,
+	"Polygon":        true,
+	"PolyLine":       true,
+	"Highlight":      true,
+	"Underline":      true,
+	"Squiggly":       true,
+	"StrikeOut":      true,
+	"Stamp":          true,
+	"Caret":          true,
+	"Ink":            true,
+	"Popup":          false,
+	"FileAttachment": true,
+	"Sound":          true,
+	"Movie":          false,
+	"Widget":         false,
+	"Screen":         false,
+	"PrinterMark":    false,
+	"TrapNet":        false,
+	"Watermark":      false,
+	"3D":             false,
+	"Redact":         true,
+}
+
+// AnnotationType returns the canonical annotation subtype for s ignoring case.
+func AnnotationType(s string) (string, error) {
+	for k := range annotTypes {
+		if strings.EqualFold(k, s) {
+			return k, nil
+		}
+	}
+	return "", errors.Errorf("unknown annotation type: %s", s)
+}
+
+func (a Annotation) String() string {
+
+	var sb strings.Builder
+
+	id := "direct"
+	if a.ObjNr > 0 {
+		id = fmt.Sprintf("obj#%d", a.ObjNr)
+	}
+	fmt.Fprintf(&sb, "  %-9s %-10s [%.2f %.2f %.2f %.2f]", id, a.Type, a.Rect[0], a.Rect[1], a.Rect[2], a.Rect[3])
+
+	if a.ID != "" {
+		fmt.Fprintf(&sb, " id=%s", a.ID)
+	}
+	if a.Title != "" {
+		fmt.Fprintf(&sb, " by=%s", a.Title)
+	}
+	if a.URI != "" {
+		fmt.Fprintf(&sb, " uri=%s", a.URI)
+	}
+	if a.Dest > 0 {
+		fmt.Fprintf(&sb, " dest=page %d", a.Dest)
+	}
+	if a.Contents != "" {
+		fmt.Fprintf(&sb, " %q", a.Contents)
+	}
+
+	return sb.String()
+}
+
+// ParseAnnotationsJSON parses a JSON array of annotations or a single JSON annotation.
+func ParseAnnotationsJSON(r io.Reader) ([]Annotation, error) {
+
+	bb, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bb = bytes.TrimSpace(bb)
+	if len(bb) > 0 && bb[0] == '{' {
+		bb = append(append([]byte{'['}, bb...), ']')
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(bb))
+	dec.DisallowUnknownFields()
+
+	aa := []Annotation{}
+	if err = dec.Decode(&aa); err != nil {
+		return nil, errors.Wrap(err, "annotations: invalid JSON")
+	}
+
+	for i := range aa {
+		if aa[i].Type, err = AnnotationType(aa[i].Type); err != nil {
+			return nil, err
+		}
+	}
+
+	return aa, nil
+}
+
+// normalizedRect returns the rectangle spanned by two opposite corners as llx, lly, urx, ury.
+func normalizedRect(x1, y1, x2, y2 float64) [4]float64 {
+	return [4]float64{math.Min(x1, x2), math.Min(y1, y2), math.Max(x1, x2), math.Max(y1, y2)}
+}
+
+func (xRefTable *XRefTable) numberArray(o Object) []float64 {
+	a, err := xRefTable.DereferenceArray(o)
+	if err != nil || a == nil {
+		return nil
+	}
+	return numbers(xRefTable, a)
+}
+
+func (xRefTable *XRefTable) text(o Object) string {
+	if o == nil {
+		return ""
+	}
+	s, err := xRefTable.DereferenceText(o)
+	if err != nil {
+		return ""
+	}
+	return s
+}
+
+// pageNrs returns a lookup table for page numbers by page dict object numbers.
+func (xRefTable *XRefTable) pageNrs() (map[int]int, error) {
+
+	irs, err := xRefTable.PageIndRefs()
+	if err != nil {
+		return nil, err
+	}
+
+	m := map[int]int{}
+	for i, ir := range irs {
+		m[ir.ObjectNumber.Value()] = i + 1
+	}
+
+	return m, nil
+}
+
+// linkTarget returns the URI or destination page of a link annotation.
+func (xRefTable *XRefTable) linkTarget(d Dict, pageNrs map[int]int) (string, int) {
+
+	o, found := d.Find("Dest")
+	if !found {
+		ad, err := xRefTable.DereferenceDict(d["A"])
+		if err != nil || ad == nil {
+			return "", 0
+		}
+		switch s := ad.NameEntry("S"); {
+		case s != nil && *s == "URI":
+			return xRefTable.text(ad["URI"]), 0
+		case s != nil && *s == "GoTo":
+			o = ad["D"]
+		default:
+			return "", 0
+		}
+	}
+
+	a, err := xRefTable.ResolveDestination(o)
+	if err != nil || len(a) == 0 {
+		return "", 0
+	}
+
+	if ir, ok := a[0].(IndirectRef); ok {
+		return "", pageNrs[ir.ObjectNumber.Value()]
+	}
+
+	return "", 0
+}
+
+func (xRefTable *XRefTable) annotation(d Dict, pageNr, objNr int, pageNrs map[int]int) Annotation {
+
+	a := Annotation{PageNr: pageNr, ObjNr: objNr}
+
+	if s := d.NameEntry("Subtype"); s != nil {
+		a.Type = *s
+	}
+
+	if ff := xRefTable.numberArray(d["Rect"]); len(ff) == 4 {
+		a.Rect = normalizedRect(ff[0], ff[1], ff[2], ff[3])
+	}
+
+	a.ID = xRefTable.text(d["NM"])
+	a.Contents = xRefTable.text(d["Contents"])
+	a.Modified = xRefTable.text(d["M"])
+	a.Title = xRefTable.text(d["T"])
+	a.Subject = xRefTable.text(d["Subj"])
+	a.Color = xRefTable.numberArray(d["C"])
+	a.InteriorColor = xRefTable.numberArray(d["IC"])
+
+	if ff := xRefTable.numberArray(d["Border"]); len(ff) >= 3 {
+		a.Border = &ff[2]
+	}
+
+	if f := d.IntEntry("F"); f != nil {
+		a.Flags = *f
+	}
+
+	if n := d.NameEntry("Name"); n != nil {
+		a.Icon = *n
+	}
+
+	if b := d.BooleanEntry("Open"); b != nil {
+		a.Open = *b
+	}
+
+	switch a.Type {
+
+	case "Link":
+		a.URI, a.Dest = xRefTable.linkTarget(d, pageNrs)
+
+	case "Line":
+		a.Line = xRefTable.numberArray(d["L"])
+
+	case "Polygon", "PolyLine":
+		a.Vertices = xRefTable.numberArray(d["Vertices"])
+
+	case "Highlight", "Underline", "Squiggly", "StrikeOut":
+		a.QuadPoints = xRefTable.numberArray(d["QuadPoints"])
+
+	case "Ink":
+		if arr, err := xRefTable.DereferenceArray(d["InkList"]); err == nil {
+			for _, o := range arr {
+				a.InkList = append(a.InkList, xRefTable.numberArray(o))
+			}
+		}
+
+	case "FreeText":
+		if da := xRefTable.text(d["DA"]); da != "" {
+			ss := strings.Fields(da)
+			for i, s := range ss {
+				if s == "Tf" && i > 0 {
+					a.FontSize, _ = strconv.ParseFloat(ss[i-1], 64)
+				}
+			}
+		}
+	}
+
+	return a
+}
+
+// pageAnnots returns the annotations array of a page dict along with a function for updating it.
+func (xRefTable *XRefTable) pageAnnots(d Dict) (Array, func(Array), error) {
+
+	o, found := d.Find("Annots")
+	if !found {
+		return nil, func(a Array) { d.Insert("Annots", a) }, nil
+	}
+
+	arr, err := xRefTable.DereferenceArray(o)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	update := func(a Array) { d.Update("Annots", a) }
+
+	if ir, ok := o.(IndirectRef); ok {
+		update = func(a Array) {
+			if entry, found := xRefTable.FindTableEntryForIndRef(&ir); found {
+				entry.Object = a
+			}
+		}
+	}
+
+	return arr, update, nil
+}
+
+// AddPageAnnot adds the annotation ir to page pageNr.
+func (xRefTable *XRefTable) AddPageAnnot(pageNr int, ir IndirectRef) error {
+
+	d, _, err := xRefTable.PageDict(pageNr)
+	if err != nil {
+		return err
+	}
+
+	arr, update, err := xRefTable.pageAnnots(d)
+	if err != nil {
+		return err
+	}
+
+	update(append(arr, ir))
+
+	return nil
+}
+
+// copyAnnotations returns copies of the annotations of annots accepted by keep for the page pageIndRef.
+// Popups and replies referring to copied annotations get redirected to the copies.
+func (xRefTable *XRefTable) copyAnnotations(annots Array, pageIndRef IndirectRef, keep func(d Dict) (bool, error)) (Array, error) {
+
+	a := Array{}
+	dd := []Dict{}
+	m := map[int]IndirectRef{}
+
+	for _, o := range annots {
+
+		d, err := xRefTable.DereferenceDict(o)
+		if err != nil {
+			return nil, err
+		}
+		if d == nil {
+			continue
+		}
+
+		if keep != nil {
+			ok, err := keep(d)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		d1 := NewDict()
+		for k, v := range d {
+			d1.Insert(k, v)
+		}
+		d1.Update("P", pageIndRef)
+
+		ir, err := xRefTable.IndRefForNewObject(d1)
+		if err != nil {
+			return nil, err
+		}
+
+		if ir0, ok := o.(IndirectRef); ok {
+			m[ir0.ObjectNumber.Value()] = *ir
+		}
+
+		a = append(a, *ir)
+		dd = append(dd, d1)
+	}
+
+	for _, d := range dd {
+		for _, k := range []string{"Popup", "IRT", "Parent"} {
+			if ir := d.IndirectRefEntry(k); ir != nil {
+				if ir1, ok := m[ir.ObjectNumber.Value()]; ok {
+					d.Update(k, ir1)
+				}
+			}
+		}
+	}
+
+	return a, nil
+}
+
+// Annotations returns the annotations of the selected pages in page order.
+func Annotations(ctx *Context, selectedPages IntSet) ([]Annotation, error) {
+
+	pageNrs, err := ctx.pageNrs()
+	if err != nil {
+		return nil, err
+	}
+
+	aa := []Annotation{}
+
+	for _, i := range sortedPageNrs(selectedPages) {
+
+		d, _, err := ctx.PageDict(i)
+		if err != nil {
+			return nil, err
+		}
+
+		arr, _, err := ctx.pageAnnots(d)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, o := range arr {
+
+			objNr := 0
+			if ir, ok := o.(IndirectRef); ok {
+				objNr = ir.ObjectNumber.Value()
+			}
+
+			ad, err := ctx.DereferenceDict(o)
+			if err != nil {
+				return nil, err
+			}
+			if ad == nil {
+				continue
+			}
+
+			aa = append(aa, ctx.annotation(ad, i, objNr, pageNrs))
+		}
+	}
+
+	return aa, nil
+}
+
+// ListAnnotations returns a list of the annotations of the selected pages.
+func ListAnnotations(ctx *Context, selectedPages IntSet) ([]string, error) {
+
+	aa, err := Annotations(ctx, selectedPages)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(aa) == 0 {
+		return []string{"no annotations"}, nil
+	}
+
+	ss := []string{}
+	pageNr := 0
+
+	for _, a := range aa {
+		if a.PageNr != pageNr {
+			pageNr = a.PageNr
+			ss = append(ss, fmt.Sprintf("page %d:", pageNr))
+		}
+		ss = append(ss, a.String())
+	}
+
+	return ss, nil
+}
+
+// annotationMatches returns true if a is of one of types or has one of ids.
+// An id matches the annotation name or the object number.
+// Without types and ids all annotations match.
+func annotationMatches(a Annotation, types, ids []string) bool {
+
+	if len(types) == 0 && len(ids) == 0 {
+		return true
+	}
+
+	if MemberOf(a.Type, types) {
+		return true
+	}
+
+	for _, id := range ids {
+		if a.ID != "" && id == a.ID {
+			return true
+		}
+		if a.ObjNr > 0 && id == strconv.Itoa(a.ObjNr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// annotationsToRemove returns the object numbers of annotations depending on the annotations of m,
+// ie. their popups and replies.
+func (xRefTable *XRefTable) annotationsToRemove(m IntSet, annots map[int]Dict) {
+
+	for changed := true; changed; {
+		changed = false
+		for objNr, d := range annots {
+			if m[objNr] {
+				if ir := d.IndirectRefEntry("Popup"); ir != nil && !m[ir.ObjectNumber.Value()] {
+					m[ir.ObjectNumber.Value()] = true
+					changed = true
+				}
+				continue
+			}
+			for _, k := range []string{"IRT", "Parent"} {
+				if ir := d.IndirectRefEntry(k); ir != nil && m[ir.ObjectNumber.Value()] && *d.Subtype() != "Widget" {
+					m[objNr] = true
+					changed = true
+				}
+			}
+		}
+	}
+}
+
+// RemoveAnnotations removes the annotations of the selected pages of one of types or with one of ids
+// along with their popups and replies and returns the number of removed annotations.
+// Widget annotations belong to form fields and are never removed.
+func RemoveAnnotations(ctx *Context, selectedPages IntSet, types, ids []string) (int, error) {
+
+	if MemberOf("Widget", types) {
+		return 0, errors.New("removeAnnotations: widget annotations belong to form fields")
+	}
+
+	pageNrs, err := ctx.pageNrs()
+	if err != nil {
+		return 0, err
+	}
+
+	// Collect all indirect annotations in order to resolve popups and replies across pages.
+	annots := map[int]Dict{}
+	remove := IntSet{}
+
+	for i := 1; i <= ctx.PageCount; i++ {
+
+		d, _, err := ctx.PageDict(i)
+		if err != nil {
+			return 0, err
+		}
+
+		arr, _, err := ctx.pageAnnots(d)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, o := range arr {
+			ir, ok := o.(IndirectRef)
+			if !ok {
+				continue
+			}
+			ad, err := ctx.DereferenceDict(ir)
+			if err != nil {
+				return 0, err
+			}
+			if ad == nil || ad.Subtype() == nil {
+				continue
+			}
+			objNr := ir.ObjectNumber.Value()
+			annots[objNr] = ad
+			if selectedPages[i] && *ad.Subtype() != "Widget" && annotationMatches(ctx.annotation(ad, i, objNr, pageNrs), types, ids) {
+				remove[objNr] = true
+			}
+		}
+	}
+
+	ctx.annotationsToRemove(remove, annots)
+
+	count := 0
+
+	for i := 1; i <= ctx.PageCount; i++ {
+
+		d, _, err := ctx.PageDict(i)
+		if err != nil {
+			return 0, err
+		}
+
+		arr, update, err := ctx.pageAnnots(d)
+		if err != nil || arr == nil {
+			if err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		a := Array{}
+
+		for _, o := range arr {
+			if ir, ok := o.(IndirectRef); ok {
+				if remove[ir.ObjectNumber.Value()] {
+					count++
+					continue
+				}
+				a = append(a, o)
+				continue
+			}
+			// Direct annotation dict.
+			ad, ok := o.(Dict)
+			if ok && selectedPages[i] && ad.Subtype() != nil && *ad.Subtype() != "Widget" &&
+				annotationMatches(ctx.annotation(ad, i, 0, pageNrs), types, ids) {
+				count++
+				continue
+			}
+			a = append(a, o)
+		}
+
+		if len(a) == len(arr) {
+			continue
+		}
+
+		if len(a) == 0 {
+			d.Delete("Annots")
+			continue
+		}
+
+		update(a)
+	}
+
+	log.Info.Printf("removed %d annotations\n", count)
+
+	return count, nil
+}
+
+func validColor(c []float64) bool {
+	switch len(c) {
+	case 0, 1, 3, 4:
+		return true
+	}
+	return false
+}
+
+// pointsRect returns the bounding box of the points defining a Line, Polygon, PolyLine, Ink
+// or text markup annotation with some room for the border.
+func (a Annotation) pointsRect() [4]float64 {
+
+	pp := append(append(append([]float64{}, a.Line...), a.Vertices...), a.QuadPoints...)
+	for _, path := range a.InkList {
+		pp = append(pp, path...)
+	}
+
+	if len(pp) < 2 {
+		return [4]float64{}
+	}
+
+	r := [4]float64{pp[0], pp[1], pp[0], pp[1]}
+	for i := 2; i+1 < len(pp); i += 2 {
+		r[0] = math.Min(r[0], pp[i])
+		r[1] = math.Min(r[1], pp[i+1])
+		r[2] = math.Max(r[2], pp[i])
+		r[3] = math.Max(r[3], pp[i+1])
+	}
+
+	w := 1.0
+	if a.Border != nil {
+		w = *a.Border
+	}
+
+	return [4]float64{r[0] - w, r[1] - w, r[2] + w, r[3] + w}
+}
+
+// annotationDict creates the annotation dict for a on the page referred to by pageIndRef.
+// Geometric, text markup, ink and free text annotations get a normal appearance.
+func (xRefTable *XRefTable) annotationDict(a Annotation, pageIndRef IndirectRef, pageIndRefs []IndirectRef) (Dict, error) {
+
+	markup, ok := annotTypes[a.Type]
+	if !ok {
+		return nil, errors.Errorf("addAnnotation: unknown annotation type: %s", a.Type)
+	}
+
+	nr := normalizedRect(a.Rect[0], a.Rect[1], a.Rect[2], a.Rect[3])
+	if nr == [4]float64{} {
+		nr = a.pointsRect()
+	}
+	r := Rect(nr[0], nr[1], nr[2], nr[3])
+	if r.Width() == 0 || r.Height() == 0 {
+		return nil, errors.Errorf("addAnnotation: %s: missing rect", a.Type)
+	}
+
+	if !validColor(a.Color) || !validColor(a.InteriorColor) {
+		return nil, errors.Errorf("addAnnotation: %s: colors need 0, 1, 3 or 4 components", a.Type)
+	}
+
+	flags := a.Flags
+	if flags == 0 {
+		flags = 4 // Print
+	}
+
+	d := Dict(map[string]Object{
+		"Type":    Name("Annot"),
+		"Subtype": Name(a.Type),
+		"Rect":    r.Array(),
+		"P":       pageIndRef,
+		"F":       Integer(flags),
+		"M":       StringLiteral(DateString(time.Now())),
+	})
+
+	texts := map[string]string{"Contents": a.Contents, "NM": a.ID}
+	if markup {
+		texts["T"] = a.Title
+		texts["Subj"] = a.Subject
+		d.Insert("CreationDate", StringLiteral(DateString(time.Now())))
+	}
+
+	for k, s := range texts {
+		if s == "" {
+			continue
+		}
+		o, err := EncodeText(s)
+		if err != nil {
+			return nil, err
+		}
+		d.Insert(k, o)
+	}
+
+	if len(a.Color) > 0 {
+		d.Insert("C", NewNumberArray(a.Color...))
+	}
+
+	if a.Border != nil {
+		d.Insert("Border", NewNumberArray(0, 0, *a.Border))
+	}
+
+	switch a.Type {
+
+	case "Text":
+		icon := a.Icon
+		if icon == "" {
+			icon = "Note"
+		}
+		d.Insert("Name", Name(icon))
+		if a.Open {
+			d.Insert("Open", Boolean(true))
+		}
+
+	case "Stamp":
+		icon := a.Icon
+		if icon == "" {
+			icon = "Draft"
+		}
+		d.Insert("Name", Name(icon))
+
+	case "Link":
+		switch {
+		case a.URI != "":
+			s, err := Escape(a.URI)
+			if err != nil {
+				return nil, err
+			}
+			d.Insert("A", Dict(map[string]Object{"S": Name("URI"), "URI": StringLiteral(*s)}))
+		case a.Dest > 0 && a.Dest <= len(pageIndRefs):
+			d.Insert("Dest", Array{pageIndRefs[a.Dest-1], Name("Fit")})
+		default:
+			return nil, errors.New("addAnnotation: Link: please provide a uri or a valid dest page")
+		}
+
+	case "FreeText":
+		fontSize := a.FontSize
+		if fontSize <= 0 {
+			fontSize = 12
+		}
+		d.Insert("DA", StringLiteral(fmt.Sprintf("/Helv %.2f Tf 0 g", fontSize)))
+
+	case "Line":
+		if len(a.Line) != 4 {
+			return nil, errors.New("addAnnotation: Line: please provide line: [x1 y1 x2 y2]")
+		}
+		d.Insert("L", NewNumberArray(a.Line...))
+
+	case "Polygon", "PolyLine":
+		if len(a.Vertices) < 4 || len(a.Vertices)%2 != 0 {
+			return nil, errors.Errorf("addAnnotation: %s: please provide vertices: [x1 y1 x2 y2 ...]", a.Type)
+		}
+		d.Insert("Vertices", NewNumberArray(a.Vertices...))
+
+	case "Highlight", "Underline", "Squiggly", "StrikeOut":
+		qp := a.QuadPoints
+		if len(qp) == 0 {
+			qp = []float64{r.LL.X, r.UR.Y, r.UR.X, r.UR.Y, r.LL.X, r.LL.Y, r.UR.X, r.LL.Y}
+		}
+		if len(qp)%8 != 0 {
+			return nil, errors.Errorf("addAnnotation: %s: quadPoints need a multiple of 8 numbers", a.Type)
+		}
+		d.Insert("QuadPoints", NewNumberArray(qp...))
+
+	case "Ink":
+		if len(a.InkList) == 0 {
+			return nil, errors.New("addAnnotation: Ink: please provide inkList: [[x1 y1 x2 y2 ...] ...]")
+		}
+		arr := Array{}
+		for _, path := range a.InkList {
+			if len(path) < 2 || len(path)%2 != 0 {
+				return nil, errors.New("addAnnotation: Ink: invalid path")
+			}
+			arr = append(arr, NewNumberArray(path...))
+		}
+		d.Insert("InkList", arr)
+
+	case "Square", "Circle", "Caret":
+
+	default:
+		return nil, errors.Errorf("addAnnotation: unsupported annotation type: %s", a.Type)
+	}
+
+	if len(a.InteriorColor) > 0 {
+		switch a.Type {
+		case "Square", "Circle", "Line", "Polygon", "PolyLine":
+			d.Insert("IC", NewNumberArray(a.InteriorColor...))
+		}
+	}
+
+	if err := xRefTable.addAnnotationAppearance(a, d, r); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// AddAnnotations adds annotations to their pages.
+// Annotations without a page number get added to all selected pages.
+func AddAnnotations(ctx *Context, aa []Annotation, selectedPages IntSet) error {
+
+	irs, err := ctx.PageIndRefs()
+	if err != nil {
+		return err
+	}
+
+	for _, a := range aa {
+
+		pageNrs := sortedPageNrs(selectedPages)
+		if a.PageNr != 0 {
+			pageNrs = []int{a.PageNr}
+		}
+
+		if len(pageNrs) == 0 {
+			return errors.Errorf("addAnnotations: %s: missing page", a.Type)
+		}
+
+		for _, i := range pageNrs {
+
+			if i < 1 || i > len(irs) {
+				return errors.Errorf("addAnnotations: %s: invalid page number: %d", a.Type, i)
+			}
+
+			d, err := ctx.annotationDict(a, irs[i-1], irs)
+			if err != nil {
+				return err
+			}
+
+			ir, err := ctx.IndRefForNewObject(d)
+			if err != nil {
+				return err
+			}
+
+			pd, _, err := ctx.PageDict(i)
+			if err != nil {
+				return err
+			}
+
+			arr, update, err := ctx.pageAnnots(pd)
+			if err != nil {
+				return err
+			}
+
+			update(append(arr, *ir))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,279 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/denisbetsi/pdfcpu/pkg/filter"
+)
+
+// kappa is the control point distance for approximating a quarter ellipse by a Bézier curve.
+const kappa = 0.5522847498
+
+// strokeColorOperator returns the stroke color operator for a falling back to c.
+func (a Annotation) strokeColorOperator(c ...float64) string {
+	if len(a.Color) > 0 {
+		return colorOperator(a.Color, true)
+	}
+	return colorOperator(c, true)
+}
+
+// borderWidth returns the border width of a which defaults to 1.
+func (a Annotation) borderWidth() float64 {
+	if a.Border != nil {
+		return *a.Border
+	}
+	return 1
+}
+
+// pathOperator returns the operator painting a path stroked with width w and optionally filled.
+func pathOperator(w float64, fill bool) string {
+	switch {
+	case w > 0 && fill:
+		return "B"
+	case fill:
+		return "f"
+	case w > 0:
+		return "S"
+	}
+	return "n"
+}
+
+func writePath(buf *bytes.Buffer, pp []float64, closed bool) {
+	for i := 0; i+1 < len(pp); i += 2 {
+		op := "l"
+		if i == 0 {
+			op = "m"
+		}
+		fmt.Fprintf(buf, "%.2f %.2f %s\n", pp[i], pp[i+1], op)
+	}
+	if closed {
+		buf.WriteString("h\n")
+	}
+}
+
+func writeEllipse(buf *bytes.Buffer, r *Rectangle) {
+	cx, cy := r.LL.X+r.Width()/2, r.LL.Y+r.Height()/2
+	rx, ry := r.Width()/2, r.Height()/2
+	kx, ky := kappa*rx, kappa*ry
+	fmt.Fprintf(buf, "%.2f %.2f m\n", cx+rx, cy)
+	fmt.Fprintf(buf, "%.2f %.2f %.2f %.2f %.2f %.2f c\n", cx+rx, cy+ky, cx+kx, cy+ry, cx, cy+ry)
+	fmt.Fprintf(buf, "%.2f %.2f %.2f %.2f %.2f %.2f c\n", cx-kx, cy+ry, cx-rx, cy+ky, cx-rx, cy)
+	fmt.Fprintf(buf, "%.2f %.2f %.2f %.2f %.2f %.2f c\n", cx-rx, cy-ky, cx-kx, cy-ry, cx, cy-ry)
+	fmt.Fprintf(buf, "%.2f %.2f %.2f %.2f %.2f %.2f c\n", cx+kx, cy-ry, cx+rx, cy-ky, cx+rx, cy)
+	buf.WriteString("h\n")
+}
+
+// writeTextMarkup writes the markup for the quadrilaterals qp, see 12.5.6.10 Text Markup Annotations.
+// The points of each quadrilateral are ordered upper left, upper right, lower left, lower right.
+func (a Annotation) writeTextMarkup(buf *bytes.Buffer, qp []float64) {
+
+	for i := 0; i+7 < len(qp); i += 8 {
+
+		x1, y1, x2, y2 := qp[i], qp[i+1], qp[i+2], qp[i+3]
+		x3, y3, x4, y4 := qp[i+4], qp[i+5], qp[i+6], qp[i+7]
+
+		// The unit vector pointing from the baseline to the top.
+		nx, ny := x1-x3, y1-y3
+		h := math.Hypot(nx, ny)
+		if h == 0 {
+			continue
+		}
+		nx, ny = nx/h, ny/h
+
+		switch a.Type {
+
+		case "Highlight":
+			writePath(buf, []float64{x1, y1, x2, y2, x4, y4, x3, y3}, true)
+			buf.WriteString("f\n")
+
+		case "Underline":
+			d := h / 14
+			fmt.Fprintf(buf, "%.2f w\n", math.Max(h/16, 0.5))
+			writePath(buf, []float64{x3 + d*nx, y3 + d*ny, x4 + d*nx, y4 + d*ny}, false)
+			buf.WriteString("S\n")
+
+		case "StrikeOut":
+			fmt.Fprintf(buf, "%.2f w\n", math.Max(h/16, 0.5))
+			writePath(buf, []float64{(x1 + x3) / 2, (y1 + y3) / 2, (x2 + x4) / 2, (y2 + y4) / 2}, false)
+			buf.WriteString("S\n")
+
+		case "Squiggly":
+			// Zigzag along the baseline with a period of h/4.
+			l := math.Hypot(x4-x3, y4-y3)
+			if l == 0 {
+				continue
+			}
+			ux, uy := (x4-x3)/l, (y4-y3)/l
+			step, amp := h/8, h/12
+			pp := []float64{}
+			for j := 0; float64(j)*step <= l; j++ {
+				s := float64(j) * step
+				d := amp / 2
+				if j%2 == 1 {
+					d += amp
+				}
+				pp = append(pp, x3+s*ux+d*nx, y3+s*uy+d*ny)
+			}
+			fmt.Fprintf(buf, "%.2f w\n", math.Max(h/24, 0.5))
+			writePath(buf, pp, false)
+			buf.WriteString("S\n")
+		}
+	}
+}
+
+// appearanceContent returns the content of the normal appearance of the annotation dict d for a within r
+// along with the resources needed. Returns nil for annotations left to the viewer.
+func (xRefTable *XRefTable) appearanceContent(a Annotation, d Dict, r *Rectangle) ([]byte, Dict) {
+
+	var buf bytes.Buffer
+
+	resources := NewDict()
+
+	w := a.borderWidth()
+	fill := colorOperator(a.InteriorColor, false)
+
+	switch a.Type {
+
+	case "Square", "Circle":
+		fmt.Fprintf(&buf, "%s %.2f w\n", a.strokeColorOperator(0), w)
+		if fill != "" {
+			fmt.Fprintf(&buf, "%s\n", fill)
+		}
+		// Keep the border within r.
+		br := Rect(r.LL.X+w/2, r.LL.Y+w/2, r.UR.X-w/2, r.UR.Y-w/2)
+		if a.Type == "Square" {
+			fmt.Fprintf(&buf, "%.2f %.2f %.2f %.2f re\n", br.LL.X, br.LL.Y, br.Width(), br.Height())
+		} else {
+			writeEllipse(&buf, br)
+		}
+		buf.WriteString(pathOperator(w, fill != "") + "\n")
+
+	case "Line", "PolyLine":
+		pp := a.Line
+		if a.Type == "PolyLine" {
+			pp = a.Vertices
+		}
+		fmt.Fprintf(&buf, "%s %.2f w 1 J 1 j\n", a.strokeColorOperator(0), w)
+		writePath(&buf, pp, false)
+		buf.WriteString(pathOperator(w, false) + "\n")
+
+	case "Polygon":
+		fmt.Fprintf(&buf, "%s %.2f w 1 j\n", a.strokeColorOperator(0), w)
+		if fill != "" {
+			fmt.Fprintf(&buf, "%s\n", fill)
+		}
+		writePath(&buf, a.Vertices, true)
+		buf.WriteString(pathOperator(w, fill != "") + "\n")
+
+	case "Ink":
+		fmt.Fprintf(&buf, "%s %.2f w 1 J 1 j\n", a.strokeColorOperator(0), w)
+		for _, path := range a.InkList {
+			writePath(&buf, path, false)
+			buf.WriteString(pathOperator(w, false) + "\n")
+		}
+
+	case "Highlight":
+		// Multiply the highlight color with the page content underneath.
+		resources.Insert("ExtGState", Dict(map[string]Object{
+			"GS0": Dict(map[string]Object{"Type": Name("ExtGState"), "BM": Name("Multiply")}),
+		}))
+		c := a.Color
+		if len(c) == 0 {
+			c = []float64{1, 1, 0}
+		}
+		fmt.Fprintf(&buf, "/GS0 gs %s\n", colorOperator(c, false))
+		a.writeTextMarkup(&buf, xRefTable.numberArray(d["QuadPoints"]))
+
+	case "Underline", "StrikeOut", "Squiggly":
+		fmt.Fprintf(&buf, "%s 1 J 1 j\n", a.strokeColorOperator(0))
+		a.writeTextMarkup(&buf, xRefTable.numberArray(d["QuadPoints"]))
+
+	case "FreeText":
+		if len(a.Color) > 0 {
+			fmt.Fprintf(&buf, "%s %.2f %.2f %.2f %.2f re f\n", colorOperator(a.Color, false), r.LL.X, r.LL.Y, r.Width(), r.Height())
+		}
+		if w > 0 {
+			fmt.Fprintf(&buf, "0 G %.2f w %.2f %.2f %.2f %.2f re S\n", w, r.LL.X+w/2, r.LL.Y+w/2, r.Width()-w, r.Height()-w)
+		}
+
+		font := parseDA(xRefTable.text(d["DA"]))
+		font.baseFont = "Helvetica"
+
+		fd := NewDict()
+		fd.InsertName("Type", "Font")
+		fd.InsertName("Subtype", "Type1")
+		fd.InsertName("BaseFont", font.baseFont)
+		fd.InsertName("Encoding", "WinAnsiEncoding")
+		resources.Insert("Font", Dict(map[string]Object{font.name: fd}))
+
+		bb, _ := winAnsiBytes(a.Contents)
+		padding := 2 + w
+		leading := font.size * 1.15
+		fmt.Fprintf(&buf, "%.2f %.2f %.2f %.2f re W n\n", r.LL.X+w, r.LL.Y+w, r.Width()-2*w, r.Height()-2*w)
+		for i, line := range font.wrapText(bb, font.size, r.Width()-2*padding) {
+			y := r.UR.Y - padding - font.size - float64(i)*leading
+			fmt.Fprintf(&buf, "BT /%s %.2f Tf %s %.2f %.2f Td (%s) Tj ET\n",
+				font.name, font.size, font.color, r.LL.X+padding, y, escapedText(line))
+		}
+
+	default:
+		return nil, nil
+	}
+
+	return buf.Bytes(), resources
+}
+
+// addAnnotationAppearance adds a normal appearance for a to the annotation dict d with rectangle r,
+// see 12.5.5 Appearance Streams. The appearance uses the coordinates of the page.
+func (xRefTable *XRefTable) addAnnotationAppearance(a Annotation, d Dict, r *Rectangle) error {
+
+	b, resources := xRefTable.appearanceContent(a, d, r)
+	if b == nil {
+		return nil
+	}
+
+	sd := &StreamDict{
+		Dict: Dict(
+			map[string]Object{
+				"Type":      Name("XObject"),
+				"Subtype":   Name("Form"),
+				"BBox":      r.Array(),
+				"Resources": resources,
+			},
+		),
+		Content:        b,
+		FilterPipeline: []PDFFilter{{Name: filter.Flate, DecodeParms: nil}},
+	}
+	sd.InsertName("Filter", filter.Flate)
+
+	if err := encodeStream(sd); err != nil {
+		return err
+	}
+
+	ir, err := xRefTable.IndRefForNewObject(*sd)
+	if err != nil {
+		return err
+	}
+
+	d.Insert("AP", Dict(map[string]Object{"N": *ir}))
+
+	return nil
+}
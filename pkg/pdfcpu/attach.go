@@ -17,8 +17,8 @@ limitations under the License.
 package pdfcpu
 
 import (
-	"io/ioutil"
-	"os"
+	"bytes"
+	"io"
 	"path/filepath"
 
 	"github.com/denisbetsi/pdfcpu/pkg/filter"
@@ -86,29 +86,33 @@ func decodedFileSpecStreamDict(xRefTable *XRefTable, fileName string, o Object)
 	return sd, nil
 }
 
-func extractAttachedFiles(ctx *Context, files StringSet) error {
+// Attachment represents an embedded file.
+type Attachment struct {
+	io.Reader
+	FileName string
+}
+
+func extractAttachedFiles(ctx *Context, files StringSet) ([]Attachment, error) {
 
-	writeFile := func(xRefTable *XRefTable, fileName string, o Object) error {
+	aa := []Attachment{}
 
-		path := ctx.Write.DirName + "/" + fileName
+	extractFile := func(xRefTable *XRefTable, fileName string, o Object) error {
 
-		log.Debug.Printf("writeFile begin: %s\n", path)
+		log.Debug.Printf("extractFile begin: %s\n", fileName)
 
 		sd, err := decodedFileSpecStreamDict(xRefTable, fileName, o)
 		if err != nil {
 			return err
 		}
 
-		log.Info.Printf("writing %s\n", path)
-
-		// TODO Refactor into returning only stream object numbers for files to be extracted.
-		// No writing to file in library!
-		err = ioutil.WriteFile(path, sd.Content, os.ModePerm)
-		if err != nil {
-			return err
+		if sd == nil {
+			log.Info.Printf("extractAttachedFiles: skipping %s\n", fileName)
+			return nil
 		}
 
-		log.Debug.Printf("writeFile end: %s \n", path)
+		aa = append(aa, Attachment{Reader: bytes.NewReader(sd.Content), FileName: fileName})
+
+		log.Debug.Printf("extractFile end: %s \n", fileName)
 
 		return nil
 	}
@@ -123,18 +127,22 @@ func extractAttachedFiles(ctx *Context, files StringSet) error {
 				continue
 			}
 
-			err := writeFile(ctx.XRefTable, fileName, v)
+			err := extractFile(ctx.XRefTable, fileName, v)
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 		}
 
-		return nil
+		return aa, nil
 	}
 
 	// Extract all files.
-	return ctx.Names["EmbeddedFiles"].Process(ctx.XRefTable, writeFile)
+	if err := ctx.Names["EmbeddedFiles"].Process(ctx.XRefTable, extractFile); err != nil {
+		return nil, err
+	}
+
+	return aa, nil
 }
 
 func fileSpectDict(xRefTable *XRefTable, filename string) (*IndirectRef, error) {
@@ -274,31 +282,30 @@ func AttachList(xRefTable *XRefTable) (list []string, err error) {
 	return list, nil
 }
 
-// AttachExtract exports specified embedded files.
-// If no files specified extract all embedded files.
-func AttachExtract(ctx *Context, files StringSet) (err error) {
+// AttachExtract returns specified embedded files.
+// If no files specified return all embedded files.
+func AttachExtract(ctx *Context, files StringSet) ([]Attachment, error) {
 
 	log.Debug.Println("Extract begin")
 
 	if !ctx.Valid {
-		err = ctx.LocateNameTree("EmbeddedFiles", false)
-		if err != nil {
-			return err
+		if err := ctx.LocateNameTree("EmbeddedFiles", false); err != nil {
+			return nil, err
 		}
 	}
 
 	if ctx.Names["EmbeddedFiles"] == nil {
-		return errors.Errorf("no attachments available.")
+		return nil, errors.Errorf("no attachments available.")
 	}
 
-	err = extractAttachedFiles(ctx, files)
+	aa, err := extractAttachedFiles(ctx, files)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	log.Debug.Println("Extract end")
 
-	return nil
+	return aa, nil
 }
 
 // AttachAdd embeds specified files.
@@ -0,0 +1,1096 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	"github.com/denisbetsi/pdfcpu/pkg/pdfcpu/fonts/metrics"
+	"github.com/pkg/errors"
+)
+
+// This file implements the detection of the bounding box of the visible content of a page.
+// The content stream is tokenized and interpreted just far enough
+// to keep track of the current transformation matrix, the clipping path,
+// the fill and stroke colors and the text state, see 8.2 Graphics Objects and 9 Text.
+// Glyph extents are approximated using the font widths and a generic ascent and descent.
+
+const maxFormNesting = 16
+
+type contentTokenKind int
+
+const (
+	ctNumber contentTokenKind = iota
+	ctName
+	ctString
+	ctArray
+	ctDict
+	ctOther
+	ctOperator
+	ctArrayEnd
+	ctDictEnd
+)
+
+type contentToken struct {
+	kind contentTokenKind
+	s    string  // operator, name or decoded string
+	f    float64 // number
+	a    []contentToken
+}
+
+// contentLexer splits a content stream into tokens.
+type contentLexer struct {
+	b   []byte
+	pos int
+}
+
+func isContentDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+func isContentWhitespace(c byte) bool {
+	return c == 0x00 || c == 0x09 || c == 0x0A || c == 0x0C || c == 0x0D || c == 0x20
+}
+
+func (l *contentLexer) skipWhitespaceAndComments() {
+	for l.pos < len(l.b) {
+		c := l.b[l.pos]
+		if isContentWhitespace(c) {
+			l.pos++
+			continue
+		}
+		if c != '%' {
+			return
+		}
+		for l.pos < len(l.b) && l.b[l.pos] != 0x0A && l.b[l.pos] != 0x0D {
+			l.pos++
+		}
+	}
+}
+
+func (l *contentLexer) regular() string {
+	i := l.pos
+	for l.pos < len(l.b) && !isContentWhitespace(l.b[l.pos]) && !isContentDelimiter(l.b[l.pos]) {
+		l.pos++
+	}
+	return string(l.b[i:l.pos])
+}
+
+func (l *contentLexer) literalString() string {
+	var buf bytes.Buffer
+	depth := 1
+	l.pos++
+	for l.pos < len(l.b) {
+		c := l.b[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return buf.String()
+			}
+		case '\\':
+			if l.pos == len(l.b) {
+				return buf.String()
+			}
+			c = l.b[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case 0x0D:
+				if l.pos < len(l.b) && l.b[l.pos] == 0x0A {
+					l.pos++
+				}
+				continue
+			case 0x0A:
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					v := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.b) && l.b[l.pos] >= '0' && l.b[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.b[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				}
+			}
+		}
+		buf.WriteByte(c)
+	}
+	return buf.String()
+}
+
+func (l *contentLexer) hexString() string {
+	var buf bytes.Buffer
+	l.pos++
+	hi, odd := byte(0), false
+	for l.pos < len(l.b) {
+		c := l.b[l.pos]
+		l.pos++
+		if c == '>' {
+			break
+		}
+		var v byte
+		switch {
+		case c >= '0' && c <= '9':
+			v = c - '0'
+		case c >= 'a' && c <= 'f':
+			v = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			v = c - 'A' + 10
+		default:
+			continue
+		}
+		if odd {
+			buf.WriteByte(hi<<4 | v)
+		} else {
+			hi = v
+		}
+		odd = !odd
+	}
+	if odd {
+		buf.WriteByte(hi << 4)
+	}
+	return buf.String()
+}
+
+// next returns the next token or false at the end of the stream.
+func (l *contentLexer) next() (contentToken, bool) {
+
+	l.skipWhitespaceAndComments()
+	if l.pos >= len(l.b) {
+		return contentToken{}, false
+	}
+
+	c := l.b[l.pos]
+
+	switch c {
+
+	case '(':
+		return contentToken{kind: ctString, s: l.literalString()}, true
+
+	case '<':
+		if l.pos+1 < len(l.b) && l.b[l.pos+1] == '<' {
+			l.pos += 2
+			// Dict contents are not needed, just consume them.
+			for {
+				t, ok := l.next()
+				if !ok || t.kind == ctDictEnd {
+					return contentToken{kind: ctDict}, true
+				}
+			}
+		}
+		return contentToken{kind: ctString, s: l.hexString()}, true
+
+	case '>':
+		l.pos++
+		if l.pos < len(l.b) && l.b[l.pos] == '>' {
+			l.pos++
+		}
+		return contentToken{kind: ctDictEnd}, true
+
+	case '[':
+		l.pos++
+		t := contentToken{kind: ctArray}
+		for {
+			t1, ok := l.next()
+			if !ok || t1.kind == ctArrayEnd {
+				return t, true
+			}
+			t.a = append(t.a, t1)
+		}
+
+	case ']':
+		l.pos++
+		return contentToken{kind: ctArrayEnd}, true
+
+	case '/':
+		l.pos++
+		return contentToken{kind: ctName, s: l.regular()}, true
+
+	case '{', '}', ')':
+		l.pos++
+		return contentToken{kind: ctOther}, true
+	}
+
+	s := l.regular()
+
+	if c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9') {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return contentToken{kind: ctOther}, true
+		}
+		return contentToken{kind: ctNumber, f: f}, true
+	}
+
+	if s == "true" || s == "false" || s == "null" {
+		return contentToken{kind: ctOther, s: s}, true
+	}
+
+	return contentToken{kind: ctOperator, s: s}, true
+}
+
+// skipInlineImageData skips the data of an inline image up to and including the EI operator.
+func (l *contentLexer) skipInlineImageData() {
+	// A single white-space character follows the ID operator.
+	l.pos++
+	for ; l.pos+1 < len(l.b); l.pos++ {
+		if l.b[l.pos] != 'E' || l.b[l.pos+1] != 'I' {
+			continue
+		}
+		if l.pos > 0 && !isContentWhitespace(l.b[l.pos-1]) {
+			continue
+		}
+		if l.pos+2 < len(l.b) && !isContentWhitespace(l.b[l.pos+2]) {
+			continue
+		}
+		l.pos += 2
+		return
+	}
+	l.pos = len(l.b)
+}
+
+// transform returns p transformed by m.
+func (m matrix) transform(x, y float64) (float64, float64) {
+	return x*m[0][0] + y*m[1][0] + m[2][0], x*m[0][1] + y*m[1][1] + m[2][1]
+}
+
+// transformRect returns the bounding box of r transformed by m.
+func (m matrix) transformRect(llx, lly, urx, ury float64) *Rectangle {
+	var r *Rectangle
+	for _, p := range [][2]float64{{llx, lly}, {urx, lly}, {urx, ury}, {llx, ury}} {
+		x, y := m.transform(p[0], p[1])
+		r = extendRect(r, x, y)
+	}
+	return r
+}
+
+// scale returns the average scaling factor of m.
+func (m matrix) scale() float64 {
+	return math.Sqrt(math.Abs(m[0][0]*m[1][1] - m[0][1]*m[1][0]))
+}
+
+func newMatrix(o []contentToken) matrix {
+	m := identMatrix
+	m[0][0], m[0][1] = o[0].f, o[1].f
+	m[1][0], m[1][1] = o[2].f, o[3].f
+	m[2][0], m[2][1] = o[4].f, o[5].f
+	return m
+}
+
+func translationMatrix(tx, ty float64) matrix {
+	m := identMatrix
+	m[2][0], m[2][1] = tx, ty
+	return m
+}
+
+// extendRect returns r extended by the point x, y.
+func extendRect(r *Rectangle, x, y float64) *Rectangle {
+	if r == nil {
+		return Rect(x, y, x, y)
+	}
+	r.LL.X, r.LL.Y = math.Min(r.LL.X, x), math.Min(r.LL.Y, y)
+	r.UR.X, r.UR.Y = math.Max(r.UR.X, x), math.Max(r.UR.Y, y)
+	return r
+}
+
+// union returns the smallest rectangle containing r1 and r2.
+func union(r1, r2 *Rectangle) *Rectangle {
+	if r1 == nil {
+		return Rect(r2.LL.X, r2.LL.Y, r2.UR.X, r2.UR.Y)
+	}
+	r1 = extendRect(r1, r2.LL.X, r2.LL.Y)
+	return extendRect(r1, r2.UR.X, r2.UR.Y)
+}
+
+// bboxFont holds the font metrics needed for estimating glyph extents.
+type bboxFont struct {
+	twoByte   bool
+	firstChar int
+	widths    []float64
+	cidWidths map[int]float64
+	dw        float64 // default width
+	scale     float64 // glyph space to text space
+	ascent    float64
+	descent   float64
+}
+
+func (f *bboxFont) width(c int) float64 {
+	if f.twoByte {
+		if w, ok := f.cidWidths[c]; ok {
+			return w * f.scale
+		}
+		return f.dw * f.scale
+	}
+	if i := c - f.firstChar; i >= 0 && i < len(f.widths) {
+		return f.widths[i] * f.scale
+	}
+	return f.dw * f.scale
+}
+
+func numbers(xRefTable *XRefTable, a Array) []float64 {
+	ff := []float64{}
+	for _, o := range a {
+		f, err := xRefTable.DereferenceNumber(o)
+		if err != nil {
+			f = 0
+		}
+		ff = append(ff, f)
+	}
+	return ff
+}
+
+// cidWidths parses the W array of a CIDFont, see 9.7.4.3 Glyph Metrics in CIDFonts.
+func cidWidths(xRefTable *XRefTable, a Array) map[int]float64 {
+	m := map[int]float64{}
+	for i := 0; i+1 < len(a); {
+		c, err := xRefTable.DereferenceNumber(a[i])
+		if err != nil {
+			return m
+		}
+		o, _ := xRefTable.Dereference(a[i+1])
+		if ws, ok := o.(Array); ok {
+			for j, w := range numbers(xRefTable, ws) {
+				m[int(c)+j] = w
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= len(a) {
+			return m
+		}
+		ff := numbers(xRefTable, a[i+1:i+3])
+		for j := int(c); j <= int(ff[0]) && j-int(c) < 0xFFFF; j++ {
+			m[j] = ff[1]
+		}
+		i += 3
+	}
+	return m
+}
+
+func newBBoxFont(xRefTable *XRefTable, d Dict) *bboxFont {
+
+	f := &bboxFont{dw: 500, scale: 0.001, ascent: 0.8, descent: -0.2}
+
+	fd := d
+	subType := d.Subtype()
+
+	switch {
+
+	case subType != nil && *subType == "Type0":
+		f.twoByte = true
+		f.dw = 1000
+		a, err := xRefTable.DereferenceArray(d["DescendantFonts"])
+		if err != nil || len(a) == 0 {
+			return f
+		}
+		if fd, err = xRefTable.DereferenceDict(a[0]); err != nil || fd == nil {
+			return f
+		}
+		if o, found := fd.Find("DW"); found {
+			if w, err := xRefTable.DereferenceNumber(o); err == nil {
+				f.dw = w
+			}
+		}
+		if a, err := xRefTable.DereferenceArray(fd["W"]); err == nil && a != nil {
+			f.cidWidths = cidWidths(xRefTable, a)
+		}
+
+	default:
+		if subType != nil && *subType == "Type3" {
+			if a, err := xRefTable.DereferenceArray(d["FontMatrix"]); err == nil && len(a) == 6 {
+				f.scale = numbers(xRefTable, a)[0]
+				f.dw *= 0.001 / f.scale
+			}
+		}
+		if a, err := xRefTable.DereferenceArray(d["Widths"]); err == nil && a != nil {
+			f.widths = numbers(xRefTable, a)
+			if fc := d.IntEntry("FirstChar"); fc != nil {
+				f.firstChar = *fc
+			}
+			break
+		}
+		// The widths of the standard 14 fonts may be omitted.
+		if bf := d.NameEntry("BaseFont"); bf != nil && MemberOf(*bf, metrics.FontNames()) {
+			f.widths = make([]float64, 256)
+			for i := range f.widths {
+				f.widths[i] = float64(metrics.CharWidth(*bf, i))
+			}
+		}
+	}
+
+	if desc, err := xRefTable.DereferenceDict(fd["FontDescriptor"]); err == nil && desc != nil {
+		if o, found := desc.Find("Ascent"); found {
+			if v, err := xRefTable.DereferenceNumber(o); err == nil && v > 0 {
+				f.ascent = v / 1000
+			}
+		}
+		if o, found := desc.Find("Descent"); found {
+			if v, err := xRefTable.DereferenceNumber(o); err == nil && v < 0 {
+				f.descent = v / 1000
+			}
+		}
+	}
+
+	return f
+}
+
+type colorModel int
+
+const (
+	colorUnknown colorModel = iota
+	colorGray
+	colorRGB
+	colorCMYK
+)
+
+// bboxGState holds the parts of the graphics state relevant for bounding box detection.
+type bboxGState struct {
+	ctm         matrix
+	clip        *Rectangle // nil means unclipped
+	lineWidth   float64
+	fillModel   colorModel
+	strokeModel colorModel
+	fillWhite   bool
+	strokeWhite bool
+
+	// text state
+	font        *bboxFont
+	fontSize    float64
+	charSpacing float64
+	wordSpacing float64
+	hScale      float64
+	leading     float64
+	rise        float64
+	renderMode  int
+}
+
+// bboxWalker accumulates the bounding box of painted content in default user space.
+type bboxWalker struct {
+	xRefTable *XRefTable
+	pageBox   *Rectangle
+	bb        *Rectangle
+	gs        bboxGState
+	stack     []bboxGState
+	path      *Rectangle // bounding box of the current path in default user space
+	clipNext  bool       // W or W* pending
+	tm, tlm   matrix
+	fonts     map[string]*bboxFont
+	depth     int
+
+	// ignoreImage optionally filters out image XObjects not to be taken into account.
+	ignoreImage func(sd *StreamDict, objNr int) bool
+}
+
+func newBBoxWalker(xRefTable *XRefTable, pageBox *Rectangle) *bboxWalker {
+	return &bboxWalker{
+		xRefTable: xRefTable,
+		pageBox:   pageBox,
+		gs:        bboxGState{ctm: identMatrix, lineWidth: 1, hScale: 1, fillModel: colorGray, strokeModel: colorGray},
+		fonts:     map[string]*bboxFont{},
+	}
+}
+
+// add extends the bounding box by r clipped to the current clipping path.
+func (w *bboxWalker) add(r *Rectangle) {
+	if r == nil {
+		return
+	}
+	if w.gs.clip != nil {
+		if r = intersection(r, w.gs.clip); r == nil {
+			return
+		}
+	}
+	w.bb = union(w.bb, r)
+}
+
+func (w *bboxWalker) addPathPoint(x, y float64) {
+	x, y = w.gs.ctm.transform(x, y)
+	w.path = extendRect(w.path, x, y)
+}
+
+func (w *bboxWalker) paintPath(fill, stroke bool) {
+
+	if w.path != nil {
+		if stroke && !w.gs.strokeWhite {
+			lw := w.gs.lineWidth * w.gs.ctm.scale() / 2
+			r := w.path
+			w.add(Rect(r.LL.X-lw, r.LL.Y-lw, r.UR.X+lw, r.UR.Y+lw))
+		} else if fill && !w.gs.fillWhite {
+			w.add(w.path)
+		}
+	}
+
+	if w.clipNext {
+		clip := w.path
+		if clip == nil {
+			clip = Rect(0, 0, 0, 0)
+		}
+		if w.gs.clip != nil {
+			if clip = intersection(clip, w.gs.clip); clip == nil {
+				clip = Rect(0, 0, 0, 0)
+			}
+		}
+		w.gs.clip = clip
+		w.clipNext = false
+	}
+
+	w.path = nil
+}
+
+// colorModel returns the color model of a color space for the purpose of detecting white.
+func (w *bboxWalker) colorModel(name string, resDict Dict) colorModel {
+
+	switch name {
+	case "DeviceGray", "CalGray", "G":
+		return colorGray
+	case "DeviceRGB", "CalRGB", "RGB":
+		return colorRGB
+	case "DeviceCMYK", "CMYK":
+		return colorCMYK
+	}
+
+	d, err := w.xRefTable.DereferenceDict(resDict["ColorSpace"])
+	if err != nil || d == nil {
+		return colorUnknown
+	}
+
+	a, err := w.xRefTable.DereferenceArray(d[name])
+	if err != nil || len(a) < 2 {
+		return colorUnknown
+	}
+
+	n, _ := w.xRefTable.Dereference(a[0])
+	cs, _ := n.(Name)
+
+	switch cs {
+	case "CalGray":
+		return colorGray
+	case "CalRGB":
+		return colorRGB
+	case "ICCBased":
+		sd, err := w.xRefTable.DereferenceStreamDict(a[1])
+		if err != nil || sd == nil {
+			return colorUnknown
+		}
+		if i := sd.IntEntry("N"); i != nil {
+			switch *i {
+			case 1:
+				return colorGray
+			case 3:
+				return colorRGB
+			case 4:
+				return colorCMYK
+			}
+		}
+	}
+
+	return colorUnknown
+}
+
+// isWhite returns true if the color components in cm represent white.
+func isWhite(cm colorModel, o []contentToken) bool {
+
+	ff := []float64{}
+	for _, t := range o {
+		if t.kind != ctNumber {
+			// Pattern
+			return false
+		}
+		ff = append(ff, t.f)
+	}
+
+	switch {
+	case cm == colorGray && len(ff) == 1:
+		return ff[0] >= 1
+	case cm == colorRGB && len(ff) == 3:
+		return ff[0] >= 1 && ff[1] >= 1 && ff[2] >= 1
+	case cm == colorCMYK && len(ff) == 4:
+		return ff[0] <= 0 && ff[1] <= 0 && ff[2] <= 0 && ff[3] <= 0
+	}
+
+	return false
+}
+
+func (w *bboxWalker) font(name string, resDict Dict) *bboxFont {
+
+	d, err := w.xRefTable.DereferenceDict(resDict["Font"])
+	if err != nil || d == nil {
+		return nil
+	}
+
+	ir, isIndRef := d[name].(IndirectRef)
+	key := name
+	if isIndRef {
+		key = ir.String()
+	}
+	if f, ok := w.fonts[key]; ok {
+		return f
+	}
+
+	fd, err := w.xRefTable.DereferenceDict(d[name])
+	if err != nil || fd == nil {
+		return nil
+	}
+
+	f := newBBoxFont(w.xRefTable, fd)
+	if isIndRef {
+		w.fonts[key] = f
+	}
+
+	return f
+}
+
+func (w *bboxWalker) showText(o contentToken) {
+
+	gs := &w.gs
+	f := gs.font
+	if f == nil {
+		f = &bboxFont{dw: 500, scale: 0.001, ascent: 0.8, descent: -0.2}
+	}
+
+	tokens := o.a
+	if o.kind == ctString {
+		tokens = []contentToken{o}
+	}
+
+	var x float64
+	minX, maxX := math.Inf(1), math.Inf(-1)
+
+	for _, t := range tokens {
+
+		if t.kind == ctNumber {
+			x -= t.f / 1000 * gs.fontSize * gs.hScale
+			continue
+		}
+
+		if t.kind != ctString {
+			continue
+		}
+
+		s := t.s
+		for i := 0; i < len(s); i++ {
+			c := int(s[i])
+			if f.twoByte && i+1 < len(s) {
+				i++
+				c = c<<8 | int(s[i])
+			}
+			w0 := f.width(c) * gs.fontSize * gs.hScale
+			minX, maxX = math.Min(minX, x), math.Max(maxX, x+w0)
+			tx := w0 + gs.charSpacing*gs.hScale
+			if !f.twoByte && c == 32 {
+				tx += gs.wordSpacing * gs.hScale
+			}
+			x += tx
+		}
+	}
+
+	visible := gs.renderMode != 3 && gs.renderMode != 7
+	switch gs.renderMode {
+	case 0, 4:
+		visible = !gs.fillWhite
+	case 1, 5:
+		visible = !gs.strokeWhite
+	}
+
+	if visible && minX <= maxX {
+		m := w.tm.multiply(gs.ctm)
+		w.add(m.transformRect(minX, gs.rise+f.descent*gs.fontSize, maxX, gs.rise+f.ascent*gs.fontSize))
+	}
+
+	w.tm = translationMatrix(x, 0).multiply(w.tm)
+}
+
+func (w *bboxWalker) nextLine(tx, ty float64) {
+	w.tlm = translationMatrix(tx, ty).multiply(w.tlm)
+	w.tm = w.tlm
+}
+
+func (w *bboxWalker) doXObject(name string, resDict Dict) error {
+
+	d, err := w.xRefTable.DereferenceDict(resDict["XObject"])
+	if err != nil || d == nil {
+		return err
+	}
+
+	sd, err := w.xRefTable.DereferenceStreamDict(d[name])
+	if err != nil || sd == nil {
+		return err
+	}
+
+	subType := sd.Subtype()
+	if subType == nil {
+		return nil
+	}
+
+	switch *subType {
+
+	case "Image":
+		if w.ignoreImage != nil {
+			objNr := 0
+			if ir, ok := d[name].(IndirectRef); ok {
+				objNr = ir.ObjectNumber.Value()
+			}
+			if w.ignoreImage(sd, objNr) {
+				return nil
+			}
+		}
+		w.add(w.gs.ctm.transformRect(0, 0, 1, 1))
+
+	case "Form":
+		if w.depth >= maxFormNesting {
+			return errors.New("bbox: form xobjects nested too deeply")
+		}
+
+		gs := w.gs
+		defer func() { w.gs = gs }()
+
+		if a, err := w.xRefTable.DereferenceArray(sd.Dict["Matrix"]); err == nil && len(a) == 6 {
+			ff := numbers(w.xRefTable, a)
+			var m matrix
+			m[0][0], m[0][1], m[1][0], m[1][1], m[2][0], m[2][1], m[2][2] = ff[0], ff[1], ff[2], ff[3], ff[4], ff[5], 1
+			w.gs.ctm = m.multiply(w.gs.ctm)
+		}
+
+		if a, err := w.xRefTable.DereferenceArray(sd.Dict["BBox"]); err == nil && len(a) == 4 {
+			ff := numbers(w.xRefTable, a)
+			r := w.gs.ctm.transformRect(ff[0], ff[1], ff[2], ff[3])
+			if w.gs.clip != nil {
+				if r = intersection(r, w.gs.clip); r == nil {
+					return nil
+				}
+			}
+			w.gs.clip = r
+		}
+
+		formResDict := resDict
+		if rd, err := w.xRefTable.DereferenceDict(sd.Dict["Resources"]); err == nil && rd != nil {
+			formResDict = rd
+		}
+
+		bb, err := contentStream(w.xRefTable, *sd)
+		if err == errNoContent {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		w.depth++
+		err = w.walk(bb, formResDict)
+		w.depth--
+		return err
+	}
+
+	return nil
+}
+
+func (w *bboxWalker) walk(content []byte, resDict Dict) error {
+
+	l := &contentLexer{b: content}
+	o := []contentToken{}
+
+	// numOperands returns true if there are at least n operands.
+	numOperands := func(n int) bool {
+		if len(o) < n {
+			return false
+		}
+		for _, t := range o[len(o)-n:] {
+			if t.kind != ctNumber {
+				return false
+			}
+		}
+		o = o[len(o)-n:]
+		return true
+	}
+
+	for {
+		t, ok := l.next()
+		if !ok {
+			return nil
+		}
+
+		if t.kind != ctOperator {
+			o = append(o, t)
+			continue
+		}
+
+		gs := &w.gs
+
+		switch t.s {
+
+		// General graphics state
+		case "w":
+			if numOperands(1) {
+				gs.lineWidth = o[0].f
+			}
+
+		// Special graphics state
+		case "q":
+			w.stack = append(w.stack, *gs)
+		case "Q":
+			if n := len(w.stack); n > 0 {
+				w.gs = w.stack[n-1]
+				w.stack = w.stack[:n-1]
+			}
+		case "cm":
+			if numOperands(6) {
+				gs.ctm = newMatrix(o).multiply(gs.ctm)
+			}
+
+		// Path construction
+		case "m", "l":
+			if numOperands(2) {
+				w.addPathPoint(o[0].f, o[1].f)
+			}
+		case "c":
+			if numOperands(6) {
+				for i := 0; i < 6; i += 2 {
+					w.addPathPoint(o[i].f, o[i+1].f)
+				}
+			}
+		case "v", "y":
+			if numOperands(4) {
+				for i := 0; i < 4; i += 2 {
+					w.addPathPoint(o[i].f, o[i+1].f)
+				}
+			}
+		case "re":
+			if numOperands(4) {
+				x, y, wd, h := o[0].f, o[1].f, o[2].f, o[3].f
+				w.addPathPoint(x, y)
+				w.addPathPoint(x+wd, y)
+				w.addPathPoint(x+wd, y+h)
+				w.addPathPoint(x, y+h)
+			}
+
+		// Path painting
+		case "S", "s":
+			w.paintPath(false, true)
+		case "f", "F", "f*":
+			w.paintPath(true, false)
+		case "B", "B*", "b", "b*":
+			w.paintPath(true, true)
+		case "n":
+			w.paintPath(false, false)
+
+		// Clipping paths
+		case "W", "W*":
+			w.clipNext = true
+
+		// Color
+		case "CS":
+			if len(o) > 0 && o[len(o)-1].kind == ctName {
+				gs.strokeModel = w.colorModel(o[len(o)-1].s, resDict)
+			}
+			gs.strokeWhite = false
+		case "cs":
+			if len(o) > 0 && o[len(o)-1].kind == ctName {
+				gs.fillModel = w.colorModel(o[len(o)-1].s, resDict)
+			}
+			gs.fillWhite = false
+		case "SC", "SCN":
+			gs.strokeWhite = isWhite(gs.strokeModel, o)
+		case "sc", "scn":
+			gs.fillWhite = isWhite(gs.fillModel, o)
+		case "G":
+			gs.strokeModel = colorGray
+			gs.strokeWhite = isWhite(colorGray, o)
+		case "g":
+			gs.fillModel = colorGray
+			gs.fillWhite = isWhite(colorGray, o)
+		case "RG":
+			gs.strokeModel = colorRGB
+			gs.strokeWhite = isWhite(colorRGB, o)
+		case "rg":
+			gs.fillModel = colorRGB
+			gs.fillWhite = isWhite(colorRGB, o)
+		case "K":
+			gs.strokeModel = colorCMYK
+			gs.strokeWhite = isWhite(colorCMYK, o)
+		case "k":
+			gs.fillModel = colorCMYK
+			gs.fillWhite = isWhite(colorCMYK, o)
+
+		// Shading
+		case "sh":
+			if gs.clip != nil {
+				w.add(gs.clip)
+			} else {
+				w.add(w.pageBox)
+			}
+
+		// Inline images
+		case "BI":
+			for {
+				t, ok := l.next()
+				if !ok {
+					return nil
+				}
+				if t.kind == ctOperator && t.s == "ID" {
+					break
+				}
+			}
+			l.skipInlineImageData()
+			w.add(gs.ctm.transformRect(0, 0, 1, 1))
+
+		// XObjects
+		case "Do":
+			if len(o) > 0 && o[len(o)-1].kind == ctName {
+				if err := w.doXObject(o[len(o)-1].s, resDict); err != nil {
+					return err
+				}
+			}
+
+		// Text objects
+		case "BT":
+			w.tm, w.tlm = identMatrix, identMatrix
+
+		// Text state
+		case "Tc":
+			if numOperands(1) {
+				gs.charSpacing = o[0].f
+			}
+		case "Tw":
+			if numOperands(1) {
+				gs.wordSpacing = o[0].f
+			}
+		case "Tz":
+			if numOperands(1) {
+				gs.hScale = o[0].f / 100
+			}
+		case "TL":
+			if numOperands(1) {
+				gs.leading = o[0].f
+			}
+		case "Ts":
+			if numOperands(1) {
+				gs.rise = o[0].f
+			}
+		case "Tr":
+			if numOperands(1) {
+				gs.renderMode = int(o[0].f)
+			}
+		case "Tf":
+			if len(o) >= 2 && o[len(o)-2].kind == ctName && o[len(o)-1].kind == ctNumber {
+				gs.font = w.font(o[len(o)-2].s, resDict)
+				gs.fontSize = o[len(o)-1].f
+			}
+
+		// Text positioning
+		case "Td":
+			if numOperands(2) {
+				w.nextLine(o[0].f, o[1].f)
+			}
+		case "TD":
+			if numOperands(2) {
+				gs.leading = -o[1].f
+				w.nextLine(o[0].f, o[1].f)
+			}
+		case "Tm":
+			if numOperands(6) {
+				w.tlm = newMatrix(o)
+				w.tm = w.tlm
+			}
+		case "T*":
+			w.nextLine(0, -gs.leading)
+
+		// Text showing
+		case "Tj", "TJ":
+			if len(o) > 0 {
+				w.showText(o[len(o)-1])
+			}
+		case "'":
+			w.nextLine(0, -gs.leading)
+			if len(o) > 0 {
+				w.showText(o[len(o)-1])
+			}
+		case "\"":
+			if len(o) >= 3 && o[len(o)-3].kind == ctNumber && o[len(o)-2].kind == ctNumber {
+				gs.wordSpacing, gs.charSpacing = o[len(o)-3].f, o[len(o)-2].f
+				w.nextLine(0, -gs.leading)
+				w.showText(o[len(o)-1])
+			}
+		}
+
+		o = o[:0]
+	}
+}
+
+// ContentBoundingBox returns the bounding box of the visible content of page pageNr in default user space
+// clipped to the CropBox in effect or nil for a page without visible content.
+// Text, paths, shadings and images are taken into account, white fills are ignored.
+func ContentBoundingBox(ctx *Context, pageNr int) (*Rectangle, error) {
+	return contentBoundingBox(ctx, pageNr, nil)
+}
+
+func contentBoundingBox(ctx *Context, pageNr int, ignoreImage func(sd *StreamDict, objNr int) bool) (*Rectangle, error) {
+
+	d, inhPAttrs, err := ctx.PageDict(pageNr)
+	if err != nil {
+		return nil, err
+	}
+
+	pageBox := inhPAttrs.cropBox
+	if pageBox == nil {
+		pageBox = inhPAttrs.mediaBox
+	}
+	if pageBox == nil {
+		return nil, errors.Errorf("ContentBoundingBox: page %d: missing MediaBox", pageNr)
+	}
+
+	o, found := d.Find("Contents")
+	if !found {
+		return nil, nil
+	}
+
+	bb, err := contentStream(ctx.XRefTable, o)
+	if err == errNoContent {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	w := newBBoxWalker(ctx.XRefTable, pageBox)
+	w.ignoreImage = ignoreImage
+	if err = w.walk(bb, inhPAttrs.resources); err != nil {
+		return nil, err
+	}
+
+	if w.bb == nil {
+		return nil, nil
+	}
+
+	r := intersection(w.bb, pageBox)
+	log.Debug.Printf("ContentBoundingBox: page %d: %v\n", pageNr, r)
+
+	return r, nil
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"math"
+	"testing"
+)
+
+func doTestContentBBox(content string, want *Rectangle, t *testing.T) {
+	w := newBBoxWalker(&XRefTable{}, Rect(0, 0, 600, 800))
+	if err := w.walk([]byte(content), nil); err != nil {
+		t.Fatalf("walk failed for <%s>: %v\n", content, err)
+	}
+
+	got := w.bb
+	if want == nil || got == nil {
+		if want != got {
+			t.Errorf("content <%s>: want bounding box %v, got %v\n", content, want, got)
+		}
+		return
+	}
+
+	for _, d := range []float64{got.LL.X - want.LL.X, got.LL.Y - want.LL.Y, got.UR.X - want.UR.X, got.UR.Y - want.UR.Y} {
+		if math.Abs(d) > 0.01 {
+			t.Errorf("content <%s>: want bounding box %v, got %v\n", content, want, got)
+			return
+		}
+	}
+}
+
+func TestContentBBox(t *testing.T) {
+
+	doTestContentBBox("", nil, t)
+	doTestContentBBox("100 100 200 50 re n", nil, t)
+	doTestContentBBox("100 100 200 50 re f", Rect(100, 100, 300, 150), t)
+	doTestContentBBox("1 g 0 0 600 800 re f 0 g 100 100 200 50 re f", Rect(100, 100, 300, 150), t)
+	doTestContentBBox("q 2 0 0 2 10 10 cm 0 0 10 10 re f Q 0 0 1 1 re f", Rect(0, 0, 30, 30), t)
+	doTestContentBBox("4 w 100 100 m 200 100 l S", Rect(98, 98, 202, 102), t)
+	doTestContentBBox("q 50 50 100 100 re W n 0 0 600 800 re f Q", Rect(50, 50, 150, 150), t)
+	doTestContentBBox("q 200 0 0 100 50 60 cm /Im0 Do Q", nil, t)
+	doTestContentBBox("q 20 0 0 10 50 60 cm BI /W 1 /H 1 /BPC 8 /CS /G ID \x00 EI Q", Rect(50, 60, 70, 70), t)
+	doTestContentBBox("BT /F1 10 Tf 100 200 Td (ab) Tj ET", Rect(100, 198, 110, 208), t)
+	doTestContentBBox("BT /F1 10 Tf 3 Tr 100 200 Td (ab) Tj ET", nil, t)
+	doTestContentBBox("BT /F1 10 Tf 12 TL 100 200 Td (a) Tj T* [(a) -500 (a)] TJ ET", Rect(100, 186, 115, 208), t)
+}
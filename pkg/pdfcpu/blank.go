@@ -0,0 +1,200 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"image"
+	_ "image/jpeg" // Register the jpeg decoder for DCT encoded images.
+	"io"
+
+	"github.com/denisbetsi/pdfcpu/pkg/filter"
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// DefaultBlankThreshold is the minimum share of white pixels of an image considered near-white.
+const DefaultBlankThreshold = 0.995
+
+// whiteLevel is the minimum luminance of a pixel counting as white.
+const whiteLevel = 0.9
+
+// imageStreamDict returns a copy of sd ready for rendering leaving sd untouched.
+// All filters but the last one defining the image format get decoded.
+func imageStreamDict(sd *StreamDict) (*StreamDict, error) {
+
+	d := NewDict()
+	for k, v := range sd.Dict {
+		d[k] = v
+	}
+
+	sd1 := *sd
+	sd1.Dict = d
+	sd1.Content = nil
+
+	f := ""
+	if n := len(sd.FilterPipeline); n > 0 {
+		f = sd.FilterPipeline[n-1].Name
+
+		if n > 1 {
+			// eg. FlateDecode followed by DCTDecode.
+			sd0 := StreamDict{Raw: sd.Raw, FilterPipeline: sd.FilterPipeline[:n-1]}
+			if err := decodeStream(&sd0); err != nil {
+				return nil, err
+			}
+			sd1.Raw = sd0.Content
+			sd1.FilterPipeline = sd.FilterPipeline[n-1:]
+		}
+	}
+
+	switch f {
+
+	case "", filter.Flate, filter.CCITTFax:
+
+		if im := d.BooleanEntry("ImageMask"); im != nil && *im {
+			if d.IntEntry("BitsPerComponent") == nil {
+				d.Insert("BitsPerComponent", Integer(1))
+			}
+			d.InsertName("ColorSpace", DeviceGrayCS)
+		}
+
+		// CCITTDecoded images sometimes don't have a ColorSpace attribute.
+		if _, found := d.Find("ColorSpace"); !found && f == filter.CCITTFax {
+			d.InsertName("ColorSpace", DeviceGrayCS)
+		}
+
+		if d.IntEntry("BitsPerComponent") == nil || d.IntEntry("Width") == nil || d.IntEntry("Height") == nil {
+			return nil, nil
+		}
+
+		if err := decodeStream(&sd1); err != nil {
+			return nil, err
+		}
+
+	case filter.DCT:
+
+	default:
+		// JPX and JBIG2 encoded images cannot be decoded.
+		return nil, nil
+	}
+
+	return &sd1, nil
+}
+
+// whiteShare returns the share of white pixels of img.
+// Transparent pixels count as white.
+func whiteShare(img image.Image) float64 {
+
+	r := img.Bounds()
+	if r.Empty() {
+		return 1
+	}
+
+	white := 0
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			if a == 0 {
+				white++
+				continue
+			}
+			lum := (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / float64(a)
+			if lum >= whiteLevel {
+				white++
+			}
+		}
+	}
+
+	return float64(white) / float64(r.Dx()*r.Dy())
+}
+
+// nearWhiteImage returns true if the share of white pixels of an image is at least threshold.
+// Images that cannot be decoded are not considered near-white.
+func nearWhiteImage(xRefTable *XRefTable, sd *StreamDict, objNr int, threshold float64) bool {
+
+	sd1, err := imageStreamDict(sd)
+	if err != nil || sd1 == nil {
+		return false
+	}
+
+	var r io.Reader
+	if len(sd1.FilterPipeline) == 0 {
+		r, _, err = renderFlateEncodedImage(xRefTable, sd1, objNr)
+	} else {
+		r, _, err = RenderImage(xRefTable, sd1, objNr)
+	}
+	if err != nil || r == nil {
+		return false
+	}
+
+	img, _, err := image.Decode(r)
+	if err != nil {
+		log.Info.Printf("nearWhiteImage: obj#%d: %v\n", objNr, err)
+		return false
+	}
+
+	return whiteShare(img) >= threshold
+}
+
+// BlankPages returns the numbers of the selected pages that are blank in ascending order.
+// A page is blank if its content draws nothing visible apart from near-white images,
+// eg. the scanned back side of a sheet. An image is near-white if the share of its pixels
+// being white is at least threshold. If no pages are selected all pages are examined.
+func BlankPages(ctx *Context, selectedPages IntSet, threshold float64) ([]int, error) {
+
+	if threshold <= 0 || threshold > 1 {
+		return nil, errors.Errorf("BlankPages: invalid threshold %.3f, please use 0.0 < threshold <= 1.0", threshold)
+	}
+
+	// Images may be shared across pages.
+	cache := map[int]bool{}
+
+	ignoreImage := func(sd *StreamDict, objNr int) bool {
+		if objNr == 0 {
+			return nearWhiteImage(ctx.XRefTable, sd, objNr, threshold)
+		}
+		white, ok := cache[objNr]
+		if !ok {
+			white = nearWhiteImage(ctx.XRefTable, sd, objNr, threshold)
+			cache[objNr] = white
+		}
+		return white
+	}
+
+	pageNrs := sortedPageNrs(selectedPages)
+	if len(selectedPages) == 0 {
+		pageNrs = make([]int, ctx.PageCount)
+		for i := range pageNrs {
+			pageNrs[i] = i + 1
+		}
+	}
+
+	blank := []int{}
+
+	for _, i := range pageNrs {
+		r, err := contentBoundingBox(ctx, i, ignoreImage)
+		if err != nil {
+			return nil, err
+		}
+		if r == nil {
+			log.Info.Printf("BlankPages: page %d is blank\n", i)
+			blank = append(blank, i)
+		}
+	}
+
+	return blank, nil
+}
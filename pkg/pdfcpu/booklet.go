@@ -0,0 +1,307 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var errInvalidBookletConfig = errors.New("Invalid booklet configuration string. Please consult pdfcpu help booklet")
+
+// BindingType defines how the sheets of a booklet get bound.
+type BindingType int
+
+// These are the supported binding types.
+const (
+	SaddleStitch BindingType = iota // Nested sheets folded and stitched along the fold.
+	PerfectBound                    // Stacked sheets cut along the fold and glued along the spine.
+)
+
+func (b BindingType) String() string {
+	switch b {
+	case SaddleStitch:
+		return "saddle"
+	case PerfectBound:
+		return "perfect"
+	}
+	return ""
+}
+
+func parseBindingType(s string) (BindingType, error) {
+	switch strings.ToLower(s) {
+	case "saddle":
+		return SaddleStitch, nil
+	case "perfect":
+		return PerfectBound, nil
+	}
+	return 0, errors.Errorf("unknown binding type: %s, please use one of saddle, perfect", s)
+}
+
+func parseSignatureSize(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, errors.Errorf("signature size: please provide the number of sheets per signature >= 0: %s", s)
+	}
+	return n, nil
+}
+
+// Booklet represents the command details for the command "Booklet".
+type Booklet struct {
+	PageSize    string      // Sheet paper size eg. A4(=default), Letter, see paperSize.go
+	PageDim     *dim        // Sheet dimensions in user units, always used in landscape mode.
+	Binding     BindingType // One of saddle(=default), perfect
+	Signature   int         // Sheets per signature for saddle stitch binding, 0(=default) means one signature.
+	RotateBacks bool        // Rotate back sides by 180 degrees for duplex printers flipping on the long edge.
+	Margin      int         // Cropbox for booklet content.
+	Border      bool        // Draw bounding box.
+}
+
+func (b Booklet) String() string {
+	return fmt.Sprintf("Booklet conf: %s %s, binding=%s, signature=%d, rotateBacks=%t\n",
+		b.PageSize, *b.PageDim, b.Binding, b.Signature, b.RotateBacks)
+}
+
+// DefaultBookletConfig returns the default booklet configuration.
+func DefaultBookletConfig() *Booklet {
+	return &Booklet{
+		PageSize: "A4",
+		PageDim:  PaperSize["A4"],
+		Binding:  SaddleStitch,
+	}
+}
+
+// ParseBookletConfig parses a booklet command string into an internal structure.
+// eg. "f: A3, s: 4" or "t: perfect, r: on"
+func ParseBookletConfig(s string) (*Booklet, error) {
+
+	b := DefaultBookletConfig()
+
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return b, nil
+	}
+
+	var setDim, setFormat bool
+
+	for _, s := range strings.Split(s, ",") {
+
+		ss := strings.Split(s, ":")
+		if len(ss) != 2 {
+			return nil, errInvalidBookletConfig
+		}
+
+		k := strings.TrimSpace(ss[0])
+		v := strings.TrimSpace(ss[1])
+
+		var err error
+
+		switch k {
+		case "f": // sheet format
+			b.PageDim, b.PageSize, err = parsePageFormat(v, setDim)
+			setFormat = true
+
+		case "d": // sheet dimensions
+			b.PageDim, b.PageSize, err = parsePageDim(v, setFormat)
+			setDim = true
+
+		case "t": // binding type
+			b.Binding, err = parseBindingType(v)
+
+		case "s": // signature size
+			b.Signature, err = parseSignatureSize(v)
+
+		case "r": // rotate back sides
+			b.RotateBacks, err = parseBorder(v)
+
+		case "b": // border
+			b.Border, err = parseBorder(v)
+
+		case "m": // margin
+			b.Margin, err = parseMargin(v)
+
+		default:
+			err = errInvalidBookletConfig
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if b.Binding == PerfectBound && b.Signature > 0 {
+		return nil, errors.New("signatures apply to saddle stitch binding only")
+	}
+
+	return b, nil
+}
+
+// bookletPageNrs returns the imposed sequence of pageNrs padded with blank pages to a multiple of 4.
+// Each consecutive pair of entries makes up the left and right half of one sheet side
+// starting with the front side of the first sheet. Blank pages are represented by 0.
+func bookletPageNrs(pageNrs []int, b *Booklet) ([]int, error) {
+
+	if len(pageNrs) == 0 {
+		return nil, errors.New("booklet: no pages selected")
+	}
+
+	pp := append([]int{}, pageNrs...)
+	for len(pp)%4 > 0 {
+		pp = append(pp, 0)
+	}
+
+	seq := []int{}
+
+	if b.Binding == PerfectBound {
+		// Each sheet is cut along the fold into 2 leaves.
+		for i := 0; i < len(pp); i += 4 {
+			seq = append(seq, pp[i], pp[i+2], pp[i+3], pp[i+1])
+		}
+		return seq, nil
+	}
+
+	sigSize := len(pp)
+	if b.Signature > 0 && 4*b.Signature < sigSize {
+		sigSize = 4 * b.Signature
+	}
+
+	for i := 0; i < len(pp); i += sigSize {
+
+		j := i + sigSize
+		if j > len(pp) {
+			j = len(pp)
+		}
+		sig := pp[i:j]
+		n := len(sig)
+
+		// Sheets get nested: the outer sheet carries the first two and last two pages of the signature.
+		for k := 0; k < n/4; k++ {
+			seq = append(seq, sig[n-1-2*k], sig[2*k], sig[2*k+1], sig[n-2-2*k])
+		}
+	}
+
+	return seq, nil
+}
+
+// bookletNUp returns the NUp configuration for the sheets of a booklet.
+func bookletNUp(b *Booklet) *NUp {
+
+	d := *b.PageDim
+	if d.Portrait() {
+		d.w, d.h = d.h, d.w
+	}
+
+	return &NUp{
+		PageSize: b.PageSize,
+		PageDim:  &d,
+		Orient:   RightDown,
+		Grid:     &dim{2, 1},
+		Margin:   b.Margin,
+		Border:   b.Border,
+	}
+}
+
+func bookletSheets(ctx *Context, seq []int, b *Booklet, nup *NUp, pagesDict *Dict, pagesIndRef *IndirectRef) error {
+
+	rr := rectsForGrid(nup)
+
+	for i := 0; i < len(seq); i += 2 {
+
+		var buf bytes.Buffer
+		formsResDict := NewDict()
+
+		back := i/2%2 == 1
+		if back && b.RotateBacks {
+			fmt.Fprintf(&buf, "q -1 0 0 -1 %d %d cm ", nup.PageDim.w, nup.PageDim.h)
+		}
+
+		for j, p := range seq[i : i+2] {
+
+			if p == 0 {
+				// Blank page.
+				continue
+			}
+
+			formIndRef, mediaBox, err := pageForm(ctx, p)
+			if err != nil {
+				return err
+			}
+			if formIndRef == nil {
+				continue
+			}
+
+			formResID := fmt.Sprintf("Fm%d", j)
+			formsResDict.Insert(formResID, *formIndRef)
+
+			nUpTilePDFBytes(&buf, mediaBox, rr[j], formResID, nup)
+		}
+
+		if back && b.RotateBacks {
+			buf.WriteString("Q ")
+		}
+
+		if err := wrapUpPage(ctx, nup, formsResDict, buf, pagesDict, pagesIndRef); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BookletFromPDF creates a booklet version of the PDF represented by xRefTable.
+// Selected pages are imposed 2-up onto both sides of landscape sheets in folding order.
+func BookletFromPDF(ctx *Context, selectedPages IntSet, b *Booklet) error {
+
+	nup := bookletNUp(b)
+	mb := RectForDim(nup.PageDim.w, nup.PageDim.h)
+
+	pagesDict := Dict(
+		map[string]Object{
+			"Type":     Name("Pages"),
+			"Count":    Integer(0),
+			"MediaBox": mb.Array(),
+		},
+	)
+
+	pagesIndRef, err := ctx.IndRefForNewObject(pagesDict)
+	if err != nil {
+		return err
+	}
+
+	seq, err := bookletPageNrs(sortedSelectedPages(selectedPages), b)
+	if err != nil {
+		return err
+	}
+
+	if err = bookletSheets(ctx, seq, b, nup, &pagesDict, pagesIndRef); err != nil {
+		return err
+	}
+
+	// Replace original pagesDict.
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return err
+	}
+
+	rootDict.Update("Pages", *pagesIndRef)
+
+	return nil
+}
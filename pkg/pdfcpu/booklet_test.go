@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"reflect"
+	"testing"
+)
+
+func doTestBookletPageNrs(desc string, pageCount int, want []int, t *testing.T) {
+	b, err := ParseBookletConfig(desc)
+	if err != nil {
+		t.Fatalf("ParseBookletConfig(%s): %v\n", desc, err)
+	}
+
+	pp := []int{}
+	for i := 1; i <= pageCount; i++ {
+		pp = append(pp, i)
+	}
+
+	got, err := bookletPageNrs(pp, b)
+	if err != nil {
+		t.Fatalf("%s, %d pages: %v\n", desc, pageCount, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s, %d pages: want %v, got %v\n", desc, pageCount, want, got)
+	}
+}
+
+func TestBookletPageNrs(t *testing.T) {
+
+	// Saddle stitch: 8 pages on 2 nested sheets.
+	doTestBookletPageNrs("", 8, []int{8, 1, 2, 7, 6, 3, 4, 5}, t)
+
+	// Padding with blank pages.
+	doTestBookletPageNrs("", 5, []int{0, 1, 2, 0, 0, 3, 4, 5}, t)
+
+	// 2 signatures of 1 sheet each.
+	doTestBookletPageNrs("s: 1", 8, []int{4, 1, 2, 3, 8, 5, 6, 7}, t)
+
+	// The last signature may be smaller.
+	doTestBookletPageNrs("s: 2", 12, []int{8, 1, 2, 7, 6, 3, 4, 5, 12, 9, 10, 11}, t)
+
+	// Perfect binding: sheets get cut along the fold.
+	doTestBookletPageNrs("t: perfect", 8, []int{1, 3, 4, 2, 5, 7, 8, 6}, t)
+
+	// No pages selected.
+	if _, err := bookletPageNrs(nil, DefaultBookletConfig()); err == nil {
+		t.Errorf("no pages: want error\n")
+	}
+}
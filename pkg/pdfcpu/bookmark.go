@@ -0,0 +1,135 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Bookmark represents a top level outline item along with the page range it covers.
+type Bookmark struct {
+	Title    string
+	PageFrom int
+	PageThru int
+}
+
+// outlineItemPageNr returns the number of the page an outline item points to or 0.
+func outlineItemPageNr(ctx *Context, d Dict, pageNrs map[int]int) (int, error) {
+
+	o, found := d.Find("Dest")
+	if !found {
+		action, err := ctx.DereferenceDict(d["A"])
+		if err != nil || action == nil {
+			return 0, err
+		}
+		if s := action.NameEntry("S"); s == nil || *s != "GoTo" {
+			return 0, nil
+		}
+		o = action["D"]
+	}
+
+	a, err := ctx.ResolveDestination(o)
+	if err != nil || len(a) == 0 {
+		return 0, err
+	}
+
+	ir, ok := a[0].(IndirectRef)
+	if !ok {
+		return 0, nil
+	}
+
+	return pageNrs[ir.ObjectNumber.Value()], nil
+}
+
+// TopLevelBookmarks returns the bookmarks for ctx's top level outline items in page order.
+// Each bookmark spans the pages up to the next bookmark.
+// Outline items not pointing to a page are skipped
+// as are outline items pointing to the same page as their predecessor.
+func TopLevelBookmarks(ctx *Context) ([]Bookmark, error) {
+
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := ctx.DereferenceDict(rootDict["Outlines"])
+	if err != nil {
+		return nil, err
+	}
+
+	if d == nil {
+		return nil, errors.New("TopLevelBookmarks: no outlines available")
+	}
+
+	irs, err := ctx.PageIndRefs()
+	if err != nil {
+		return nil, err
+	}
+
+	pageNrs := map[int]int{}
+	for i, ir := range irs {
+		pageNrs[ir.ObjectNumber.Value()] = i + 1
+	}
+
+	bms := []Bookmark{}
+
+	for ir := d.IndirectRefEntry("First"); ir != nil; ir = d.IndirectRefEntry("Next") {
+
+		if d, err = ctx.DereferenceDict(*ir); err != nil {
+			return nil, err
+		}
+
+		pageNr, err := outlineItemPageNr(ctx, d, pageNrs)
+		if err != nil {
+			return nil, err
+		}
+
+		if pageNr == 0 {
+			continue
+		}
+
+		title, err := ctx.DereferenceText(d["Title"])
+		if err != nil {
+			return nil, err
+		}
+
+		bms = append(bms, Bookmark{Title: title, PageFrom: pageNr})
+	}
+
+	sort.SliceStable(bms, func(i, j int) bool { return bms[i].PageFrom < bms[j].PageFrom })
+
+	// Get rid of bookmarks sharing their first page with their predecessor.
+	bb := []Bookmark{}
+	for _, bm := range bms {
+		if len(bb) > 0 && bb[len(bb)-1].PageFrom == bm.PageFrom {
+			continue
+		}
+		bb = append(bb, bm)
+	}
+
+	for i := range bb {
+		if i == len(bb)-1 {
+			bb[i].PageThru = len(irs)
+			break
+		}
+		bb[i].PageThru = bb[i+1].PageFrom - 1
+	}
+
+	return bb, nil
+}
@@ -0,0 +1,475 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// The page boundaries, see 14.11.2 Page Boundaries.
+var boxNames = []string{"MediaBox", "CropBox", "TrimBox", "BleedBox", "ArtBox"}
+
+var errInvalidBoxConfig = errors.New("Invalid box configuration string. Please consult pdfcpu help boxes")
+
+// boxName returns the box name for a box type like trim, t or TrimBox.
+func boxName(s string) (string, error) {
+
+	s = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "box")
+
+	if s == "" {
+		return "", errInvalidBoxConfig
+	}
+
+	for _, bn := range boxNames {
+		n := strings.ToLower(strings.TrimSuffix(bn, "Box"))
+		if s == n || s == n[:1] {
+			return bn, nil
+		}
+	}
+
+	return "", errors.Errorf("unknown box type: %s", s)
+}
+
+// ParseBoxTypes parses a comma separated list of box types like "trim,bleed" or "t,b".
+func ParseBoxTypes(s string) ([]string, error) {
+
+	if s == "" {
+		return nil, nil
+	}
+
+	var bb []string
+
+	for _, s := range strings.Split(s, ",") {
+		bn, err := boxName(s)
+		if err != nil {
+			return nil, err
+		}
+		if !MemberOf(bn, bb) {
+			bb = append(bb, bn)
+		}
+	}
+
+	return bb, nil
+}
+
+// margin represents the distance of a box edge to the corresponding edge of the MediaBox.
+type margin struct {
+	v   float64
+	rel bool // v is a fraction of the MediaBox width or height.
+}
+
+func (m margin) value(length float64) float64 {
+	if m.rel {
+		return m.v * length
+	}
+	return m.v
+}
+
+// Box represents the specification of a page boundary.
+// A box is defined by exactly one of:
+// an absolute rectangle,
+// a paper size centered within the MediaBox
+// or margins relative to the MediaBox.
+type Box struct {
+	Rect      *Rectangle
+	PaperSize string
+	dim       *dim
+	margins   []margin // top, right, bottom, left
+}
+
+func (b Box) String() string {
+
+	if b.Rect != nil {
+		return fmt.Sprintf("%s", b.Rect)
+	}
+
+	if b.dim != nil {
+		return fmt.Sprintf("%s (%s)", b.PaperSize, *b.dim)
+	}
+
+	ss := []string{}
+	for _, m := range b.margins {
+		if m.rel {
+			ss = append(ss, fmt.Sprintf("%.2f%%", m.v*100))
+			continue
+		}
+		ss = append(ss, fmt.Sprintf("%.2f", m.v))
+	}
+
+	return "margins: " + strings.Join(ss, " ")
+}
+
+// PageBoundaries represents a set of page boundaries for setting.
+type PageBoundaries map[string]*Box
+
+func parseBoxRect(s string) (*Rectangle, error) {
+
+	ss := strings.Fields(strings.Trim(s, "[]"))
+	if len(ss) != 4 {
+		return nil, errors.Errorf("invalid box rectangle: %s", s)
+	}
+
+	f := make([]float64, 4)
+	for i, s := range ss {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid box rectangle: %s", s)
+		}
+		f[i] = v
+	}
+
+	r := Rect(math.Min(f[0], f[2]), math.Min(f[1], f[3]), math.Max(f[0], f[2]), math.Max(f[1], f[3]))
+	if r.Width() == 0 || r.Height() == 0 {
+		return nil, errors.Errorf("invalid box rectangle: %s", s)
+	}
+
+	return r, nil
+}
+
+func parseMargins(s string) ([]margin, error) {
+
+	ss := strings.Fields(s)
+	if len(ss) != 1 && len(ss) != 2 && len(ss) != 4 {
+		return nil, errors.Errorf("margins: please provide 1, 2 or 4 values: %s", s)
+	}
+
+	mm := []margin{}
+
+	for _, s := range ss {
+		var m margin
+		if strings.HasSuffix(s, "%") {
+			s = strings.TrimSuffix(s, "%")
+			m.rel = true
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid margin: %s", s)
+		}
+		if m.rel {
+			if v <= -50 || v >= 50 {
+				return nil, errors.Errorf("invalid relative margin: %s%%, must be within -50 and 50", s)
+			}
+			v /= 100
+		}
+		m.v = v
+		mm = append(mm, m)
+	}
+
+	// Expand to top, right, bottom, left.
+	switch len(mm) {
+	case 1:
+		mm = []margin{mm[0], mm[0], mm[0], mm[0]}
+	case 2:
+		mm = []margin{mm[0], mm[1], mm[0], mm[1]}
+	}
+
+	return mm, nil
+}
+
+func parseBox(s string) (*Box, error) {
+
+	s = strings.TrimSpace(s)
+
+	if s == "" {
+		return nil, errInvalidBoxConfig
+	}
+
+	if strings.HasPrefix(s, "[") {
+		r, err := parseBoxRect(s)
+		if err != nil {
+			return nil, err
+		}
+		return &Box{Rect: r}, nil
+	}
+
+	if c := s[0]; c < '0' || c > '9' {
+		if c != '-' && c != '.' {
+			d, v, err := parsePageFormat(s, false)
+			if err != nil {
+				return nil, err
+			}
+			return &Box{PaperSize: v, dim: d}, nil
+		}
+	}
+
+	mm, err := parseMargins(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Box{margins: mm}, nil
+}
+
+// ParsePageBoundaries parses a box configuration string into an internal structure.
+// eg. "trim: 10, bleed: 5%, art: [20 20 575 822]" or "t: A4, b: 10 20"
+func ParsePageBoundaries(s string) (PageBoundaries, error) {
+
+	if s == "" {
+		return nil, errInvalidBoxConfig
+	}
+
+	pb := PageBoundaries{}
+
+	for _, s := range strings.Split(s, ",") {
+
+		ss := strings.SplitN(s, ":", 2)
+		if len(ss) != 2 {
+			return nil, errInvalidBoxConfig
+		}
+
+		bn, err := boxName(ss[0])
+		if err != nil {
+			return nil, err
+		}
+
+		if pb[bn] != nil {
+			return nil, errors.Errorf("duplicate box type: %s", bn)
+		}
+
+		if pb[bn], err = parseBox(ss[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	return pb, nil
+}
+
+// rect returns the rectangle for b relative to mediaBox.
+func (b Box) rect(mediaBox *Rectangle) *Rectangle {
+
+	if b.Rect != nil {
+		return b.Rect
+	}
+
+	if b.dim != nil {
+		c := mediaBox.Center()
+		w, h := float64(b.dim.w), float64(b.dim.h)
+		return Rect(c.X-w/2, c.Y-h/2, c.X+w/2, c.Y+h/2)
+	}
+
+	w, h := mediaBox.Width(), mediaBox.Height()
+	top, right, bottom, left := b.margins[0], b.margins[1], b.margins[2], b.margins[3]
+
+	return Rect(
+		mediaBox.LL.X+left.value(w),
+		mediaBox.LL.Y+bottom.value(h),
+		mediaBox.UR.X-right.value(w),
+		mediaBox.UR.Y-top.value(h),
+	)
+}
+
+// intersection returns the intersection of r1 and r2 or nil.
+func intersection(r1, r2 *Rectangle) *Rectangle {
+
+	r := Rect(
+		math.Max(r1.LL.X, r2.LL.X),
+		math.Max(r1.LL.Y, r2.LL.Y),
+		math.Min(r1.UR.X, r2.UR.X),
+		math.Min(r1.UR.Y, r2.UR.Y),
+	)
+
+	if r.Width() <= 0 || r.Height() <= 0 {
+		return nil
+	}
+
+	return r
+}
+
+func addPageBoxes(xRefTable *XRefTable, pageNr int, pb PageBoundaries) error {
+
+	d, inhPAttrs, err := xRefTable.PageDict(pageNr)
+	if err != nil {
+		return err
+	}
+
+	mediaBox := inhPAttrs.mediaBox
+	if mediaBox == nil {
+		return errors.Errorf("addPageBoxes: page %d: missing MediaBox", pageNr)
+	}
+
+	for _, bn := range boxNames {
+
+		b := pb[bn]
+		if b == nil {
+			continue
+		}
+
+		r := b.rect(mediaBox)
+
+		if bn == "MediaBox" {
+			if r.Width() <= 0 || r.Height() <= 0 {
+				return errors.Errorf("addPageBoxes: page %d: invalid MediaBox %s", pageNr, b)
+			}
+		} else {
+			// All other boxes are clipped to the MediaBox.
+			if r = intersection(r, mediaBox); r == nil {
+				return errors.Errorf("addPageBoxes: page %d: %s %s outside of MediaBox", pageNr, bn, b)
+			}
+		}
+
+		log.Debug.Printf("addPageBoxes: page %d: %s = %s\n", pageNr, bn, r)
+
+		d.Update(bn, r.Array())
+
+		if bn == "MediaBox" {
+			mediaBox = r
+		}
+	}
+
+	return nil
+}
+
+// AddBoxes sets page boundaries for selected pages.
+// Boxes specified by margins or paper size are positioned relative to the MediaBox in effect.
+func AddBoxes(ctx *Context, selectedPages IntSet, pb PageBoundaries) error {
+
+	for _, k := range sortedPageNrs(selectedPages) {
+		if err := addPageBoxes(ctx.XRefTable, k, pb); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveBoxes removes page boundaries from selected pages.
+// Removed boxes fall back to their default, see 14.11.2 Page Boundaries.
+func RemoveBoxes(ctx *Context, selectedPages IntSet, boxes []string) error {
+
+	if MemberOf("MediaBox", boxes) {
+		return errors.New("RemoveBoxes: MediaBox is required and may not be removed")
+	}
+
+	for _, k := range sortedPageNrs(selectedPages) {
+
+		d, _, err := ctx.PageDict(k)
+		if err != nil {
+			return err
+		}
+
+		for _, bn := range boxes {
+			d.Delete(bn)
+		}
+	}
+
+	return nil
+}
+
+func sortedPageNrs(selectedPages IntSet) []int {
+
+	pp := []int{}
+	for k, v := range selectedPages {
+		if v {
+			pp = append(pp, k)
+		}
+	}
+	sort.Ints(pp)
+
+	return pp
+}
+
+func pageBox(xRefTable *XRefTable, d Dict, bn string) (*Rectangle, error) {
+
+	o, found := d.Find(bn)
+	if !found {
+		return nil, nil
+	}
+
+	a, err := xRefTable.DereferenceArray(o)
+	if err != nil || len(a) != 4 {
+		return nil, err
+	}
+
+	return rect(xRefTable, a)
+}
+
+func listPageBoxes(xRefTable *XRefTable, pageNr int, boxes []string) ([]string, error) {
+
+	d, inhPAttrs, err := xRefTable.PageDict(pageNr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Boxes in effect.
+	rr := map[string]*Rectangle{"MediaBox": inhPAttrs.mediaBox, "CropBox": inhPAttrs.cropBox}
+
+	// The CropBox defaults to the MediaBox, all other boxes default to the CropBox.
+	defaults := map[string]string{"CropBox": "MediaBox", "TrimBox": "CropBox", "BleedBox": "CropBox", "ArtBox": "CropBox"}
+
+	ss := []string{fmt.Sprintf("page %d:", pageNr)}
+
+	for _, bn := range boxNames {
+
+		r := rr[bn]
+		if bn != "MediaBox" && bn != "CropBox" {
+			if r, err = pageBox(xRefTable, d, bn); err != nil {
+				return nil, err
+			}
+			rr[bn] = r
+		}
+
+		if !MemberOf(bn, boxes) {
+			continue
+		}
+
+		if r != nil {
+			s := ""
+			if _, found := d.Find(bn); !found {
+				s = " (inherited)"
+			}
+			ss = append(ss, fmt.Sprintf("  %-8s %s%s", bn, r, s))
+			continue
+		}
+
+		// Resolve the default box.
+		dn := defaults[bn]
+		for rr[dn] == nil && dn != "MediaBox" {
+			dn = defaults[dn]
+		}
+		ss = append(ss, fmt.Sprintf("  %-8s = %s (default)", bn, dn))
+	}
+
+	return ss, nil
+}
+
+// ListBoxes returns a list of page boundaries for selected pages.
+// If no boxes are specified all page boundaries are listed.
+func ListBoxes(ctx *Context, selectedPages IntSet, boxes []string) ([]string, error) {
+
+	if len(boxes) == 0 {
+		boxes = boxNames
+	}
+
+	ss := []string{}
+
+	for _, k := range sortedPageNrs(selectedPages) {
+		s, err := listPageBoxes(ctx.XRefTable, k, boxes)
+		if err != nil {
+			return nil, err
+		}
+		ss = append(ss, s...)
+	}
+
+	return ss, nil
+}
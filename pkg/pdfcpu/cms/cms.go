@@ -0,0 +1,368 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cms implements the parts of the Cryptographic Message Syntax (RFC 5652)
+// and of PKCS#12 (RFC 7292) needed for signing PDF files.
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"sort"
+	"time"
+
+	// Register the supported digest algorithms.
+	_ "crypto/sha1"
+	_ "crypto/sha512"
+
+	"github.com/pkg/errors"
+)
+
+// Object identifiers, see RFC 5652, RFC 5035, RFC 3279, RFC 4055, RFC 5758.
+var (
+	oidData       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+
+	oidAttrContentType        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttrMessageDigest      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttrSigningTime        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidAttrSigningCertificate = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidRSA           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSHA1WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}
+	oidSHA256WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSHA384WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidSHA512WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+
+	oidECDSAWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 1}
+	oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+)
+
+var digestAlgorithms = map[crypto.Hash]asn1.ObjectIdentifier{
+	crypto.SHA1:   oidSHA1,
+	crypto.SHA256: oidSHA256,
+	crypto.SHA384: oidSHA384,
+	crypto.SHA512: oidSHA512,
+}
+
+var ecdsaAlgorithms = map[crypto.Hash]asn1.ObjectIdentifier{
+	crypto.SHA1:   oidECDSAWithSHA1,
+	crypto.SHA256: oidECDSAWithSHA256,
+	crypto.SHA384: oidECDSAWithSHA384,
+	crypto.SHA512: oidECDSAWithSHA512,
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+// essCertIDv2 omits hashAlgorithm which defaults to SHA-256, see RFC 5035.
+type essCertIDv2 struct {
+	CertHash []byte
+}
+
+type signingCertificateV2 struct {
+	Certs []essCertIDv2
+}
+
+// Signer holds the credentials of a signer.
+type Signer struct {
+	Key         crypto.Signer       // The private key.
+	Certificate *x509.Certificate   // The signer certificate.
+	Chain       []*x509.Certificate // Further certificates to be embedded, eg. intermediate CAs.
+}
+
+// Options configure the creation of a signature.
+type Options struct {
+	Hash        crypto.Hash // The digest algorithm, defaults to SHA-256.
+	CAdES       bool        // Add the ESS signing-certificate-v2 attribute required by CAdES.
+	SigningTime time.Time   // If not zero the time is included as signing-time attribute.
+}
+
+// Validate checks whether the key of s matches its certificate.
+func (s *Signer) Validate() error {
+
+	if s.Key == nil || s.Certificate == nil {
+		return errors.New("cms: missing key or certificate")
+	}
+
+	pub, ok := s.Key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(s.Certificate.PublicKey) {
+		return errors.New("cms: private key does not match certificate")
+	}
+
+	switch s.Key.Public().(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		return errors.Errorf("cms: unsupported key type: %T", s.Key.Public())
+	}
+
+	return nil
+}
+
+func rawSet(b []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: b}
+}
+
+func newAttribute(oid asn1.ObjectIdentifier, v interface{}) (attribute, error) {
+	b, err := asn1.Marshal(v)
+	if err != nil {
+		return attribute{}, err
+	}
+	return attribute{Type: oid, Values: rawSet(b)}, nil
+}
+
+// marshalAttributes returns the DER encoded content of a SET OF attributes sorted as required by DER.
+func marshalAttributes(attrs []attribute) ([]byte, error) {
+
+	bb := make([][]byte, len(attrs))
+	for i, a := range attrs {
+		b, err := asn1.Marshal(a)
+		if err != nil {
+			return nil, err
+		}
+		bb[i] = b
+	}
+
+	sort.Slice(bb, func(i, j int) bool { return bytes.Compare(bb[i], bb[j]) < 0 })
+
+	return bytes.Join(bb, nil), nil
+}
+
+// signatureAlgorithm returns the signature algorithm of a signer info for key and h.
+func signatureAlgorithm(key crypto.PublicKey, h crypto.Hash) (pkix.AlgorithmIdentifier, error) {
+
+	switch key.(type) {
+
+	case *rsa.PublicKey:
+		return pkix.AlgorithmIdentifier{Algorithm: oidRSA, Parameters: asn1.NullRawValue}, nil
+
+	case *ecdsa.PublicKey:
+		return pkix.AlgorithmIdentifier{Algorithm: ecdsaAlgorithms[h]}, nil
+	}
+
+	return pkix.AlgorithmIdentifier{}, errors.Errorf("cms: unsupported key type: %T", key)
+}
+
+func (s *Signer) certificates() asn1.RawValue {
+
+	var buf bytes.Buffer
+
+	buf.Write(s.Certificate.Raw)
+	for _, c := range s.Chain {
+		if !c.Equal(s.Certificate) {
+			buf.Write(c.Raw)
+		}
+	}
+
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: buf.Bytes()}
+}
+
+// signedAttributes returns the signed attributes for a content digest.
+func (s *Signer) signedAttributes(contentType asn1.ObjectIdentifier, digest []byte, opts Options) ([]attribute, error) {
+
+	attrs := []attribute{}
+
+	a, err := newAttribute(oidAttrContentType, contentType)
+	if err != nil {
+		return nil, err
+	}
+	attrs = append(attrs, a)
+
+	if a, err = newAttribute(oidAttrMessageDigest, digest); err != nil {
+		return nil, err
+	}
+	attrs = append(attrs, a)
+
+	if !opts.SigningTime.IsZero() {
+		if a, err = newAttribute(oidAttrSigningTime, opts.SigningTime.UTC()); err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, a)
+	}
+
+	if opts.CAdES {
+		h := sha256.Sum256(s.Certificate.Raw)
+		if a, err = newAttribute(oidAttrSigningCertificate, signingCertificateV2{Certs: []essCertIDv2{{CertHash: h[:]}}}); err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, a)
+	}
+
+	return attrs, nil
+}
+
+// SignDetached returns a DER encoded CMS SignedData with a detached signature for the content read from r.
+func SignDetached(r io.Reader, s *Signer, opts Options) ([]byte, error) {
+
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	if opts.Hash == 0 {
+		opts.Hash = crypto.SHA256
+	}
+
+	if _, ok := digestAlgorithms[opts.Hash]; !ok || !opts.Hash.Available() {
+		return nil, errors.Errorf("cms: unsupported digest algorithm: %s", opts.Hash)
+	}
+
+	h := opts.Hash.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+
+	return s.signedData(oidData, h.Sum(nil), nil, opts)
+}
+
+// signedData returns a DER encoded CMS SignedData for content of contentType with the given digest.
+// The content gets encapsulated unless eContent is nil.
+func (s *Signer) signedData(contentType asn1.ObjectIdentifier, digest, eContent []byte, opts Options) ([]byte, error) {
+
+	attrs, err := s.signedAttributes(contentType, digest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	attrBytes, err := marshalAttributes(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	// The signature covers the DER encoding of the signed attributes as SET OF.
+	b, err := asn1.Marshal(rawSet(attrBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	h := opts.Hash.New()
+	h.Write(b)
+
+	signature, err := s.Key.Sign(rand.Reader, h.Sum(nil), opts.Hash)
+	if err != nil {
+		return nil, errors.Wrap(err, "cms: signing failed")
+	}
+
+	sigAlg, err := signatureAlgorithm(s.Key.Public(), opts.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	sid, err := asn1.Marshal(issuerAndSerialNumber{
+		Issuer:       asn1.RawValue{FullBytes: s.Certificate.RawIssuer},
+		SerialNumber: s.Certificate.SerialNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	digestAlg := pkix.AlgorithmIdentifier{Algorithm: digestAlgorithms[opts.Hash], Parameters: asn1.NullRawValue}
+
+	eci := encapsulatedContentInfo{EContentType: contentType}
+	if eContent != nil {
+		b, err := asn1.Marshal(eContent)
+		if err != nil {
+			return nil, err
+		}
+		eci.EContent = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: b}
+	}
+
+	version := 1
+	if !contentType.Equal(oidData) {
+		version = 3
+	}
+
+	sd := signedData{
+		Version:          version,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{digestAlg},
+		EncapContentInfo: eci,
+		Certificates:     s.certificates(),
+		SignerInfos: []signerInfo{{
+			Version:            1,
+			SID:                asn1.RawValue{FullBytes: sid},
+			DigestAlgorithm:    digestAlg,
+			SignedAttrs:        asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: attrBytes},
+			SignatureAlgorithm: sigAlg,
+			Signature:          signature,
+		}},
+	}
+
+	return marshalContentInfo(oidSignedData, sd)
+}
+
+func marshalContentInfo(contentType asn1.ObjectIdentifier, content interface{}) ([]byte, error) {
+
+	b, err := asn1.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(contentInfo{
+		ContentType: contentType,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: b},
+	})
+}
@@ -0,0 +1,377 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var inDir = filepath.Join("..", "..", "testdata", "sign")
+
+func readFile(t *testing.T, fileName string) []byte {
+	t.Helper()
+	b, err := ioutil.ReadFile(filepath.Join(inDir, fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestRC2(t *testing.T) {
+
+	// Test vectors from RFC 2268.
+	for _, v := range []struct {
+		key, plain, cipher string
+		t1                 int
+	}{
+		{"0000000000000000", "0000000000000000", "ebb773f993278eff", 63},
+		{"ffffffffffffffff", "ffffffffffffffff", "278b27e42e2f0d49", 64},
+		{"3000000000000000", "1000000000000001", "30649edf9be7d2c2", 64},
+		{"88bca90e90875a7f0f79c384627bafb2", "0000000000000000", "2269552ab0f85ca6", 128},
+	} {
+		key, _ := hex.DecodeString(v.key)
+		plain, _ := hex.DecodeString(v.plain)
+
+		c := newRC2Cipher(key, v.t1)
+
+		got := make([]byte, 8)
+		c.Encrypt(got, plain)
+		if hex.EncodeToString(got) != v.cipher {
+			t.Errorf("rc2 key %s: want %s, got %x\n", v.key, v.cipher, got)
+		}
+
+		c.Decrypt(got, got)
+		if !bytes.Equal(got, plain) {
+			t.Errorf("rc2 key %s: decrypt: want %s, got %x\n", v.key, v.plain, got)
+		}
+	}
+}
+
+func TestDecodePKCS12(t *testing.T) {
+
+	for _, fileName := range []string{"signer.p12", "signer-legacy.p12"} {
+
+		b := readFile(t, fileName)
+
+		s, err := DecodePKCS12(b, "test")
+		if err != nil {
+			t.Fatalf("%s: %v\n", fileName, err)
+		}
+
+		if s.Certificate.Subject.CommonName != "Jane Doe" {
+			t.Errorf("%s: want signer Jane Doe, got %s\n", fileName, s.Certificate.Subject.CommonName)
+		}
+
+		if len(s.Chain) != 1 || s.Chain[0].Subject.CommonName != "pdfcpu Test CA" {
+			t.Errorf("%s: want the test CA as chain, got %d certificates\n", fileName, len(s.Chain))
+		}
+
+		if _, err := DecodePKCS12(b, "wrong"); err != ErrInvalidPassword {
+			t.Errorf("%s: want ErrInvalidPassword, got %v\n", fileName, err)
+		}
+	}
+}
+
+func TestParsePEM(t *testing.T) {
+
+	for _, v := range []struct {
+		cert, key, cn string
+	}{
+		{"signer.pem", "signer.key", "Jane Doe"},
+		{"ec.pem", "ec.key", "John Roe"},
+	} {
+		s, err := ParsePEM(readFile(t, v.cert), readFile(t, v.key))
+		if err != nil {
+			t.Fatalf("%s: %v\n", v.cert, err)
+		}
+		if s.Certificate.Subject.CommonName != v.cn {
+			t.Errorf("%s: want %s, got %s\n", v.cert, v.cn, s.Certificate.Subject.CommonName)
+		}
+	}
+
+	if _, err := ParsePEM(readFile(t, "signer.pem"), readFile(t, "ec.key")); err == nil {
+		t.Errorf("want error for key not matching certificate\n")
+	}
+}
+
+func TestSignDetached(t *testing.T) {
+
+	content := []byte("%PDF-1.7 signed bytes")
+	signingTime := time.Date(2019, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, v := range []struct {
+		cert, key string
+		opts      Options
+	}{
+		{"signer.pem", "signer.key", Options{SigningTime: signingTime}},
+		{"ec.pem", "ec.key", Options{CAdES: true, Hash: crypto.SHA384}},
+	} {
+
+		s, err := ParsePEM(readFile(t, v.cert), readFile(t, v.key))
+		if err != nil {
+			t.Fatalf("%s: %v\n", v.cert, err)
+		}
+
+		b, err := SignDetached(bytes.NewReader(content), s, v.opts)
+		if err != nil {
+			t.Fatalf("%s: %v\n", v.cert, err)
+		}
+
+		var ci contentInfo
+		if _, err := asn1.Unmarshal(b, &ci); err != nil || !ci.ContentType.Equal(oidSignedData) {
+			t.Fatalf("%s: invalid content info: %v\n", v.cert, err)
+		}
+
+		var sd signedData
+		if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+			t.Fatalf("%s: invalid signed data: %v\n", v.cert, err)
+		}
+
+		if len(sd.EncapContentInfo.EContent.Bytes) > 0 {
+			t.Errorf("%s: want detached signature\n", v.cert)
+		}
+
+		if len(sd.SignerInfos) != 1 {
+			t.Fatalf("%s: want 1 signer info, got %d\n", v.cert, len(sd.SignerInfos))
+		}
+		si := sd.SignerInfos[0]
+
+		attrs, err := asn1.Marshal(rawSet(si.SignedAttrs.Bytes))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		alg := x509.SHA256WithRSA
+		if v.opts.Hash == crypto.SHA384 {
+			alg = x509.ECDSAWithSHA384
+		}
+		if err := s.Certificate.CheckSignature(alg, attrs, si.Signature); err != nil {
+			t.Errorf("%s: signature: %v\n", v.cert, err)
+		}
+
+		h := crypto.SHA256
+		if v.opts.Hash != 0 {
+			h = v.opts.Hash
+		}
+		hh := h.New()
+		hh.Write(content)
+		if !bytes.Contains(si.SignedAttrs.Bytes, hh.Sum(nil)) {
+			t.Errorf("%s: missing message digest\n", v.cert)
+		}
+
+		hasAttr := func(oid asn1.ObjectIdentifier) bool {
+			b, _ := asn1.Marshal(oid)
+			return bytes.Contains(si.SignedAttrs.Bytes, b)
+		}
+
+		if hasAttr(oidAttrSigningTime) != !v.opts.SigningTime.IsZero() {
+			t.Errorf("%s: signing time attribute mismatch\n", v.cert)
+		}
+		if hasAttr(oidAttrSigningCertificate) != v.opts.CAdES {
+			t.Errorf("%s: signing certificate attribute mismatch\n", v.cert)
+		}
+	}
+
+	if _, err := SignDetached(strings.NewReader(""), &Signer{}, Options{}); err == nil {
+		t.Errorf("want error for missing credentials\n")
+	}
+}
+
+func TestVerify(t *testing.T) {
+
+	content := []byte("%PDF-1.7 signed bytes")
+	signingTime := time.Date(2019, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	block, _ := pem.Decode(readFile(t, "ca.pem"))
+	ca, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	for _, v := range []struct {
+		cert, key string
+		opts      Options
+	}{
+		{"signer.pem", "signer.key", Options{SigningTime: signingTime}},
+		{"ec.pem", "ec.key", Options{CAdES: true, Hash: crypto.SHA512}},
+	} {
+
+		s, err := ParsePEM(readFile(t, v.cert), readFile(t, v.key))
+		if err != nil {
+			t.Fatalf("%s: %v\n", v.cert, err)
+		}
+
+		b, err := SignDetached(bytes.NewReader(content), s, v.opts)
+		if err != nil {
+			t.Fatalf("%s: %v\n", v.cert, err)
+		}
+
+		// PDF signature values are zero padded.
+		sd, err := ParseSignedData(append(b, make([]byte, 64)...))
+		if err != nil {
+			t.Fatalf("%s: %v\n", v.cert, err)
+		}
+
+		if !sd.Signer.Equal(s.Certificate) {
+			t.Fatalf("%s: unexpected signer or digest algorithm\n", v.cert)
+		}
+		if !sd.SigningTime.Equal(v.opts.SigningTime) {
+			t.Errorf("%s: signing time: want %s, got %s\n", v.cert, v.opts.SigningTime, sd.SigningTime)
+		}
+
+		if err := sd.Verify(bytes.NewReader(content)); err != nil {
+			t.Fatalf("%s: %v\n", v.cert, err)
+		}
+
+		if err := sd.Verify(bytes.NewReader(content[1:])); err != ErrDigestMismatch {
+			t.Fatalf("%s: want %v, got %v\n", v.cert, ErrDigestMismatch, err)
+		}
+
+		if _, err := sd.VerifyChain(roots, time.Now()); err != nil {
+			t.Fatalf("%s: %v\n", v.cert, err)
+		}
+
+		if _, err := sd.VerifyChain(x509.NewCertPool(), time.Now()); err == nil {
+			t.Fatalf("%s: want untrusted chain\n", v.cert)
+		}
+	}
+}
+
+func TestTimeStamp(t *testing.T) {
+
+	content := []byte("%PDF-1.7 time-stamped bytes")
+	genTime := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	s, err := ParsePEM(readFile(t, "tsa.pem"), readFile(t, "tsa.key"))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	tsa := &LocalTSA{Signer: s, Clock: func() time.Time { return genTime }}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := ioutil.ReadAll(r.Body)
+		resp, err := tsa.RoundTrip(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/timestamp-reply")
+		w.Write(resp)
+	}))
+	defer srv.Close()
+
+	b, err := TimeStamp(HTTPTSAClient{URL: srv.URL}, bytes.NewReader(content), crypto.SHA256)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	sd, err := ParseSignedData(b)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	ti, err := sd.VerifyTimeStamp(bytes.NewReader(content), nil)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if !ti.GenTime.Equal(genTime) || ti.SerialNumber.Int64() != 1 || !ti.Policy.Equal(oidLocalTSAPolicy) {
+		t.Fatalf("unexpected time-stamp info: %v %v %v\n", ti.GenTime, ti.SerialNumber, ti.Policy)
+	}
+
+	if _, err := sd.VerifyTimeStamp(strings.NewReader("other bytes"), nil); err != ErrDigestMismatch {
+		t.Fatalf("want digest mismatch, got %v\n", err)
+	}
+
+	// A malformed request gets rejected.
+	resp, err := tsa.RoundTrip([]byte{0x30, 0x00})
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if _, err := TimeStamp(roundTripFunc(func([]byte) ([]byte, error) { return resp, nil }), bytes.NewReader(content), 0); err == nil {
+		t.Fatalf("want rejection\n")
+	}
+}
+
+type roundTripFunc func([]byte) ([]byte, error)
+
+func (f roundTripFunc) RoundTrip(req []byte) ([]byte, error) {
+	return f(req)
+}
+
+func TestEnvelope(t *testing.T) {
+
+	content := []byte("pdfcpu enveloped content")
+
+	jane, err := ParsePEM(readFile(t, "signer.pem"), readFile(t, "signer.key"))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	tsa, err := ParsePEM(readFile(t, "tsa.pem"), readFile(t, "tsa.key"))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	b, err := Envelope(content, []*x509.Certificate{jane.Certificate})
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	c, err := OpenEnvelope(b, jane.Certificate, jane.Key)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if !bytes.Equal(c, content) {
+		t.Fatalf("content mismatch: %q\n", c)
+	}
+
+	if _, err := OpenEnvelope(b, tsa.Certificate, tsa.Key); err != ErrNotRecipient {
+		t.Fatalf("want ErrNotRecipient, got %v\n", err)
+	}
+
+	// An envelope using DES-EDE3-CBC created by OpenSSL.
+	c, err = OpenEnvelope(readFile(t, "envelope.p7m"), jane.Certificate, jane.Key)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if !bytes.Equal(c, content) {
+		t.Fatalf("content mismatch: %q\n", c)
+	}
+
+	// Only RSA recipients are supported.
+	block, _ := pem.Decode(readFile(t, "ec.pem"))
+	ec, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if _, err := Envelope(content, []*x509.Certificate{ec}); err == nil {
+		t.Fatalf("want error for EC recipient\n")
+	}
+}
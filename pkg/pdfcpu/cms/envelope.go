@@ -0,0 +1,275 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cms
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	oidEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	oidRC2CBC        = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 2}
+)
+
+// ErrNotRecipient is returned when opening an envelope not addressed to the given certificate.
+var ErrNotRecipient = errors.New("cms: not a recipient")
+
+type envelopedData struct {
+	Version              int
+	OriginatorInfo       asn1.RawValue   `asn1:"optional,tag:0"`
+	RecipientInfos       []asn1.RawValue `asn1:"set"`
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type keyTransRecipientInfo struct {
+	Version                int
+	RID                    asn1.RawValue
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedKey           []byte
+}
+
+type rc2CBCParams struct {
+	Version int
+	IV      []byte
+}
+
+// Envelope returns a DER encoded CMS EnvelopedData holding content encrypted for recipients.
+// The content is encrypted using AES-256-CBC, the content encryption key using RSA PKCS #1 v1.5.
+func Envelope(content []byte, recipients []*x509.Certificate) ([]byte, error) {
+
+	if len(recipients) == 0 {
+		return nil, errors.New("cms: missing recipients")
+	}
+
+	key := make([]byte, 32)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+
+	ris := []asn1.RawValue{}
+	for _, c := range recipients {
+
+		pub, ok := c.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.Errorf("cms: %s: only RSA recipients are supported", c.Subject.CommonName)
+		}
+
+		ek, err := rsa.EncryptPKCS1v15(rand.Reader, pub, key)
+		if err != nil {
+			return nil, err
+		}
+
+		rid, err := asn1.Marshal(issuerAndSerialNumber{
+			Issuer:       asn1.RawValue{FullBytes: c.RawIssuer},
+			SerialNumber: c.SerialNumber,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := asn1.Marshal(keyTransRecipientInfo{
+			RID:                    asn1.RawValue{FullBytes: rid},
+			KeyEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidRSA, Parameters: asn1.NullRawValue},
+			EncryptedKey:           ek,
+		})
+		if err != nil {
+			return nil, err
+		}
+		ris = append(ris, asn1.RawValue{FullBytes: b})
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// PKCS #7 padding
+	n := aes.BlockSize - len(content)%aes.BlockSize
+	b := make([]byte, len(content)+n)
+	copy(b, content)
+	for i := len(content); i < len(b); i++ {
+		b[i] = byte(n)
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(b, b)
+
+	params, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+
+	ed, err := asn1.Marshal(envelopedData{
+		RecipientInfos: ris,
+		EncryptedContentInfo: encryptedContentInfo{
+			ContentType:                oidData,
+			ContentEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: params}},
+			EncryptedContent:           b,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(contentInfo{
+		ContentType: oidEnvelopedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: ed},
+	})
+}
+
+// contentCipher returns the cipher and iv for the content encryption algorithm alg.
+func contentCipher(alg pkix.AlgorithmIdentifier, key []byte) (cipher.Block, []byte, error) {
+
+	var (
+		block cipher.Block
+		iv    []byte
+		err   error
+	)
+
+	switch {
+
+	case alg.Algorithm.Equal(oidAES128CBC), alg.Algorithm.Equal(oidAES192CBC), alg.Algorithm.Equal(oidAES256CBC):
+		block, err = aes.NewCipher(key)
+
+	case alg.Algorithm.Equal(oidDESEDE3CBC):
+		block, err = des.NewTripleDESCipher(key)
+
+	case alg.Algorithm.Equal(oidRC2CBC):
+		var p rc2CBCParams
+		if alg.Parameters.Tag == asn1.TagOctetString {
+			// RFC 2268 6: the iv only, 32 effective key bits.
+			if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &p.IV); err != nil {
+				return nil, nil, errors.Wrap(err, "cms: invalid RC2 parameters")
+			}
+			return newRC2Cipher(key, 32), p.IV, nil
+		}
+		if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &p); err != nil {
+			return nil, nil, errors.Wrap(err, "cms: invalid RC2 parameters")
+		}
+		// RFC 2268 6: the effective key bits are encoded by a parameter version.
+		bits := map[int]int{160: 40, 120: 64, 58: 128}[p.Version]
+		if bits == 0 {
+			bits = p.Version
+		}
+		return newRC2Cipher(key, bits), p.IV, nil
+
+	default:
+		return nil, nil, errors.Errorf("cms: unsupported content encryption algorithm: %s", alg.Algorithm)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &iv); err != nil {
+		return nil, nil, errors.Wrap(err, "cms: invalid iv")
+	}
+
+	return block, iv, nil
+}
+
+// OpenEnvelope returns the content of the DER encoded CMS EnvelopedData b
+// using the private key belonging to the recipient certificate cert.
+// Returns ErrNotRecipient if b is not addressed to cert.
+func OpenEnvelope(b []byte, cert *x509.Certificate, key crypto.PrivateKey) ([]byte, error) {
+
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(b, &ci); err != nil {
+		return nil, errors.Wrap(err, "cms: invalid content info")
+	}
+
+	if !ci.ContentType.Equal(oidEnvelopedData) {
+		return nil, errors.Errorf("cms: unexpected content type: %s", ci.ContentType)
+	}
+
+	var ed envelopedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &ed); err != nil {
+		return nil, errors.Wrap(err, "cms: invalid enveloped data")
+	}
+
+	dec, ok := key.(crypto.Decrypter)
+	if !ok {
+		return nil, errors.New("cms: private key does not support decryption")
+	}
+
+	var ek []byte
+	for _, raw := range ed.RecipientInfos {
+
+		// Only key transport recipients are supported, the others are tagged.
+		if raw.Class != asn1.ClassUniversal {
+			continue
+		}
+
+		var ri keyTransRecipientInfo
+		if _, err := asn1.Unmarshal(raw.FullBytes, &ri); err != nil {
+			return nil, errors.Wrap(err, "cms: invalid recipient info")
+		}
+
+		c, err := signerCertificate(ri.RID, []*x509.Certificate{cert})
+		if err != nil {
+			return nil, err
+		}
+
+		if c != nil {
+			ek = ri.EncryptedKey
+			break
+		}
+	}
+
+	if ek == nil {
+		return nil, ErrNotRecipient
+	}
+
+	k, err := dec.Decrypt(rand.Reader, ek, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "cms: unable to decrypt content encryption key")
+	}
+
+	eci := ed.EncryptedContentInfo
+	block, iv, err := contentCipher(eci.ContentEncryptionAlgorithm, k)
+	if err != nil {
+		return nil, err
+	}
+
+	bs := block.BlockSize()
+	if len(iv) != bs || len(eci.EncryptedContent)%bs != 0 {
+		return nil, errors.New("cms: invalid encrypted content")
+	}
+
+	out := make([]byte, len(eci.EncryptedContent))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, eci.EncryptedContent)
+
+	out, err = unpad(out, bs)
+	if err != nil {
+		return nil, errors.New("cms: invalid encrypted content")
+	}
+
+	return out, nil
+}
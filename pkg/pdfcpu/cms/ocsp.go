@@ -0,0 +1,148 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cms
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var oidOCSPBasic = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+
+// ASN.1 structures of RFC 6960 4.2.1.
+type ocspResponse struct {
+	Status        asn1.Enumerated
+	ResponseBytes responseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type responseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type basicOCSPResponse struct {
+	TBSResponseData    responseData
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type responseData struct {
+	Version     int `asn1:"optional,default:0,explicit,tag:0"`
+	ResponderID asn1.RawValue
+	ProducedAt  time.Time `asn1:"generalized"`
+	Responses   []singleResponse
+	Extensions  []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type certID struct {
+	HashAlgorithm  pkix.AlgorithmIdentifier
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+}
+
+type revokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+type singleResponse struct {
+	CertID     certID
+	Good       asn1.Flag        `asn1:"tag:0,optional"`
+	Revoked    revokedInfo      `asn1:"tag:1,optional"`
+	Unknown    asn1.Flag        `asn1:"tag:2,optional"`
+	ThisUpdate time.Time        `asn1:"generalized"`
+	NextUpdate time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	Extensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+// OCSPResponse is a parsed basic OCSP response, see RFC 6960.
+// The response signature is not verified.
+type OCSPResponse struct {
+	ProducedAt   time.Time
+	Certificates []*x509.Certificate // The certificates of the responder.
+
+	responses []singleResponse
+}
+
+// ParseOCSPResponse parses a DER encoded OCSP response.
+func ParseOCSPResponse(b []byte) (*OCSPResponse, error) {
+
+	var resp ocspResponse
+	if _, err := asn1.Unmarshal(b, &resp); err != nil {
+		return nil, errors.Wrap(err, "cms: invalid OCSP response")
+	}
+
+	if resp.Status != 0 {
+		return nil, errors.Errorf("cms: unsuccessful OCSP response: status=%d", resp.Status)
+	}
+
+	if !resp.ResponseBytes.ResponseType.Equal(oidOCSPBasic) {
+		return nil, errors.Errorf("cms: unsupported OCSP response type: %s", resp.ResponseBytes.ResponseType)
+	}
+
+	var basic basicOCSPResponse
+	if _, err := asn1.Unmarshal(resp.ResponseBytes.Response, &basic); err != nil {
+		return nil, errors.Wrap(err, "cms: invalid basic OCSP response")
+	}
+
+	r := &OCSPResponse{
+		ProducedAt: basic.TBSResponseData.ProducedAt,
+		responses:  basic.TBSResponseData.Responses,
+	}
+
+	for _, raw := range basic.Certificates {
+		c, err := x509.ParseCertificate(raw.FullBytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "cms: invalid OCSP responder certificate")
+		}
+		r.Certificates = append(r.Certificates, c)
+	}
+
+	return r, nil
+}
+
+// Covers returns true if r holds a status for c.
+func (r *OCSPResponse) Covers(c *x509.Certificate) bool {
+
+	for _, sr := range r.responses {
+
+		if sr.CertID.SerialNumber == nil || sr.CertID.SerialNumber.Cmp(c.SerialNumber) != 0 {
+			continue
+		}
+
+		h, err := hashForOID(sr.CertID.HashAlgorithm.Algorithm)
+		if err != nil || !h.Available() {
+			continue
+		}
+
+		d := h.New()
+		d.Write(c.RawIssuer)
+		if bytes.Equal(d.Sum(nil), sr.CertID.IssuerNameHash) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,607 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cms
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"hash"
+	"unicode/utf16"
+
+	"github.com/pkg/errors"
+)
+
+// Object identifiers, see RFC 7292 and RFC 8018.
+var (
+	oidEncryptedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+
+	oidKeyBag              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidPKCS8ShroudedKeyBag = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidX509Certificate     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+
+	oidPBEWithSHAAnd128BitRC2CBC  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 5}
+	oidPBEWithSHAAnd40BitRC2CBC   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 6}
+	oidPBEWithSHAAnd3KeyTripleDES = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPBEWithSHAAnd2KeyTripleDES = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 4}
+
+	oidPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+
+	oidDESEDE3CBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+	oidAES128CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// ErrInvalidPassword is returned for PKCS#12 data failing the integrity check.
+var ErrInvalidPassword = errors.New("pkcs12: invalid password")
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"optional,tag:0"`
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue   `asn1:"explicit,tag:0"`
+	Attributes []asn1.RawValue `asn1:"set,optional"`
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"explicit,tag:0"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+var hashes = map[string]crypto.Hash{
+	oidSHA1.String():           crypto.SHA1,
+	oidSHA256.String():         crypto.SHA256,
+	oidSHA384.String():         crypto.SHA384,
+	oidSHA512.String():         crypto.SHA512,
+	oidHMACWithSHA1.String():   crypto.SHA1,
+	oidHMACWithSHA256.String(): crypto.SHA256,
+	oidHMACWithSHA384.String(): crypto.SHA384,
+	oidHMACWithSHA512.String(): crypto.SHA512,
+}
+
+// bmpString returns the password encoding used by PKCS#12 key derivation, UTF-16BE with a trailing NUL.
+func bmpString(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, 0, 2*len(u)+2)
+	for _, r := range u {
+		b = append(b, byte(r>>8), byte(r))
+	}
+	return append(b, 0, 0)
+}
+
+// pkcs12KDF derives size bytes of key material for purpose id, see RFC 7292 Appendix B.2.
+func pkcs12KDF(h crypto.Hash, id byte, password, salt []byte, iterations, size int) []byte {
+
+	u := h.Size()
+	v := h.New().BlockSize()
+
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		n := v * ((len(b) + v - 1) / v)
+		out := make([]byte, n)
+		for i := range out {
+			out[i] = b[i%len(b)]
+		}
+		return out
+	}
+
+	d := make([]byte, v)
+	for i := range d {
+		d[i] = id
+	}
+
+	in := append(fill(salt), fill(password)...)
+
+	var out []byte
+
+	for len(out) < size {
+
+		hh := h.New()
+		hh.Write(d)
+		hh.Write(in)
+		a := hh.Sum(nil)
+		for i := 1; i < iterations; i++ {
+			hh.Reset()
+			hh.Write(a)
+			a = hh.Sum(a[:0])
+		}
+		out = append(out, a...)
+
+		b := make([]byte, v)
+		for i := range b {
+			b[i] = a[i%u]
+		}
+
+		// I_j = (I_j + B + 1) mod 2^(v*8)
+		for j := 0; j < len(in); j += v {
+			c := 1
+			for k := v - 1; k >= 0; k-- {
+				c += int(in[j+k]) + int(b[k])
+				in[j+k] = byte(c)
+				c >>= 8
+			}
+		}
+	}
+
+	return out[:size]
+}
+
+func unpad(b []byte, blockSize int) ([]byte, error) {
+	n := len(b)
+	if n == 0 || n%blockSize != 0 {
+		return nil, ErrInvalidPassword
+	}
+	p := int(b[n-1])
+	if p == 0 || p > blockSize || p > n {
+		return nil, ErrInvalidPassword
+	}
+	for _, c := range b[n-p:] {
+		if int(c) != p {
+			return nil, ErrInvalidPassword
+		}
+	}
+	return b[:n-p], nil
+}
+
+// pbes2Cipher returns the cipher and iv for PBES2 parameters.
+func pbes2Cipher(params []byte, password string) (cipher.Block, []byte, error) {
+
+	var p pbes2Params
+	if _, err := asn1.Unmarshal(params, &p); err != nil {
+		return nil, nil, err
+	}
+
+	if !p.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, nil, errors.Errorf("pkcs12: unsupported key derivation function: %s", p.KeyDerivationFunc.Algorithm)
+	}
+
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(p.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, nil, err
+	}
+
+	prf := crypto.SHA1
+	if len(kdf.PRF.Algorithm) > 0 {
+		var ok bool
+		if prf, ok = hashes[kdf.PRF.Algorithm.String()]; !ok {
+			return nil, nil, errors.Errorf("pkcs12: unsupported pseudo random function: %s", kdf.PRF.Algorithm)
+		}
+	}
+
+	var keyLen int
+	alg := p.EncryptionScheme.Algorithm
+	switch {
+	case alg.Equal(oidAES128CBC):
+		keyLen = 16
+	case alg.Equal(oidAES192CBC):
+		keyLen = 24
+	case alg.Equal(oidAES256CBC):
+		keyLen = 32
+	case alg.Equal(oidDESEDE3CBC):
+		keyLen = 24
+	default:
+		return nil, nil, errors.Errorf("pkcs12: unsupported encryption scheme: %s", alg)
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(p.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, nil, err
+	}
+
+	key, err := pbkdf2.Key(prf.New, password, kdf.Salt, kdf.IterationCount, keyLen)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var block cipher.Block
+	if alg.Equal(oidDESEDE3CBC) {
+		block, err = des.NewTripleDESCipher(key)
+	} else {
+		block, err = aes.NewCipher(key)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(iv) != block.BlockSize() {
+		return nil, nil, errors.New("pkcs12: invalid iv")
+	}
+
+	return block, iv, nil
+}
+
+// decrypt decrypts b using the password based encryption scheme alg.
+func decrypt(alg pkix.AlgorithmIdentifier, b []byte, password string) ([]byte, error) {
+
+	var (
+		block cipher.Block
+		iv    []byte
+		err   error
+	)
+
+	if alg.Algorithm.Equal(oidPBES2) {
+		if block, iv, err = pbes2Cipher(alg.Parameters.FullBytes, password); err != nil {
+			return nil, err
+		}
+	} else {
+
+		var p pbeParams
+		if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &p); err != nil {
+			return nil, err
+		}
+
+		pw := bmpString(password)
+		kdf := func(id byte, size int) []byte {
+			return pkcs12KDF(crypto.SHA1, id, pw, p.Salt, p.Iterations, size)
+		}
+
+		switch {
+		case alg.Algorithm.Equal(oidPBEWithSHAAnd3KeyTripleDES):
+			block, err = des.NewTripleDESCipher(kdf(1, 24))
+		case alg.Algorithm.Equal(oidPBEWithSHAAnd2KeyTripleDES):
+			k := kdf(1, 16)
+			block, err = des.NewTripleDESCipher(append(k, k[:8]...))
+		case alg.Algorithm.Equal(oidPBEWithSHAAnd40BitRC2CBC):
+			block = newRC2Cipher(kdf(1, 5), 40)
+		case alg.Algorithm.Equal(oidPBEWithSHAAnd128BitRC2CBC):
+			block = newRC2Cipher(kdf(1, 16), 128)
+		default:
+			return nil, errors.Errorf("pkcs12: unsupported encryption algorithm: %s", alg.Algorithm)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		iv = kdf(2, block.BlockSize())
+	}
+
+	if len(b)%block.BlockSize() != 0 {
+		return nil, errors.New("pkcs12: invalid encrypted data length")
+	}
+
+	out := make([]byte, len(b))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, b)
+
+	return unpad(out, block.BlockSize())
+}
+
+// verifyMac checks the integrity of the authenticated safe content.
+func verifyMac(md macData, content []byte, password string) error {
+
+	h, ok := hashes[md.Mac.Algorithm.Algorithm.String()]
+	if !ok {
+		return errors.Errorf("pkcs12: unsupported MAC algorithm: %s", md.Mac.Algorithm.Algorithm)
+	}
+
+	key := pkcs12KDF(h, 3, bmpString(password), md.MacSalt, md.Iterations, h.Size())
+
+	mac := hmac.New(func() hash.Hash { return h.New() }, key)
+	mac.Write(content)
+
+	if !hmac.Equal(mac.Sum(nil), md.Mac.Digest) {
+		return ErrInvalidPassword
+	}
+
+	return nil
+}
+
+// safeBags returns the bags of the authenticated safe content decrypting encrypted contents.
+func safeBags(content []byte, password string) ([]safeBag, error) {
+
+	var authSafe []contentInfo
+	if _, err := asn1.Unmarshal(content, &authSafe); err != nil {
+		return nil, errors.Wrap(err, "pkcs12: invalid authenticated safe")
+	}
+
+	bags := []safeBag{}
+
+	for _, ci := range authSafe {
+
+		var data []byte
+
+		switch {
+
+		case ci.ContentType.Equal(oidData):
+			if _, err := asn1.Unmarshal(ci.Content.Bytes, &data); err != nil {
+				return nil, err
+			}
+
+		case ci.ContentType.Equal(oidEncryptedData):
+			var ed encryptedData
+			if _, err := asn1.Unmarshal(ci.Content.Bytes, &ed); err != nil {
+				return nil, err
+			}
+			var err error
+			if data, err = decrypt(ed.EncryptedContentInfo.ContentEncryptionAlgorithm, ed.EncryptedContentInfo.EncryptedContent, password); err != nil {
+				return nil, err
+			}
+
+		default:
+			return nil, errors.Errorf("pkcs12: unsupported content type: %s", ci.ContentType)
+		}
+
+		var bb []safeBag
+		if _, err := asn1.Unmarshal(data, &bb); err != nil {
+			return nil, errors.Wrap(err, "pkcs12: invalid safe contents")
+		}
+
+		bags = append(bags, bb...)
+	}
+
+	return bags, nil
+}
+
+func signerFromPKCS8(b []byte) (crypto.Signer, error) {
+
+	k, err := x509.ParsePKCS8PrivateKey(b)
+	if err != nil {
+		return nil, err
+	}
+
+	s, ok := k.(crypto.Signer)
+	if !ok {
+		return nil, errors.Errorf("unsupported private key type: %T", k)
+	}
+
+	return s, nil
+}
+
+// newSigner returns the signer for key along with the matching certificate and the remaining certificates as chain.
+func newSigner(key crypto.Signer, certs []*x509.Certificate) (*Signer, error) {
+
+	s := &Signer{Key: key}
+
+	pub, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return nil, errors.Errorf("unsupported key type: %T", key.Public())
+	}
+
+	for _, c := range certs {
+		if s.Certificate == nil && pub.Equal(c.PublicKey) {
+			s.Certificate = c
+			continue
+		}
+		s.Chain = append(s.Chain, c)
+	}
+
+	if s.Certificate == nil {
+		return nil, errors.New("no certificate matching the private key")
+	}
+
+	return s, s.Validate()
+}
+
+// DecodePKCS12 returns the signer contained in the DER encoded PKCS#12 data b.
+func DecodePKCS12(b []byte, password string) (*Signer, error) {
+
+	var pfx pfxPdu
+	rest, err := asn1.Unmarshal(b, &pfx)
+	if err != nil {
+		return nil, errors.Wrap(err, "pkcs12: invalid or BER encoded file")
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("pkcs12: trailing data")
+	}
+
+	if pfx.Version != 3 {
+		return nil, errors.Errorf("pkcs12: unsupported version: %d", pfx.Version)
+	}
+
+	if !pfx.AuthSafe.ContentType.Equal(oidData) {
+		return nil, errors.New("pkcs12: only password integrity mode is supported")
+	}
+
+	var content []byte
+	if _, err := asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &content); err != nil {
+		return nil, errors.Wrap(err, "pkcs12: invalid authenticated safe")
+	}
+
+	if len(pfx.MacData.Mac.Algorithm.Algorithm) > 0 {
+		if err := verifyMac(pfx.MacData, content, password); err != nil {
+			return nil, err
+		}
+	}
+
+	bags, err := safeBags(content, password)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		key   crypto.Signer
+		certs []*x509.Certificate
+	)
+
+	for _, bag := range bags {
+
+		switch {
+
+		case bag.ID.Equal(oidCertBag):
+			var cb certBag
+			if _, err := asn1.Unmarshal(bag.Value.Bytes, &cb); err != nil {
+				return nil, err
+			}
+			if !cb.ID.Equal(oidX509Certificate) {
+				continue
+			}
+			c, err := x509.ParseCertificate(cb.Data)
+			if err != nil {
+				return nil, err
+			}
+			certs = append(certs, c)
+
+		case bag.ID.Equal(oidKeyBag), bag.ID.Equal(oidPKCS8ShroudedKeyBag):
+			if key != nil {
+				return nil, errors.New("pkcs12: more than one private key")
+			}
+			b := bag.Value.Bytes
+			if bag.ID.Equal(oidPKCS8ShroudedKeyBag) {
+				var epki encryptedPrivateKeyInfo
+				if _, err := asn1.Unmarshal(b, &epki); err != nil {
+					return nil, err
+				}
+				if b, err = decrypt(epki.Algorithm, epki.EncryptedData, password); err != nil {
+					return nil, err
+				}
+			}
+			if key, err = signerFromPKCS8(b); err != nil {
+				return nil, errors.Wrap(err, "pkcs12")
+			}
+		}
+	}
+
+	if key == nil {
+		return nil, errors.New("pkcs12: missing private key")
+	}
+
+	s, err := newSigner(key, certs)
+	if err != nil {
+		return nil, errors.Wrap(err, "pkcs12")
+	}
+
+	return s, nil
+}
+
+// ParsePEM returns the signer for PEM encoded certificates and a PEM encoded private key.
+// certPEM may contain the certificate chain. The key may be in PKCS#1, PKCS#8 or SEC 1 format.
+func ParsePEM(certPEM, keyPEM []byte) (*Signer, error) {
+
+	var (
+		key   crypto.Signer
+		certs []*x509.Certificate
+	)
+
+	for _, b := range [][]byte{certPEM, keyPEM} {
+
+		for {
+
+			var block *pem.Block
+			if block, b = pem.Decode(b); block == nil {
+				break
+			}
+
+			switch block.Type {
+
+			case "CERTIFICATE":
+				c, err := x509.ParseCertificate(block.Bytes)
+				if err != nil {
+					return nil, err
+				}
+				certs = append(certs, c)
+
+			case "PRIVATE KEY":
+				k, err := signerFromPKCS8(block.Bytes)
+				if err != nil {
+					return nil, err
+				}
+				key = k
+
+			case "RSA PRIVATE KEY":
+				k, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+				if err != nil {
+					return nil, err
+				}
+				key = k
+
+			case "EC PRIVATE KEY":
+				k, err := x509.ParseECPrivateKey(block.Bytes)
+				if err != nil {
+					return nil, err
+				}
+				key = k
+
+			case "ENCRYPTED PRIVATE KEY":
+				return nil, errors.New("pem: encrypted private keys are not supported, please use PKCS#12")
+			}
+		}
+	}
+
+	if key == nil {
+		return nil, errors.New("pem: missing private key")
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("pem: missing certificate")
+	}
+
+	return newSigner(key, certs)
+}
@@ -0,0 +1,335 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Object identifiers, see RFC 3161.
+var (
+	oidTSTInfo = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+
+	// The default policy of LocalTSA.
+	oidLocalTSAPolicy = asn1.ObjectIdentifier{1, 2, 3, 4, 1}
+)
+
+// PKIStatus values, see RFC 3161 2.4.2.
+const (
+	statusGranted         = 0
+	statusGrantedWithMods = 1
+	statusRejection       = 2
+)
+
+// PKIFailureInfo bits.
+const (
+	failBadAlg     = 0
+	failBadRequest = 2
+)
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional,default:false"`
+	Extensions     []pkix.Extension      `asn1:"optional,tag:0"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString asn1.RawValue  `asn1:"optional"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time        `asn1:"generalized"`
+	Accuracy       accuracy         `asn1:"optional"`
+	Ordering       bool             `asn1:"optional,default:false"`
+	Nonce          *big.Int         `asn1:"optional"`
+	TSA            asn1.RawValue    `asn1:"optional,tag:0"`
+	Extensions     []pkix.Extension `asn1:"optional,tag:1"`
+}
+
+// TSAClient exchanges DER encoded RFC 3161 time-stamp requests and responses with a time-stamp authority.
+type TSAClient interface {
+	RoundTrip(req []byte) ([]byte, error)
+}
+
+// HTTPTSAClient is a TSAClient using the HTTP transport of RFC 3161 3.4.
+// Credentials for basic authentication may be part of URL.
+type HTTPTSAClient struct {
+	URL    string
+	Client *http.Client // Defaults to http.DefaultClient.
+}
+
+// RoundTrip posts req to the time-stamp authority and returns its response.
+func (c HTTPTSAClient) RoundTrip(req []byte) ([]byte, error) {
+
+	hc := c.Client
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+
+	resp, err := hc.Post(c.URL, "application/timestamp-query", bytes.NewReader(req))
+	if err != nil {
+		return nil, errors.Wrap(err, "cms: time-stamp request failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("cms: time-stamp request failed: %s", resp.Status)
+	}
+
+	return ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// TimeStampInfo holds the attributes of a time-stamp token.
+type TimeStampInfo struct {
+	Policy        asn1.ObjectIdentifier
+	SerialNumber  *big.Int
+	GenTime       time.Time
+	Hash          crypto.Hash // The digest algorithm of the message imprint.
+	HashedMessage []byte
+	Nonce         *big.Int
+}
+
+// TimeStamp returns a DER encoded RFC 3161 time-stamp token for the content read from r obtained using c.
+func TimeStamp(c TSAClient, r io.Reader, h crypto.Hash) ([]byte, error) {
+
+	if h == 0 {
+		h = crypto.SHA256
+	}
+
+	oid, ok := digestAlgorithms[h]
+	if !ok || !h.Available() {
+		return nil, errors.Errorf("cms: unsupported digest algorithm: %s", h)
+	}
+
+	d := h.New()
+	if _, err := io.Copy(d, r); err != nil {
+		return nil, err
+	}
+
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := asn1.Marshal(timeStampReq{
+		Version:        1,
+		MessageImprint: messageImprint{HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.NullRawValue}, HashedMessage: d.Sum(nil)},
+		Nonce:          nonce,
+		CertReq:        true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := c.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp timeStampResp
+	if _, err := asn1.Unmarshal(b, &resp); err != nil {
+		return nil, errors.Wrap(err, "cms: invalid time-stamp response")
+	}
+
+	if s := resp.Status.Status; s != statusGranted && s != statusGrantedWithMods {
+		return nil, errors.Errorf("cms: time-stamp request rejected: status=%d", s)
+	}
+
+	token := resp.TimeStampToken.FullBytes
+
+	sd, err := ParseSignedData(token)
+	if err != nil {
+		return nil, err
+	}
+
+	ti, err := sd.VerifyTimeStamp(nil, d.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	if ti.Nonce == nil || ti.Nonce.Cmp(nonce) != 0 {
+		return nil, errors.New("cms: time-stamp nonce mismatch")
+	}
+
+	return token, nil
+}
+
+// TimeStampInfo returns the attributes of the time-stamp token sd.
+func (sd *SignedData) TimeStampInfo() (*TimeStampInfo, error) {
+
+	if !sd.ContentType.Equal(oidTSTInfo) {
+		return nil, errors.New("cms: not a time-stamp token")
+	}
+
+	var ti tstInfo
+	if _, err := asn1.Unmarshal(sd.Content, &ti); err != nil {
+		return nil, errors.Wrap(err, "cms: invalid time-stamp token info")
+	}
+
+	h, err := hashForOID(ti.MessageImprint.HashAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TimeStampInfo{
+		Policy:        ti.Policy,
+		SerialNumber:  ti.SerialNumber,
+		GenTime:       ti.GenTime,
+		Hash:          h,
+		HashedMessage: ti.MessageImprint.HashedMessage,
+		Nonce:         ti.Nonce,
+	}, nil
+}
+
+// VerifyTimeStamp checks the signature of the time-stamp token sd and whether it
+// applies to the content read from r. If digest is not nil it is used instead of r.
+func (sd *SignedData) VerifyTimeStamp(r io.Reader, digest []byte) (*TimeStampInfo, error) {
+
+	ti, err := sd.TimeStampInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sd.Verify(nil); err != nil {
+		return nil, err
+	}
+
+	if digest == nil {
+		if !ti.Hash.Available() {
+			return nil, errors.Errorf("cms: unsupported digest algorithm: %s", ti.Hash)
+		}
+		h := ti.Hash.New()
+		if _, err := io.Copy(h, r); err != nil {
+			return nil, err
+		}
+		digest = h.Sum(nil)
+	}
+
+	if !bytes.Equal(ti.HashedMessage, digest) {
+		return nil, ErrDigestMismatch
+	}
+
+	return ti, nil
+}
+
+// LocalTSA is an in-process time-stamp authority, eg. for testing.
+// Its tokens always include the certificate chain of the TSA.
+type LocalTSA struct {
+	Signer *Signer               // The credentials of the TSA, the certificate needs the time stamping extended key usage.
+	Policy asn1.ObjectIdentifier // The TSA policy.
+	Clock  func() time.Time      // Defaults to time.Now.
+
+	mu     sync.Mutex
+	serial int64
+}
+
+func failure(bit int) ([]byte, error) {
+	fi := asn1.BitString{Bytes: []byte{0x80 >> uint(bit)}, BitLength: bit + 1}
+	return asn1.Marshal(timeStampResp{Status: pkiStatusInfo{Status: statusRejection, FailInfo: fi}})
+}
+
+// RoundTrip answers the time-stamp request req.
+func (tsa *LocalTSA) RoundTrip(req []byte) ([]byte, error) {
+
+	var tr timeStampReq
+	if _, err := asn1.Unmarshal(req, &tr); err != nil || tr.Version != 1 {
+		return failure(failBadRequest)
+	}
+
+	h, err := hashForOID(tr.MessageImprint.HashAlgorithm.Algorithm)
+	if err != nil || len(tr.MessageImprint.HashedMessage) != h.Size() {
+		return failure(failBadAlg)
+	}
+
+	tsa.mu.Lock()
+	tsa.serial++
+	serial := tsa.serial
+	tsa.mu.Unlock()
+
+	now := time.Now
+	if tsa.Clock != nil {
+		now = tsa.Clock
+	}
+
+	policy := tsa.Policy
+	if policy == nil {
+		policy = oidLocalTSAPolicy
+	}
+
+	b, err := asn1.Marshal(tstInfo{
+		Version:        1,
+		Policy:         policy,
+		MessageImprint: tr.MessageImprint,
+		SerialNumber:   big.NewInt(serial),
+		GenTime:        now().UTC().Truncate(time.Second),
+		Accuracy:       accuracy{Seconds: 1},
+		Nonce:          tr.Nonce,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d := crypto.SHA256.New()
+	d.Write(b)
+
+	token, err := tsa.Signer.signedData(oidTSTInfo, d.Sum(nil), b, Options{Hash: crypto.SHA256, CAdES: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(timeStampResp{
+		Status:         pkiStatusInfo{Status: statusGranted},
+		TimeStampToken: asn1.RawValue{FullBytes: token},
+	})
+}
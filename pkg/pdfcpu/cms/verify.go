@@ -0,0 +1,332 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrDigestMismatch is returned when the message digest of a signature does not match the signed content.
+	ErrDigestMismatch = errors.New("cms: message digest mismatch")
+
+	// ErrInvalidSignature is returned when the signature value does not verify against the signer certificate.
+	ErrInvalidSignature = errors.New("cms: invalid signature")
+)
+
+// SignedData is a parsed CMS SignedData with a single signer.
+type SignedData struct {
+	Certificates []*x509.Certificate // The embedded certificates.
+	Signer       *x509.Certificate   // The signer certificate, nil if not embedded.
+	Hash         crypto.Hash         // The digest algorithm of the signer.
+	SigningTime  time.Time           // The signing-time attribute, zero if missing.
+	ContentType  asn1.ObjectIdentifier
+	Content      []byte // The encapsulated content, nil for a detached signature.
+
+	si    signerInfo
+	attrs []attribute
+}
+
+func hashForOID(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
+	for h, o := range digestAlgorithms {
+		if o.Equal(oid) {
+			return h, nil
+		}
+	}
+	return 0, errors.Errorf("cms: unsupported digest algorithm: %s", oid)
+}
+
+func parseCertificates(raw asn1.RawValue) ([]*x509.Certificate, error) {
+
+	certs := []*x509.Certificate{}
+
+	for b := raw.Bytes; len(b) > 0; {
+		var v asn1.RawValue
+		rest, err := asn1.Unmarshal(b, &v)
+		if err != nil {
+			return nil, err
+		}
+		b = rest
+		// Skip other certificate formats.
+		if v.Class != asn1.ClassUniversal || v.Tag != asn1.TagSequence {
+			continue
+		}
+		c, err := x509.ParseCertificate(v.FullBytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, c)
+	}
+
+	return certs, nil
+}
+
+// signerCertificate returns the certificate identified by sid.
+func signerCertificate(sid asn1.RawValue, certs []*x509.Certificate) (*x509.Certificate, error) {
+
+	// subjectKeyIdentifier [0]
+	if sid.Class == asn1.ClassContextSpecific && sid.Tag == 0 {
+		for _, c := range certs {
+			if bytes.Equal(c.SubjectKeyId, sid.Bytes) {
+				return c, nil
+			}
+		}
+		return nil, nil
+	}
+
+	var ias issuerAndSerialNumber
+	if _, err := asn1.Unmarshal(sid.FullBytes, &ias); err != nil {
+		return nil, errors.Wrap(err, "cms: invalid signer identifier")
+	}
+
+	for _, c := range certs {
+		if bytes.Equal(c.RawIssuer, ias.Issuer.FullBytes) && c.SerialNumber.Cmp(ias.SerialNumber) == 0 {
+			return c, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func parseAttributes(b []byte) ([]attribute, error) {
+
+	attrs := []attribute{}
+
+	for len(b) > 0 {
+		var a attribute
+		rest, err := asn1.Unmarshal(b, &a)
+		if err != nil {
+			return nil, errors.Wrap(err, "cms: invalid attribute")
+		}
+		attrs = append(attrs, a)
+		b = rest
+	}
+
+	return attrs, nil
+}
+
+// attribute unmarshals the first value of the attribute oid into v and returns false if there is no such attribute.
+func (sd *SignedData) attribute(oid asn1.ObjectIdentifier, v interface{}) (bool, error) {
+	for _, a := range sd.attrs {
+		if a.Type.Equal(oid) {
+			if _, err := asn1.Unmarshal(a.Values.Bytes, v); err != nil {
+				return true, errors.Wrapf(err, "cms: invalid attribute %s", oid)
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ParseSignedData parses a DER encoded CMS ContentInfo containing a SignedData.
+// Trailing bytes like the zero padding of a PDF signature are ignored.
+func ParseSignedData(b []byte) (*SignedData, error) {
+
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(b, &ci); err != nil {
+		return nil, errors.Wrap(err, "cms: invalid content info")
+	}
+
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, errors.Errorf("cms: unexpected content type: %s", ci.ContentType)
+	}
+
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, errors.Wrap(err, "cms: invalid signed data")
+	}
+
+	if len(sd.SignerInfos) != 1 {
+		return nil, errors.Errorf("cms: expected 1 signer, got %d", len(sd.SignerInfos))
+	}
+
+	certs, err := parseCertificates(sd.Certificates)
+	if err != nil {
+		return nil, errors.Wrap(err, "cms: invalid certificate")
+	}
+
+	si := sd.SignerInfos[0]
+
+	h, err := hashForOID(si.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := signerCertificate(si.SID, certs)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &SignedData{
+		Certificates: certs,
+		Signer:       signer,
+		Hash:         h,
+		ContentType:  sd.EncapContentInfo.EContentType,
+		si:           si,
+	}
+
+	if len(sd.EncapContentInfo.EContent.Bytes) > 0 {
+		var content []byte
+		if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent.Bytes, &content); err != nil {
+			return nil, errors.Wrap(err, "cms: invalid encapsulated content")
+		}
+		res.Content = content
+	}
+
+	if len(si.SignedAttrs.Bytes) > 0 {
+		if res.attrs, err = parseAttributes(si.SignedAttrs.Bytes); err != nil {
+			return nil, err
+		}
+		var t time.Time
+		ok, err := res.attribute(oidAttrSigningTime, &t)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			res.SigningTime = t
+		}
+	}
+
+	return res, nil
+}
+
+// verifySignature checks the signature value over digest using the public key of c.
+func verifySignature(c *x509.Certificate, h crypto.Hash, digest, sig []byte) error {
+
+	switch pub := c.PublicKey.(type) {
+
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(pub, h, digest, sig); err != nil {
+			return ErrInvalidSignature
+		}
+
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, digest, sig) {
+			return ErrInvalidSignature
+		}
+
+	default:
+		return errors.Errorf("cms: unsupported key type: %T", c.PublicKey)
+	}
+
+	return nil
+}
+
+// Verify checks the message digest and the signature of sd against the content read from r.
+// For a signature with encapsulated content r may be nil.
+func (sd *SignedData) Verify(r io.Reader) error {
+
+	if sd.Signer == nil {
+		return errors.New("cms: missing signer certificate")
+	}
+
+	if !sd.Hash.Available() {
+		return errors.Errorf("cms: unsupported digest algorithm: %s", sd.Hash)
+	}
+
+	if r == nil {
+		r = bytes.NewReader(sd.Content)
+	}
+
+	h := sd.Hash.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return err
+	}
+	digest := h.Sum(nil)
+
+	if sd.attrs == nil {
+		// The signature is calculated directly over the content.
+		return verifySignature(sd.Signer, sd.Hash, digest, sd.si.Signature)
+	}
+
+	var md []byte
+	ok, err := sd.attribute(oidAttrMessageDigest, &md)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("cms: missing message digest attribute")
+	}
+	if !bytes.Equal(md, digest) {
+		return ErrDigestMismatch
+	}
+
+	var ct asn1.ObjectIdentifier
+	if ok, err = sd.attribute(oidAttrContentType, &ct); err != nil {
+		return err
+	}
+	if !ok || !ct.Equal(sd.ContentType) {
+		return errors.New("cms: content type attribute mismatch")
+	}
+
+	// The signature covers the DER encoding of the signed attributes as SET OF.
+	b, err := asn1.Marshal(rawSet(sd.si.SignedAttrs.Bytes))
+	if err != nil {
+		return err
+	}
+
+	h = sd.Hash.New()
+	h.Write(b)
+
+	return verifySignature(sd.Signer, sd.Hash, h.Sum(nil), sd.si.Signature)
+}
+
+// VerifyChain builds a certificate chain from the signer certificate to one of roots
+// using the embedded certificates as intermediates, validated at time t.
+// If roots is nil the system roots are used.
+func (sd *SignedData) VerifyChain(roots *x509.CertPool, t time.Time) ([]*x509.Certificate, error) {
+
+	if sd.Signer == nil {
+		return nil, errors.New("cms: missing signer certificate")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range sd.Certificates {
+		if !c.Equal(sd.Signer) {
+			intermediates.AddCert(c)
+		}
+	}
+
+	chains, err := sd.Signer.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   t,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "cms")
+	}
+
+	return chains[0], nil
+}
+
+// SignerName returns a display name of the subject of c.
+func SignerName(c *x509.Certificate) string {
+	if c.Subject.CommonName != "" {
+		return c.Subject.CommonName
+	}
+	return c.Subject.String()
+}
@@ -16,6 +16,11 @@ limitations under the License.
 
 package pdfcpu
 
+import (
+	"crypto"
+	"crypto/x509"
+)
+
 const (
 	// ValidationStrict ensures 100% compliance with the spec (PDF 32000-1:2008).
 	ValidationStrict int = iota
@@ -72,6 +77,32 @@ const (
 	ROTATE
 	NUP
 	INFO
+	LISTBOXES
+	ADDBOXES
+	REMOVEBOXES
+	CROP
+	RESIZE
+	BOOKLET
+	POSTER
+	CUT
+	ARRANGEPAGES
+	REMOVEBLANKPAGES
+	LISTBLANKPAGES
+	LISTANNOTATIONS
+	ADDANNOTATIONS
+	REMOVEANNOTATIONS
+	FLATTENANNOTATIONS
+	LISTFORMFIELDS
+	FILLFORM
+	EXPORTFORM
+	FLATTENFORM
+	IMPORTFORM
+	CREATEFORMFIELDS
+	SIGN
+	LISTSIGNATURES
+	VERIFYSIGNATURES
+	TIMESTAMP
+	ADDLTV
 )
 
 // Configuration of a Context.
@@ -124,6 +155,22 @@ type Configuration struct {
 	// Supplied user access permissions, see Table 22
 	Permissions int16
 
+	// Recipients for public-key encryption (Adobe.PubSec) replacing the passwords.
+	Recipients []Recipient
+
+	// Supplied recipient certificate and private key for decrypting public-key encrypted files.
+	RecipientCert *x509.Certificate
+	RecipientKey  crypto.PrivateKey
+
+	// Merge: Create a top level bookmark for each merged file.
+	CreateBookmarks bool
+
+	// Verify the digital signatures of a file when reading it.
+	VerifySignatures bool
+
+	// Trusted certificates for signature verification, nil for the system roots.
+	TrustedCerts *x509.CertPool
+
 	// Command being executed.
 	Cmd CommandMode
 }
@@ -182,7 +229,7 @@ func (c *Configuration) ValidationModeString() string {
 // ReducedFeatureSet returns true if complex entries like annotations shall not be written.
 func (c *Configuration) ReducedFeatureSet() bool {
 	switch c.Cmd {
-	case SPLIT, TRIM, EXTRACTPAGES, MERGE, IMPORTIMAGES:
+	case MERGE, IMPORTIMAGES:
 		return true
 	}
 	return false
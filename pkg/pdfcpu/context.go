@@ -632,6 +632,7 @@ type WriteContext struct {
 	WriteToObjectStream bool          // if true start to embed objects into object streams and obey ObjectStreamMaxObjects.
 	CurrentObjStream    *int          // if not nil, any new non-stream-object gets added to the object stream with this object number.
 	Eol                 string        // end of line char sequence
+	Quiet               bool          // if true suppress progress output, eg. for writes measuring the resulting file size.
 }
 
 // NewWriteContext returns a new WriteContext.
@@ -1647,8 +1647,6 @@ func createRadioButtonField(xRefTable *XRefTable, pageAnnots *Array) (*IndirectR
 			"Type":    Name("Annot"),
 			"Subtype": Name("Widget"),
 			"Parent":  *indRef,
-			"T":       StringLiteral("Radio1"),
-			"TU":      StringLiteral("Radio1"),
 			"AS":      Name("card1"),
 			"AP": Dict(
 				map[string]Object{
@@ -1674,8 +1672,6 @@ func createRadioButtonField(xRefTable *XRefTable, pageAnnots *Array) (*IndirectR
 			"Type":    Name("Annot"),
 			"Subtype": Name("Widget"),
 			"Parent":  *indRef,
-			"T":       StringLiteral("Radio2"),
-			"TU":      StringLiteral("Radio2"),
 			"AS":      Name("Off"),
 			"AP": Dict(
 				map[string]Object{
@@ -1797,6 +1793,59 @@ func createSubmitButton(xRefTable *XRefTable, pageAnnots *Array) (*IndirectRef,
 	return ir, nil
 }
 
+func createChoiceField(xRefTable *XRefTable, pageAnnots *Array, name string, flags uint32, rect Array, v Object) (*IndirectRef, error) {
+
+	fontDict, err := createFontDict(xRefTable)
+	if err != nil {
+		return nil, err
+	}
+
+	d := Dict(
+		map[string]Object{
+			"FT":      Name("Ch"),
+			"Ff":      Integer(flags),
+			"Rect":    rect,
+			"Type":    Name("Annot"),
+			"Subtype": Name("Widget"),
+			"T":       StringLiteral(name),
+			"TU":      StringLiteral(name),
+			"DA":      StringLiteral("/Helvetica 0 Tf 0 g"),
+			"DR":      Dict(map[string]Object{"Font": Dict(map[string]Object{"Helvetica": *fontDict})}),
+			"Opt": Array{
+				NewStringArray("r", "Red"),
+				NewStringArray("g", "Green"),
+				NewStringArray("b", "Blue"),
+			},
+			"V": v,
+		},
+	)
+
+	ir, err := xRefTable.IndRefForNewObject(d)
+	if err != nil {
+		return nil, err
+	}
+
+	*pageAnnots = append(*pageAnnots, *ir)
+
+	return ir, nil
+}
+
+func createComboBoxField(xRefTable *XRefTable, pageAnnots *Array) (*IndirectRef, error) {
+
+	var flags uint32
+	flags = setBit(flags, 18)
+
+	return createChoiceField(xRefTable, pageAnnots, "ComboBox", flags, NewNumberArray(100, 500, 230, 520), StringLiteral("g"))
+}
+
+func createListBoxField(xRefTable *XRefTable, pageAnnots *Array) (*IndirectRef, error) {
+
+	var flags uint32
+	flags = setBit(flags, 22)
+
+	return createChoiceField(xRefTable, pageAnnots, "ListBox", flags, NewNumberArray(250, 480, 330, 540), NewStringArray("r", "b"))
+}
+
 func streamObjForXFAElement(xRefTable *XRefTable, s string) (*IndirectRef, error) {
 
 	sd := &StreamDict{
@@ -1859,6 +1908,16 @@ func createAcroFormDict(xRefTable *XRefTable) (Dict, Array, error) {
 		return nil, nil, err
 	}
 
+	comboBox, err := createComboBoxField(xRefTable, &pageAnnots)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	listBox, err := createListBoxField(xRefTable, &pageAnnots)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	xfaArr, err := createXFAArray(xRefTable)
 	if err != nil {
 		return nil, nil, err
@@ -1866,7 +1925,7 @@ func createAcroFormDict(xRefTable *XRefTable) (Dict, Array, error) {
 
 	d := Dict(
 		map[string]Object{
-			"Fields":          Array{*text, *checkBox, *radioButton, *resetButton, *submitButton}, // indRefs of fieldDicts
+			"Fields":          Array{*text, *checkBox, *radioButton, *resetButton, *submitButton, *comboBox, *listBox}, // indRefs of fieldDicts
 			"NeedAppearances": Boolean(true),
 			"CO":              Array{*text},
 			"XFA":             xfaArr,
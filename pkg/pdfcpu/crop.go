@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"strings"
+
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+)
+
+// ParseCropBox parses a crop configuration string into an internal structure.
+// An empty string or "auto" selects the bounding box of the visible content and results in a nil box.
+// eg. "10", "5% 10%", "[20 20 575 822]" or "A5"
+func ParseCropBox(s string) (*Box, error) {
+
+	s = strings.TrimSpace(s)
+
+	if s == "" || strings.ToLower(s) == "auto" {
+		return nil, nil
+	}
+
+	return parseBox(s)
+}
+
+// Crop sets the CropBox of selected pages.
+// If b is nil the CropBox is set to the bounding box of the visible page content.
+// Pages without visible content are left unchanged.
+func Crop(ctx *Context, selectedPages IntSet, b *Box) error {
+
+	for _, k := range sortedPageNrs(selectedPages) {
+
+		if b != nil {
+			if err := addPageBoxes(ctx.XRefTable, k, PageBoundaries{"CropBox": b}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		r, err := ContentBoundingBox(ctx, k)
+		if err != nil {
+			return err
+		}
+
+		if r == nil {
+			log.Info.Printf("Crop: page %d: no visible content\n", k)
+			continue
+		}
+
+		d, _, err := ctx.PageDict(k)
+		if err != nil {
+			return err
+		}
+
+		log.Debug.Printf("Crop: page %d: CropBox = %s\n", k, r)
+
+		d.Update("CropBox", r.Array())
+	}
+
+	return nil
+}
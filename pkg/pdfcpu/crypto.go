@@ -64,6 +64,32 @@ var (
 		LISTPERMISSIONS:    {0, 0},
 		SETPERMISSIONS:     {0, 0},
 		ADDWATERMARKS:      {1, 0},
+		LISTBOXES:          {0, 0},
+		ADDBOXES:           {0, 1},
+		REMOVEBOXES:        {0, 1},
+		CROP:               {0, 1},
+		RESIZE:             {0, 1},
+		BOOKLET:            {0, 1},
+		POSTER:             {0, 1},
+		CUT:                {0, 1},
+		ARRANGEPAGES:       {0, 1},
+		REMOVEBLANKPAGES:   {0, 1},
+		LISTBLANKPAGES:     {0, 0},
+		LISTANNOTATIONS:    {0, 0},
+		ADDANNOTATIONS:     {0, 1},
+		REMOVEANNOTATIONS:  {0, 1},
+		FLATTENANNOTATIONS: {0, 1},
+		LISTFORMFIELDS:     {0, 0},
+		FILLFORM:           {0, 1},
+		EXPORTFORM:         {0, 0},
+		FLATTENFORM:        {0, 1},
+		IMPORTFORM:         {0, 1},
+		CREATEFORMFIELDS:   {0, 1},
+		SIGN:               {0, 1},
+		LISTSIGNATURES:     {0, 0},
+		VERIFYSIGNATURES:   {0, 0},
+		TIMESTAMP:          {0, 1},
+		ADDLTV:             {0, 1},
 		//DECRYPT:            {1, 0},
 	}
 )
@@ -501,7 +527,7 @@ func validateOwnerPassword(ctx *Context) (ok bool, err error) {
 }
 
 // SupportedCFEntry returns true if all entries found are supported.
-func supportedCFEntry(d Dict) (bool, error) {
+func supportedCFEntry(d Dict, filter string) (bool, error) {
 
 	cfm := d.NameEntry("CFM")
 	if cfm != nil && *cfm != "V2" && *cfm != "AESV2" && *cfm != "AESV3" {
@@ -513,9 +539,15 @@ func supportedCFEntry(d Dict) (bool, error) {
 		return false, errors.New("supportedCFEntry: invalid entry \"AuthEvent\"")
 	}
 
-	l := d.IntEntry("Length")
-	if l != nil && (*l < 5 || *l > 16) && *l != 32 {
-		return false, errors.New("supportedCFEntry: invalid entry \"Length\"")
+	if l := d.IntEntry("Length"); l != nil {
+		ok := *l >= 5 && *l <= 16 || *l == 32
+		if filter == "Adobe.PubSec" {
+			// The public-key security handler expresses the length in bits.
+			ok = ok || *l == 128 || *l == 256
+		}
+		if !ok {
+			return false, errors.New("supportedCFEntry: invalid entry \"Length\"")
+		}
 	}
 
 	return cfm != nil && (*cfm == "AESV2" || *cfm == "AESV3"), nil
@@ -550,7 +582,7 @@ func validatePermissions(ctx *Context) (bool, error) {
 
 	// Algorithm 3.2a 5.
 
-	if ctx.E.R != 5 {
+	if ctx.E.R != 5 || ctx.E.PubSec {
 		return true, nil
 	}
 
@@ -573,7 +605,7 @@ func writePermissions(ctx *Context, d Dict) error {
 
 	// Algorithm 3.10
 
-	if ctx.E.R != 5 {
+	if ctx.E.R != 5 || ctx.E.PubSec {
 		return nil
 	}
 
@@ -684,7 +716,7 @@ func getV(d Dict) (*int, error) {
 
 	return v, nil
 }
-func checkStmf(ctx *Context, stmf *string, cfDict Dict) error {
+func checkStmf(ctx *Context, stmf *string, cfDict Dict, filter string) error {
 
 	if stmf != nil && *stmf != "Identity" {
 
@@ -693,7 +725,7 @@ func checkStmf(ctx *Context, stmf *string, cfDict Dict) error {
 			return errors.Errorf("checkStmf: entry \"%s\" missing in \"CF\"", *stmf)
 		}
 
-		aes, err := supportedCFEntry(d)
+		aes, err := supportedCFEntry(d, filter)
 		if err != nil {
 			return errors.Wrapf(err, "checkStmv: unsupported \"%s\" entry in \"CF\"", *stmf)
 		}
@@ -703,7 +735,7 @@ func checkStmf(ctx *Context, stmf *string, cfDict Dict) error {
 	return nil
 }
 
-func checkV(ctx *Context, d Dict) (*int, error) {
+func checkV(ctx *Context, d Dict, filter string) (*int, error) {
 
 	v, err := getV(d)
 	if err != nil {
@@ -723,7 +755,7 @@ func checkV(ctx *Context, d Dict) (*int, error) {
 
 	// StmF
 	stmf := d.NameEntry("StmF")
-	err = checkStmf(ctx, stmf, cfDict)
+	err = checkStmf(ctx, stmf, cfDict, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -735,7 +767,7 @@ func checkV(ctx *Context, d Dict) (*int, error) {
 		if d1 == nil {
 			return nil, errors.Errorf("checkV: entry \"%s\" missing in \"CF\"", *strf)
 		}
-		aes, err := supportedCFEntry(d1)
+		aes, err := supportedCFEntry(d1, filter)
 		if err != nil {
 			return nil, errors.Wrapf(err, "checkV: unsupported \"%s\" entry in \"CF\"", *strf)
 		}
@@ -749,7 +781,7 @@ func checkV(ctx *Context, d Dict) (*int, error) {
 		if d == nil {
 			return nil, errors.Errorf("checkV: entry \"%s\" missing in \"CF\"", *eff)
 		}
-		aes, err := supportedCFEntry(d)
+		aes, err := supportedCFEntry(d, filter)
 		if err != nil {
 			return nil, errors.Wrapf(err, "checkV: unsupported \"%s\" entry in \"CF\"", *strf)
 		}
@@ -870,8 +902,11 @@ func supportedEncryption(ctx *Context, d Dict) (*Enc, error) {
 
 	// Filter
 	filter := d.NameEntry("Filter")
+	if filter != nil && *filter == "Adobe.PubSec" {
+		return supportedPubSecEncryption(ctx, d)
+	}
 	if filter == nil || *filter != "Standard" {
-		return nil, errors.New("unsupported encryption: filter must be \"Standard\"")
+		return nil, errors.New("unsupported encryption: filter must be \"Standard\" or \"Adobe.PubSec\"")
 	}
 
 	// SubFilter
@@ -880,7 +915,7 @@ func supportedEncryption(ctx *Context, d Dict) (*Enc, error) {
 	}
 
 	// V
-	v, err := checkV(ctx, d)
+	v, err := checkV(ctx, d, *filter)
 	if err != nil {
 		return nil, err
 	}
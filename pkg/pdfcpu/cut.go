@@ -0,0 +1,435 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/denisbetsi/pdfcpu/pkg/filter"
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	"github.com/pkg/errors"
+)
+
+var errInvalidCutConfig = errors.New("Invalid cut configuration string. Please consult pdfcpu help cut")
+
+// Cut represents the command details for the command "Cut".
+type Cut struct {
+	Hor  []float64 // Horizontal cuts as fractions of the page height measured from the top.
+	Vert []float64 // Vertical cuts as fractions of the page width measured from the left.
+	RTL  bool      // Order the sub pages of a row from right to left.
+}
+
+func (c Cut) String() string {
+	order := "ltr"
+	if c.RTL {
+		order = "rtl"
+	}
+	return fmt.Sprintf("Cut conf: hor=%v, vert=%v, order=%s\n", c.Hor, c.Vert, order)
+}
+
+func parseCutFractions(s string) ([]float64, error) {
+
+	ff := []float64{}
+
+	for _, s := range strings.Fields(s) {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || f <= 0 || f >= 1 {
+			return nil, errors.Errorf("cut: please provide fractions 0.0 < f < 1.0: %s", s)
+		}
+		ff = append(ff, f)
+	}
+
+	if len(ff) == 0 {
+		return nil, errInvalidCutConfig
+	}
+
+	sort.Float64s(ff)
+
+	// Remove duplicates.
+	j := 0
+	for i := 1; i < len(ff); i++ {
+		if ff[i] != ff[j] {
+			j++
+			ff[j] = ff[i]
+		}
+	}
+
+	return ff[:j+1], nil
+}
+
+func parseCutOrder(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "ltr":
+		return false, nil
+	case "rtl":
+		return true, nil
+	}
+	return false, errors.Errorf("unknown cut order: %s, please use one of ltr, rtl", s)
+}
+
+// ParseCutConfig parses a cut command string into an internal structure.
+// eg. "v: .5" or "h: .33 .66, v: .5, o: rtl"
+func ParseCutConfig(s string) (*Cut, error) {
+
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, errInvalidCutConfig
+	}
+
+	c := &Cut{}
+
+	for _, s := range strings.Split(s, ",") {
+
+		ss := strings.Split(s, ":")
+		if len(ss) != 2 {
+			return nil, errInvalidCutConfig
+		}
+
+		k := strings.TrimSpace(ss[0])
+		v := strings.TrimSpace(ss[1])
+
+		var err error
+
+		switch k {
+		case "h": // horizontal cuts
+			c.Hor, err = parseCutFractions(v)
+
+		case "v": // vertical cuts
+			c.Vert, err = parseCutFractions(v)
+
+		case "o": // order
+			c.RTL, err = parseCutOrder(v)
+
+		default:
+			err = errInvalidCutConfig
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(c.Hor) == 0 && len(c.Vert) == 0 {
+		return nil, errors.New("cut: please provide horizontal and/or vertical cuts")
+	}
+
+	return c, nil
+}
+
+// cutRects returns the sub rectangles of box for the page as displayed using rotation
+// in reading order: rows from top to bottom, each row left to right or right to left.
+func cutRects(box *Rectangle, rotation int, c *Cut) []*Rectangle {
+
+	xs := append(append([]float64{0}, c.Vert...), 1)
+	ys := append(append([]float64{0}, c.Hor...), 1)
+
+	// Map display fractions (u,v) with v pointing up onto fractions of the unrotated box.
+	unrotate := func(u, v float64) (float64, float64) {
+		switch rotation {
+		case 90:
+			return 1 - v, u
+		case 180:
+			return 1 - u, 1 - v
+		case 270:
+			return v, 1 - u
+		}
+		return u, v
+	}
+
+	rr := []*Rectangle{}
+
+	for i := 0; i < len(ys)-1; i++ {
+
+		v0, v1 := 1-ys[i+1], 1-ys[i]
+
+		for j := 0; j < len(xs)-1; j++ {
+
+			col := j
+			if c.RTL {
+				col = len(xs) - 2 - j
+			}
+			u0, u1 := xs[col], xs[col+1]
+
+			x0, y0 := unrotate(u0, v0)
+			x1, y1 := unrotate(u1, v1)
+
+			rr = append(rr, Rect(
+				box.LL.X+math.Min(x0, x1)*box.Width(),
+				box.LL.Y+math.Min(y0, y1)*box.Height(),
+				box.LL.X+math.Max(x0, x1)*box.Width(),
+				box.LL.Y+math.Max(y0, y1)*box.Height(),
+			))
+		}
+	}
+
+	return rr
+}
+
+// cutAnnotations adds copies of the annotations of annots intersecting r to the sub page d referenced by ir.
+// Sub pages keep the user space of the original page, so annotation coordinates remain valid as is.
+func cutAnnotations(xRefTable *XRefTable, annots Array, d Dict, ir IndirectRef, r *Rectangle) error {
+
+	a, err := xRefTable.copyAnnotations(annots, ir, func(ad Dict) (bool, error) {
+		arr, err := xRefTable.DereferenceArray(ad["Rect"])
+		if err != nil || len(arr) != 4 {
+			return false, err
+		}
+		ar, err := rect(xRefTable, arr)
+		if err != nil {
+			return false, err
+		}
+		// Zero sized annotations, eg. hidden widgets, intersect when touching.
+		ar = Rect(math.Min(ar.LL.X, ar.UR.X), math.Min(ar.LL.Y, ar.UR.Y), math.Max(ar.LL.X, ar.UR.X), math.Max(ar.LL.Y, ar.UR.Y))
+		return ar.LL.X <= r.UR.X && ar.UR.X >= r.LL.X && ar.LL.Y <= r.UR.Y && ar.UR.Y >= r.LL.Y, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(a) > 0 {
+		d.Insert("Annots", a)
+	}
+
+	return nil
+}
+
+// cutPage creates the sub pages for page pageNr.
+// All sub pages share the original page content wrapped into a form XObject.
+// Each sub page gets copies of the annotations intersecting it.
+func cutPage(ctx *Context, pageNr int, c *Cut) ([]IndirectRef, error) {
+
+	pageDict, inhPAttrs, err := ctx.PageDict(pageNr)
+	if err != nil {
+		return nil, err
+	}
+
+	annots, _, err := ctx.pageAnnots(pageDict)
+	if err != nil {
+		return nil, err
+	}
+
+	if inhPAttrs.mediaBox == nil {
+		return nil, errors.Errorf("cutPage: page %d: missing MediaBox", pageNr)
+	}
+
+	box := inhPAttrs.mediaBox
+	if inhPAttrs.cropBox != nil {
+		if r := intersection(inhPAttrs.cropBox, box); r != nil {
+			box = r
+		}
+	}
+
+	rotation := (inhPAttrs.rotate%360 + 360) % 360
+
+	formIndRef, _, err := pageForm(ctx, pageNr)
+	if err != nil {
+		return nil, err
+	}
+
+	resDict := NewDict()
+	var contentsIndRef *IndirectRef
+
+	if formIndRef != nil {
+
+		// Compress the page content shared by all sub pages.
+		entry, _ := ctx.FindTableEntryForIndRef(formIndRef)
+		fsd, _ := entry.Object.(StreamDict)
+		fsd.InsertName("Filter", filter.Flate)
+		fsd.FilterPipeline = []PDFFilter{{Name: filter.Flate, DecodeParms: nil}}
+		if err = encodeStream(&fsd); err != nil {
+			return nil, err
+		}
+		entry.Object = fsd
+
+		resDict.Insert("XObject", Dict(map[string]Object{"Fm0": *formIndRef}))
+
+		sd := &StreamDict{Dict: NewDict()}
+		sd.InsertName("Filter", filter.Flate)
+		sd.FilterPipeline = []PDFFilter{{Name: filter.Flate, DecodeParms: nil}}
+		sd.Content = []byte("/Fm0 Do")
+
+		if err = encodeStream(sd); err != nil {
+			return nil, err
+		}
+
+		if contentsIndRef, err = ctx.IndRefForNewObject(*sd); err != nil {
+			return nil, err
+		}
+	}
+
+	resIndRef, err := ctx.IndRefForNewObject(resDict)
+	if err != nil {
+		return nil, err
+	}
+
+	rr := cutRects(box, rotation, c)
+
+	log.Info.Printf("cut: page %d: %s into %d pages\n", pageNr, box, len(rr))
+
+	irs := []IndirectRef{}
+
+	for _, r := range rr {
+
+		d := Dict(
+			map[string]Object{
+				"Type":      Name("Page"),
+				"MediaBox":  r.Array(),
+				"CropBox":   r.Array(),
+				"Resources": *resIndRef,
+				"Rotate":    Integer(rotation),
+			},
+		)
+
+		if contentsIndRef != nil {
+			d.Insert("Contents", *contentsIndRef)
+		}
+
+		ir, err := ctx.IndRefForNewObject(d)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = cutAnnotations(ctx.XRefTable, annots, d, *ir, r); err != nil {
+			return nil, err
+		}
+
+		irs = append(irs, *ir)
+	}
+
+	return irs, nil
+}
+
+// replacePages replaces the pages of the page tree rooted at root with the page dicts of m
+// and returns the resulting change in page count.
+func replacePages(xRefTable *XRefTable, root *IndirectRef, p *int, m map[int][]IndirectRef) (int, error) {
+
+	d, err := xRefTable.DereferenceDict(*root)
+	if err != nil {
+		return 0, err
+	}
+
+	kids := d.ArrayEntry("Kids")
+	if kids == nil {
+		return 0, nil
+	}
+
+	i := 0
+	a := Array{}
+
+	for _, o := range kids {
+
+		if o == nil {
+			continue
+		}
+
+		// Dereference next page node dict.
+		ir, ok := o.(IndirectRef)
+		if !ok {
+			return 0, errors.Errorf("replacePages: corrupt page node dict")
+		}
+
+		pageNodeDict, err := xRefTable.DereferenceDict(ir)
+		if err != nil {
+			return 0, err
+		}
+
+		switch *pageNodeDict.Type() {
+
+		case "Pages":
+			// Recurse over sub pagetree.
+			j, err := replacePages(xRefTable, &ir, p, m)
+			if err != nil {
+				return 0, err
+			}
+			a = append(a, ir)
+			i += j
+
+		case "Page":
+			*p++
+			irs, ok := m[*p]
+			if !ok {
+				a = append(a, ir)
+				continue
+			}
+			for _, ir := range irs {
+				pd, err := xRefTable.DereferenceDict(ir)
+				if err != nil {
+					return 0, err
+				}
+				pd.Insert("Parent", *root)
+				a = append(a, ir)
+			}
+			i += len(irs) - 1
+		}
+	}
+
+	d.Update("Kids", a)
+
+	return i, d.IncrementBy("Count", i)
+}
+
+// CutPages cuts each selected page into sub pages along the horizontal and vertical cuts of c.
+// Cuts refer to the page as displayed. Each sub page replaces the original page in reading order.
+// Links, outlines and named destinations pointing to a cut page get redirected to its first sub page.
+func CutPages(ctx *Context, selectedPages IntSet, c *Cut) error {
+
+	pageIndRefs, err := ctx.PageIndRefs()
+	if err != nil {
+		return err
+	}
+
+	m := map[int][]IndirectRef{}
+
+	// Destinations pointing to a cut page get redirected to its first sub page.
+	dm := map[int]IndirectRef{}
+
+	for _, k := range sortedPageNrs(selectedPages) {
+		irs, err := cutPage(ctx, k, c)
+		if err != nil {
+			return err
+		}
+		m[k] = irs
+		if len(irs) > 0 && k <= len(pageIndRefs) {
+			dm[pageIndRefs[k-1].ObjectNumber.Value()] = irs[0]
+		}
+	}
+
+	root, err := ctx.Pages()
+	if err != nil {
+		return err
+	}
+
+	p := 0
+	i, err := replacePages(ctx.XRefTable, root, &p, m)
+	if err != nil {
+		return err
+	}
+
+	ctx.PageCount += i
+
+	return visitDests(ctx.XRefTable, func(a Array) {
+		if ir, ok := a[0].(IndirectRef); ok {
+			if ir1, ok := dm[ir.ObjectNumber.Value()]; ok {
+				a[0] = ir1
+			}
+		}
+	})
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"testing"
+)
+
+func doTestCutRects(desc string, rotation int, want []*Rectangle, t *testing.T) {
+	c, err := ParseCutConfig(desc)
+	if err != nil {
+		t.Fatalf("ParseCutConfig(%s): %v\n", desc, err)
+	}
+
+	got := cutRects(Rect(0, 0, 600, 800), rotation, c)
+	if len(got) != len(want) {
+		t.Fatalf("%s, rot=%d: want %d rects, got %d\n", desc, rotation, len(want), len(got))
+	}
+
+	for i, r := range got {
+		if !r.equals(*want[i]) {
+			t.Errorf("%s, rot=%d, rect %d: want %s, got %s\n", desc, rotation, i, want[i], r)
+		}
+	}
+}
+
+func TestCutRects(t *testing.T) {
+
+	doTestCutRects("v: .5", 0, []*Rectangle{Rect(0, 0, 300, 800), Rect(300, 0, 600, 800)}, t)
+
+	doTestCutRects("v: .5, o: rtl", 0, []*Rectangle{Rect(300, 0, 600, 800), Rect(0, 0, 300, 800)}, t)
+
+	// Rows run top down.
+	doTestCutRects("h: .25", 0, []*Rectangle{Rect(0, 600, 600, 800), Rect(0, 0, 600, 600)}, t)
+
+	// The left half of a page rotated clockwise shows the bottom half of the unrotated page.
+	doTestCutRects("v: .5", 90, []*Rectangle{Rect(0, 0, 600, 400), Rect(0, 400, 600, 800)}, t)
+
+	doTestCutRects("v: .5", 270, []*Rectangle{Rect(0, 400, 600, 800), Rect(0, 0, 600, 400)}, t)
+}
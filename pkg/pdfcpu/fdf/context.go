@@ -0,0 +1,179 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fdf
+
+import (
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	pdf "github.com/denisbetsi/pdfcpu/pkg/pdfcpu"
+	"github.com/pkg/errors"
+)
+
+// fieldValue returns the value of a form field as FDF object.
+func fieldValue(f pdf.Field) (pdf.Object, bool) {
+
+	switch f.Type {
+
+	case pdf.FieldText, pdf.FieldComboBox:
+		return encodeText(f.Value), true
+
+	case pdf.FieldListBox:
+		if len(f.Values) > 1 {
+			a := pdf.Array{}
+			for _, v := range f.Values {
+				a = append(a, encodeText(v))
+			}
+			return a, true
+		}
+		return encodeText(f.Value), true
+
+	case pdf.FieldCheckBox, pdf.FieldRadioGroup:
+		if f.Value == "" {
+			return pdf.Name("Off"), true
+		}
+		return pdf.Name(f.Value), true
+	}
+
+	return nil, false
+}
+
+// exportedAnnot returns true for annotations subject to export.
+// Widgets belong to fields, popups go along with their parent and links are not markup.
+func exportedAnnot(d pdf.Dict) bool {
+	st := d.Subtype()
+	return st != nil && !pdf.MemberOf(*st, []string{"Widget", "Popup", "Link"})
+}
+
+// Export returns the form field values and annotations of ctx.
+func Export(ctx *pdf.Context) (*Data, error) {
+
+	data := &Data{}
+
+	ff, err := pdf.FormFields(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range ff {
+		if v, ok := fieldValue(f); ok {
+			data.Fields = append(data.Fields, Field{Name: f.Name, Value: v})
+		}
+	}
+
+	for i := 1; i <= ctx.PageCount; i++ {
+
+		d, _, err := ctx.PageDict(i)
+		if err != nil {
+			return nil, err
+		}
+
+		arr, err := ctx.DereferenceArray(d["Annots"])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, o := range arr {
+
+			ad, err := ctx.DereferenceDict(o)
+			if err != nil {
+				return nil, err
+			}
+			if ad == nil || !exportedAnnot(ad) {
+				continue
+			}
+
+			if ad, err = annotDict(ctx.Dereference, ad); err != nil {
+				return nil, err
+			}
+
+			data.Annots = append(data.Annots, Annotation{PageNr: i, Dict: ad})
+		}
+	}
+
+	return data, nil
+}
+
+// formValue returns the FDF field value v for filling a form.
+func formValue(v pdf.Object) (interface{}, error) {
+
+	switch v := v.(type) {
+
+	case pdf.StringLiteral, pdf.HexLiteral, pdf.Name:
+		return text(v), nil
+
+	case pdf.Array:
+		ss := []string{}
+		for _, o := range v {
+			ss = append(ss, text(o))
+		}
+		return ss, nil
+	}
+
+	return nil, errors.Errorf("unsupported value: %s", v)
+}
+
+// Import fills the form fields of ctx with the field values of data and adds its annotations.
+func Import(ctx *pdf.Context, data *Data) error {
+
+	if len(data.Fields) > 0 {
+
+		fv := pdf.FormValues{}
+
+		for _, f := range data.Fields {
+			v, err := formValue(f.Value)
+			if err != nil {
+				return errors.Wrapf(err, "fdf import: %s", f.Name)
+			}
+			fv[f.Name] = v
+		}
+
+		if err := pdf.FillForm(ctx, fv); err != nil {
+			return err
+		}
+	}
+
+	pageIndRefs, err := ctx.PageIndRefs()
+	if err != nil {
+		return err
+	}
+
+	for _, a := range data.Annots {
+
+		if a.PageNr < 1 || a.PageNr > len(pageIndRefs) {
+			return errors.Errorf("fdf import: %s annotation: invalid page: %d", *a.Dict.Subtype(), a.PageNr)
+		}
+
+		d := pdf.NewDict()
+		for k, v := range a.Dict {
+			d[k] = v
+		}
+		d.Update("Type", pdf.Name("Annot"))
+		d.Update("P", pageIndRefs[a.PageNr-1])
+
+		ir, err := ctx.IndRefForNewObject(d)
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.AddPageAnnot(a.PageNr, *ir); err != nil {
+			return err
+		}
+	}
+
+	log.Info.Printf("imported %d field values, %d annotations\n", len(data.Fields), len(data.Annots))
+
+	return nil
+}
@@ -0,0 +1,418 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fdf implements reading and writing form field values and annotations
+// in Forms Data Format (FDF, see 12.7.8) and its XML counterpart XFDF.
+package fdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pdf "github.com/denisbetsi/pdfcpu/pkg/pdfcpu"
+	"github.com/pkg/errors"
+)
+
+// Field represents the value of a form field.
+type Field struct {
+	Name  string     // The fully qualified field name.
+	Value pdf.Object // V, a text string, a name or an array of text strings.
+}
+
+// Annotation represents an annotation dict along with the page it belongs to.
+type Annotation struct {
+	PageNr int
+	Dict   pdf.Dict
+}
+
+// Data represents form field values and annotations exchanged via FDF or XFDF.
+type Data struct {
+	File   string // The PDF file the data belongs to.
+	Fields []Field
+	Annots []Annotation
+}
+
+// maxDepth limits the resolution of nested objects guarding against reference cycles.
+const maxDepth = 16
+
+// annotRefKeys are annotation dict entries referring to objects of the surrounding document.
+var annotRefKeys = []string{"P", "Popup", "Parent", "IRT", "AP", "StructParent"}
+
+// resolve returns a copy of o with all indirect references resolved using deref.
+// Streams are not supported and resolve to nil along with the entries referring to them.
+func resolve(deref func(pdf.Object) (pdf.Object, error), o pdf.Object, depth int) (pdf.Object, error) {
+
+	if depth > maxDepth {
+		return nil, nil
+	}
+
+	o, err := deref(o)
+	if err != nil || o == nil {
+		return nil, err
+	}
+
+	switch o := o.(type) {
+
+	case pdf.Dict:
+		d := pdf.NewDict()
+		for k, v := range o {
+			v1, err := resolve(deref, v, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if v1 != nil {
+				d[k] = v1
+			}
+		}
+		return d, nil
+
+	case pdf.Array:
+		a := pdf.Array{}
+		for _, v := range o {
+			v1, err := resolve(deref, v, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if v1 != nil {
+				a = append(a, v1)
+			}
+		}
+		return a, nil
+
+	case pdf.StreamDict:
+		return nil, nil
+	}
+
+	return o, nil
+}
+
+// annotDict returns a copy of the annotation dict d suitable for exchange.
+func annotDict(deref func(pdf.Object) (pdf.Object, error), d pdf.Dict) (pdf.Dict, error) {
+
+	d1 := pdf.NewDict()
+	for k, v := range d {
+		d1[k] = v
+	}
+	for _, k := range annotRefKeys {
+		d1.Delete(k)
+	}
+
+	o, err := resolve(deref, d1, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.(pdf.Dict), nil
+}
+
+// text returns the string represented by a string literal, hex literal or name.
+func text(o pdf.Object) string {
+
+	var s string
+	var err error
+
+	switch o := o.(type) {
+	case pdf.StringLiteral:
+		s, err = pdf.StringLiteralToString(o.Value())
+	case pdf.HexLiteral:
+		s, err = pdf.HexLiteralToString(o.Value())
+	case pdf.Name:
+		s = o.Value()
+	}
+
+	if err != nil {
+		return ""
+	}
+
+	return s
+}
+
+func encodeText(s string) pdf.Object {
+	o, err := pdf.EncodeText(s)
+	if err != nil {
+		return pdf.StringLiteral("")
+	}
+	return o
+}
+
+// objects maps the object numbers of an FDF file to their objects.
+type objects map[int]pdf.Object
+
+func (objs objects) deref(o pdf.Object) (pdf.Object, error) {
+	if ir, ok := o.(pdf.IndirectRef); ok {
+		return objs[ir.ObjectNumber.Value()], nil
+	}
+	return o, nil
+}
+
+var objHeader = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+
+// parseObjects parses the indirect objects of s.
+func parseObjects(s string) (objects, error) {
+
+	objs := objects{}
+
+	for {
+		loc := objHeader.FindStringSubmatchIndex(s)
+		if loc == nil {
+			return objs, nil
+		}
+
+		objNr, err := strconv.Atoi(s[loc[2]:loc[3]])
+		if err != nil {
+			return nil, err
+		}
+
+		o, rest, err := pdf.ParseNextObject(s[loc[1]:])
+		if err != nil {
+			return nil, errors.Wrapf(err, "fdf: object %d", objNr)
+		}
+		objs[objNr] = o
+
+		rest = strings.TrimLeft(rest, " \t\r\n")
+		if strings.HasPrefix(rest, "stream") {
+			// Streams like appearances are not supported.
+			delete(objs, objNr)
+			i := strings.Index(rest, "endstream")
+			if i < 0 {
+				return nil, errors.Errorf("fdf: object %d: missing endstream", objNr)
+			}
+			rest = rest[i:]
+		}
+
+		i := strings.Index(rest, "endobj")
+		if i < 0 {
+			return nil, errors.Errorf("fdf: object %d: missing endobj", objNr)
+		}
+		s = rest[i+len("endobj"):]
+	}
+}
+
+// collectFields appends the values of the field dicts in a and their kids to ff.
+func collectFields(a pdf.Array, parentName string, ff *[]Field) {
+
+	for _, o := range a {
+
+		d, ok := o.(pdf.Dict)
+		if !ok {
+			continue
+		}
+
+		name := parentName
+		if t := text(d["T"]); t != "" {
+			if name != "" {
+				name += "."
+			}
+			name += t
+		}
+
+		if v, found := d.Find("V"); found && v != nil {
+			*ff = append(*ff, Field{Name: name, Value: v})
+		}
+
+		if kids, ok := d["Kids"].(pdf.Array); ok {
+			collectFields(kids, name, ff)
+		}
+	}
+}
+
+// Read reads form field values and annotations from an FDF file.
+func Read(r io.Reader) (*Data, error) {
+
+	bb, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s := string(bb)
+	if !strings.HasPrefix(strings.TrimLeft(s, " \t\r\n"), "%FDF-") {
+		return nil, errors.New("fdf: missing header")
+	}
+
+	i := strings.LastIndex(s, "trailer")
+	if i < 0 {
+		return nil, errors.New("fdf: missing trailer")
+	}
+
+	objs, err := parseObjects(s[:i])
+	if err != nil {
+		return nil, err
+	}
+
+	o, _, err := pdf.ParseNextObject(s[i+len("trailer"):])
+	if err != nil {
+		return nil, errors.Wrap(err, "fdf: trailer")
+	}
+	trailer, ok := o.(pdf.Dict)
+	if !ok {
+		return nil, errors.New("fdf: corrupt trailer")
+	}
+
+	o, _ = objs.deref(trailer["Root"])
+	root, ok := o.(pdf.Dict)
+	if !ok {
+		return nil, errors.New("fdf: missing catalog")
+	}
+	o, _ = objs.deref(root["FDF"])
+	d, ok := o.(pdf.Dict)
+	if !ok {
+		return nil, errors.New("fdf: missing FDF dict")
+	}
+
+	data := &Data{}
+
+	o, err = resolve(objs.deref, d["F"], 0)
+	if err != nil {
+		return nil, err
+	}
+	switch f := o.(type) {
+	case pdf.Dict:
+		data.File = text(f["F"])
+	default:
+		data.File = text(f)
+	}
+
+	o, err = resolve(objs.deref, d["Fields"], 0)
+	if err != nil {
+		return nil, err
+	}
+	if a, ok := o.(pdf.Array); ok {
+		collectFields(a, "", &data.Fields)
+	}
+
+	o, _ = objs.deref(d["Annots"])
+	a, _ := o.(pdf.Array)
+
+	for _, o := range a {
+
+		o, _ = objs.deref(o)
+		ad, ok := o.(pdf.Dict)
+		if !ok || ad.Subtype() == nil || *ad.Subtype() == "Popup" {
+			continue
+		}
+
+		pageNr := 1
+		if i := ad.IntEntry("Page"); i != nil {
+			pageNr = *i + 1
+		}
+
+		ad, err = annotDict(objs.deref, ad)
+		if err != nil {
+			return nil, err
+		}
+		ad.Delete("Page")
+
+		data.Annots = append(data.Annots, Annotation{PageNr: pageNr, Dict: ad})
+	}
+
+	return data, nil
+}
+
+// fieldNode represents a node of the field tree built from fully qualified field names.
+type fieldNode struct {
+	name  string
+	value pdf.Object
+	kids  []*fieldNode
+}
+
+func (n *fieldNode) kid(name string) *fieldNode {
+	for _, k := range n.kids {
+		if k.name == name {
+			return k
+		}
+	}
+	k := &fieldNode{name: name}
+	n.kids = append(n.kids, k)
+	return k
+}
+
+func (n *fieldNode) array() pdf.Array {
+	a := pdf.Array{}
+	for _, k := range n.kids {
+		d := pdf.NewDict()
+		d.Insert("T", encodeText(k.name))
+		if k.value != nil {
+			d.Insert("V", k.value)
+		}
+		if len(k.kids) > 0 {
+			d.Insert("Kids", k.array())
+		}
+		a = append(a, d)
+	}
+	return a
+}
+
+func fieldTree(ff []Field) *fieldNode {
+	root := &fieldNode{}
+	for _, f := range ff {
+		n := root
+		for _, s := range strings.Split(f.Name, ".") {
+			n = n.kid(s)
+		}
+		n.value = f.Value
+	}
+	return root
+}
+
+// Write writes the form field values and annotations of data as FDF file.
+func Write(w io.Writer, data *Data) error {
+
+	d := pdf.NewDict()
+
+	if data.File != "" {
+		d.Insert("F", encodeText(data.File))
+	}
+
+	if len(data.Fields) > 0 {
+		d.Insert("Fields", fieldTree(data.Fields).array())
+	}
+
+	// The annotations are written as indirect objects following the catalog.
+	if len(data.Annots) > 0 {
+		a := pdf.Array{}
+		for i := range data.Annots {
+			a = append(a, *pdf.NewIndirectRef(i+2, 0))
+		}
+		d.Insert("Annots", a)
+	}
+
+	root := pdf.Dict(map[string]pdf.Object{"FDF": d})
+
+	var buf bytes.Buffer
+
+	buf.WriteString("%FDF-1.2\n%\xE2\xE3\xCF\xD3\n")
+	fmt.Fprintf(&buf, "1 0 obj\n%s\nendobj\n", root.PDFString())
+
+	for i, a := range data.Annots {
+		ad := pdf.NewDict()
+		for k, v := range a.Dict {
+			ad[k] = v
+		}
+		ad.Update("Page", pdf.Integer(a.PageNr-1))
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+2, ad.PDFString())
+	}
+
+	buf.WriteString("trailer\n<</Root 1 0 R>>\n%%EOF\n")
+
+	_, err := w.Write(buf.Bytes())
+
+	return err
+}
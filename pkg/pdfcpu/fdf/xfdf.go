@@ -0,0 +1,511 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fdf
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	pdf "github.com/denisbetsi/pdfcpu/pkg/pdfcpu"
+	"github.com/pkg/errors"
+)
+
+const xfdfNamespace = "http://ns.adobe.com/xfdf/"
+
+type xfdfDoc struct {
+	XMLName xml.Name    `xml:"xfdf"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	File    *xfdfFile   `xml:"f"`
+	Fields  []xfdfField `xml:"fields>field"`
+	Annots  *xfdfAnnots `xml:"annots"`
+}
+
+type xfdfAnnots struct {
+	Annots []xfdfAnnot `xml:",any"`
+}
+
+type xfdfFile struct {
+	Href string `xml:"href,attr"`
+}
+
+type xfdfField struct {
+	Name   string      `xml:"name,attr"`
+	Values []string    `xml:"value"`
+	Fields []xfdfField `xml:"field"`
+}
+
+type xfdfAnnot struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Contents string     `xml:"contents,omitempty"`
+	Vertices string     `xml:"vertices,omitempty"`
+	InkList  *xfdfInk   `xml:"inklist"`
+}
+
+type xfdfInk struct {
+	Gestures []string `xml:"gesture"`
+}
+
+// xfdfSubtypes are the annotation types supported by XFDF.
+var xfdfSubtypes = []string{
+	"Text", "FreeText", "Line", "Square", "Circle", "Polygon", "PolyLine", "Highlight", "Underline",
+	"Squiggly", "StrikeOut", "Stamp", "Caret", "Ink", "FileAttachment", "Sound",
+}
+
+// xfdfFlags are the names of the annotation flags, see 12.5.3.
+var xfdfFlags = []string{
+	"invisible", "hidden", "print", "nozoom", "norotate", "noview", "readonly", "locked", "togglenoview", "lockedcontents",
+}
+
+// xfdfAttrs maps XFDF attributes onto annotation dict entries of simple type.
+var xfdfAttrs = []struct {
+	attr, key string
+	kind      byte // t(ext), n(ame), f(loat), a(rray of numbers), c(olor)
+}{
+	{"name", "NM", 't'},
+	{"title", "T", 't'},
+	{"subject", "Subj", 't'},
+	{"date", "M", 't'},
+	{"creationdate", "CreationDate", 't'},
+	{"icon", "Name", 'n'},
+	{"opacity", "CA", 'f'},
+	{"rect", "Rect", 'a'},
+	{"coords", "QuadPoints", 'a'},
+	{"color", "C", 'c'},
+	{"interior-color", "IC", 'c'},
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatNumbers(ff []float64, sep string) string {
+	ss := make([]string, len(ff))
+	for i, f := range ff {
+		ss[i] = formatFloat(f)
+	}
+	return strings.Join(ss, sep)
+}
+
+// formatPoints returns ff as "x1,y1;x2,y2...".
+func formatPoints(ff []float64) string {
+	ss := []string{}
+	for i := 0; i+1 < len(ff); i += 2 {
+		ss = append(ss, formatFloat(ff[i])+","+formatFloat(ff[i+1]))
+	}
+	return strings.Join(ss, ";")
+}
+
+func parseNumbers(s string) ([]float64, error) {
+	ff := []float64{}
+	for _, s := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		ff = append(ff, f)
+	}
+	return ff, nil
+}
+
+func number(o pdf.Object) (float64, bool) {
+	switch o := o.(type) {
+	case pdf.Integer:
+		return float64(o.Value()), true
+	case pdf.Float:
+		return o.Value(), true
+	}
+	return 0, false
+}
+
+func numbers(o pdf.Object) []float64 {
+	a, ok := o.(pdf.Array)
+	if !ok {
+		return nil
+	}
+	ff := []float64{}
+	for _, o := range a {
+		if f, ok := number(o); ok {
+			ff = append(ff, f)
+		}
+	}
+	return ff
+}
+
+// formatColor returns the color components ff as "#RRGGBB".
+func formatColor(ff []float64) string {
+
+	switch len(ff) {
+	case 1:
+		ff = []float64{ff[0], ff[0], ff[0]}
+	case 3:
+	case 4:
+		k := ff[3]
+		ff = []float64{(1 - ff[0]) * (1 - k), (1 - ff[1]) * (1 - k), (1 - ff[2]) * (1 - k)}
+	default:
+		return ""
+	}
+
+	return fmt.Sprintf("#%02X%02X%02X", int(ff[0]*255+.5), int(ff[1]*255+.5), int(ff[2]*255+.5))
+}
+
+func parseColor(s string) ([]float64, error) {
+	if len(s) != 7 || s[0] != '#' {
+		return nil, errors.Errorf("invalid color: %s", s)
+	}
+	ff := make([]float64, 3)
+	for i := range ff {
+		c, err := strconv.ParseUint(s[1+2*i:3+2*i], 16, 8)
+		if err != nil {
+			return nil, errors.Errorf("invalid color: %s", s)
+		}
+		ff[i] = float64(c) / 255
+	}
+	return ff, nil
+}
+
+func xmlAttr(name, value string) xml.Attr {
+	return xml.Attr{Name: xml.Name{Local: name}, Value: value}
+}
+
+// xfdfAnnotation returns the XFDF element for an annotation.
+func xfdfAnnotation(a Annotation) (*xfdfAnnot, bool) {
+
+	d := a.Dict
+
+	st := d.Subtype()
+	if st == nil || !pdf.MemberOf(*st, xfdfSubtypes) {
+		return nil, false
+	}
+
+	xa := &xfdfAnnot{XMLName: xml.Name{Local: strings.ToLower(*st)}}
+	xa.Attrs = append(xa.Attrs, xmlAttr("page", strconv.Itoa(a.PageNr-1)))
+
+	for _, m := range xfdfAttrs {
+		o, found := d.Find(m.key)
+		if !found {
+			continue
+		}
+		var s string
+		switch m.kind {
+		case 't', 'n':
+			s = text(o)
+		case 'f':
+			if f, ok := number(o); ok {
+				s = formatFloat(f)
+			}
+		case 'a':
+			s = formatNumbers(numbers(o), ",")
+		case 'c':
+			s = formatColor(numbers(o))
+		}
+		if s != "" {
+			xa.Attrs = append(xa.Attrs, xmlAttr(m.attr, s))
+		}
+	}
+
+	if f := d.IntEntry("F"); f != nil && *f > 0 {
+		ss := []string{}
+		for i, s := range xfdfFlags {
+			if *f&(1<<uint(i)) > 0 {
+				ss = append(ss, s)
+			}
+		}
+		xa.Attrs = append(xa.Attrs, xmlAttr("flags", strings.Join(ss, ",")))
+	}
+
+	if bs, ok := d["BS"].(pdf.Dict); ok {
+		if f, ok := number(bs["W"]); ok {
+			xa.Attrs = append(xa.Attrs, xmlAttr("width", formatFloat(f)))
+		}
+	}
+
+	if ff := numbers(d["L"]); len(ff) == 4 {
+		xa.Attrs = append(xa.Attrs, xmlAttr("start", formatNumbers(ff[:2], ",")), xmlAttr("end", formatNumbers(ff[2:], ",")))
+	}
+
+	xa.Contents = text(d["Contents"])
+	xa.Vertices = formatPoints(numbers(d["Vertices"]))
+
+	if a, ok := d["InkList"].(pdf.Array); ok {
+		xa.InkList = &xfdfInk{}
+		for _, o := range a {
+			xa.InkList.Gestures = append(xa.InkList.Gestures, formatPoints(numbers(o)))
+		}
+	}
+
+	return xa, true
+}
+
+// annotation returns the annotation for an XFDF element.
+func (xa xfdfAnnot) annotation() (*Annotation, error) {
+
+	st := ""
+	for _, s := range xfdfSubtypes {
+		if strings.EqualFold(s, xa.XMLName.Local) {
+			st = s
+			break
+		}
+	}
+	if st == "" {
+		return nil, nil
+	}
+
+	d := pdf.NewDict()
+	d.InsertName("Type", "Annot")
+	d.InsertName("Subtype", st)
+
+	a := &Annotation{PageNr: 1, Dict: d}
+
+	var start, end []float64
+
+	for _, attr := range xa.Attrs {
+
+		s := attr.Value
+
+		switch attr.Name.Local {
+
+		case "page":
+			i, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, errors.Errorf("%s: invalid page: %s", st, s)
+			}
+			a.PageNr = i + 1
+			continue
+
+		case "flags":
+			f := 0
+			for _, s := range strings.Split(s, ",") {
+				for i, flag := range xfdfFlags {
+					if strings.TrimSpace(s) == flag {
+						f |= 1 << uint(i)
+					}
+				}
+			}
+			d.Insert("F", pdf.Integer(f))
+			continue
+
+		case "width":
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, errors.Errorf("%s: invalid width: %s", st, s)
+			}
+			d.Insert("BS", pdf.Dict(map[string]pdf.Object{"W": pdf.Float(f)}))
+			continue
+
+		case "start", "end":
+			ff, err := parseNumbers(s)
+			if err != nil || len(ff) != 2 {
+				return nil, errors.Errorf("%s: invalid %s: %s", st, attr.Name.Local, s)
+			}
+			if attr.Name.Local == "start" {
+				start = ff
+			} else {
+				end = ff
+			}
+			continue
+		}
+
+		for _, m := range xfdfAttrs {
+
+			if m.attr != attr.Name.Local {
+				continue
+			}
+
+			switch m.kind {
+			case 't':
+				d.Insert(m.key, encodeText(s))
+			case 'n':
+				d.InsertName(m.key, s)
+			case 'f':
+				f, err := strconv.ParseFloat(s, 64)
+				if err != nil {
+					return nil, errors.Errorf("%s: invalid %s: %s", st, m.attr, s)
+				}
+				d.Insert(m.key, pdf.Float(f))
+			case 'a':
+				ff, err := parseNumbers(s)
+				if err != nil {
+					return nil, errors.Errorf("%s: invalid %s: %s", st, m.attr, s)
+				}
+				d.Insert(m.key, pdf.NewNumberArray(ff...))
+			case 'c':
+				ff, err := parseColor(s)
+				if err != nil {
+					return nil, errors.Wrap(err, st)
+				}
+				d.Insert(m.key, pdf.NewNumberArray(ff...))
+			}
+		}
+	}
+
+	if _, found := d.Find("Rect"); !found {
+		return nil, errors.Errorf("%s: missing rect", st)
+	}
+
+	if start != nil && end != nil {
+		d.Insert("L", pdf.NewNumberArray(start[0], start[1], end[0], end[1]))
+	}
+
+	if xa.Contents != "" {
+		d.Insert("Contents", encodeText(xa.Contents))
+	}
+
+	if xa.Vertices != "" {
+		ff, err := parseNumbers(xa.Vertices)
+		if err != nil {
+			return nil, errors.Errorf("%s: invalid vertices: %s", st, xa.Vertices)
+		}
+		d.Insert("Vertices", pdf.NewNumberArray(ff...))
+	}
+
+	if xa.InkList != nil {
+		ink := pdf.Array{}
+		for _, g := range xa.InkList.Gestures {
+			ff, err := parseNumbers(g)
+			if err != nil {
+				return nil, errors.Errorf("%s: invalid gesture: %s", st, g)
+			}
+			ink = append(ink, pdf.NewNumberArray(ff...))
+		}
+		d.Insert("InkList", ink)
+	}
+
+	return a, nil
+}
+
+func collectXFDFFields(xff []xfdfField, parentName string, ff *[]Field) {
+
+	for _, xf := range xff {
+
+		name := xf.Name
+		if parentName != "" {
+			name = parentName + "." + name
+		}
+
+		switch len(xf.Values) {
+		case 0:
+		case 1:
+			*ff = append(*ff, Field{Name: name, Value: encodeText(xf.Values[0])})
+		default:
+			a := pdf.Array{}
+			for _, v := range xf.Values {
+				a = append(a, encodeText(v))
+			}
+			*ff = append(*ff, Field{Name: name, Value: a})
+		}
+
+		collectXFDFFields(xf.Fields, name, ff)
+	}
+}
+
+// ReadXFDF reads form field values and annotations from an XFDF file.
+func ReadXFDF(r io.Reader) (*Data, error) {
+
+	doc := xfdfDoc{}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, errors.Wrap(err, "xfdf")
+	}
+
+	data := &Data{}
+
+	if doc.File != nil {
+		data.File = doc.File.Href
+	}
+
+	collectXFDFFields(doc.Fields, "", &data.Fields)
+
+	if doc.Annots == nil {
+		return data, nil
+	}
+
+	for _, xa := range doc.Annots.Annots {
+		a, err := xa.annotation()
+		if err != nil {
+			return nil, errors.Wrap(err, "xfdf")
+		}
+		if a != nil {
+			data.Annots = append(data.Annots, *a)
+		}
+	}
+
+	return data, nil
+}
+
+func (n *fieldNode) xfdfFields() []xfdfField {
+
+	xff := []xfdfField{}
+
+	for _, k := range n.kids {
+
+		xf := xfdfField{Name: k.name}
+
+		switch v := k.value.(type) {
+		case nil:
+		case pdf.Array:
+			for _, o := range v {
+				xf.Values = append(xf.Values, text(o))
+			}
+		default:
+			xf.Values = []string{text(v)}
+		}
+
+		xf.Fields = k.xfdfFields()
+		xff = append(xff, xf)
+	}
+
+	return xff
+}
+
+// WriteXFDF writes the form field values and annotations of data as XFDF file.
+// Annotation types not supported by XFDF are skipped.
+func WriteXFDF(w io.Writer, data *Data) error {
+
+	doc := xfdfDoc{Xmlns: xfdfNamespace}
+
+	if data.File != "" {
+		doc.File = &xfdfFile{Href: data.File}
+	}
+
+	doc.Fields = fieldTree(data.Fields).xfdfFields()
+
+	if len(data.Annots) > 0 {
+		doc.Annots = &xfdfAnnots{}
+		for _, a := range data.Annots {
+			if xa, ok := xfdfAnnotation(a); ok {
+				doc.Annots.Annots = append(doc.Annots.Annots, *xa)
+			}
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
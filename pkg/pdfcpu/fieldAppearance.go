@@ -0,0 +1,507 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/denisbetsi/pdfcpu/pkg/filter"
+	"github.com/denisbetsi/pdfcpu/pkg/pdfcpu/fonts/metrics"
+)
+
+// defaultFieldFont is the resource name of the font used for fields lacking a usable font.
+const defaultFieldFont = "Helv"
+
+// winAnsi maps the runes of WinAnsiEncoding 0x80-0x9F, see D.2 Latin Character Set and Encodings.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B,
+	'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// winAnsiBytes returns s encoded in WinAnsiEncoding.
+// Runes not covered by the encoding are replaced by '?' in which case ok is false.
+func winAnsiBytes(s string) (bb []byte, ok bool) {
+	ok = true
+	for _, r := range s {
+		switch {
+		case r >= 0x20 && r < 0x7F || r >= 0xA0 && r <= 0xFF:
+			bb = append(bb, byte(r))
+		case winAnsi[r] > 0:
+			bb = append(bb, winAnsi[r])
+		default:
+			bb = append(bb, '?')
+			ok = false
+		}
+	}
+	return bb, ok
+}
+
+// fieldFont represents the font used for rendering a field value.
+type fieldFont struct {
+	name     string  // resource name
+	baseFont string  // standard font used for metrics
+	size     float64 // 0 for auto size
+	color    string  // color operators
+}
+
+func (f fieldFont) textWidth(bb []byte, fontSize float64) float64 {
+	w := 0
+	for _, b := range bb {
+		w += metrics.CharWidth(f.baseFont, int(b))
+	}
+	return float64(w) * fontSize / 1000
+}
+
+// parseDA parses a default appearance string, eg. "/Helv 12 Tf 0 g".
+func parseDA(da string) fieldFont {
+
+	f := fieldFont{color: "0 g"}
+
+	colors := []string{}
+	operands := []string{}
+
+	for _, s := range strings.Fields(da) {
+		switch s {
+		case "Tf":
+			if n := len(operands); n >= 2 {
+				f.name = strings.TrimPrefix(operands[n-2], "/")
+				f.size, _ = strconv.ParseFloat(operands[n-1], 64)
+			}
+		case "g", "rg", "k":
+			colors = append(colors, strings.Join(append(operands, s), " "))
+		default:
+			operands = append(operands, s)
+			continue
+		}
+		operands = nil
+	}
+
+	if len(colors) > 0 {
+		f.color = colors[len(colors)-1]
+	}
+
+	return f
+}
+
+// colorOperator returns the operator setting color c for fill or stroke.
+func colorOperator(c []float64, stroke bool) string {
+
+	op := ""
+	switch len(c) {
+	case 1:
+		op = "g"
+	case 3:
+		op = "rg"
+	case 4:
+		op = "k"
+	default:
+		return ""
+	}
+
+	if stroke {
+		op = strings.ToUpper(op)
+	}
+
+	ss := make([]string, len(c))
+	for i, f := range c {
+		ss[i] = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	return strings.Join(ss, " ") + " " + op
+}
+
+// simpleFontDict returns true if d is a font explicitly using WinAnsiEncoding.
+// Fonts without an encoding use their built-in encoding which may not match WinAnsi encoded text.
+func (xRefTable *XRefTable) simpleFontDict(d Dict) bool {
+
+	if st := d.Subtype(); st == nil || !MemberOf(*st, []string{"Type1", "TrueType", "MMType1"}) {
+		return false
+	}
+
+	o, err := xRefTable.Dereference(d["Encoding"])
+	if err != nil || o == nil {
+		return false
+	}
+
+	switch o := o.(type) {
+
+	case Name:
+		return o.Value() == "WinAnsiEncoding"
+
+	case Dict:
+		if _, found := o.Find("Differences"); found {
+			return false
+		}
+		n := o.NameEntry("BaseEncoding")
+		return n != nil && *n == "WinAnsiEncoding"
+	}
+
+	return false
+}
+
+// defaultFieldFontIndRef returns the Helvetica font of the form default resources creating it if necessary.
+func (xRefTable *XRefTable) defaultFieldFontIndRef(af Dict) (*IndirectRef, error) {
+	return xRefTable.formFontIndRef(af, defaultFieldFont, "Helvetica")
+}
+
+// formFontIndRef returns the standard font baseFont named name of the form default resources creating it if necessary.
+func (xRefTable *XRefTable) formFontIndRef(af Dict, name, baseFont string) (*IndirectRef, error) {
+
+	dr, err := xRefTable.DereferenceDict(af["DR"])
+	if err != nil {
+		return nil, err
+	}
+	if dr == nil {
+		dr = NewDict()
+		af.Insert("DR", dr)
+	}
+
+	fonts, err := xRefTable.DereferenceDict(dr["Font"])
+	if err != nil {
+		return nil, err
+	}
+	if fonts == nil {
+		fonts = NewDict()
+		dr.Insert("Font", fonts)
+	}
+
+	symbolic := baseFont == "Symbol" || baseFont == "ZapfDingbats"
+
+	if ir := fonts.IndirectRefEntry(name); ir != nil {
+		if d, err := xRefTable.DereferenceDict(*ir); err == nil && d != nil && (symbolic || xRefTable.simpleFontDict(d)) {
+			return ir, nil
+		}
+	}
+
+	d := NewDict()
+	d.InsertName("Type", "Font")
+	d.InsertName("Subtype", "Type1")
+	d.InsertName("BaseFont", baseFont)
+	if !symbolic {
+		d.InsertName("Encoding", "WinAnsiEncoding")
+	}
+
+	ir, err := xRefTable.IndRefForNewObject(d)
+	if err != nil {
+		return nil, err
+	}
+
+	fonts.Update(name, *ir)
+
+	return ir, nil
+}
+
+// fieldFontIndRef resolves the font of the default appearance of a field.
+func (xRefTable *XRefTable) fieldFontIndRef(af Dict, drs []Object, f *fieldFont) (*IndirectRef, error) {
+
+	for _, o := range drs {
+
+		dr, err := xRefTable.DereferenceDict(o)
+		if err != nil || dr == nil {
+			continue
+		}
+
+		fonts, err := xRefTable.DereferenceDict(dr["Font"])
+		if err != nil || fonts == nil {
+			continue
+		}
+
+		ir := fonts.IndirectRefEntry(f.name)
+		if ir == nil {
+			continue
+		}
+
+		d, err := xRefTable.DereferenceDict(*ir)
+		if err != nil || d == nil || !xRefTable.simpleFontDict(d) {
+			break
+		}
+
+		f.baseFont = "Helvetica"
+		if bf := d.NameEntry("BaseFont"); bf != nil {
+			s := *bf
+			if i := strings.Index(s, "+"); i == 6 {
+				// Strip subset prefix.
+				s = s[i+1:]
+			}
+			if MemberOf(s, metrics.FontNames()) {
+				f.baseFont = s
+			}
+		}
+
+		return ir, nil
+	}
+
+	f.name = defaultFieldFont
+	f.baseFont = "Helvetica"
+
+	return xRefTable.defaultFieldFontIndRef(af)
+}
+
+// wrapText breaks bb into lines fitting into width.
+func (f fieldFont) wrapText(bb []byte, fontSize, width float64) [][]byte {
+
+	lines := [][]byte{}
+
+	for _, para := range bytes.Split(bb, []byte{'\n'}) {
+
+		line := []byte{}
+
+		for _, word := range bytes.Split(bytes.TrimRight(para, "\r"), []byte{' '}) {
+			if len(line) == 0 {
+				line = word
+				continue
+			}
+			s := append(append(append([]byte{}, line...), ' '), word...)
+			if f.textWidth(s, fontSize) > width {
+				lines = append(lines, line)
+				line = word
+				continue
+			}
+			line = s
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+func escapedText(bb []byte) string {
+	s, err := Escape(string(bb))
+	if err != nil {
+		return ""
+	}
+	return *s
+}
+
+// alignedX returns the horizontal offset of text of width tw aligned within width w according to q.
+func alignedX(q int, tw, w, padding float64) float64 {
+	switch q {
+	case 1:
+		return (w - tw) / 2
+	case 2:
+		return w - padding - tw
+	}
+	return padding
+}
+
+// fieldAppearanceContent returns the content of the normal appearance of a text or choice field widget of width w and height h.
+func (f *formField) fieldAppearanceContent(font *fieldFont, bw, w, h float64) ([]byte, bool) {
+
+	var buf bytes.Buffer
+
+	padding := 2 + bw
+
+	fmt.Fprintf(&buf, "%.2f %.2f %.2f %.2f re W n\n", bw, bw, w-2*bw, h-2*bw)
+
+	ok := true
+
+	encode := func(s string) []byte {
+		bb, ok1 := winAnsiBytes(s)
+		ok = ok && ok1
+		return bb
+	}
+
+	switch {
+
+	case f.Type == FieldListBox:
+		fontSize := font.size
+		if fontSize == 0 {
+			fontSize = 12
+		}
+		leading := fontSize * 1.15
+		for i, opt := range f.opts {
+			y := h - padding - float64(i+1)*leading
+			if MemberOf(opt[0], f.Values) {
+				fmt.Fprintf(&buf, "q 0.6 0.75 0.85 rg %.2f %.2f %.2f %.2f re f Q\n", bw, y, w-2*bw, leading)
+			}
+			bb := encode(opt[1])
+			x := alignedX(f.q, font.textWidth(bb, fontSize), w, padding)
+			fmt.Fprintf(&buf, "BT /%s %.2f Tf %s %.2f %.2f Td (%s) Tj ET\n",
+				font.name, fontSize, font.color, x, y+0.25*leading, escapedText(bb))
+		}
+
+	case f.Type == FieldText && f.Flags&fieldMultiline > 0:
+		fontSize := font.size
+		if fontSize == 0 {
+			fontSize = 12
+		}
+		leading := fontSize * 1.15
+		for i, line := range font.wrapText(encode(f.Value), fontSize, w-2*padding) {
+			y := h - padding - fontSize - float64(i)*leading
+			x := alignedX(f.q, font.textWidth(line, fontSize), w, padding)
+			fmt.Fprintf(&buf, "BT /%s %.2f Tf %s %.2f %.2f Td (%s) Tj ET\n",
+				font.name, fontSize, font.color, x, y, escapedText(line))
+		}
+
+	default:
+		s := f.Value
+		switch {
+		case f.Type == FieldComboBox:
+			for _, opt := range f.opts {
+				if opt[0] == s {
+					s = opt[1]
+					break
+				}
+			}
+		case f.Flags&fieldPassword > 0:
+			s = strings.Repeat("*", len([]rune(s)))
+		}
+
+		bb := encode(s)
+
+		fontSize := font.size
+		if fontSize == 0 {
+			fontSize = (h - 2*bw) * 0.7
+			if tw := font.textWidth(bb, fontSize); tw > w-2*padding && tw > 0 {
+				fontSize *= (w - 2*padding) / tw
+			}
+			if fontSize < 4 {
+				fontSize = 4
+			}
+		}
+
+		y := (h-fontSize)/2 + 0.22*fontSize
+
+		if f.Type == FieldText && f.Flags&fieldComb > 0 && f.MaxLen > 0 {
+			cell := w / float64(f.MaxLen)
+			for i, b := range bb {
+				x := float64(i)*cell + (cell-font.textWidth([]byte{b}, fontSize))/2
+				fmt.Fprintf(&buf, "BT /%s %.2f Tf %s %.2f %.2f Td (%s) Tj ET\n",
+					font.name, fontSize, font.color, x, y, escapedText([]byte{b}))
+			}
+			break
+		}
+
+		x := alignedX(f.q, font.textWidth(bb, fontSize), w, padding)
+		fmt.Fprintf(&buf, "BT /%s %.2f Tf %s %.2f %.2f Td (%s) Tj ET\n",
+			font.name, fontSize, font.color, x, y, escapedText(bb))
+	}
+
+	return buf.Bytes(), ok
+}
+
+// widgetBackground writes the background and border of the widget w of width and height as defined by its MK dict to buf.
+// Returns the border width.
+func (xRefTable *XRefTable) widgetBackground(buf *bytes.Buffer, w Dict, width, height float64) (float64, error) {
+
+	mk, err := xRefTable.DereferenceDict(w["MK"])
+	if err != nil || mk == nil {
+		return 0, err
+	}
+
+	if op := colorOperator(xRefTable.numberArray(mk["BG"]), false); op != "" {
+		fmt.Fprintf(buf, "%s 0 0 %.2f %.2f re f\n", op, width, height)
+	}
+
+	op := colorOperator(xRefTable.numberArray(mk["BC"]), true)
+	if op == "" {
+		return 0, nil
+	}
+
+	bw := 1.
+	if bs, err := xRefTable.DereferenceDict(w["BS"]); err == nil && bs != nil {
+		if o, found := bs.Find("W"); found {
+			if f, err := xRefTable.DereferenceNumber(o); err == nil {
+				bw = f
+			}
+		}
+	} else if ff := xRefTable.numberArray(w["Border"]); len(ff) >= 3 {
+		bw = ff[2]
+	}
+
+	if bw > 0 {
+		fmt.Fprintf(buf, "%s %.2f w %.2f %.2f %.2f %.2f re S\n", op, bw, bw/2, bw/2, width-bw, height-bw)
+	}
+
+	return bw, nil
+}
+
+// formXObject returns a new form XObject for an appearance of size w x h using a single font.
+func (xRefTable *XRefTable) formXObject(b []byte, w, h float64, fontName string, fontIndRef IndirectRef) (*IndirectRef, error) {
+
+	sd := &StreamDict{
+		Dict: Dict(
+			map[string]Object{
+				"Type":      Name("XObject"),
+				"Subtype":   Name("Form"),
+				"BBox":      NewNumberArray(0, 0, w, h),
+				"Resources": Dict(map[string]Object{"Font": Dict(map[string]Object{fontName: fontIndRef})}),
+			},
+		),
+		Content:        b,
+		FilterPipeline: []PDFFilter{{Name: filter.Flate, DecodeParms: nil}},
+	}
+	sd.InsertName("Filter", filter.Flate)
+
+	if err := encodeStream(sd); err != nil {
+		return nil, err
+	}
+
+	return xRefTable.IndRefForNewObject(*sd)
+}
+
+// fieldAppearance renders the value of the text or choice field f into a new normal appearance of its widget w.
+// Returns false if the value contains characters not covered by the field font.
+func (xRefTable *XRefTable) fieldAppearance(af Dict, f *formField, w Dict) (bool, error) {
+
+	a := xRefTable.numberArray(w["Rect"])
+	if len(a) != 4 {
+		return true, nil
+	}
+	r := normalizedRect(a[0], a[1], a[2], a[3])
+	width, height := r[2]-r[0], r[3]-r[1]
+
+	da := f.da
+	if o, found := w.Find("DA"); found {
+		da = xRefTable.text(o)
+	}
+	font := parseDA(da)
+
+	fontIndRef, err := xRefTable.fieldFontIndRef(af, []Object{w["DR"], f.d["DR"], af["DR"]}, &font)
+	if err != nil {
+		return false, err
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString("/Tx BMC\nq\n")
+
+	bw, err := xRefTable.widgetBackground(&buf, w, width, height)
+	if err != nil {
+		return false, err
+	}
+
+	bb, ok := f.fieldAppearanceContent(&font, bw, width, height)
+	buf.Write(bb)
+
+	buf.WriteString("Q\nEMC\n")
+
+	ir, err := xRefTable.formXObject(buf.Bytes(), width, height, font.name, *fontIndRef)
+	if err != nil {
+		return false, err
+	}
+
+	w.Update("AP", Dict(map[string]Object{"N": *ir}))
+
+	return ok, nil
+}
@@ -0,0 +1,502 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/denisbetsi/pdfcpu/pkg/filter"
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// Annotation flags, see 12.5.3.
+const (
+	annHidden = 1 << 1
+	annPrint  = 1 << 2
+	annNoView = 1 << 5
+)
+
+// Flatten represents the configuration for flattening annotations.
+type Flatten struct {
+	Types   []string // Annotation subtypes to be flattened, all if empty.
+	Hidden  bool     // Also flatten annotations flagged Hidden or NoView.
+	NoPrint bool     // Also flatten annotations not flagged Print.
+}
+
+// flattenable returns true if the flags of the annotation dict d qualify for flattening.
+func (f Flatten) flattenable(d Dict) bool {
+
+	flags := 0
+	if i := d.IntEntry("F"); i != nil {
+		flags = *i
+	}
+
+	if flags&(annHidden|annNoView) > 0 && !f.Hidden {
+		return false
+	}
+
+	return flags&annPrint > 0 || f.NoPrint
+}
+
+// appearance returns the normal appearance stream of the annotation dict d.
+// A nil stream with found == true means the annotation does not draw anything in its current state.
+func (xRefTable *XRefTable) appearance(d Dict) (ir *IndirectRef, sd *StreamDict, found bool, err error) {
+
+	ap, err := xRefTable.DereferenceDict(d["AP"])
+	if err != nil || ap == nil {
+		return nil, nil, false, err
+	}
+
+	o, found := ap.Find("N")
+	if !found {
+		return nil, nil, false, nil
+	}
+
+	o1, err := xRefTable.Dereference(o)
+	if err != nil || o1 == nil {
+		return nil, nil, false, err
+	}
+
+	if states, ok := o1.(Dict); ok {
+		// Choose the appearance for the current appearance state.
+		as := d.NameEntry("AS")
+		if as == nil {
+			return nil, nil, true, nil
+		}
+		if o, found = states.Find(*as); !found {
+			return nil, nil, true, nil
+		}
+	}
+
+	ir1, ok := o.(IndirectRef)
+	if !ok {
+		return nil, nil, false, errors.New("flattenAnnotations: corrupt appearance stream")
+	}
+
+	sd, err = xRefTable.DereferenceStreamDict(ir1)
+	if err != nil || sd == nil {
+		return nil, nil, false, err
+	}
+
+	return &ir1, sd, true, nil
+}
+
+// appearanceMatrix returns the matrix mapping the appearance sd onto rect, see 12.5.5 Algorithm: Appearance streams.
+// The Matrix of sd gets applied by the Do operator.
+func (xRefTable *XRefTable) appearanceMatrix(sd *StreamDict, rect *Rectangle) (*matrix, error) {
+
+	bb := xRefTable.numberArray(sd.Dict["BBox"])
+	if len(bb) != 4 {
+		return nil, errors.New("flattenAnnotations: appearance stream missing BBox")
+	}
+
+	m := identMatrix
+	if ff := xRefTable.numberArray(sd.Dict["Matrix"]); len(ff) == 6 {
+		m[0][0], m[0][1], m[1][0], m[1][1], m[2][0], m[2][1] = ff[0], ff[1], ff[2], ff[3], ff[4], ff[5]
+	}
+
+	r := m.transformRect(bb[0], bb[1], bb[2], bb[3])
+	if r.Width() == 0 || r.Height() == 0 || rect.Width() == 0 || rect.Height() == 0 {
+		return nil, nil
+	}
+
+	a := identMatrix
+	a[0][0] = rect.Width() / r.Width()
+	a[1][1] = rect.Height() / r.Height()
+	a[2][0] = rect.LL.X - r.LL.X*a[0][0]
+	a[2][1] = rect.LL.Y - r.LL.Y*a[1][1]
+
+	return &a, nil
+}
+
+func (xRefTable *XRefTable) flatContentStream(b []byte) (*IndirectRef, error) {
+
+	sd := &StreamDict{Dict: NewDict()}
+	sd.InsertName("Filter", filter.Flate)
+	sd.FilterPipeline = []PDFFilter{{Name: filter.Flate, DecodeParms: nil}}
+	sd.Content = b
+
+	if err := encodeStream(sd); err != nil {
+		return nil, err
+	}
+
+	return xRefTable.IndRefForNewObject(*sd)
+}
+
+// appendPageContent appends b to the content of the page dict d drawing the appearances
+// in the XObject resources forms. The original content gets wrapped into q/Q.
+func (xRefTable *XRefTable) appendPageContent(d Dict, resources Dict, forms map[string]IndirectRef, b []byte) error {
+
+	// Copy the resources in effect so shared or inherited resources remain untouched.
+	res := NewDict()
+	for k, v := range resources {
+		res[k] = v
+	}
+
+	xobjs := NewDict()
+	xo, err := xRefTable.DereferenceDict(res["XObject"])
+	if err != nil {
+		return err
+	}
+	for k, v := range xo {
+		xobjs[k] = v
+	}
+	for k, v := range forms {
+		xobjs[k] = v
+	}
+	res["XObject"] = xobjs
+
+	d.Update("Resources", res)
+
+	o, found := d.Find("Contents")
+	if !found {
+		ir, err := xRefTable.flatContentStream(b)
+		if err != nil {
+			return err
+		}
+		d.Insert("Contents", *ir)
+		return nil
+	}
+
+	irPre, err := xRefTable.flatContentStream([]byte("q\n"))
+	if err != nil {
+		return err
+	}
+
+	irPost, err := xRefTable.flatContentStream(append([]byte("Q\n"), b...))
+	if err != nil {
+		return err
+	}
+
+	contents := Array{*irPre}
+
+	o1, err := xRefTable.Dereference(o)
+	if err != nil {
+		return err
+	}
+	if a, ok := o1.(Array); ok {
+		contents = append(contents, a...)
+	} else {
+		contents = append(contents, o)
+	}
+
+	d.Update("Contents", append(contents, *irPost))
+
+	return nil
+}
+
+// formName returns a name for an XObject resource not used in xobjs nor forms.
+func formName(xobjs Dict, forms map[string]IndirectRef) string {
+	for i := 0; ; i++ {
+		s := fmt.Sprintf("Fa%d", i)
+		if _, found := xobjs.Find(s); found {
+			continue
+		}
+		if _, found := forms[s]; found {
+			continue
+		}
+		return s
+	}
+}
+
+// flattenPageAnnotations draws the normal appearance of each annotation of page pageNr matching into the page content
+// and removes it along with its popup. Annotations without appearance are left untouched.
+// Returns the number of flattened annotations and the object numbers of those being indirect objects.
+func (xRefTable *XRefTable) flattenPageAnnotations(pageNr int, match func(d Dict) bool) (int, IntSet, error) {
+
+	d, inhPAttrs, err := xRefTable.PageDict(pageNr)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	arr, update, err := xRefTable.pageAnnots(d)
+	if err != nil || len(arr) == 0 {
+		return 0, nil, err
+	}
+
+	xobjs, err := xRefTable.DereferenceDict(inhPAttrs.resources["XObject"])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var buf bytes.Buffer
+	forms := map[string]IndirectRef{}
+	flattened := IntSet{}
+	popups := IntSet{}
+	remove := map[int]bool{}
+
+	for i, o := range arr {
+
+		ad, err := xRefTable.DereferenceDict(o)
+		if err != nil {
+			return 0, nil, err
+		}
+		if ad == nil || ad.Subtype() == nil || *ad.Subtype() == "Popup" || !match(ad) {
+			continue
+		}
+
+		ir, sd, found, err := xRefTable.appearance(ad)
+		if err != nil {
+			return 0, nil, err
+		}
+		if !found {
+			log.Info.Printf("flattenAnnotations: page %d: skipping %s annotation without appearance\n", pageNr, *ad.Subtype())
+			continue
+		}
+
+		if sd != nil {
+			a := xRefTable.numberArray(ad["Rect"])
+			if len(a) != 4 {
+				return 0, nil, errors.Errorf("flattenAnnotations: page %d: %s annotation missing Rect", pageNr, *ad.Subtype())
+			}
+			nr := normalizedRect(a[0], a[1], a[2], a[3])
+
+			m, err := xRefTable.appearanceMatrix(sd, Rect(nr[0], nr[1], nr[2], nr[3]))
+			if err != nil {
+				return 0, nil, err
+			}
+
+			if m != nil {
+				if sd.Subtype() == nil {
+					sd.InsertName("Subtype", "Form")
+				}
+				name := formName(xobjs, forms)
+				forms[name] = *ir
+				fmt.Fprintf(&buf, "q %.4f %.4f %.4f %.4f %.4f %.4f cm /%s Do Q\n",
+					m[0][0], m[0][1], m[1][0], m[1][1], m[2][0], m[2][1], name)
+			}
+		}
+
+		remove[i] = true
+
+		if ir, ok := o.(IndirectRef); ok {
+			flattened[ir.ObjectNumber.Value()] = true
+		}
+
+		if ir := ad.IndirectRefEntry("Popup"); ir != nil {
+			popups[ir.ObjectNumber.Value()] = true
+		}
+	}
+
+	if len(remove) == 0 {
+		return 0, nil, nil
+	}
+
+	a := Array{}
+	for i, o := range arr {
+		if remove[i] {
+			continue
+		}
+		if ir, ok := o.(IndirectRef); ok && popups[ir.ObjectNumber.Value()] {
+			continue
+		}
+		a = append(a, o)
+	}
+
+	if len(a) == 0 {
+		d.Delete("Annots")
+	} else {
+		update(a)
+	}
+
+	if buf.Len() == 0 {
+		return len(remove), flattened, nil
+	}
+
+	return len(remove), flattened, xRefTable.appendPageContent(d, inhPAttrs.resources, forms, buf.Bytes())
+}
+
+// FlattenAnnotations draws the normal appearance of the annotations of the selected pages into the page content
+// and removes them. Widget annotations belong to form fields and are never flattened.
+// Returns the number of flattened annotations.
+func FlattenAnnotations(ctx *Context, selectedPages IntSet, f *Flatten) (int, error) {
+
+	if f == nil {
+		f = &Flatten{}
+	}
+
+	if MemberOf("Widget", f.Types) {
+		return 0, errors.New("flattenAnnotations: widget annotations belong to form fields")
+	}
+
+	match := func(d Dict) bool {
+		t := *d.Subtype()
+		if t == "Widget" || len(f.Types) > 0 && !MemberOf(t, f.Types) {
+			return false
+		}
+		return f.flattenable(d)
+	}
+
+	count := 0
+
+	for _, i := range sortedPageNrs(selectedPages) {
+		n, _, err := ctx.flattenPageAnnotations(i, match)
+		if err != nil {
+			return 0, err
+		}
+		count += n
+	}
+
+	log.Info.Printf("flattened %d annotations\n", count)
+
+	return count, nil
+}
+
+// ensureFieldAppearances generates missing appearance streams for text and choice fields.
+// If the form asks for NeedAppearances all of them get regenerated.
+func (xRefTable *XRefTable) ensureFieldAppearances(af Dict, ff []*formField) error {
+
+	regenerate := false
+	if b := af.BooleanEntry("NeedAppearances"); b != nil {
+		regenerate = *b
+	}
+
+	for _, f := range ff {
+
+		if f.Type != FieldText && f.Type != FieldComboBox && f.Type != FieldListBox {
+			continue
+		}
+
+		for _, w := range f.widgets {
+
+			if !regenerate {
+				_, _, found, err := xRefTable.appearance(w.d)
+				if err != nil {
+					return err
+				}
+				if found {
+					continue
+				}
+			}
+
+			ok, err := xRefTable.fieldAppearance(af, f, w.d)
+			if err != nil {
+				return errors.Wrapf(err, "flattenForm: %s", f.Name)
+			}
+			if !ok {
+				log.Info.Printf("flattenForm: %s: value not fully covered by field font\n", f.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// removePageWidgets removes all widget annotations of page pageNr.
+func (xRefTable *XRefTable) removePageWidgets(pageNr int) error {
+
+	d, _, err := xRefTable.PageDict(pageNr)
+	if err != nil {
+		return err
+	}
+
+	arr, update, err := xRefTable.pageAnnots(d)
+	if err != nil || len(arr) == 0 {
+		return err
+	}
+
+	a := Array{}
+	for _, o := range arr {
+		ad, err := xRefTable.DereferenceDict(o)
+		if err != nil {
+			return err
+		}
+		if ad != nil && ad.Subtype() != nil && *ad.Subtype() == "Widget" {
+			continue
+		}
+		a = append(a, o)
+	}
+
+	if len(a) == len(arr) {
+		return nil
+	}
+
+	if len(a) == 0 {
+		d.Delete("Annots")
+		return nil
+	}
+
+	update(a)
+
+	return nil
+}
+
+// FlattenForm draws the appearance of all visible and printable form field widgets into the page content
+// and removes the widgets along with the AcroForm. Hidden and non printing widgets get discarded.
+// Missing appearances of text and choice fields get generated from the field's default appearance.
+// With annots set all other annotations get flattened as well.
+// Returns the number of flattened widgets.
+func FlattenForm(ctx *Context, annots bool) (int, error) {
+
+	af, ff, err := ctx.formFields()
+	if err != nil {
+		return 0, err
+	}
+
+	if af == nil {
+		return 0, errors.New("flattenForm: no form available")
+	}
+
+	if err := ctx.ensureFieldAppearances(af, ff); err != nil {
+		return 0, err
+	}
+
+	// Only printable widgets get flattened.
+	f := Flatten{}
+
+	match := func(d Dict) bool {
+		return *d.Subtype() == "Widget" && f.flattenable(d)
+	}
+
+	count := 0
+
+	for i := 1; i <= ctx.PageCount; i++ {
+
+		n, _, err := ctx.flattenPageAnnotations(i, match)
+		if err != nil {
+			return 0, err
+		}
+		count += n
+
+		// Remove hidden and non printing widgets and widgets without appearance.
+		if err := ctx.removePageWidgets(i); err != nil {
+			return 0, err
+		}
+	}
+
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return 0, err
+	}
+	rootDict.Delete("AcroForm")
+
+	log.Info.Printf("flattened %d form field widgets\n", count)
+
+	if annots {
+		pages := IntSet{}
+		for i := 1; i <= ctx.PageCount; i++ {
+			pages[i] = true
+		}
+		if _, err := FlattenAnnotations(ctx, pages, nil); err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
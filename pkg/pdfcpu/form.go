@@ -0,0 +1,976 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// The field types.
+const (
+	FieldText       = "Text"
+	FieldCheckBox   = "CheckBox"
+	FieldRadioGroup = "RadioGroup"
+	FieldPushButton = "PushButton"
+	FieldComboBox   = "ComboBox"
+	FieldListBox    = "ListBox"
+	FieldSignature  = "Signature"
+)
+
+// Field flags, see 12.7.3.1 and 12.7.4.
+const (
+	fieldReadOnly          = 1 << 0
+	fieldRequired          = 1 << 1
+	fieldNoExport          = 1 << 2
+	fieldMultiline         = 1 << 12
+	fieldPassword          = 1 << 13
+	fieldNoToggleToOff     = 1 << 14
+	fieldRadio             = 1 << 15
+	fieldPushbutton        = 1 << 16
+	fieldCombo             = 1 << 17
+	fieldEdit              = 1 << 18
+	fieldSort              = 1 << 19
+	fieldFileSelect        = 1 << 20
+	fieldMultiSelect       = 1 << 21
+	fieldDoNotSpellCheck   = 1 << 22
+	fieldDoNotScroll       = 1 << 23
+	fieldComb              = 1 << 24
+	fieldRichText          = 1 << 25 // Text fields
+	fieldRadiosInUnison    = 1 << 25 // Radio groups
+	fieldCommitOnSelChange = 1 << 26
+)
+
+var fieldFlagNames = []struct {
+	flag  int
+	name  string
+	types []string // nil for all field types.
+}{
+	{fieldReadOnly, "ReadOnly", nil},
+	{fieldRequired, "Required", nil},
+	{fieldNoExport, "NoExport", nil},
+	{fieldMultiline, "Multiline", []string{FieldText}},
+	{fieldPassword, "Password", []string{FieldText}},
+	{fieldFileSelect, "FileSelect", []string{FieldText}},
+	{fieldDoNotSpellCheck, "DoNotSpellCheck", []string{FieldText, FieldComboBox, FieldListBox}},
+	{fieldDoNotScroll, "DoNotScroll", []string{FieldText}},
+	{fieldComb, "Comb", []string{FieldText}},
+	{fieldRichText, "RichText", []string{FieldText}},
+	{fieldNoToggleToOff, "NoToggleToOff", []string{FieldRadioGroup}},
+	{fieldRadiosInUnison, "RadiosInUnison", []string{FieldRadioGroup}},
+	{fieldEdit, "Edit", []string{FieldComboBox}},
+	{fieldSort, "Sort", []string{FieldComboBox, FieldListBox}},
+	{fieldMultiSelect, "MultiSelect", []string{FieldListBox}},
+	{fieldCommitOnSelChange, "CommitOnSelChange", []string{FieldComboBox, FieldListBox}},
+}
+
+// Field represents a terminal form field, see 12.7.3 Field Dictionaries.
+type Field struct {
+	Name    string   // The fully qualified field name.
+	Type    string   // One of the field types.
+	Value   string   // The current value, the selected option or button state.
+	Values  []string // The selected options of a multi select list box.
+	Default string   // DV
+	Options []string // The export values of choice fields, the on states of buttons.
+	Flags   int      // Ff
+	MaxLen  int      // The maximum length of the value of a text field.
+	Pages   []int    // The pages displaying the field.
+	ObjNr   int      // The object number of the field dict.
+}
+
+// FlagNames returns the names of the flags set for f.
+func (f Field) FlagNames() []string {
+	ss := []string{}
+	for _, fl := range fieldFlagNames {
+		if f.Flags&fl.flag > 0 && (fl.types == nil || MemberOf(f.Type, fl.types)) {
+			ss = append(ss, fl.name)
+		}
+	}
+	return ss
+}
+
+func (f Field) String() string {
+
+	v := strconv.Quote(f.Value)
+	switch f.Type {
+	case FieldCheckBox, FieldRadioGroup:
+		v = f.Value
+	case FieldListBox:
+		if f.Flags&fieldMultiSelect > 0 {
+			v = fmt.Sprintf("%q", f.Values)
+		}
+	case FieldPushButton, FieldSignature:
+		v = ""
+	}
+
+	s := fmt.Sprintf("%-11s %-24s %s", f.Type, f.Name, v)
+
+	if len(f.Options) > 0 {
+		s += fmt.Sprintf(" options=%v", f.Options)
+	}
+
+	if ss := f.FlagNames(); len(ss) > 0 {
+		s += fmt.Sprintf(" flags=%s", strings.Join(ss, ","))
+	}
+
+	if len(f.Pages) > 0 {
+		pp := make([]string, len(f.Pages))
+		for i, p := range f.Pages {
+			pp[i] = strconv.Itoa(p)
+		}
+		s += fmt.Sprintf(" page=%s", strings.Join(pp, ","))
+	}
+
+	return s
+}
+
+// widget is a widget annotation of a field.
+type widget struct {
+	d      Dict
+	objNr  int
+	pageNr int
+}
+
+// formField is a terminal field along with its inherited attributes and widgets.
+type formField struct {
+	Field
+	d       Dict
+	ft      string      // FT
+	da      string      // DA
+	q       int         // Q
+	opts    [][2]string // Choice field options: export value, display text.
+	v       Object      // The signature dict of a signature field.
+	widgets []widget
+}
+
+// inheritedFieldAttrs are the inheritable attributes of the field tree, see 12.7.3.1 and 12.7.3.3.
+type inheritedFieldAttrs struct {
+	ft     string
+	ff     int
+	da     string
+	q      int
+	v, dv  Object
+	maxLen int
+}
+
+// onState returns the on state of the widget of a button, the first appearance state other than Off.
+func (xRefTable *XRefTable) onState(d Dict) string {
+
+	ap, err := xRefTable.DereferenceDict(d["AP"])
+	if err != nil || ap == nil {
+		return ""
+	}
+
+	states, err := xRefTable.DereferenceDict(ap["N"])
+	if err != nil || states == nil {
+		return ""
+	}
+
+	ss := []string{}
+	for k := range states {
+		if k != "Off" {
+			ss = append(ss, k)
+		}
+	}
+	if len(ss) == 0 {
+		return ""
+	}
+
+	sort.Strings(ss)
+
+	return ss[0]
+}
+
+// textValue returns the text of o being a text string or a text stream.
+func (xRefTable *XRefTable) textValue(o Object) string {
+
+	o, err := xRefTable.Dereference(o)
+	if err != nil || o == nil {
+		return ""
+	}
+
+	if sd, ok := o.(StreamDict); ok {
+		if err := decodeStream(&sd); err != nil {
+			return ""
+		}
+		return string(sd.Content)
+	}
+
+	return xRefTable.text(o)
+}
+
+// textValues returns the texts of o being a text string or an array of text strings.
+func (xRefTable *XRefTable) textValues(o Object) []string {
+
+	a, err := xRefTable.DereferenceArray(o)
+	if err != nil {
+		if s := xRefTable.textValue(o); s != "" {
+			return []string{s}
+		}
+		return nil
+	}
+
+	ss := []string{}
+	for _, o := range a {
+		ss = append(ss, xRefTable.text(o))
+	}
+
+	return ss
+}
+
+func (xRefTable *XRefTable) nameValue(o Object) string {
+	o, err := xRefTable.Dereference(o)
+	if err != nil || o == nil {
+		return ""
+	}
+	if n, ok := o.(Name); ok {
+		return n.Value()
+	}
+	return xRefTable.text(o)
+}
+
+// choiceOptions returns the options of a choice field.
+func (xRefTable *XRefTable) choiceOptions(d Dict) [][2]string {
+
+	a, err := xRefTable.DereferenceArray(d["Opt"])
+	if err != nil || a == nil {
+		return nil
+	}
+
+	opts := [][2]string{}
+
+	for _, o := range a {
+		o, err := xRefTable.Dereference(o)
+		if err != nil || o == nil {
+			continue
+		}
+		if a1, ok := o.(Array); ok {
+			if len(a1) == 2 {
+				opts = append(opts, [2]string{xRefTable.text(a1[0]), xRefTable.text(a1[1])})
+			}
+			continue
+		}
+		s := xRefTable.text(o)
+		opts = append(opts, [2]string{s, s})
+	}
+
+	return opts
+}
+
+func (ff *formField) setType() {
+
+	switch ff.ft {
+
+	case "Tx":
+		ff.Type = FieldText
+
+	case "Btn":
+		switch {
+		case ff.Flags&fieldPushbutton > 0:
+			ff.Type = FieldPushButton
+		case ff.Flags&fieldRadio > 0:
+			ff.Type = FieldRadioGroup
+		default:
+			ff.Type = FieldCheckBox
+		}
+
+	case "Ch":
+		ff.Type = FieldListBox
+		if ff.Flags&fieldCombo > 0 {
+			ff.Type = FieldComboBox
+		}
+
+	case "Sig":
+		ff.Type = FieldSignature
+	}
+}
+
+// buttonOpts returns the export values of the widgets of a button field with an Opt entry.
+func (xRefTable *XRefTable) buttonOpts(d Dict) []string {
+	a, err := xRefTable.DereferenceArray(d["Opt"])
+	if err != nil || a == nil {
+		return nil
+	}
+	ss := make([]string, len(a))
+	for i, o := range a {
+		ss[i] = xRefTable.text(o)
+	}
+	return ss
+}
+
+func (xRefTable *XRefTable) setFieldValue(ff *formField, inh inheritedFieldAttrs) {
+
+	switch ff.Type {
+
+	case FieldText:
+		ff.Value = xRefTable.textValue(inh.v)
+		ff.Default = xRefTable.textValue(inh.dv)
+		ff.MaxLen = inh.maxLen
+
+	case FieldCheckBox, FieldRadioGroup:
+		ff.Value = xRefTable.nameValue(inh.v)
+		if ff.Value == "" {
+			ff.Value = "Off"
+		}
+		ff.Default = xRefTable.nameValue(inh.dv)
+		for _, w := range ff.widgets {
+			if s := xRefTable.onState(w.d); s != "" && !MemberOf(s, ff.Options) {
+				ff.Options = append(ff.Options, s)
+			}
+		}
+		if opts := xRefTable.buttonOpts(ff.d); len(opts) > 0 {
+			// The on states are widget indices into Opt.
+			if i, err := strconv.Atoi(ff.Value); err == nil && i >= 0 && i < len(opts) {
+				ff.Value = opts[i]
+			}
+			ff.Options = []string{}
+			for _, s := range opts {
+				if !MemberOf(s, ff.Options) {
+					ff.Options = append(ff.Options, s)
+				}
+			}
+		}
+
+	case FieldComboBox, FieldListBox:
+		ff.opts = xRefTable.choiceOptions(ff.d)
+		for _, opt := range ff.opts {
+			ff.Options = append(ff.Options, opt[0])
+		}
+		ff.Values = xRefTable.textValues(inh.v)
+		if len(ff.Values) > 0 {
+			ff.Value = ff.Values[0]
+		}
+		ff.Default = strings.Join(xRefTable.textValues(inh.dv), ",")
+
+	case FieldSignature:
+		ff.v = inh.v
+	}
+}
+
+func (xRefTable *XRefTable) inheritFieldAttrs(d Dict, inh inheritedFieldAttrs) inheritedFieldAttrs {
+
+	if n := d.NameEntry("FT"); n != nil {
+		inh.ft = *n
+	}
+
+	if i, err := xRefTable.DereferenceInteger(d["Ff"]); err == nil && i != nil {
+		inh.ff = i.Value()
+	}
+
+	if o, found := d.Find("DA"); found {
+		inh.da = xRefTable.text(o)
+	}
+
+	if i, err := xRefTable.DereferenceInteger(d["Q"]); err == nil && i != nil {
+		inh.q = i.Value()
+	}
+
+	if i, err := xRefTable.DereferenceInteger(d["MaxLen"]); err == nil && i != nil {
+		inh.maxLen = i.Value()
+	}
+
+	if o, found := d.Find("V"); found {
+		inh.v = o
+	}
+
+	if o, found := d.Find("DV"); found {
+		inh.dv = o
+	}
+
+	return inh
+}
+
+func (xRefTable *XRefTable) collectFields(o Object, parentName string, inh inheritedFieldAttrs, visited IntSet, ff *[]*formField) error {
+
+	objNr := 0
+	if ir, ok := o.(IndirectRef); ok {
+		objNr = ir.ObjectNumber.Value()
+		if visited[objNr] {
+			return nil
+		}
+		visited[objNr] = true
+	}
+
+	d, err := xRefTable.DereferenceDict(o)
+	if err != nil || d == nil {
+		return err
+	}
+
+	name := parentName
+	if o, found := d.Find("T"); found {
+		if t := xRefTable.text(o); parentName == "" {
+			name = t
+		} else {
+			name = parentName + "." + t
+		}
+	}
+
+	inh = xRefTable.inheritFieldAttrs(d, inh)
+
+	kids, err := xRefTable.DereferenceArray(d["Kids"])
+	if err != nil {
+		return err
+	}
+
+	// Kids without partial name are the widgets of a terminal field.
+	terminal := true
+	for _, o := range kids {
+		kd, err := xRefTable.DereferenceDict(o)
+		if err != nil {
+			return err
+		}
+		if _, found := kd.Find("T"); found {
+			terminal = false
+			break
+		}
+	}
+
+	if !terminal {
+		for _, o := range kids {
+			if err := xRefTable.collectFields(o, name, inh, visited, ff); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	f := &formField{d: d, ft: inh.ft, da: inh.da, q: inh.q}
+	f.Name = name
+	f.Flags = inh.ff
+	f.ObjNr = objNr
+
+	if len(kids) == 0 {
+		if st := d.Subtype(); st != nil && *st == "Widget" {
+			f.widgets = append(f.widgets, widget{d: d, objNr: objNr})
+		}
+	}
+
+	for _, o := range kids {
+		kd, err := xRefTable.DereferenceDict(o)
+		if err != nil {
+			return err
+		}
+		w := widget{d: kd}
+		if ir, ok := o.(IndirectRef); ok {
+			w.objNr = ir.ObjectNumber.Value()
+		}
+		f.widgets = append(f.widgets, w)
+	}
+
+	f.setType()
+	xRefTable.setFieldValue(f, inh)
+
+	*ff = append(*ff, f)
+
+	return nil
+}
+
+// acroForm returns the AcroForm dict or nil.
+func (xRefTable *XRefTable) acroForm() (Dict, error) {
+
+	rootDict, err := xRefTable.Catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	return xRefTable.DereferenceDict(rootDict["AcroForm"])
+}
+
+// formFields returns the AcroForm dict and its terminal fields in field tree order.
+func (xRefTable *XRefTable) formFields() (Dict, []*formField, error) {
+
+	af, err := xRefTable.acroForm()
+	if err != nil || af == nil {
+		return nil, nil, err
+	}
+
+	inh := inheritedFieldAttrs{}
+	if o, found := af.Find("DA"); found {
+		inh.da = xRefTable.text(o)
+	}
+	if i, err := xRefTable.DereferenceInteger(af["Q"]); err == nil && i != nil {
+		inh.q = i.Value()
+	}
+
+	fields, err := xRefTable.DereferenceArray(af["Fields"])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ff := []*formField{}
+	visited := IntSet{}
+
+	for _, o := range fields {
+		if err := xRefTable.collectFields(o, "", inh, visited, &ff); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Locate the widgets.
+	pageNrs := map[int]int{}
+	for i := 1; i <= xRefTable.PageCount; i++ {
+		d, _, err := xRefTable.PageDict(i)
+		if err != nil {
+			return nil, nil, err
+		}
+		arr, _, err := xRefTable.pageAnnots(d)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, o := range arr {
+			if ir, ok := o.(IndirectRef); ok {
+				pageNrs[ir.ObjectNumber.Value()] = i
+			}
+		}
+	}
+
+	for _, f := range ff {
+		for i, w := range f.widgets {
+			if pageNr, ok := pageNrs[w.objNr]; ok {
+				f.widgets[i].pageNr = pageNr
+				if !memberOfInts(pageNr, f.Pages) {
+					f.Pages = append(f.Pages, pageNr)
+				}
+			}
+		}
+	}
+
+	return af, ff, nil
+}
+
+func memberOfInts(i int, ii []int) bool {
+	for _, j := range ii {
+		if i == j {
+			return true
+		}
+	}
+	return false
+}
+
+// FormFields returns the terminal fields of the form of ctx.
+func FormFields(ctx *Context) ([]Field, error) {
+
+	_, ff, err := ctx.formFields()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]Field, len(ff))
+	for i, f := range ff {
+		fields[i] = f.Field
+	}
+
+	return fields, nil
+}
+
+// ListFormFields returns a list of the terminal fields of the form of ctx.
+func ListFormFields(ctx *Context) ([]string, error) {
+
+	fields, err := FormFields(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) == 0 {
+		return []string{"no form fields"}, nil
+	}
+
+	ss := []string{}
+	for _, f := range fields {
+		ss = append(ss, f.String())
+	}
+
+	return ss, nil
+}
+
+// FormValues maps fully qualified field names to field values.
+// A value is a string for text fields, combo boxes and radio groups, a bool or the on state for check boxes
+// and a string or a list of strings for list boxes.
+type FormValues map[string]interface{}
+
+// ParseFormValuesJSON parses a JSON object mapping field names to values or an array of such objects.
+func ParseFormValuesJSON(r io.Reader) ([]FormValues, error) {
+
+	bb, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bb = bytes.TrimSpace(bb)
+
+	dec := json.NewDecoder(bytes.NewReader(bb))
+	dec.UseNumber()
+
+	if len(bb) > 0 && bb[0] == '[' {
+		vv := []FormValues{}
+		if err := dec.Decode(&vv); err != nil {
+			return nil, errors.Wrap(err, "parseFormValues")
+		}
+		return vv, nil
+	}
+
+	v := FormValues{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, errors.Wrap(err, "parseFormValues")
+	}
+
+	return []FormValues{v}, nil
+}
+
+// ExportFormValues returns the values of all fields of the form of ctx that can be filled.
+func ExportFormValues(ctx *Context) (FormValues, error) {
+
+	_, ff, err := ctx.formFields()
+	if err != nil {
+		return nil, err
+	}
+
+	fv := FormValues{}
+
+	for _, f := range ff {
+
+		switch f.Type {
+
+		case FieldText, FieldComboBox, FieldRadioGroup:
+			fv[f.Name] = f.Value
+
+		case FieldCheckBox:
+			fv[f.Name] = f.Value != "Off"
+
+		case FieldListBox:
+			if f.Flags&fieldMultiSelect > 0 {
+				ss := f.Values
+				if ss == nil {
+					ss = []string{}
+				}
+				fv[f.Name] = ss
+				continue
+			}
+			fv[f.Name] = f.Value
+		}
+	}
+
+	return fv, nil
+}
+
+// stringValue returns v as a string.
+func stringValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+	return "", errors.Errorf("unsupported value: %v", v)
+}
+
+// stringValues returns v as a list of strings.
+func stringValues(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case []string:
+		return v, nil
+	case []interface{}:
+		ss := make([]string, len(v))
+		for i, v := range v {
+			s, err := stringValue(v)
+			if err != nil {
+				return nil, err
+			}
+			ss[i] = s
+		}
+		return ss, nil
+	}
+	s, err := stringValue(v)
+	if err != nil || s == "" {
+		return nil, err
+	}
+	return []string{s}, nil
+}
+
+func (xRefTable *XRefTable) fillTextField(f *formField, v interface{}) error {
+
+	s, err := stringValue(v)
+	if err != nil {
+		return err
+	}
+
+	if f.MaxLen > 0 && len([]rune(s)) > f.MaxLen {
+		return errors.Errorf("value exceeds maximum length of %d", f.MaxLen)
+	}
+
+	o, err := EncodeText(s)
+	if err != nil {
+		return err
+	}
+
+	f.d.Update("V", o)
+	f.d.Delete("RV")
+	f.Value = s
+
+	return nil
+}
+
+// isOn returns true if v switches a check box on.
+func isOn(v interface{}) (on bool, state string, err error) {
+	if b, ok := v.(bool); ok {
+		return b, "", nil
+	}
+	s, err := stringValue(v)
+	if err != nil {
+		return false, "", err
+	}
+	switch strings.ToLower(s) {
+	case "", "off", "false", "no", "0":
+		return false, "", nil
+	case "on", "true", "yes", "1":
+		return true, "", nil
+	}
+	return true, s, nil
+}
+
+func (xRefTable *XRefTable) fillButtonField(f *formField, v interface{}) error {
+
+	on, state, err := isOn(v)
+	if err != nil {
+		return err
+	}
+
+	opts := xRefTable.buttonOpts(f.d)
+
+	if on && state == "" && f.Type == FieldRadioGroup {
+		return errors.New("please select one of the options")
+	}
+
+	if on && state != "" && !MemberOf(state, f.Options) {
+		return errors.Errorf("invalid option %q, please use one of %v", state, f.Options)
+	}
+
+	if !on && f.Type == FieldRadioGroup && f.Flags&fieldNoToggleToOff > 0 {
+		return errors.New("radio group cannot be switched off")
+	}
+
+	value := "Off"
+
+	for i, w := range f.widgets {
+
+		onState := xRefTable.onState(w.d)
+
+		as := "Off"
+		switch {
+		case !on || onState == "":
+		case state == "":
+			as = onState
+		case len(opts) > 0:
+			if i < len(opts) && opts[i] == state {
+				as = onState
+			}
+		case onState == state:
+			as = onState
+		}
+
+		w.d.Update("AS", Name(as))
+
+		if as != "Off" && value == "Off" {
+			value = as
+		}
+	}
+
+	f.d.Update("V", Name(value))
+
+	f.Value = value
+	if i, err := strconv.Atoi(value); err == nil && i >= 0 && i < len(opts) {
+		f.Value = opts[i]
+	}
+
+	return nil
+}
+
+// choiceExportValue returns the export value of the option s being an export value or a display text.
+func (f *formField) choiceExportValue(s string) (string, bool) {
+	for _, opt := range f.opts {
+		if opt[0] == s {
+			return s, true
+		}
+	}
+	for _, opt := range f.opts {
+		if opt[1] == s {
+			return opt[0], true
+		}
+	}
+	return s, false
+}
+
+func (xRefTable *XRefTable) fillChoiceField(f *formField, v interface{}) error {
+
+	ss, err := stringValues(v)
+	if err != nil {
+		return err
+	}
+
+	if len(ss) > 1 && (f.Type == FieldComboBox || f.Flags&fieldMultiSelect == 0) {
+		return errors.New("please select a single option")
+	}
+
+	ii := Array{}
+
+	for i, s := range ss {
+		s1, ok := f.choiceExportValue(s)
+		if !ok && !(f.Type == FieldComboBox && f.Flags&fieldEdit > 0) {
+			return errors.Errorf("invalid option %q, please use one of %v", s, f.Options)
+		}
+		ss[i] = s1
+		for j, opt := range f.opts {
+			if opt[0] == s1 {
+				ii = append(ii, Integer(j))
+			}
+		}
+	}
+
+	switch len(ss) {
+	case 0:
+		f.d.Delete("V")
+	case 1:
+		o, err := EncodeText(ss[0])
+		if err != nil {
+			return err
+		}
+		f.d.Update("V", o)
+	default:
+		a := Array{}
+		for _, s := range ss {
+			o, err := EncodeText(s)
+			if err != nil {
+				return err
+			}
+			a = append(a, o)
+		}
+		f.d.Update("V", a)
+	}
+
+	f.d.Delete("I")
+	if len(ii) > 1 {
+		sort.Slice(ii, func(i, j int) bool { return ii[i].(Integer) < ii[j].(Integer) })
+		f.d.Insert("I", ii)
+	}
+
+	f.Values = ss
+	f.Value = ""
+	if len(ss) > 0 {
+		f.Value = ss[0]
+	}
+
+	return nil
+}
+
+// FillForm sets the values of the form fields of ctx and updates their appearances.
+// Hybrid forms get converted to AcroForms by removing XFA.
+func FillForm(ctx *Context, fv FormValues) error {
+
+	af, ff, err := ctx.formFields()
+	if err != nil {
+		return err
+	}
+
+	if af == nil {
+		return errors.New("fillForm: no form available")
+	}
+
+	m := map[string]*formField{}
+	for _, f := range ff {
+		m[f.Name] = f
+	}
+
+	names := make([]string, 0, len(fv))
+	for k := range fv {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	needAppearances := false
+
+	for _, name := range names {
+
+		f, ok := m[name]
+		if !ok {
+			return errors.Errorf("fillForm: unknown field: %s", name)
+		}
+
+		v := fv[name]
+
+		switch f.Type {
+
+		case FieldText:
+			err = ctx.fillTextField(f, v)
+
+		case FieldCheckBox, FieldRadioGroup:
+			err = ctx.fillButtonField(f, v)
+
+		case FieldComboBox, FieldListBox:
+			err = ctx.fillChoiceField(f, v)
+
+		default:
+			err = errors.Errorf("%s fields cannot be filled", f.Type)
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "fillForm: %s", name)
+		}
+
+		if f.Type == FieldCheckBox || f.Type == FieldRadioGroup {
+			continue
+		}
+
+		for _, w := range f.widgets {
+			ok, err := ctx.fieldAppearance(af, f, w.d)
+			if err != nil {
+				return errors.Wrapf(err, "fillForm: %s", name)
+			}
+			if !ok {
+				needAppearances = true
+			}
+		}
+	}
+
+	if _, found := af.Find("XFA"); found {
+		log.Info.Println("fillForm: removing XFA")
+		af.Delete("XFA")
+	}
+
+	if needAppearances {
+		// Let the viewer render characters not covered by the field font.
+		af.Update("NeedAppearances", Boolean(true))
+	}
+
+	return nil
+}
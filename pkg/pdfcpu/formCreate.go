@@ -0,0 +1,606 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// formFonts maps the standard fonts usable for form fields to their customary resource names.
+var formFonts = map[string]string{
+	"Helvetica":             "Helv",
+	"Helvetica-Bold":        "HeBo",
+	"Helvetica-Oblique":     "HeOb",
+	"Helvetica-BoldOblique": "HeBO",
+	"Times-Roman":           "TiRo",
+	"Times-Bold":            "TiBo",
+	"Times-Italic":          "TiIt",
+	"Times-BoldItalic":      "TiBI",
+	"Courier":               "Cour",
+	"Courier-Bold":          "CoBo",
+	"Courier-Oblique":       "CoOb",
+	"Courier-BoldOblique":   "CoBO",
+}
+
+// buttonFont is the resource name of the ZapfDingbats font used for check boxes and radio buttons.
+const buttonFont = "ZaDb"
+
+// The ZapfDingbats characters drawn for the on state of buttons along with their widths.
+const (
+	checkChar   = '4' // a20, check mark
+	radioChar   = 'l' // a71, black circle
+	checkWidth  = 846
+	radioWidth  = 791
+	dingbatsCap = .7 // approximate glyph height in text space units
+)
+
+// FieldSpec describes a form field to be created.
+// Radio buttons sharing a name make up a radio group.
+type FieldSpec struct {
+	Page            int         `json:"page"`                      // The page displaying the field.
+	Rect            [4]float64  `json:"rect"`                      // llx, lly, urx, ury in user space.
+	Name            string      `json:"name"`                      // The partial field name.
+	Type            string      `json:"type"`                      // Text, CheckBox, RadioGroup, ComboBox or ListBox.
+	Default         interface{} `json:"default,omitempty"`         // The default value as for form fill.
+	Font            string      `json:"font,omitempty"`            // A standard font, defaults to Helvetica.
+	FontSize        float64     `json:"fontSize,omitempty"`        // 0 for auto size.
+	Color           []float64   `json:"color,omitempty"`           // Text color.
+	Options         []string    `json:"options,omitempty"`         // Choice options, the on state of a check box or radio button.
+	Align           string      `json:"align,omitempty"`           // left, center or right.
+	MaxLen          int         `json:"maxLen,omitempty"`          // The maximum length of a text field value.
+	Flags           []string    `json:"flags,omitempty"`           // Field flags as listed by form list, eg. Required, Multiline.
+	Tooltip         string      `json:"tooltip,omitempty"`         // TU
+	BorderColor     []float64   `json:"borderColor,omitempty"`     // MK BC
+	BackgroundColor []float64   `json:"backgroundColor,omitempty"` // MK BG
+}
+
+// ParseFieldSpecsJSON parses a JSON field spec or an array of field specs.
+func ParseFieldSpecsJSON(r io.Reader) ([]FieldSpec, error) {
+
+	bb, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bb = bytes.TrimSpace(bb)
+	if len(bb) > 0 && bb[0] == '{' {
+		bb = append(append([]byte{'['}, bb...), ']')
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(bb))
+	dec.DisallowUnknownFields()
+	dec.UseNumber()
+
+	ff := []FieldSpec{}
+	if err = dec.Decode(&ff); err != nil {
+		return nil, errors.Wrap(err, "form fields: invalid JSON")
+	}
+
+	return ff, nil
+}
+
+func validFieldColor(c []float64) bool {
+	if len(c) != 0 && len(c) != 1 && len(c) != 3 && len(c) != 4 {
+		return false
+	}
+	for _, f := range c {
+		if f < 0 || f > 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// validate checks fs and normalizes its type and rect. Returns the field flags.
+func (fs *FieldSpec) validate(pageCount int) (int, error) {
+
+	if fs.Name == "" {
+		return 0, errors.New("missing field name")
+	}
+	if strings.Contains(fs.Name, ".") {
+		return 0, errors.Errorf("%s: field names must not contain '.'", fs.Name)
+	}
+
+	t := ""
+	for _, s := range []string{FieldText, FieldCheckBox, FieldRadioGroup, FieldComboBox, FieldListBox} {
+		if strings.EqualFold(s, fs.Type) {
+			t = s
+		}
+	}
+	if t == "" {
+		return 0, errors.Errorf("%s: unsupported field type %q, use Text, CheckBox, RadioGroup, ComboBox or ListBox", fs.Name, fs.Type)
+	}
+	fs.Type = t
+
+	if fs.Page < 1 || fs.Page > pageCount {
+		return 0, errors.Errorf("%s: invalid page: %d", fs.Name, fs.Page)
+	}
+
+	fs.Rect = normalizedRect(fs.Rect[0], fs.Rect[1], fs.Rect[2], fs.Rect[3])
+	if fs.Rect[2]-fs.Rect[0] <= 0 || fs.Rect[3]-fs.Rect[1] <= 0 {
+		return 0, errors.Errorf("%s: invalid rect", fs.Name)
+	}
+
+	if fs.Font != "" {
+		if _, ok := formFonts[fs.Font]; !ok {
+			return 0, errors.Errorf("%s: unsupported font: %s", fs.Name, fs.Font)
+		}
+	}
+
+	if fs.FontSize < 0 {
+		return 0, errors.Errorf("%s: invalid font size", fs.Name)
+	}
+
+	if !MemberOf(fs.Align, []string{"", "left", "center", "right"}) {
+		return 0, errors.Errorf("%s: invalid align: %s, use left, center or right", fs.Name, fs.Align)
+	}
+
+	if fs.MaxLen < 0 || fs.MaxLen > 0 && fs.Type != FieldText {
+		return 0, errors.Errorf("%s: invalid maxLen", fs.Name)
+	}
+
+	if !validFieldColor(fs.Color) || !validFieldColor(fs.BorderColor) || !validFieldColor(fs.BackgroundColor) {
+		return 0, errors.Errorf("%s: colors need 1, 3 or 4 components within [0,1]", fs.Name)
+	}
+
+	switch fs.Type {
+	case FieldComboBox, FieldListBox:
+		if len(fs.Options) == 0 {
+			return 0, errors.Errorf("%s: missing options", fs.Name)
+		}
+	case FieldRadioGroup:
+		if len(fs.Options) != 1 {
+			return 0, errors.Errorf("%s: a radio button needs its export value as single option", fs.Name)
+		}
+	case FieldCheckBox:
+		if len(fs.Options) > 1 {
+			return 0, errors.Errorf("%s: a check box takes its on state as single option", fs.Name)
+		}
+	default:
+		if len(fs.Options) > 0 {
+			return 0, errors.Errorf("%s: text fields take no options", fs.Name)
+		}
+	}
+
+	flags := 0
+	switch fs.Type {
+	case FieldRadioGroup:
+		flags = fieldRadio | fieldNoToggleToOff
+	case FieldComboBox:
+		flags = fieldCombo
+	}
+
+	for _, s := range fs.Flags {
+		found := false
+		for _, fn := range fieldFlagNames {
+			if strings.EqualFold(fn.name, s) && (fn.types == nil || MemberOf(fs.Type, fn.types)) {
+				flags |= fn.flag
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, errors.Errorf("%s: unsupported flag for %s: %s", fs.Name, fs.Type, s)
+		}
+	}
+
+	return flags, nil
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// defaultAppearance returns the DA string of fs and makes sure its font is part of the form resources.
+func (xRefTable *XRefTable) defaultAppearance(af Dict, fs FieldSpec) (string, error) {
+
+	font := fs.Font
+	if font == "" {
+		font = "Helvetica"
+	}
+
+	name := formFonts[font]
+	if _, err := xRefTable.formFontIndRef(af, name, font); err != nil {
+		return "", err
+	}
+
+	color := colorOperator(fs.Color, false)
+	if color == "" {
+		color = "0 g"
+	}
+
+	return fmt.Sprintf("/%s %s Tf %s", name, formatNumber(fs.FontSize), color), nil
+}
+
+// widgetDict returns a new widget annotation dict for fs.
+func widgetDict(fs FieldSpec, pageIndRef IndirectRef) Dict {
+
+	d := NewDict()
+	d.InsertName("Type", "Annot")
+	d.InsertName("Subtype", "Widget")
+	d.Insert("Rect", NewNumberArray(fs.Rect[:]...))
+	d.Insert("P", pageIndRef)
+	d.Insert("F", Integer(annPrint))
+
+	mk := NewDict()
+	if len(fs.BorderColor) > 0 {
+		mk.Insert("BC", NewNumberArray(fs.BorderColor...))
+		d.Insert("BS", Dict(map[string]Object{"W": Integer(1), "S": Name("S")}))
+	}
+	if len(fs.BackgroundColor) > 0 {
+		mk.Insert("BG", NewNumberArray(fs.BackgroundColor...))
+	}
+	if len(mk) > 0 {
+		d.Insert("MK", mk)
+	}
+
+	return d
+}
+
+// buttonAppearance sets the normal appearances of the check box or radio button widget w.
+func (xRefTable *XRefTable) buttonAppearance(af, w Dict, fs FieldSpec, onState string) error {
+
+	fontIndRef, err := xRefTable.formFontIndRef(af, buttonFont, "ZapfDingbats")
+	if err != nil {
+		return err
+	}
+
+	width, height := fs.Rect[2]-fs.Rect[0], fs.Rect[3]-fs.Rect[1]
+
+	c, cw := checkChar, checkWidth
+	if fs.Type == FieldRadioGroup {
+		c, cw = radioChar, radioWidth
+	}
+
+	mk, _ := w["MK"].(Dict)
+	if mk == nil {
+		mk = NewDict()
+		w.Insert("MK", mk)
+	}
+	mk.Insert("CA", StringLiteral(string(c)))
+
+	var off, on bytes.Buffer
+
+	off.WriteString("q\n")
+	bw, err := xRefTable.widgetBackground(&off, w, width, height)
+	if err != nil {
+		return err
+	}
+	off.WriteString("Q\n")
+
+	on.Write(off.Bytes())
+
+	fontSize := fs.FontSize
+	if fontSize == 0 {
+		fontSize = (math.Min(width, height) - 2*bw) * .8
+	}
+	color := colorOperator(fs.Color, false)
+	if color == "" {
+		color = "0 g"
+	}
+	x := (width - float64(cw)*fontSize/1000) / 2
+	y := (height - dingbatsCap*fontSize) / 2
+	fmt.Fprintf(&on, "q\n%s BT /%s %.2f Tf %.2f %.2f Td (%c) Tj ET\nQ\n", color, buttonFont, fontSize, x, y, c)
+
+	irOff, err := xRefTable.formXObject(off.Bytes(), width, height, buttonFont, *fontIndRef)
+	if err != nil {
+		return err
+	}
+
+	irOn, err := xRefTable.formXObject(on.Bytes(), width, height, buttonFont, *fontIndRef)
+	if err != nil {
+		return err
+	}
+
+	w.Insert("AP", Dict(map[string]Object{"N": Dict(map[string]Object{onState: *irOn, "Off": *irOff})}))
+	w.InsertName("AS", "Off")
+
+	return nil
+}
+
+// insertFieldEntries inserts the field dict entries for fs into d.
+func (xRefTable *XRefTable) insertFieldEntries(af, d Dict, fs FieldSpec, flags int) error {
+
+	ft := "Tx"
+	switch fs.Type {
+	case FieldCheckBox, FieldRadioGroup:
+		ft = "Btn"
+	case FieldComboBox, FieldListBox:
+		ft = "Ch"
+	}
+	d.InsertName("FT", ft)
+
+	t, err := EncodeText(fs.Name)
+	if err != nil {
+		return err
+	}
+	d.Insert("T", t)
+
+	if fs.Tooltip != "" {
+		tu, err := EncodeText(fs.Tooltip)
+		if err != nil {
+			return err
+		}
+		d.Insert("TU", tu)
+	}
+
+	if flags != 0 {
+		d.Insert("Ff", Integer(flags))
+	}
+
+	if ft == "Btn" {
+		d.InsertName("V", "Off")
+		d.Insert("DA", StringLiteral(fmt.Sprintf("/%s 0 Tf 0 g", buttonFont)))
+		return nil
+	}
+
+	da, err := xRefTable.defaultAppearance(af, fs)
+	if err != nil {
+		return err
+	}
+	d.Insert("DA", StringLiteral(da))
+
+	switch fs.Align {
+	case "center":
+		d.Insert("Q", Integer(1))
+	case "right":
+		d.Insert("Q", Integer(2))
+	}
+
+	if fs.MaxLen > 0 {
+		d.Insert("MaxLen", Integer(fs.MaxLen))
+	}
+
+	if len(fs.Options) > 0 {
+		opts := Array{}
+		for _, s := range fs.Options {
+			o, err := EncodeText(s)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, o)
+		}
+		d.Insert("Opt", opts)
+	}
+
+	return nil
+}
+
+// createField creates the field for the specs fss sharing a name along with its widgets.
+// Returns the indirect reference of the field dict.
+func (xRefTable *XRefTable) createField(af Dict, fss []FieldSpec, pageIndRefs []IndirectRef) (*IndirectRef, error) {
+
+	fs := fss[0]
+
+	flags, err := fs.validate(xRefTable.PageCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if fs.Type != FieldRadioGroup {
+
+		if len(fss) > 1 {
+			return nil, errors.Errorf("%s: duplicate field", fs.Name)
+		}
+
+		d := widgetDict(fs, pageIndRefs[fs.Page-1])
+		if err := xRefTable.insertFieldEntries(af, d, fs, flags); err != nil {
+			return nil, err
+		}
+
+		if fs.Type == FieldCheckBox {
+			onState := "Yes"
+			if len(fs.Options) == 1 {
+				onState = fs.Options[0]
+			}
+			if err := xRefTable.buttonAppearance(af, d, fs, onState); err != nil {
+				return nil, err
+			}
+		}
+
+		ir, err := xRefTable.IndRefForNewObject(d)
+		if err != nil {
+			return nil, err
+		}
+
+		return ir, xRefTable.AddPageAnnot(fs.Page, *ir)
+	}
+
+	// A radio group is a field with one widget per button.
+	d := NewDict()
+	if err := xRefTable.insertFieldEntries(af, d, fs, flags); err != nil {
+		return nil, err
+	}
+
+	ir, err := xRefTable.IndRefForNewObject(d)
+	if err != nil {
+		return nil, err
+	}
+
+	kids := Array{}
+	states := []string{}
+
+	for i := range fss {
+
+		fs := fss[i]
+		if i > 0 {
+			if !strings.EqualFold(fs.Type, FieldRadioGroup) {
+				return nil, errors.Errorf("%s: duplicate field", fs.Name)
+			}
+			if _, err := fs.validate(xRefTable.PageCount); err != nil {
+				return nil, err
+			}
+		}
+
+		if MemberOf(fs.Options[0], states) {
+			return nil, errors.Errorf("%s: duplicate radio button: %s", fs.Name, fs.Options[0])
+		}
+		states = append(states, fs.Options[0])
+
+		w := widgetDict(fs, pageIndRefs[fs.Page-1])
+		w.Insert("Parent", *ir)
+		if err := xRefTable.buttonAppearance(af, w, fs, fs.Options[0]); err != nil {
+			return nil, err
+		}
+
+		wir, err := xRefTable.IndRefForNewObject(w)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := xRefTable.AddPageAnnot(fs.Page, *wir); err != nil {
+			return nil, err
+		}
+
+		kids = append(kids, *wir)
+	}
+
+	d.Insert("Kids", kids)
+
+	return ir, nil
+}
+
+// createAcroForm returns the AcroForm dict of ctx creating it if necessary.
+func (xRefTable *XRefTable) createAcroForm() (Dict, error) {
+
+	af, err := xRefTable.acroForm()
+	if err != nil || af != nil {
+		return af, err
+	}
+
+	rootDict, err := xRefTable.Catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	af = NewDict()
+	af.Insert("Fields", Array{})
+	af.Insert("DA", StringLiteral(fmt.Sprintf("/%s 0 Tf 0 g", defaultFieldFont)))
+	if _, err := xRefTable.defaultFieldFontIndRef(af); err != nil {
+		return nil, err
+	}
+
+	rootDict.Insert("AcroForm", af)
+
+	return af, nil
+}
+
+// CreateFormFields adds the fields specified by fss to the form of ctx creating the form if necessary.
+// Appearances for all new fields get generated.
+func CreateFormFields(ctx *Context, fss []FieldSpec) error {
+
+	if len(fss) == 0 {
+		return errors.New("createFormFields: missing field specs")
+	}
+
+	af, err := ctx.createAcroForm()
+	if err != nil {
+		return err
+	}
+
+	_, ff, err := ctx.formFields()
+	if err != nil {
+		return err
+	}
+
+	existing := map[string]bool{}
+	for _, f := range ff {
+		existing[f.Name] = true
+	}
+
+	// Group the specs by name keeping their order.
+	names := []string{}
+	groups := map[string][]FieldSpec{}
+	for _, fs := range fss {
+		if _, ok := groups[fs.Name]; !ok {
+			names = append(names, fs.Name)
+		}
+		groups[fs.Name] = append(groups[fs.Name], fs)
+	}
+
+	pageIndRefs, err := ctx.PageIndRefs()
+	if err != nil {
+		return err
+	}
+
+	fields, err := ctx.DereferenceArray(af["Fields"])
+	if err != nil {
+		return err
+	}
+
+	fv := FormValues{}
+
+	for _, name := range names {
+
+		if existing[name] {
+			return errors.Errorf("createFormFields: field already exists: %s", name)
+		}
+
+		ir, err := ctx.createField(af, groups[name], pageIndRefs)
+		if err != nil {
+			return errors.Wrap(err, "createFormFields")
+		}
+
+		fields = append(fields, *ir)
+
+		for _, fs := range groups[name] {
+			if fs.Default != nil {
+				fv[name] = fs.Default
+			}
+		}
+	}
+
+	af.Update("Fields", fields)
+
+	if len(fv) > 0 {
+		if err := FillForm(ctx, fv); err != nil {
+			return errors.Wrap(err, "createFormFields: default")
+		}
+	}
+
+	_, ff, err = ctx.formFields()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range ff {
+		if _, ok := fv[f.Name]; !ok {
+			continue
+		}
+		if v, found := f.d.Find("V"); found {
+			f.d.Update("DV", v)
+		}
+	}
+
+	if err := ctx.ensureFieldAppearances(af, ff); err != nil {
+		return err
+	}
+
+	log.Info.Printf("created %d form fields\n", len(names))
+
+	return nil
+}
@@ -17,10 +17,11 @@ limitations under the License.
 package pdfcpu
 
 import (
+	"bytes"
 	"image"
 	"image/color"
 	"image/png"
-	"io/ioutil"
+	"io"
 	"os"
 
 	"github.com/denisbetsi/pdfcpu/pkg/filter"
@@ -249,38 +250,30 @@ func softMask(xRefTable *XRefTable, d *StreamDict, w, h, objNr int) ([]byte, err
 	return sm, nil
 }
 
-func writeImgToJPG(filename string, sd *StreamDict) (string, error) {
+func renderImgToJPG(sd *StreamDict) (io.Reader, string, error) {
 
-	filename += ".jpg"
-	return filename, ioutil.WriteFile(filename, sd.Raw, os.ModePerm)
+	return bytes.NewReader(sd.Raw), "jpg", nil
 }
 
-func writeImgToJPX(filename string, sd *StreamDict) (string, error) {
+func renderImgToJPX(sd *StreamDict) (io.Reader, string, error) {
 
-	filename += ".jpx"
-	return filename, ioutil.WriteFile(filename, sd.Raw, os.ModePerm)
+	return bytes.NewReader(sd.Raw), "jpx", nil
 }
 
-func writeImgToTIFF(filename string, img *image.CMYK) (string, error) {
+func renderImgToTIFF(img *image.CMYK) (io.Reader, string, error) {
 
-	filename += ".tif"
-
-	f, err := os.Create(filename)
-	if err != nil {
-		return "", err
-	}
-	defer f.Close()
+	var buf bytes.Buffer
 
 	// TODO softmask handling.
 
-	return filename, tiff.Encode(f, img, nil)
+	return &buf, "tif", tiff.Encode(&buf, img, nil)
 }
 
-func writeDeviceCMYKToTIFF(filename string, im *PDFImage) (string, error) {
+func renderDeviceCMYKToTIFF(im *PDFImage) (io.Reader, string, error) {
 
 	b := im.sd.Content
 
-	log.Debug.Printf("writeDeviceCMYKToTIFF: CMYK objNr=%d w=%d h=%d bpc=%d buflen=%d\n", im.objNr, im.w, im.h, im.bpc, len(b))
+	log.Debug.Printf("renderDeviceCMYKToTIFF: CMYK objNr=%d w=%d h=%d bpc=%d buflen=%d\n", im.objNr, im.w, im.h, im.bpc, len(b))
 
 	img := image.NewCMYK(image.Rect(0, 0, im.w, im.h))
 
@@ -295,32 +288,26 @@ func writeDeviceCMYKToTIFF(filename string, im *PDFImage) (string, error) {
 		}
 	}
 
-	return writeImgToTIFF(filename, img)
+	return renderImgToTIFF(img)
 }
 
-func writeImgToPNG(filename string, img image.Image) (string, error) {
+func renderImgToPNG(img image.Image) (io.Reader, string, error) {
 
-	filename += ".png"
+	var buf bytes.Buffer
 
-	f, err := os.Create(filename)
-	if err != nil {
-		return "", err
-	}
-	defer f.Close()
-
-	return filename, png.Encode(f, img)
+	return &buf, "png", png.Encode(&buf, img)
 }
 
-func writeDeviceGrayToPNG(filename string, im *PDFImage) (string, error) {
+func renderDeviceGrayToPNG(im *PDFImage) (io.Reader, string, error) {
 
 	b := im.sd.Content
 
-	log.Debug.Printf("writeDeviceGrayToPNG: objNr=%d w=%d h=%d bpc=%d buflen=%d\n", im.objNr, im.w, im.h, im.bpc, len(b))
+	log.Debug.Printf("renderDeviceGrayToPNG: objNr=%d w=%d h=%d bpc=%d buflen=%d\n", im.objNr, im.w, im.h, im.bpc, len(b))
 
 	// Validate buflen.
 	// For streams not using compression there is a trailing 0x0A in addition to the imagebytes.
 	if len(b) < (im.bpc*im.w*im.h+7)/8 {
-		return "", errors.Errorf("writeDeviceGrayToPNG: objNr=%d corrupt image object %v\n", im.objNr, *im.sd)
+		return nil, "", errors.Errorf("renderDeviceGrayToPNG: objNr=%d corrupt image object %v\n", im.objNr, *im.sd)
 	}
 
 	img := image.NewGray(image.Rect(0, 0, im.w, im.h))
@@ -342,19 +329,19 @@ func writeDeviceGrayToPNG(filename string, im *PDFImage) (string, error) {
 		}
 	}
 
-	return writeImgToPNG(filename, img)
+	return renderImgToPNG(img)
 }
 
-func writeDeviceRGBToPNG(filename string, im *PDFImage) (string, error) {
+func renderDeviceRGBToPNG(im *PDFImage) (io.Reader, string, error) {
 
 	b := im.sd.Content
 
-	log.Debug.Printf("writeDeviceRGBToPNG: objNr=%d w=%d h=%d bpc=%d buflen=%d\n", im.objNr, im.w, im.h, im.bpc, len(b))
+	log.Debug.Printf("renderDeviceRGBToPNG: objNr=%d w=%d h=%d bpc=%d buflen=%d\n", im.objNr, im.w, im.h, im.bpc, len(b))
 
 	// Validate buflen.
 	// Sometimes there is a trailing 0x0A in addition to the imagebytes.
 	if len(b) < (3*im.bpc*im.w*im.h+7)/8 {
-		return "", errors.Errorf("writeDeviceRGBToPNG: objNr=%d corrupt image object\n", im.objNr)
+		return nil, "", errors.Errorf("renderDeviceRGBToPNG: objNr=%d corrupt image object\n", im.objNr)
 	}
 
 	// TODO Support bpc and decode.
@@ -372,7 +359,7 @@ func writeDeviceRGBToPNG(filename string, im *PDFImage) (string, error) {
 		}
 	}
 
-	return writeImgToPNG(filename, img)
+	return renderImgToPNG(img)
 }
 
 func ensureDeviceRGBCS(xRefTable *XRefTable, o Object) bool {
@@ -390,14 +377,14 @@ func ensureDeviceRGBCS(xRefTable *XRefTable, o Object) bool {
 	return false
 }
 
-func writeCalRGBToPNG(filename string, im *PDFImage) (string, error) {
+func renderCalRGBToPNG(im *PDFImage) (io.Reader, string, error) {
 
 	b := im.sd.Content
 
-	log.Debug.Printf("writeCalRGBToPNG: objNr=%d w=%d h=%d bpc=%d buflen=%d\n", im.objNr, im.w, im.h, im.bpc, len(b))
+	log.Debug.Printf("renderCalRGBToPNG: objNr=%d w=%d h=%d bpc=%d buflen=%d\n", im.objNr, im.w, im.h, im.bpc, len(b))
 
 	if len(b) < (3*im.bpc*im.w*im.h+7)/8 {
-		return "", errors.Errorf("writeCalRGBToPNG: objNr=%d corrupt image object %v\n", im.objNr, *im.sd)
+		return nil, "", errors.Errorf("renderCalRGBToPNG: objNr=%d corrupt image object %v\n", im.objNr, *im.sd)
 	}
 
 	// Optional int array "Range", length 2*N specifies min,max values of color components.
@@ -413,10 +400,10 @@ func writeCalRGBToPNG(filename string, im *PDFImage) (string, error) {
 			i += 3
 		}
 	}
-	return writeImgToPNG(filename, img)
+	return renderImgToPNG(img)
 }
 
-func writeICCBased(xRefTable *XRefTable, filename string, im *PDFImage, cs Array) (string, error) {
+func renderICCBased(xRefTable *XRefTable, im *PDFImage, cs Array) (io.Reader, string, error) {
 
 	//  Any ICC profile >= ICC.1:2004:10 is sufficient for any PDF version <= 1.7
 	//  If the embedded ICC profile version is newer than the one used by the Reader, substitute with Alternate color space.
@@ -425,13 +412,13 @@ func writeICCBased(xRefTable *XRefTable, filename string, im *PDFImage, cs Array
 
 	b := im.sd.Content
 
-	log.Debug.Printf("writeICCBasedToPNGFile: objNr=%d w=%d h=%d bpc=%d buflen=%d\n", im.objNr, im.w, im.h, im.bpc, len(b))
+	log.Debug.Printf("renderICCBased: objNr=%d w=%d h=%d bpc=%d buflen=%d\n", im.objNr, im.w, im.h, im.bpc, len(b))
 
 	// 1,3 or 4 color components.
 	n := *iccProfileStream.IntEntry("N")
 
 	if !IntMemberOf(n, []int{1, 3, 4}) {
-		return "", errors.Errorf("writeICCBasedToPNGFile: objNr=%d, N must be 1,3 or 4, got:%d\n", im.objNr, n)
+		return nil, "", errors.Errorf("renderICCBased: objNr=%d, N must be 1,3 or 4, got:%d\n", im.objNr, n)
 	}
 
 	// TODO: Transform linear XYZ to RGB according to ICC profile.
@@ -441,27 +428,27 @@ func writeICCBased(xRefTable *XRefTable, filename string, im *PDFImage, cs Array
 	// Validate buflen.
 	// Sometimes there is a trailing 0x0A in addition to the imagebytes.
 	if len(b) < (n*im.bpc*im.w*im.h+7)/8 {
-		return "", errors.Errorf("writeICCBased: objNr=%d corrupt image object %v\n", im.objNr, *im.sd)
+		return nil, "", errors.Errorf("renderICCBased: objNr=%d corrupt image object %v\n", im.objNr, *im.sd)
 	}
 
 	switch n {
 	case 1:
 		// Gray
-		return writeDeviceGrayToPNG(filename, im)
+		return renderDeviceGrayToPNG(im)
 
 	case 3:
 		// RGB
-		return writeDeviceRGBToPNG(filename, im)
+		return renderDeviceRGBToPNG(im)
 
 	case 4:
 		// CMYK
-		return writeDeviceCMYKToTIFF(filename, im)
+		return renderDeviceCMYKToTIFF(im)
 	}
 
-	return "", nil
+	return nil, "", nil
 }
 
-func writeIndexedRGBToPNG(filename string, im *PDFImage, lookup []byte) (string, error) {
+func renderIndexedRGBToPNG(im *PDFImage, lookup []byte) (io.Reader, string, error) {
 
 	b := im.sd.Content
 
@@ -489,10 +476,10 @@ func writeIndexedRGBToPNG(filename string, im *PDFImage, lookup []byte) (string,
 		}
 	}
 
-	return writeImgToPNG(filename, img)
+	return renderImgToPNG(img)
 }
 
-func writeIndexedCMYKToTIFF(filename string, im *PDFImage, lookup []byte) (string, error) {
+func renderIndexedCMYKToTIFF(im *PDFImage, lookup []byte) (io.Reader, string, error) {
 
 	b := im.sd.Content
 
@@ -516,36 +503,36 @@ func writeIndexedCMYKToTIFF(filename string, im *PDFImage, lookup []byte) (strin
 		}
 	}
 
-	return writeImgToTIFF(filename, img)
+	return renderImgToTIFF(img)
 }
 
-func writeIndexedNameCS(filename string, im *PDFImage, cs Name, maxInd int, lookup []byte) (string, error) {
+func renderIndexedNameCS(im *PDFImage, cs Name, maxInd int, lookup []byte) (io.Reader, string, error) {
 
 	switch cs {
 
 	case DeviceRGBCS:
 
 		if len(lookup) < 3*(maxInd+1) {
-			return "", errors.Errorf("writeIndexedNameCS: objNr=%d, corrupt DeviceRGB lookup table\n", im.objNr)
+			return nil, "", errors.Errorf("renderIndexedNameCS: objNr=%d, corrupt DeviceRGB lookup table\n", im.objNr)
 		}
 
-		return writeIndexedRGBToPNG(filename, im, lookup)
+		return renderIndexedRGBToPNG(im, lookup)
 
 	case DeviceCMYKCS:
 
 		if len(lookup) < 4*(maxInd+1) {
-			return "", errors.Errorf("writeIndexedNameCS: objNr=%d, corrupt DeviceCMYK lookup table\n", im.objNr)
+			return nil, "", errors.Errorf("renderIndexedNameCS: objNr=%d, corrupt DeviceCMYK lookup table\n", im.objNr)
 		}
 
-		return writeIndexedCMYKToTIFF(filename, im, lookup)
+		return renderIndexedCMYKToTIFF(im, lookup)
 	}
 
-	log.Info.Printf("writeIndexedNameCS: objNr=%d, unsupported base colorspace %s\n", im.objNr, cs.String())
+	log.Info.Printf("renderIndexedNameCS: objNr=%d, unsupported base colorspace %s\n", im.objNr, cs.String())
 
-	return "", ErrUnsupportedColorSpace
+	return nil, "", ErrUnsupportedColorSpace
 }
 
-func writeIndexedArrayCS(xRefTable *XRefTable, filename string, im *PDFImage, csa Array, maxInd int, lookup []byte) (string, error) {
+func renderIndexedArrayCS(xRefTable *XRefTable, im *PDFImage, csa Array, maxInd int, lookup []byte) (io.Reader, string, error) {
 
 	b := im.sd.Content
 
@@ -560,12 +547,12 @@ func writeIndexedArrayCS(xRefTable *XRefTable, filename string, im *PDFImage, cs
 		// 1,3 or 4 color components.
 		n := *iccProfileStream.IntEntry("N")
 		if !IntMemberOf(n, []int{1, 3, 4}) {
-			return "", errors.Errorf("writeIndexedArrayCS: objNr=%d, N must be 1,3 or 4, got:%d\n", im.objNr, n)
+			return nil, "", errors.Errorf("renderIndexedArrayCS: objNr=%d, N must be 1,3 or 4, got:%d\n", im.objNr, n)
 		}
 
 		// Validate the lookup table.
 		if len(lookup) < n*(maxInd+1) {
-			return "", errors.Errorf("writeIndexedArrayCS: objNr=%d, corrupt ICCBased lookup table\n", im.objNr)
+			return nil, "", errors.Errorf("renderIndexedArrayCS: objNr=%d, corrupt ICCBased lookup table\n", im.objNr)
 		}
 
 		// TODO: Transform linear XYZ to RGB according to ICC profile.
@@ -585,25 +572,25 @@ func writeIndexedArrayCS(xRefTable *XRefTable, filename string, im *PDFImage, cs
 					i++
 				}
 			}
-			return writeImgToPNG(filename, img)
+			return renderImgToPNG(img)
 
 		case 3:
 			// RGB
-			return writeIndexedRGBToPNG(filename, im, lookup)
+			return renderIndexedRGBToPNG(im, lookup)
 
 		case 4:
 			// CMYK
-			log.Debug.Printf("writeIndexedArrayCS: CMYK objNr=%d w=%d h=%d bpc=%d buflen=%d\n", im.objNr, im.w, im.h, im.bpc, len(b))
-			return writeIndexedCMYKToTIFF(filename, im, lookup)
+			log.Debug.Printf("renderIndexedArrayCS: CMYK objNr=%d w=%d h=%d bpc=%d buflen=%d\n", im.objNr, im.w, im.h, im.bpc, len(b))
+			return renderIndexedCMYKToTIFF(im, lookup)
 		}
 	}
 
-	log.Info.Printf("writeIndexedArrayCS: objNr=%d, unsupported base colorspace %s\n", im.objNr, csa)
+	log.Info.Printf("renderIndexedArrayCS: objNr=%d, unsupported base colorspace %s\n", im.objNr, csa)
 
-	return "", ErrUnsupportedColorSpace
+	return nil, "", ErrUnsupportedColorSpace
 }
 
-func writeIndexed(xRefTable *XRefTable, filename string, im *PDFImage, cs Array) (string, error) {
+func renderIndexed(xRefTable *XRefTable, im *PDFImage, cs Array) (io.Reader, string, error) {
 
 	// Identify the base color space.
 	baseCS, _ := xRefTable.Dereference(cs[1])
@@ -615,48 +602,51 @@ func writeIndexed(xRefTable *XRefTable, filename string, im *PDFImage, cs Array)
 	var lookup []byte
 	lookup, err := colorLookupTable(xRefTable, cs[3])
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
 	if lookup == nil {
-		return "", errors.Errorf("writeIndexed: objNr=%d IndexedCS with corrupt lookup table %s\n", im.objNr, cs)
+		return nil, "", errors.Errorf("renderIndexed: objNr=%d IndexedCS with corrupt lookup table %s\n", im.objNr, cs)
 	}
 	//fmt.Printf("lookup: \n%s\n", hex.Dump(l))
 
 	b := im.sd.Content
 
-	log.Debug.Printf("writeIndexed: objNr=%d w=%d h=%d bpc=%d buflen=%d maxInd=%d\n", im.objNr, im.w, im.h, im.bpc, len(b), maxInd)
+	log.Debug.Printf("renderIndexed: objNr=%d w=%d h=%d bpc=%d buflen=%d maxInd=%d\n", im.objNr, im.w, im.h, im.bpc, len(b), maxInd)
 
 	// Validate buflen.
 	// The image data is a sequence of index values for pixels.
 	// Sometimes there is a trailing 0x0A.
 	if len(b) < (im.bpc*im.w*im.h+7)/8 {
-		return "", errors.Errorf("writeIndexed: objNr=%d corrupt image object %v\n", im.objNr, *im.sd)
+		return nil, "", errors.Errorf("renderIndexed: objNr=%d corrupt image object %v\n", im.objNr, *im.sd)
 	}
 
 	switch cs := baseCS.(type) {
 	case Name:
-		return writeIndexedNameCS(filename, im, cs, maxInd.Value(), lookup)
+		return renderIndexedNameCS(im, cs, maxInd.Value(), lookup)
 
 	case Array:
-		return writeIndexedArrayCS(xRefTable, filename, im, cs, maxInd.Value(), lookup)
+		return renderIndexedArrayCS(xRefTable, im, cs, maxInd.Value(), lookup)
 	}
 
-	return "", nil
+	return nil, "", nil
 }
 
-func writeFlateEncodedImage(xRefTable *XRefTable, filename string, sd *StreamDict, objNr int) (string, error) {
+func renderFlateEncodedImage(xRefTable *XRefTable, sd *StreamDict, objNr int) (io.Reader, string, error) {
 
 	pdfImage, err := pdfImage(xRefTable, sd, objNr)
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
 
 	o, err := xRefTable.DereferenceDictEntry(sd.Dict, "ColorSpace")
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
 
-	var fn string
+	var (
+		r   io.Reader
+		ext string
+	)
 
 	switch cs := o.(type) {
 
@@ -664,16 +654,16 @@ func writeFlateEncodedImage(xRefTable *XRefTable, filename string, sd *StreamDic
 		switch cs {
 
 		case DeviceGrayCS:
-			fn, err = writeDeviceGrayToPNG(filename, pdfImage)
+			r, ext, err = renderDeviceGrayToPNG(pdfImage)
 
 		case DeviceRGBCS:
-			fn, err = writeDeviceRGBToPNG(filename, pdfImage)
+			r, ext, err = renderDeviceRGBToPNG(pdfImage)
 
 		case DeviceCMYKCS:
-			fn, err = writeDeviceCMYKToTIFF(filename, pdfImage)
+			r, ext, err = renderDeviceCMYKToTIFF(pdfImage)
 
 		default:
-			log.Info.Printf("writeFlateEncodedImage: objNr=%d, unsupported name colorspace %s\n", objNr, cs.String())
+			log.Info.Printf("renderFlateEncodedImage: objNr=%d, unsupported name colorspace %s\n", objNr, cs.String())
 			err = ErrUnsupportedColorSpace
 		}
 
@@ -683,48 +673,70 @@ func writeFlateEncodedImage(xRefTable *XRefTable, filename string, sd *StreamDic
 		switch csn {
 
 		case CalRGBCS:
-			fn, err = writeCalRGBToPNG(filename, pdfImage)
+			r, ext, err = renderCalRGBToPNG(pdfImage)
 
 		case ICCBasedCS:
-			fn, err = writeICCBased(xRefTable, filename, pdfImage, cs)
+			r, ext, err = renderICCBased(xRefTable, pdfImage, cs)
 
 		case IndexedCS:
-			fn, err = writeIndexed(xRefTable, filename, pdfImage, cs)
+			r, ext, err = renderIndexed(xRefTable, pdfImage, cs)
 
 		default:
-			log.Info.Printf("writeFlateEncodedImage: objNr=%d, unsupported array colorspace %s\n", objNr, csn)
+			log.Info.Printf("renderFlateEncodedImage: objNr=%d, unsupported array colorspace %s\n", objNr, csn)
 			err = ErrUnsupportedColorSpace
 
 		}
 
 	}
 
-	return fn, err
+	return r, ext, err
 }
 
-// WriteImage writes a PDF image object to disk.
-func WriteImage(xRefTable *XRefTable, filename string, sd *StreamDict, objNr int) (fileName string, err error) {
+// RenderImage returns a reader for a PDF image object's data and the file extension for its format.
+// The reader is nil for images using an unsupported color space.
+func RenderImage(xRefTable *XRefTable, sd *StreamDict, objNr int) (io.Reader, string, error) {
 
 	switch sd.FilterPipeline[0].Name {
 
 	case filter.Flate, filter.CCITTFax:
-		// If color space is CMYK then write .tif else write .png
-		fn, err := writeFlateEncodedImage(xRefTable, filename, sd, objNr)
+		// If color space is CMYK then render .tif else render .png
+		r, ext, err := renderFlateEncodedImage(xRefTable, sd, objNr)
 		if err != nil {
 			if err == ErrUnsupportedColorSpace {
 				log.Info.Printf("Image obj#%d uses an unsupported color space. Please see the logfile for details.\n", objNr)
 				err = nil
 			}
 		}
-		return fn, err
+		return r, ext, err
 
 	case filter.DCT:
-		return writeImgToJPG(filename, sd)
+		return renderImgToJPG(sd)
 
 	case filter.JPX:
-		return writeImgToJPX(filename, sd)
+		return renderImgToJPX(sd)
 
 	}
 
-	return "", nil
+	return nil, "", nil
+}
+
+// WriteImage writes a PDF image object to disk.
+func WriteImage(xRefTable *XRefTable, filename string, sd *StreamDict, objNr int) (fileName string, err error) {
+
+	r, ext, err := RenderImage(xRefTable, sd, objNr)
+	if err != nil || r == nil {
+		return "", err
+	}
+
+	fileName = filename + "." + ext
+
+	f, err := os.Create(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, r)
+
+	return fileName, err
 }
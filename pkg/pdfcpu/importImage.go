@@ -73,16 +73,19 @@ func parsePageFormat(v string, setDim bool) (*dim, string, error) {
 		port = true
 	}
 
-	d, ok := PaperSize[v]
+	pd, ok := PaperSize[v]
 	if !ok {
 		return nil, v, errors.Errorf("Page format %s is unsupported.\n", v)
 	}
 
+	// Don't modify the PaperSize entry.
+	d := *pd
+
 	if d.Portrait() && land || d.Landscape() && port {
 		d.w, d.h = d.h, d.w
 	}
 
-	return d, v, nil
+	return &d, v, nil
 }
 
 func parsePageDim(v string, setFormat bool) (*dim, string, error) {
@@ -0,0 +1,460 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/denisbetsi/pdfcpu/pkg/filter"
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	"github.com/denisbetsi/pdfcpu/pkg/pdfcpu/cms"
+	"github.com/pkg/errors"
+)
+
+var errInvalidLTVConfig = errors.New("ltv: invalid configuration string, please refer to pdfcpu help signatures")
+
+// LTV holds locally supplied validation data for the long-term validation of signatures,
+// see ISO 32000-2 12.8.4.3 Document Security Store.
+type LTV struct {
+	Certs []*x509.Certificate // CA and responder certificates not embedded in the signatures.
+	CRLs  [][]byte            // DER encoded certificate revocation lists.
+	OCSPs [][]byte            // DER encoded OCSP responses.
+}
+
+// readPEMOrDER returns the DER encoded blocks of type typ of a PEM file or the content of a DER file.
+func readPEMOrDER(fileName, typ string) ([][]byte, error) {
+
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Contains(b, []byte("-----BEGIN")) {
+		return [][]byte{b}, nil
+	}
+
+	bb := [][]byte{}
+	for {
+		var block *pem.Block
+		if block, b = pem.Decode(b); block == nil {
+			break
+		}
+		if block.Type == typ {
+			bb = append(bb, block.Bytes)
+		}
+	}
+
+	if len(bb) == 0 {
+		return nil, errors.Errorf("ltv: %s: no %s found", fileName, typ)
+	}
+
+	return bb, nil
+}
+
+// ReadFiles adds certificates, CRLs and OCSP responses read from PEM or DER encoded files.
+func (ltv *LTV) ReadFiles(certFiles, crlFiles, ocspFiles []string) error {
+
+	for _, fn := range certFiles {
+		bb, err := readPEMOrDER(fn, "CERTIFICATE")
+		if err != nil {
+			return err
+		}
+		for _, b := range bb {
+			c, err := x509.ParseCertificate(b)
+			if err != nil {
+				return errors.Wrapf(err, "ltv: %s", fn)
+			}
+			ltv.Certs = append(ltv.Certs, c)
+		}
+	}
+
+	for _, fn := range crlFiles {
+		bb, err := readPEMOrDER(fn, "X509 CRL")
+		if err != nil {
+			return err
+		}
+		for _, b := range bb {
+			if _, err := x509.ParseRevocationList(b); err != nil {
+				return errors.Wrapf(err, "ltv: %s", fn)
+			}
+			ltv.CRLs = append(ltv.CRLs, b)
+		}
+	}
+
+	for _, fn := range ocspFiles {
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return err
+		}
+		if _, err := cms.ParseOCSPResponse(b); err != nil {
+			return errors.Wrapf(err, "ltv: %s", fn)
+		}
+		ltv.OCSPs = append(ltv.OCSPs, b)
+	}
+
+	return nil
+}
+
+// ParseLTVConfig parses an LTV command string and reads the files referenced.
+// eg. "certs: ca.pem, crls: ca.crl, ocsps: signer.ocsp intermediate.ocsp"
+func ParseLTVConfig(s string) (*LTV, error) {
+
+	var certFiles, crlFiles, ocspFiles []string
+
+	for _, s := range strings.Split(s, ",") {
+
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+
+		ss := strings.SplitN(s, ":", 2)
+		if len(ss) != 2 {
+			return nil, errInvalidLTVConfig
+		}
+
+		k := strings.TrimSpace(ss[0])
+		v := strings.Fields(ss[1])
+
+		switch k {
+		case "certs":
+			certFiles = append(certFiles, v...)
+		case "crls":
+			crlFiles = append(crlFiles, v...)
+		case "ocsps":
+			ocspFiles = append(ocspFiles, v...)
+		default:
+			return nil, errInvalidLTVConfig
+		}
+	}
+
+	ltv := &LTV{}
+	if err := ltv.ReadFiles(certFiles, crlFiles, ocspFiles); err != nil {
+		return nil, err
+	}
+
+	return ltv, nil
+}
+
+// chain returns the certificate chain of c built from certs as far as available.
+func chain(c *x509.Certificate, certs []*x509.Certificate) []*x509.Certificate {
+
+	cc := []*x509.Certificate{c}
+
+	for {
+		if bytes.Equal(c.RawIssuer, c.RawSubject) {
+			return cc
+		}
+
+		var issuer *x509.Certificate
+		for _, ic := range certs {
+			if bytes.Equal(ic.RawSubject, c.RawIssuer) && c.CheckSignatureFrom(ic) == nil {
+				issuer = ic
+				break
+			}
+		}
+
+		if issuer == nil {
+			return cc
+		}
+
+		for _, ic := range cc {
+			if ic.Equal(issuer) {
+				return cc
+			}
+		}
+
+		cc = append(cc, issuer)
+		c = issuer
+	}
+}
+
+// dssStore collects the streams of a DSS dict avoiding duplicates.
+type dssStore struct {
+	ctx     *Context
+	dss     Dict
+	streams map[[32]byte]IndirectRef
+}
+
+func (ctx *Context) newDSSStore(dss Dict) (*dssStore, error) {
+
+	st := &dssStore{ctx: ctx, dss: dss, streams: map[[32]byte]IndirectRef{}}
+
+	for _, k := range []string{"Certs", "CRLs", "OCSPs"} {
+		a, err := ctx.DereferenceArray(dss[k])
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range a {
+			ir, ok := o.(IndirectRef)
+			if !ok {
+				continue
+			}
+			sd, err := ctx.DereferenceStreamDict(ir)
+			if err != nil || sd == nil {
+				return nil, err
+			}
+			if sd.Content == nil {
+				if err := decodeStream(sd); err != nil {
+					return nil, err
+				}
+			}
+			st.streams[sha256.Sum256(sd.Content)] = ir
+		}
+		if a != nil {
+			dss[k] = a
+		}
+	}
+
+	return st, nil
+}
+
+// add returns an indirect reference to a stream holding b listed under key.
+func (st *dssStore) add(key string, b []byte) (IndirectRef, error) {
+
+	h := sha256.Sum256(b)
+	if ir, ok := st.streams[h]; ok {
+		return ir, nil
+	}
+
+	sd := StreamDict{
+		Dict:           NewDict(),
+		Content:        b,
+		FilterPipeline: []PDFFilter{{Name: filter.Flate, DecodeParms: nil}},
+	}
+	sd.InsertName("Filter", filter.Flate)
+	if err := encodeStream(&sd); err != nil {
+		return IndirectRef{}, err
+	}
+
+	ir, err := st.ctx.IndRefForNewObject(sd)
+	if err != nil {
+		return IndirectRef{}, err
+	}
+
+	a, _ := st.dss[key].(Array)
+	st.dss[key] = append(a, *ir)
+	st.streams[h] = *ir
+
+	return *ir, nil
+}
+
+// dss returns the DSS dict of ctx, a new one if missing.
+// The object number of the modified existing object gets recorded in dirty.
+func (ctx *Context) dss(dirty IntSet) (Dict, error) {
+
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := ctx.DereferenceDict(rootDict["DSS"])
+	if err != nil {
+		return nil, err
+	}
+
+	if d != nil {
+		if ir, ok := rootDict["DSS"].(IndirectRef); ok {
+			dirty[ir.ObjectNumber.Value()] = true
+		} else {
+			dirty[ctx.Root.ObjectNumber.Value()] = true
+		}
+		return d, nil
+	}
+
+	d = NewDict()
+	ir, err := ctx.IndRefForNewObject(d)
+	if err != nil {
+		return nil, err
+	}
+
+	rootDict.Insert("DSS", *ir)
+	dirty[ctx.Root.ObjectNumber.Value()] = true
+
+	// Declare the PAdES extension of PDF 1.7.
+	if _, found := rootDict.Find("Extensions"); !found {
+		ext := NewDict()
+		ext.InsertName("BaseVersion", "1.7")
+		ext.Insert("ExtensionLevel", Integer(5))
+		rootDict.Insert("Extensions", Dict(map[string]Object{"ESIC": ext}))
+	}
+
+	return d, nil
+}
+
+// vriEntry returns the validation related information of s.
+func (st *dssStore) vriEntry(s *Signature, ltv *LTV, tu time.Time) (Dict, error) {
+
+	certs := append(append([]*x509.Certificate{}, s.sd.Certificates...), ltv.Certs...)
+
+	ocsps := []*cms.OCSPResponse{}
+	for _, b := range ltv.OCSPs {
+		r, err := cms.ParseOCSPResponse(b)
+		if err != nil {
+			return nil, err
+		}
+		ocsps = append(ocsps, r)
+		certs = append(certs, r.Certificates...)
+	}
+
+	cc := chain(s.sd.Signer, certs)
+
+	d := NewDict()
+
+	a := Array{}
+	for _, c := range cc {
+		ir, err := st.add("Certs", c.Raw)
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, ir)
+	}
+
+	// Include the responder certificates of matching OCSP responses.
+	oa := Array{}
+	for i, r := range ocsps {
+		for _, c := range cc {
+			if !r.Covers(c) {
+				continue
+			}
+			ir, err := st.add("OCSPs", ltv.OCSPs[i])
+			if err != nil {
+				return nil, err
+			}
+			oa = append(oa, ir)
+			for _, rc := range r.Certificates {
+				ir, err := st.add("Certs", rc.Raw)
+				if err != nil {
+					return nil, err
+				}
+				a = append(a, ir)
+			}
+			break
+		}
+	}
+
+	ca := Array{}
+	for _, b := range ltv.CRLs {
+		crl, err := x509.ParseRevocationList(b)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range cc {
+			if bytes.Equal(crl.RawIssuer, c.RawIssuer) {
+				ir, err := st.add("CRLs", b)
+				if err != nil {
+					return nil, err
+				}
+				ca = append(ca, ir)
+				break
+			}
+		}
+	}
+
+	d.Insert("Cert", a)
+	if len(oa) > 0 {
+		d.Insert("OCSP", oa)
+	}
+	if len(ca) > 0 {
+		d.Insert("CRL", ca)
+	}
+	d.Insert("TU", StringLiteral(DateString(tu.UTC())))
+
+	return d, nil
+}
+
+// AddLTV adds a Document Security Store holding the validation data of all signatures of ctx
+// read from the original file content orig along with the locally supplied data of ltv.
+// The DSS gets appended to orig as an incremental update and the result is written to w.
+func AddLTV(ctx *Context, orig []byte, w io.Writer, ltv *LTV) error {
+
+	if ctx.Encrypt != nil {
+		return errors.New("ltv: encrypted files are not supported")
+	}
+
+	if ltv == nil {
+		ltv = &LTV{}
+	}
+
+	sigs, err := ctx.Signatures()
+	if err != nil {
+		return err
+	}
+
+	if len(sigs) == 0 {
+		return errors.New("ltv: no signatures")
+	}
+
+	size := *ctx.Size
+	dirty := IntSet{}
+
+	dss, err := ctx.dss(dirty)
+	if err != nil {
+		return err
+	}
+
+	st, err := ctx.newDSSStore(dss)
+	if err != nil {
+		return err
+	}
+
+	vri, err := ctx.DereferenceDict(dss["VRI"])
+	if err != nil {
+		return err
+	}
+	if vri == nil {
+		vri = NewDict()
+	}
+
+	now := time.Now()
+
+	for _, s := range sigs {
+
+		if s.sd == nil || s.Signer == nil {
+			log.Info.Printf("ltv: skipping signature %s: %v\n", s.FieldName, s.Err)
+			continue
+		}
+
+		d, err := st.vriEntry(s, ltv, now)
+		if err != nil {
+			return err
+		}
+
+		// The key is the uppercase hex SHA-1 digest of the signature value.
+		h := sha1.Sum(s.contents)
+		vri.Update(strings.ToUpper(hex.EncodeToString(h[:])), d)
+	}
+
+	dss.Update("VRI", vri)
+
+	b, _, err := ctx.appendIncrementalUpdate(orig, size, dirty, 0)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+
+	return err
+}
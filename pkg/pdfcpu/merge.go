@@ -17,7 +17,11 @@ limitations under the License.
 package pdfcpu
 
 import (
+	"path/filepath"
+	"strings"
+
 	"github.com/denisbetsi/pdfcpu/pkg/log"
+	"github.com/pkg/errors"
 )
 
 func patchIndRef(ir *IndirectRef, lookup map[int]int) {
@@ -305,6 +309,15 @@ func MergeXRefTables(ctxSource, ctxDest *Context) (err error) {
 	log.Debug.Println("appendSourceObjectsToDest")
 	appendSourceObjectsToDest(ctxSource, ctxDest)
 
+	// Add a top level bookmark for ctxSource.
+	if ctxDest.CreateBookmarks {
+		log.Debug.Println("appendBookmark")
+		err = appendBookmark(ctxSource, ctxDest)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Mark source's root object as free.
 	err = ctxDest.DeleteObject(int(ctxSource.Root.ObjectNumber))
 	if err != nil {
@@ -328,3 +341,320 @@ func MergeXRefTables(ctxSource, ctxDest *Context) (err error) {
 
 	return nil
 }
+
+func bookmarkTitle(ctx *Context) (Object, error) {
+
+	// Prefer the document title.
+	if ctx.Info != nil {
+
+		d, err := ctx.DereferenceDict(*ctx.Info)
+		if err != nil {
+			return nil, err
+		}
+
+		if o, found := d.Find("Title"); found {
+
+			o, err = ctx.Dereference(o)
+			if err != nil {
+				return nil, err
+			}
+
+			s, err := ctx.DereferenceText(o)
+			if err == nil && strings.TrimSpace(s) != "" {
+				return o, nil
+			}
+		}
+	}
+
+	// Fall back to the file name.
+	s := "Untitled"
+	if ctx.Read.FileName != "" {
+		fn := filepath.Base(ctx.Read.FileName)
+		s = strings.TrimSuffix(fn, filepath.Ext(fn))
+	}
+
+	esc, err := Escape(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return StringLiteral(*esc), nil
+}
+
+// resolveOutlineDestinations replaces named destinations of outline items by explicit destinations.
+// This is necessary for outlines surviving their document catalog.
+func resolveOutlineDestinations(ctx *Context, first *IndirectRef) error {
+
+	for ir := first; ir != nil; {
+
+		d, err := ctx.DereferenceDict(*ir)
+		if err != nil {
+			return err
+		}
+
+		if o, found := d.Find("Dest"); found {
+			a, err := ctx.ResolveDestination(o)
+			if err != nil {
+				return err
+			}
+			if a == nil {
+				d.Delete("Dest")
+			} else {
+				d.Update("Dest", a)
+			}
+		}
+
+		if o, found := d.Find("A"); found {
+			action, err := ctx.DereferenceDict(o)
+			if err != nil {
+				return err
+			}
+			if s := action.NameEntry("S"); s != nil && *s == "GoTo" {
+				a, err := ctx.ResolveDestination(action["D"])
+				if err != nil {
+					return err
+				}
+				if a != nil {
+					action.Update("D", a)
+				}
+			}
+		}
+
+		if err = resolveOutlineDestinations(ctx, d.IndirectRefEntry("First")); err != nil {
+			return err
+		}
+
+		ir = d.IndirectRefEntry("Next")
+	}
+
+	return nil
+}
+
+// createBookmark creates an outline item in ctxDest for the document represented by ctxFile.
+// The item points to the first page of ctxFile and takes over any existing outlines of ctxFile as children.
+func createBookmark(ctxFile, ctxDest *Context, parent IndirectRef) (*IndirectRef, Dict, error) {
+
+	title, err := bookmarkTitle(ctxFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pages, err := ctxFile.PageIndRefs()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(pages) == 0 {
+		return nil, nil, errors.New("createBookmark: missing pages")
+	}
+
+	d := Dict(
+		map[string]Object{
+			"Title":  title,
+			"Parent": parent,
+			"Dest":   Array{pages[0], Name("Fit")},
+		},
+	)
+
+	ir, err := ctxDest.IndRefForNewObject(d)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rootDict, err := ctxFile.Catalog()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	o, found := rootDict.Find("Outlines")
+	if !found {
+		return ir, d, nil
+	}
+
+	outlinesDict, err := ctxFile.DereferenceDict(o)
+	if err != nil || outlinesDict == nil {
+		return ir, d, err
+	}
+
+	first := outlinesDict.IndirectRefEntry("First")
+	last := outlinesDict.IndirectRefEntry("Last")
+	if first == nil || last == nil {
+		return ir, d, nil
+	}
+
+	if err = resolveOutlineDestinations(ctxFile, first); err != nil {
+		return nil, nil, err
+	}
+
+	// Nest the existing top level outline items.
+	c := 0
+	for item := first; item != nil; {
+		itemDict, err := ctxFile.DereferenceDict(*item)
+		if err != nil {
+			return nil, nil, err
+		}
+		itemDict.Update("Parent", *ir)
+		c++
+		item = itemDict.IndirectRefEntry("Next")
+	}
+
+	if i := outlinesDict.IntEntry("Count"); i != nil && *i != 0 {
+		c = *i
+		if c < 0 {
+			c = -c
+		}
+	}
+
+	d.Insert("First", *first)
+	d.Insert("Last", *last)
+
+	// Start out closed.
+	d.Insert("Count", Integer(-c))
+
+	return ir, d, nil
+}
+
+// CreateMergeOutlines replaces ctx's outlines by a single bookmark for ctx's document.
+// Any existing outlines are nested underneath this bookmark.
+// Every file merged into ctx subsequently will contribute another top level bookmark.
+func CreateMergeOutlines(ctx *Context) error {
+
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return err
+	}
+
+	outlinesDict := Dict(map[string]Object{"Type": Name("Outlines")})
+
+	outlinesIndRef, err := ctx.IndRefForNewObject(outlinesDict)
+	if err != nil {
+		return err
+	}
+
+	ir, _, err := createBookmark(ctx, ctx, *outlinesIndRef)
+	if err != nil {
+		return err
+	}
+
+	outlinesDict.Insert("First", *ir)
+	outlinesDict.Insert("Last", *ir)
+	outlinesDict.Insert("Count", Integer(1))
+
+	rootDict.Update("Outlines", *outlinesIndRef)
+
+	return nil
+}
+
+// appendBookmark adds a top level bookmark for ctxSource to ctxDest's outlines.
+func appendBookmark(ctxSource, ctxDest *Context) error {
+
+	rootDict, err := ctxDest.Catalog()
+	if err != nil {
+		return err
+	}
+
+	outlinesIndRef := rootDict.IndirectRefEntry("Outlines")
+	if outlinesIndRef == nil {
+		return errors.New("appendBookmark: missing outlines")
+	}
+
+	outlinesDict, err := ctxDest.DereferenceDict(*outlinesIndRef)
+	if err != nil {
+		return err
+	}
+
+	ir, d, err := createBookmark(ctxSource, ctxDest, *outlinesIndRef)
+	if err != nil {
+		return err
+	}
+
+	last := outlinesDict.IndirectRefEntry("Last")
+	if last == nil {
+		outlinesDict.Update("First", *ir)
+	} else {
+		lastDict, err := ctxDest.DereferenceDict(*last)
+		if err != nil {
+			return err
+		}
+		lastDict.Update("Next", *ir)
+		d.Insert("Prev", *last)
+	}
+
+	outlinesDict.Update("Last", *ir)
+
+	return outlinesDict.IncrementBy("Count", 1)
+}
+
+// InsertPagesFrom inserts the selected pages of ctxSource into ctxDest before page before.
+// If before is the successor of the last page of ctxDest the pages get appended.
+// ctxSource gets consumed in the process.
+func InsertPagesFrom(ctxSource, ctxDest *Context, selectedPages IntSet, before int) error {
+
+	if before < 1 || before > ctxDest.PageCount+1 {
+		return errors.Errorf("InsertPagesFrom: invalid page number %d, please use 1 <= page <= %d", before, ctxDest.PageCount+1)
+	}
+
+	// Reduce source to the selected pages and get rid of references to all other pages.
+	if err := TrimPages(ctxSource, selectedPages); err != nil {
+		return err
+	}
+
+	irs, err := ctxSource.PageIndRefs()
+	if err != nil {
+		return err
+	}
+
+	// Detach the pages from the source page tree so the source page tree does not get migrated.
+	a := Array{}
+	for _, ir := range irs {
+		d, err := ctxSource.DereferenceDict(ir)
+		if err != nil {
+			return err
+		}
+		d.Delete("Parent")
+		a = append(a, ir)
+	}
+
+	// Migrate the pages and all objects they depend on into ctxDest.
+	if err = migrateObject(ctxSource, ctxDest, a); err != nil {
+		return err
+	}
+
+	destIrs, err := ctxDest.PageIndRefs()
+	if err != nil {
+		return err
+	}
+
+	// Hook the migrated pages into the dest page tree.
+	pages := []IndirectRef{}
+	for _, o := range a {
+		pages = append(pages, o.(IndirectRef))
+	}
+
+	m := map[int][]IndirectRef{}
+	if before > ctxDest.PageCount {
+		m[ctxDest.PageCount] = append([]IndirectRef{destIrs[ctxDest.PageCount-1]}, pages...)
+	} else {
+		m[before] = append(pages, destIrs[before-1])
+	}
+
+	root, err := ctxDest.Pages()
+	if err != nil {
+		return err
+	}
+
+	p := 0
+	i, err := replacePages(ctxDest.XRefTable, root, &p, m)
+	if err != nil {
+		return err
+	}
+
+	ctxDest.PageCount += i
+
+	if ctxDest.Version() < ctxSource.Version() {
+		v := ctxSource.Version()
+		ctxDest.RootVersion = &v
+	}
+
+	return nil
+}
@@ -599,27 +599,29 @@ func nupFromOneImage(ctx *Context, fileName string, nup *NUp, pagesDict *Dict, p
 	return nil
 }
 
-func wrapUpPage(ctx *Context, nup *NUp, d Dict, buf bytes.Buffer, pagesDict *Dict, pagesIndRef *IndirectRef) error {
-
-	xRefTable := ctx.XRefTable
-
-	resourceDict := Dict(
-		map[string]Object{
-			"XObject": d,
-		},
-	)
+// appendPage creates a new page for content and resDict and appends it to the page tree.
+func appendPage(ctx *Context, resDict Dict, content []byte, mediaBox *Rectangle, pagesDict *Dict, pagesIndRef *IndirectRef) error {
 
-	resIndRef, err := xRefTable.IndRefForNewObject(resourceDict)
+	resIndRef, err := ctx.IndRefForNewObject(resDict)
 	if err != nil {
 		return err
 	}
 
+	return appendPageForResources(ctx, *resIndRef, content, mediaBox, pagesDict, pagesIndRef)
+}
+
+// appendPageForResources creates a new page for content using the shared resources resIndRef
+// and appends it to the page tree.
+func appendPageForResources(ctx *Context, resIndRef IndirectRef, content []byte, mediaBox *Rectangle, pagesDict *Dict, pagesIndRef *IndirectRef) error {
+
+	xRefTable := ctx.XRefTable
+
 	contents := &StreamDict{Dict: NewDict()}
 	contents.InsertName("Filter", filter.Flate)
 	contents.FilterPipeline = []PDFFilter{{Name: filter.Flate, DecodeParms: nil}}
-	contents.Content = buf.Bytes()
+	contents.Content = content
 
-	err = encodeStream(contents)
+	err := encodeStream(contents)
 	if err != nil {
 		return err
 	}
@@ -629,16 +631,12 @@ func wrapUpPage(ctx *Context, nup *NUp, d Dict, buf bytes.Buffer, pagesDict *Dic
 		return err
 	}
 
-	// mediabox = physical page dimensions
-	dim := nup.PageDim
-	mediaBox := RectForDim(dim.w, dim.h)
-
 	pageDict := Dict(
 		map[string]Object{
 			"Type":      Name("Page"),
 			"Parent":    *pagesIndRef,
 			"MediaBox":  mediaBox.Array(),
-			"Resources": *resIndRef,
+			"Resources": resIndRef,
 			"Contents":  *contentsIndRef,
 		},
 	)
@@ -658,6 +656,21 @@ func wrapUpPage(ctx *Context, nup *NUp, d Dict, buf bytes.Buffer, pagesDict *Dic
 	return nil
 }
 
+func wrapUpPage(ctx *Context, nup *NUp, d Dict, buf bytes.Buffer, pagesDict *Dict, pagesIndRef *IndirectRef) error {
+
+	resourceDict := Dict(
+		map[string]Object{
+			"XObject": d,
+		},
+	)
+
+	// mediabox = physical page dimensions
+	dim := nup.PageDim
+	mediaBox := RectForDim(dim.w, dim.h)
+
+	return appendPage(ctx, resourceDict, buf.Bytes(), mediaBox, pagesDict, pagesIndRef)
+}
+
 func nupFromMultipleImages(ctx *Context, fileNames []string, nup *NUp, pagesDict *Dict, pagesIndRef *IndirectRef) error {
 
 	if nup.PageGrid {
@@ -764,11 +777,51 @@ func sortedSelectedPages(selectedPages IntSet) []int {
 	return pageNumbers
 }
 
+// pageForm returns a form XObject wrapping the content of page pageNr along with its MediaBox.
+// A nil form is returned for a page without content.
+func pageForm(ctx *Context, pageNr int) (*IndirectRef, *Rectangle, error) {
+
+	d, inhPAttrs, err := ctx.PageDict(pageNr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if d == nil {
+		return nil, nil, errors.Errorf("unknown page number: %d\n", pageNr)
+	}
+
+	// Retrieve content stream bytes.
+
+	o, found := d.Find("Contents")
+	if !found {
+		return nil, nil, nil
+	}
+
+	bb, err := contentStream(ctx.XRefTable, o)
+	if err != nil {
+		if err == errNoContent {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	// Create an object for this resDict in xRefTable.
+	ir, err := ctx.IndRefForNewObject(inhPAttrs.resources)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	formIndRef, err := createNUpFormForPDFResource(ctx.XRefTable, ir, bb, inhPAttrs.mediaBox)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return formIndRef, inhPAttrs.mediaBox, nil
+}
+
 func nupPages(ctx *Context, selectedPages IntSet, nup *NUp, pagesDict *Dict, pagesIndRef *IndirectRef) error {
 
 	var buf bytes.Buffer
 
-	xRefTable := ctx.XRefTable
 	formsResDict := NewDict()
 	rr := rectsForGrid(nup)
 
@@ -786,45 +839,18 @@ func nupPages(ctx *Context, selectedPages IntSet, nup *NUp, pagesDict *Dict, pag
 			formsResDict = NewDict()
 		}
 
-		d, inhPAttrs, err := ctx.PageDict(p)
+		formIndRef, mediaBox, err := pageForm(ctx, p)
 		if err != nil {
 			return err
 		}
-		if d == nil {
-			return errors.Errorf("unknown page number: %d\n", i)
-		}
-
-		// Retrieve content stream bytes.
-
-		o, found := d.Find("Contents")
-		if !found {
+		if formIndRef == nil {
 			continue
 		}
 
-		var bb []byte
-		bb, err = contentStream(xRefTable, o)
-		if err != nil {
-			if err == errNoContent {
-				continue
-			}
-			return err
-		}
-
-		// Create an object for this resDict in xRefTable.
-		ir, err := ctx.IndRefForNewObject(inhPAttrs.resources)
-		if err != nil {
-			return err
-		}
-
-		formIndRef, err := createNUpFormForPDFResource(xRefTable, ir, bb, inhPAttrs.mediaBox)
-		if err != nil {
-			return err
-		}
-
 		formResID := fmt.Sprintf("Fm%d", i)
 		formsResDict.Insert(formResID, *formIndRef)
 
-		nUpTilePDFBytes(&buf, inhPAttrs.mediaBox, rr[i%len(rr)], formResID, nup)
+		nUpTilePDFBytes(&buf, mediaBox, rr[i%len(rr)], formResID, nup)
 	}
 
 	// Wrap incomplete nUp page.
@@ -822,6 +822,13 @@ func parseObject(line *string) (Object, error) {
 	return value, nil
 }
 
+// ParseNextObject parses the next direct object or indirect reference of s and returns it along with the rest of s.
+// Useful for reading PDF syntax outside of a PDF file, eg. FDF.
+func ParseNextObject(s string) (Object, string, error) {
+	o, err := parseObject(&s)
+	return o, s, err
+}
+
 // parseXRefStreamDict creates a XRefStreamDict out of a StreamDict.
 func parseXRefStreamDict(sd *StreamDict) (*XRefStreamDict, error) {
 
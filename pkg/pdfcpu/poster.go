@@ -0,0 +1,363 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	"github.com/pkg/errors"
+)
+
+var errInvalidPosterConfig = errors.New("Invalid poster configuration string. Please consult pdfcpu help poster")
+
+const (
+	posterCropMarkLength = 12.
+	posterCropMarkGap    = 3.
+	posterLabelFontSize  = 7.
+)
+
+// Poster represents the command details for the command "Poster".
+type Poster struct {
+	PageSize  string  // Sheet paper size eg. A4(=default), Letter, see paperSize.go
+	PageDim   *dim    // Sheet dimensions in user units.
+	Scale     float64 // Scale factor applied to the page before tiling.
+	Margin    float64 // Unprintable sheet margin in user units.
+	Overlap   float64 // Overlap of adjacent tiles in user units.
+	CropMarks bool    // Draw crop marks at the tile corners.
+	Labels    bool    // Print row/column labels into the sheet margin.
+}
+
+func (p Poster) String() string {
+	return fmt.Sprintf("Poster conf: %s %s, scale=%.2f, margin=%.2f, overlap=%.2f, cropMarks=%t, labels=%t\n",
+		p.PageSize, *p.PageDim, p.Scale, p.Margin, p.Overlap, p.CropMarks, p.Labels)
+}
+
+// DefaultPosterConfig returns the default poster configuration.
+func DefaultPosterConfig() *Poster {
+	return &Poster{
+		PageSize: "A4",
+		PageDim:  PaperSize["A4"],
+		Scale:    1,
+		Margin:   18,
+	}
+}
+
+func parsePosterLength(s, name string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return 0, errors.Errorf("%s: please provide a positive value in user units: %s", name, s)
+	}
+	return f, nil
+}
+
+func parsePosterScale(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f <= 0 {
+		return 0, errors.Errorf("scale factor: please provide a value > 0: %s", s)
+	}
+	return f, nil
+}
+
+// ParsePosterConfig parses a poster command string into an internal structure.
+// eg. "f: Letter, o: 20, c: on" or "s: 0.5, l: on"
+func ParsePosterConfig(s string) (*Poster, error) {
+
+	p := DefaultPosterConfig()
+
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return p, nil
+	}
+
+	var setDim, setFormat bool
+
+	for _, s := range strings.Split(s, ",") {
+
+		ss := strings.Split(s, ":")
+		if len(ss) != 2 {
+			return nil, errInvalidPosterConfig
+		}
+
+		k := strings.TrimSpace(ss[0])
+		v := strings.TrimSpace(ss[1])
+
+		var err error
+
+		switch k {
+		case "f": // sheet format
+			p.PageDim, p.PageSize, err = parsePageFormat(v, setDim)
+			setFormat = true
+
+		case "d": // sheet dimensions
+			p.PageDim, p.PageSize, err = parsePageDim(v, setFormat)
+			setDim = true
+
+		case "s": // scale factor
+			p.Scale, err = parsePosterScale(v)
+
+		case "m": // margin
+			p.Margin, err = parsePosterLength(v, "margin")
+
+		case "o": // overlap
+			p.Overlap, err = parsePosterLength(v, "overlap")
+
+		case "c": // crop marks
+			p.CropMarks, err = parseBorder(v)
+
+		case "l": // labels
+			p.Labels, err = parseBorder(v)
+
+		default:
+			err = errInvalidPosterConfig
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// posterGrid returns the number of tile columns and rows needed for a poster of size w x h
+// using tiles of size tw x th overlapping by overlap.
+func posterGrid(w, h, tw, th, overlap float64) (int, int) {
+	n := func(l, tl float64) int {
+		if l <= tl {
+			return 1
+		}
+		return int(math.Ceil((l - overlap) / (tl - overlap)))
+	}
+	return n(w, tw), n(h, th)
+}
+
+// posterLayout returns the sheet dimensions to be used along with the number of tile columns and rows.
+// The sheet orientation resulting in fewer sheets is chosen.
+func posterLayout(w, h float64, p *Poster) (*dim, int, int, error) {
+
+	var (
+		best       *dim
+		cols, rows int
+	)
+
+	d := *p.PageDim
+
+	for _, d := range []dim{d, {d.h, d.w}} {
+
+		tw, th := float64(d.w)-2*p.Margin, float64(d.h)-2*p.Margin
+		if tw <= p.Overlap || th <= p.Overlap {
+			return nil, 0, 0, errors.New("poster: margin and overlap exceed the sheet size")
+		}
+
+		c, r := posterGrid(w, h, tw, th, p.Overlap)
+		if best == nil || c*r < cols*rows {
+			d := d
+			best, cols, rows = &d, c, r
+		}
+	}
+
+	return best, cols, rows, nil
+}
+
+// posterNormalizeMatrix returns the matrix mapping box as displayed using rotation onto (0, 0, w, h) scaled by scale.
+func posterNormalizeMatrix(box *Rectangle, rotation int, scale float64) (matrix, float64, float64) {
+
+	m := identMatrix
+	sin := math.Sin(float64(-rotation) * degToRad)
+	cos := math.Cos(float64(-rotation) * degToRad)
+	m[0][0], m[0][1] = cos, sin
+	m[1][0], m[1][1] = -sin, cos
+
+	r := m.transformRect(box.LL.X, box.LL.Y, box.UR.X, box.UR.Y)
+
+	t := identMatrix
+	t[2][0], t[2][1] = -r.LL.X, -r.LL.Y
+
+	s := identMatrix
+	s[0][0], s[1][1] = scale, scale
+
+	return m.multiply(t).multiply(s), r.Width() * scale, r.Height() * scale
+}
+
+func posterCropMarks(wr *bytes.Buffer, r *Rectangle) {
+
+	l := posterCropMarkLength
+	g := posterCropMarkGap
+
+	fmt.Fprint(wr, "q 0 G 0.5 w [] 0 d ")
+	for _, c := range []struct{ x, y, dx, dy float64 }{
+		{r.LL.X, r.LL.Y, -1, -1},
+		{r.UR.X, r.LL.Y, 1, -1},
+		{r.UR.X, r.UR.Y, 1, 1},
+		{r.LL.X, r.UR.Y, -1, 1},
+	} {
+		// A horizontal and a vertical mark pointing away from the tile.
+		fmt.Fprintf(wr, "%.2f %.2f m %.2f %.2f l S ", c.x+c.dx*g, c.y, c.x+c.dx*(g+l), c.y)
+		fmt.Fprintf(wr, "%.2f %.2f m %.2f %.2f l S ", c.x, c.y+c.dy*g, c.x, c.y+c.dy*(g+l))
+	}
+	fmt.Fprint(wr, "Q ")
+}
+
+func posterLabel(wr *bytes.Buffer, r *Rectangle, pageNr, row, col, rows, cols int) {
+
+	y := r.LL.Y - posterCropMarkGap - posterLabelFontSize
+	if y < 2 {
+		y = 2
+	}
+
+	label := fmt.Sprintf("page %d  row %d/%d  column %d/%d", pageNr, row, rows, col, cols)
+
+	fmt.Fprintf(wr, "q 0 g BT /F0 %.0f Tf %.2f %.2f Td (%s) Tj ET Q ",
+		posterLabelFontSize, r.LL.X+posterCropMarkGap+posterCropMarkLength, y, label)
+}
+
+func posterTiles(ctx *Context, pageNr int, p *Poster, fontIndRef *IndirectRef, pagesDict *Dict, pagesIndRef *IndirectRef) error {
+
+	_, inhPAttrs, err := ctx.PageDict(pageNr)
+	if err != nil {
+		return err
+	}
+
+	formIndRef, mediaBox, err := pageForm(ctx, pageNr)
+	if err != nil {
+		return err
+	}
+
+	box := mediaBox
+	if formIndRef == nil {
+		box = inhPAttrs.mediaBox
+	}
+	if inhPAttrs.cropBox != nil {
+		if r := intersection(inhPAttrs.cropBox, box); r != nil {
+			box = r
+		}
+	}
+
+	m, w, h := posterNormalizeMatrix(box, inhPAttrs.rotate, p.Scale)
+
+	sheetDim, cols, rows, err := posterLayout(w, h, p)
+	if err != nil {
+		return err
+	}
+
+	log.Info.Printf("poster: page %d: %.2f x %.2f on %d x %d sheets of %s\n", pageNr, w, h, cols, rows, sheetDim)
+
+	sheet := RectForDim(sheetDim.w, sheetDim.h)
+	tile := Rect(p.Margin, p.Margin, sheet.UR.X-p.Margin, sheet.UR.Y-p.Margin)
+	stepX, stepY := tile.Width()-p.Overlap, tile.Height()-p.Overlap
+
+	resDict := NewDict()
+	if formIndRef != nil {
+		resDict.Insert("XObject", Dict(map[string]Object{"Fm0": *formIndRef}))
+	}
+	if fontIndRef != nil {
+		resDict.Insert("Font", Dict(map[string]Object{"F0": *fontIndRef}))
+	}
+
+	// All tiles of a page share its resources.
+	resIndRef, err := ctx.IndRefForNewObject(resDict)
+	if err != nil {
+		return err
+	}
+
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+
+			var buf bytes.Buffer
+
+			// The poster region shown by this tile, rows run top down.
+			x0 := float64(col) * stepX
+			y0 := h - float64(row)*stepY - tile.Height()
+
+			if formIndRef != nil {
+				t := identMatrix
+				t[2][0], t[2][1] = tile.LL.X-x0, tile.LL.Y-y0
+				tm := m.multiply(t)
+
+				fmt.Fprintf(&buf, "q %.2f %.2f %.2f %.2f re W n ", tile.LL.X, tile.LL.Y, tile.Width(), tile.Height())
+				fmt.Fprintf(&buf, "%.4f %.4f %.4f %.4f %.4f %.4f cm ", tm[0][0], tm[0][1], tm[1][0], tm[1][1], tm[2][0], tm[2][1])
+				fmt.Fprintf(&buf, "%.2f %.2f %.2f %.2f re W n /Fm0 Do Q ", box.LL.X, box.LL.Y, box.Width(), box.Height())
+			}
+
+			if p.CropMarks {
+				posterCropMarks(&buf, tile)
+			}
+
+			if p.Labels {
+				posterLabel(&buf, tile, pageNr, row+1, col+1, rows, cols)
+			}
+
+			if err := appendPageForResources(ctx, *resIndRef, buf.Bytes(), sheet, pagesDict, pagesIndRef); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// PosterFromPDF tiles each selected page across as many sheets as needed.
+// Tiles are arranged in rows from top to bottom, each row from left to right.
+func PosterFromPDF(ctx *Context, selectedPages IntSet, p *Poster) error {
+
+	mb := RectForDim(p.PageDim.w, p.PageDim.h)
+
+	pagesDict := Dict(
+		map[string]Object{
+			"Type":     Name("Pages"),
+			"Count":    Integer(0),
+			"MediaBox": mb.Array(),
+		},
+	)
+
+	pagesIndRef, err := ctx.IndRefForNewObject(pagesDict)
+	if err != nil {
+		return err
+	}
+
+	var fontIndRef *IndirectRef
+	if p.Labels {
+		d := NewDict()
+		d.InsertName("Type", "Font")
+		d.InsertName("Subtype", "Type1")
+		d.InsertName("BaseFont", "Helvetica")
+		if fontIndRef, err = ctx.IndRefForNewObject(d); err != nil {
+			return err
+		}
+	}
+
+	for _, k := range sortedSelectedPages(selectedPages) {
+		if err = posterTiles(ctx, k, p, fontIndRef, &pagesDict, pagesIndRef); err != nil {
+			return err
+		}
+	}
+
+	// Replace original pagesDict.
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return err
+	}
+
+	rootDict.Update("Pages", *pagesIndRef)
+
+	return nil
+}
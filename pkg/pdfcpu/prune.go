@@ -0,0 +1,682 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// undoLog records modifications of dicts so they can be reverted.
+type undoLog []func()
+
+func (u *undoLog) update(d Dict, key string, o Object) {
+	v, found := d[key]
+	*u = append(*u, func() {
+		if found {
+			d[key] = v
+			return
+		}
+		delete(d, key)
+	})
+	d[key] = o
+}
+
+func (u *undoLog) delete(d Dict, key string) {
+	v, found := d[key]
+	if !found {
+		return
+	}
+	*u = append(*u, func() { d[key] = v })
+	delete(d, key)
+}
+
+func (u undoLog) undo() {
+	for i := len(u) - 1; i >= 0; i-- {
+		u[i]()
+	}
+}
+
+// pruner reduces the document level structures referring to pages
+// to the set of pages about to be written.
+type pruner struct {
+	ctx    *Context
+	pages  IntSet // obj numbers of page dicts being written.
+	annots IntSet // obj numbers of annotations of pages being written.
+	undoLog
+}
+
+func (p *pruner) collectPages() error {
+
+	irs, err := p.ctx.PageIndRefs()
+	if err != nil {
+		return err
+	}
+
+	for i, ir := range irs {
+
+		writePage := p.ctx.Write.SelectedPages[i+1]
+		if p.ctx.Cmd == REMOVEPAGES {
+			writePage = !writePage
+		}
+		if !writePage {
+			continue
+		}
+
+		p.pages[ir.ObjectNumber.Value()] = true
+
+		d, err := p.ctx.DereferenceDict(ir)
+		if err != nil {
+			return err
+		}
+
+		a, err := p.ctx.DereferenceArray(d["Annots"])
+		if err != nil {
+			return err
+		}
+
+		for _, o := range a {
+			if ir, ok := o.(IndirectRef); ok {
+				p.annots[ir.ObjectNumber.Value()] = true
+			}
+		}
+	}
+
+	return nil
+}
+
+// destPage returns the obj number of the page a destination points to.
+// ok is false for destinations not pointing to a local page.
+func (p *pruner) destPage(o Object) (objNr int, ok bool, err error) {
+
+	a, err := p.ctx.ResolveDestination(o)
+	if err != nil {
+		// Leave undecodable destinations alone.
+		log.Write.Printf("destPage: %v\n", err)
+		return 0, false, nil
+	}
+
+	if len(a) == 0 {
+		return 0, false, nil
+	}
+
+	ir, ok := a[0].(IndirectRef)
+	if !ok {
+		return 0, false, nil
+	}
+
+	return ir.ObjectNumber.Value(), true, nil
+}
+
+// target returns the obj number of the page d's destination or GoTo action points to.
+func (p *pruner) target(d Dict) (objNr int, ok bool, err error) {
+
+	if o, found := d.Find("Dest"); found {
+		return p.destPage(o)
+	}
+
+	o, found := d.Find("A")
+	if !found {
+		return 0, false, nil
+	}
+
+	action, err := p.ctx.DereferenceDict(o)
+	if err != nil || action == nil {
+		return 0, false, err
+	}
+
+	if s := action.NameEntry("S"); s == nil || *s != "GoTo" {
+		return 0, false, nil
+	}
+
+	return p.destPage(action["D"])
+}
+
+// neutralize removes d's destination or action if it points to a page not being written.
+func (p *pruner) neutralize(d Dict) (removed bool, err error) {
+
+	objNr, ok, err := p.target(d)
+	if err != nil || !ok || p.pages[objNr] {
+		return false, err
+	}
+
+	p.delete(d, "Dest")
+	p.delete(d, "A")
+
+	return true, nil
+}
+
+func (p *pruner) pruneAnnotations() error {
+
+	for objNr := range p.annots {
+
+		d, err := p.ctx.DereferenceDict(IndirectRef{ObjectNumber: Integer(objNr)})
+		if err != nil {
+			return err
+		}
+
+		if d == nil {
+			continue
+		}
+
+		if _, err = p.neutralize(d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pruneOutlineItems prunes the list of outline items starting with first.
+// An item survives if it points to a page being written or if any of its descendants survive.
+// Returns the surviving list and the number of visible items.
+func (p *pruner) pruneOutlineItems(first *IndirectRef) (*IndirectRef, *IndirectRef, int, error) {
+
+	var (
+		kept       []IndirectRef
+		keptDicts  []Dict
+		visibleAll int
+	)
+
+	for ir := first; ir != nil; {
+
+		d, err := p.ctx.DereferenceDict(*ir)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+
+		next := d.IndirectRefEntry("Next")
+
+		f, l, visible, err := p.pruneOutlineItems(d.IndirectRefEntry("First"))
+		if err != nil {
+			return nil, nil, 0, err
+		}
+
+		_, pageTarget, err := p.target(d)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+
+		removed, err := p.neutralize(d)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+
+		_, hasAction := d.Find("A")
+		keep := f != nil || (pageTarget && !removed) || (!pageTarget && hasAction)
+
+		if !keep {
+			ir = next
+			continue
+		}
+
+		open := true
+		if c := d.IntEntry("Count"); c != nil && *c < 0 {
+			open = false
+		}
+
+		if f == nil {
+			p.delete(d, "First")
+			p.delete(d, "Last")
+			p.delete(d, "Count")
+		} else {
+			p.update(d, "First", *f)
+			p.update(d, "Last", *l)
+			if open {
+				p.update(d, "Count", Integer(visible))
+			} else {
+				p.update(d, "Count", Integer(-visible))
+			}
+		}
+
+		visibleAll++
+		if open {
+			visibleAll += visible
+		}
+
+		kept = append(kept, *ir)
+		keptDicts = append(keptDicts, d)
+
+		ir = next
+	}
+
+	if len(kept) == 0 {
+		return nil, nil, 0, nil
+	}
+
+	// Relink the surviving siblings.
+	for i, d := range keptDicts {
+		if i == 0 {
+			p.delete(d, "Prev")
+		} else {
+			p.update(d, "Prev", kept[i-1])
+		}
+		if i == len(kept)-1 {
+			p.delete(d, "Next")
+		} else {
+			p.update(d, "Next", kept[i+1])
+		}
+	}
+
+	return &kept[0], &kept[len(kept)-1], visibleAll, nil
+}
+
+func (p *pruner) pruneOutlines(rootDict Dict) error {
+
+	o, found := rootDict.Find("Outlines")
+	if !found {
+		return nil
+	}
+
+	d, err := p.ctx.DereferenceDict(o)
+	if err != nil || d == nil {
+		return err
+	}
+
+	first, last, visible, err := p.pruneOutlineItems(d.IndirectRefEntry("First"))
+	if err != nil {
+		return err
+	}
+
+	if first == nil {
+		p.delete(rootDict, "Outlines")
+		return nil
+	}
+
+	p.update(d, "First", *first)
+	p.update(d, "Last", *last)
+	p.update(d, "Count", Integer(visible))
+
+	return nil
+}
+
+// keepDest returns true if a named destination shall be written.
+func (p *pruner) keepDest(o Object) (bool, error) {
+
+	objNr, ok, err := p.destPage(o)
+	if err != nil {
+		return false, err
+	}
+
+	return !ok || p.pages[objNr], nil
+}
+
+func (p *pruner) collectDestNames(d Dict, a *Array) error {
+
+	names, err := p.ctx.DereferenceArray(d["Names"])
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i+1 < len(names); i += 2 {
+		keep, err := p.keepDest(names[i+1])
+		if err != nil {
+			return err
+		}
+		if keep {
+			*a = append(*a, names[i], names[i+1])
+		}
+	}
+
+	kids, err := p.ctx.DereferenceArray(d["Kids"])
+	if err != nil {
+		return err
+	}
+
+	for _, o := range kids {
+
+		d1, err := p.ctx.DereferenceDict(o)
+		if err != nil {
+			return err
+		}
+
+		if d1 == nil {
+			continue
+		}
+
+		if err = p.collectDestNames(d1, a); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *pruner) pruneDests(rootDict Dict) error {
+
+	// PDF 1.1 named destinations.
+	d, err := p.ctx.DereferenceDict(rootDict["Dests"])
+	if err != nil {
+		return err
+	}
+
+	if d != nil {
+		d1 := NewDict()
+		for k, v := range d {
+			keep, err := p.keepDest(v)
+			if err != nil {
+				return err
+			}
+			if keep {
+				d1.Insert(k, v)
+			}
+		}
+		p.update(rootDict, "Dests", d1)
+	}
+
+	// PDF 1.2 named destinations.
+	namesDict, err := p.ctx.DereferenceDict(rootDict["Names"])
+	if err != nil || namesDict == nil {
+		return err
+	}
+
+	d, err = p.ctx.DereferenceDict(namesDict["Dests"])
+	if err != nil || d == nil {
+		return err
+	}
+
+	// Replace the name tree by a single leaf holding the surviving entries.
+	a := Array{}
+	if err = p.collectDestNames(d, &a); err != nil {
+		return err
+	}
+
+	d1 := NewDict()
+	d1.Insert("Names", a)
+	p.update(namesDict, "Dests", d1)
+
+	// Bypass the name tree cache.
+	if n, found := p.ctx.Names["Dests"]; found {
+		delete(p.ctx.Names, "Dests")
+		p.undoLog = append(p.undoLog, func() { p.ctx.Names["Dests"] = n })
+	}
+
+	return nil
+}
+
+// pruneFields prunes an array of form fields.
+// A field survives if any of its widgets lives on a page being written.
+func (p *pruner) pruneFields(a Array) (Array, error) {
+
+	fields := Array{}
+
+	for _, o := range a {
+
+		ir, ok := o.(IndirectRef)
+		if !ok {
+			fields = append(fields, o)
+			continue
+		}
+
+		d, err := p.ctx.DereferenceDict(ir)
+		if err != nil {
+			return nil, err
+		}
+
+		if d == nil {
+			continue
+		}
+
+		kids, err := p.ctx.DereferenceArray(d["Kids"])
+		if err != nil {
+			return nil, err
+		}
+
+		if kids == nil {
+			// Terminal field merged with its widget annotation.
+			if p.annots[ir.ObjectNumber.Value()] {
+				fields = append(fields, o)
+			}
+			continue
+		}
+
+		kids1, err := p.pruneFields(kids)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(kids1) == 0 {
+			continue
+		}
+
+		if len(kids1) < len(kids) {
+			p.update(d, "Kids", kids1)
+		}
+
+		fields = append(fields, o)
+	}
+
+	return fields, nil
+}
+
+func (p *pruner) pruneAcroForm(rootDict Dict) error {
+
+	d, err := p.ctx.DereferenceDict(rootDict["AcroForm"])
+	if err != nil || d == nil {
+		return err
+	}
+
+	a, err := p.ctx.DereferenceArray(d["Fields"])
+	if err != nil {
+		return err
+	}
+
+	fields, err := p.pruneFields(a)
+	if err != nil {
+		return err
+	}
+
+	if len(fields) == 0 {
+		p.delete(rootDict, "AcroForm")
+		return nil
+	}
+
+	p.update(d, "Fields", fields)
+
+	return nil
+}
+
+func (p *pruner) pruneOpenAction(rootDict Dict) error {
+
+	o, found := rootDict.Find("OpenAction")
+	if !found {
+		return nil
+	}
+
+	o, err := p.ctx.Dereference(o)
+	if err != nil {
+		return err
+	}
+
+	var objNr int
+	var ok bool
+
+	if action, isDict := o.(Dict); isDict {
+		objNr, ok, err = p.target(Dict(map[string]Object{"A": action}))
+	} else {
+		objNr, ok, err = p.destPage(o)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if ok && !p.pages[objNr] {
+		p.delete(rootDict, "OpenAction")
+	}
+
+	return nil
+}
+
+// prunePageReferences reduces outlines, named destinations, form fields and links
+// to the pages selected for writing, so that no references to omitted pages remain.
+// All modifications are reverted by calling the returned undo func.
+func prunePageReferences(ctx *Context, rootDict Dict) (func(), error) {
+
+	log.Write.Println("prunePageReferences begin")
+
+	p := &pruner{ctx: ctx, pages: IntSet{}, annots: IntSet{}}
+
+	if err := p.collectPages(); err != nil {
+		return nil, err
+	}
+
+	// Structure trees reference all pages.
+	p.delete(rootDict, "StructTreeRoot")
+
+	for _, f := range []func(Dict) error{
+		p.pruneOutlines,
+		p.pruneDests,
+		p.pruneAcroForm,
+		p.pruneOpenAction,
+	} {
+		if err := f(rootDict); err != nil {
+			p.undo()
+			return nil, err
+		}
+	}
+
+	if err := p.pruneAnnotations(); err != nil {
+		p.undo()
+		return nil, err
+	}
+
+	log.Write.Println("prunePageReferences end")
+
+	return p.undo, nil
+}
+
+func (xRefTable *XRefTable) markUsedObjects(o Object, used IntSet) error {
+
+	switch o := o.(type) {
+
+	case IndirectRef:
+		objNr := o.ObjectNumber.Value()
+		if used[objNr] {
+			return nil
+		}
+		used[objNr] = true
+
+		o1, err := xRefTable.Dereference(o)
+		if err != nil || o1 == nil {
+			return err
+		}
+
+		return xRefTable.markUsedObjects(o1, used)
+
+	case Dict:
+		for _, v := range o {
+			if err := xRefTable.markUsedObjects(v, used); err != nil {
+				return err
+			}
+		}
+
+	case StreamDict:
+		return xRefTable.markUsedObjects(o.Dict, used)
+
+	case Array:
+		for _, v := range o {
+			if err := xRefTable.markUsedObjects(v, used); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
+// usedObjects returns the obj numbers of all objects reachable from the trailer.
+func (xRefTable *XRefTable) usedObjects() (IntSet, error) {
+
+	used := IntSet{}
+
+	for _, ir := range []*IndirectRef{xRefTable.Root, xRefTable.Info, xRefTable.Encrypt} {
+		if ir == nil {
+			continue
+		}
+		if err := xRefTable.markUsedObjects(*ir, used); err != nil {
+			return nil, err
+		}
+	}
+
+	if xRefTable.AdditionalStreams != nil {
+		if err := xRefTable.markUsedObjects(*xRefTable.AdditionalStreams, used); err != nil {
+			return nil, err
+		}
+	}
+
+	return used, nil
+}
+
+// TrimPages reduces ctx to selectedPages.
+// References to dropped pages are pruned and objects no longer in use get removed from the xref table
+// along with all free objects, eg. before ctx gets merged into another context.
+func TrimPages(ctx *Context, selectedPages IntSet) error {
+
+	pageNrs := sortedPageNrs(selectedPages)
+	if len(pageNrs) == 0 {
+		return errors.New("TrimPages: no pages selected")
+	}
+
+	if err := ctx.ArrangePages(pageNrs); err != nil {
+		return err
+	}
+
+	ctx.PageCount = len(pageNrs)
+
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return err
+	}
+
+	// Get rid of any references to dropped pages.
+	selected := ctx.Write.SelectedPages
+	ctx.Write.SelectedPages = IntSet{}
+	for i := 1; i <= ctx.PageCount; i++ {
+		ctx.Write.SelectedPages[i] = true
+	}
+
+	_, err = prunePageReferences(ctx, rootDict)
+	ctx.Write.SelectedPages = selected
+	if err != nil {
+		return err
+	}
+
+	used, err := ctx.usedObjects()
+	if err != nil {
+		return err
+	}
+
+	for objNr, entry := range ctx.Table {
+		if objNr == 0 || (!entry.Free && used[objNr]) {
+			continue
+		}
+		delete(ctx.Table, objNr)
+		delete(ctx.Read.ObjectStreams, objNr)
+		delete(ctx.Read.XRefStreams, objNr)
+		delete(ctx.LinearizationObjs, objNr)
+	}
+
+	// Reset the free list.
+	zero := int64(0)
+	ctx.Table[0].Offset = &zero
+
+	log.Info.Printf("TrimPages: %d objects in use\n", len(used))
+
+	return nil
+}
@@ -0,0 +1,375 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+// Functions dealing with the public-key security handler, see 7.6.5.
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/denisbetsi/pdfcpu/pkg/pdfcpu/cms"
+	"github.com/pkg/errors"
+)
+
+// The supported SubFilter values of the public-key security handler.
+const (
+	SubFilterPubSecS4 = "adbe.pkcs7.s4" // RC4, recipients in the encryption dict.
+	SubFilterPubSecS5 = "adbe.pkcs7.s5" // Crypt filters, recipients in the crypt filter dict.
+)
+
+// Recipient is a recipient of a file encrypted using the public-key security handler.
+type Recipient struct {
+	Certificate *x509.Certificate
+	Permissions int16 // user access permissions, see Table 22
+}
+
+// ParseRecipients reads the recipient certificates of a comma separated list of PEM or DER files.
+// A file name may be followed by ":none" or ":all" overriding permissions for the recipients of this file.
+// eg. "alice.pem, bob.pem:all"
+func ParseRecipients(s string, permissions int16) ([]Recipient, error) {
+
+	rr := []Recipient{}
+
+	for _, fn := range strings.Split(s, ",") {
+
+		fn = strings.TrimSpace(fn)
+		if fn == "" {
+			continue
+		}
+
+		p := permissions
+		if i := strings.LastIndex(fn, ":"); i > 0 {
+			switch fn[i+1:] {
+			case "none":
+				p, fn = PermissionsNone, fn[:i]
+			case "all":
+				p, fn = PermissionsAll, fn[:i]
+			}
+		}
+
+		bb, err := readPEMOrDER(fn, "CERTIFICATE")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range bb {
+			c, err := x509.ParseCertificate(b)
+			if err != nil {
+				return nil, errors.Wrapf(err, "encrypt: %s", fn)
+			}
+			rr = append(rr, Recipient{Certificate: c, Permissions: p})
+		}
+	}
+
+	if len(rr) == 0 {
+		return nil, errors.New("encrypt: missing recipients")
+	}
+
+	return rr, nil
+}
+
+// ReadRecipientCredentials sets the certificate and private key used for decrypting public-key encrypted files
+// read from a PKCS#12 file (.p12, .pfx) or from a PEM encoded certificate along with a PEM encoded private key.
+func (c *Configuration) ReadRecipientCredentials(certFile, keyFile, password string) error {
+
+	s, err := readCredentials(certFile, keyFile, password)
+	if err != nil {
+		return err
+	}
+
+	c.RecipientCert, c.RecipientKey = s.Certificate, s.Key
+
+	return nil
+}
+
+// newPubSecEncryptDict creates a new EncryptDict using the public-key security handler.
+func newPubSecEncryptDict(needAES bool, keyLength int, recipients [][]byte) Dict {
+
+	d := NewDict()
+
+	d.Insert("Filter", Name("Adobe.PubSec"))
+
+	a := Array{}
+	for _, b := range recipients {
+		a = append(a, HexLiteral(hex.EncodeToString(b)))
+	}
+
+	if !needAES {
+		d.Insert("SubFilter", Name(SubFilterPubSecS4))
+		d.Insert("V", Integer(2))
+		d.Insert("Length", Integer(keyLength))
+		d.Insert("Recipients", a)
+		return d
+	}
+
+	d.Insert("SubFilter", Name(SubFilterPubSecS5))
+
+	v, cfm := 4, "AESV2"
+	if keyLength == 256 {
+		v, cfm = 5, "AESV3"
+	}
+	d.Insert("V", Integer(v))
+	d.Insert("Length", Integer(keyLength))
+
+	d.Insert("StmF", Name("DefaultCryptFilter"))
+	d.Insert("StrF", Name("DefaultCryptFilter"))
+
+	// The public-key security handler expresses the length in bits.
+	d1 := NewDict()
+	d1.Insert("AuthEvent", Name("DocOpen"))
+	d1.Insert("CFM", Name(cfm))
+	d1.Insert("Length", Integer(keyLength))
+	d1.Insert("Recipients", a)
+
+	d2 := NewDict()
+	d2.Insert("DefaultCryptFilter", d1)
+
+	d.Insert("CF", d2)
+
+	return d
+}
+
+func recipientBytes(a Array) ([][]byte, error) {
+
+	bb := [][]byte{}
+
+	for _, o := range a {
+
+		var (
+			b   []byte
+			err error
+		)
+
+		switch o := o.(type) {
+		case StringLiteral:
+			b, err = Unescape(o.Value())
+		case HexLiteral:
+			b, err = o.Bytes()
+		default:
+			err = errors.New("unsupported encryption: invalid entry in \"Recipients\"")
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		bb = append(bb, b)
+	}
+
+	if len(bb) == 0 {
+		return nil, errors.New("unsupported encryption: required entry \"Recipients\" missing")
+	}
+
+	return bb, nil
+}
+
+// supportedPubSecEncryption returns a pointer to a struct encapsulating the public-key encryption used.
+func supportedPubSecEncryption(ctx *Context, d Dict) (*Enc, error) {
+
+	// SubFilter
+	sf := d.NameEntry("SubFilter")
+	if sf == nil || *sf != SubFilterPubSecS4 && *sf != SubFilterPubSecS5 {
+		return nil, errors.New("unsupported encryption: \"SubFilter\" must be \"adbe.pkcs7.s4\" or \"adbe.pkcs7.s5\"")
+	}
+
+	// V
+	v, err := checkV(ctx, d, "Adobe.PubSec")
+	if err != nil {
+		return nil, err
+	}
+
+	encMeta := true
+	if emd := d.BooleanEntry("EncryptMetadata"); emd != nil {
+		encMeta = *emd
+	}
+
+	var (
+		a Array
+		l int
+		r int
+	)
+
+	if *sf == SubFilterPubSecS4 {
+
+		if *v != 1 && *v != 2 {
+			return nil, errors.New("unsupported encryption: \"adbe.pkcs7.s4\" requires \"V\" 1 or 2")
+		}
+
+		if l, err = length(d); err != nil {
+			return nil, err
+		}
+
+		a = d.ArrayEntry("Recipients")
+		r = *v + 1
+
+	} else {
+
+		if *v != 4 && *v != 5 {
+			return nil, errors.New("unsupported encryption: \"adbe.pkcs7.s5\" requires \"V\" 4 or 5")
+		}
+
+		stmf := d.NameEntry("StmF")
+		if stmf == nil || *stmf == "Identity" {
+			return nil, errors.New("unsupported encryption: required entry \"StmF\" missing")
+		}
+
+		cf := d.DictEntry("CF").DictEntry(*stmf)
+
+		l = 128
+		if *v == 5 {
+			l = 256
+		} else if i := cf.IntEntry("Length"); i != nil && *i >= 40 && *i <= 128 {
+			l = *i
+		}
+
+		if emd := cf.BooleanEntry("EncryptMetadata"); emd != nil {
+			encMeta = *emd
+		}
+
+		a = cf.ArrayEntry("Recipients")
+		r = *v
+	}
+
+	rr, err := recipientBytes(a)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Enc{
+			L:          l,
+			R:          r,
+			V:          *v,
+			Emd:        encMeta,
+			PubSec:     true,
+			Recipients: rr},
+		nil
+}
+
+// pubSecKey returns the file encryption key for seed, see Algorithm 7.6.5.3.
+func pubSecKey(seed []byte, e *Enc) []byte {
+
+	var h hash.Hash
+	if e.V == 5 {
+		h = sha256.New()
+	} else {
+		h = sha1.New()
+	}
+
+	h.Write(seed)
+
+	for _, b := range e.Recipients {
+		h.Write(b)
+	}
+
+	if e.V >= 4 && !e.Emd {
+		h.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	}
+
+	return h.Sum(nil)[:e.L/8]
+}
+
+// setupPubSecEncryptionKey recovers the file encryption key and the user access permissions
+// using the supplied recipient certificate and private key.
+func setupPubSecEncryptionKey(ctx *Context) error {
+
+	if needsOwnerAndUserPassword(ctx.Cmd) {
+		return errors.New("pdfcpu: passwords and permissions of public-key encrypted files cannot be changed")
+	}
+
+	if ctx.RecipientCert == nil || ctx.RecipientKey == nil {
+		return errors.New("Please provide the recipient certificate and private key with -cert")
+	}
+
+	for _, b := range ctx.E.Recipients {
+
+		c, err := cms.OpenEnvelope(b, ctx.RecipientCert, ctx.RecipientKey)
+		if err == cms.ErrNotRecipient {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// A 20 byte seed followed by the permissions.
+		if len(c) < 24 {
+			return errors.New("encryption: invalid recipient data")
+		}
+
+		ctx.E.P = int(int32(binary.BigEndian.Uint32(c[20:24])))
+		ctx.EncKey = pubSecKey(c[:20], ctx.E)
+
+		return handlePermissions(ctx)
+	}
+
+	return errors.Errorf("encryption: %s is not a recipient of this file", ctx.RecipientCert.Subject.CommonName)
+}
+
+// newPubSecEncryption creates the EncryptDict and the file encryption key for ctx.Recipients.
+// Recipients sharing the same permissions share a CMS envelope.
+func newPubSecEncryption(ctx *Context) (Dict, error) {
+
+	if ctx.EncryptUsingAES && ctx.EncryptKeyLength < 128 {
+		return nil, errors.New("encrypt: public-key encryption using AES requires a key length of 128 or 256")
+	}
+
+	seed := make([]byte, 20)
+	if _, err := io.ReadFull(rand.Reader, seed); err != nil {
+		return nil, err
+	}
+
+	pp := []int16{}
+	m := map[int16][]*x509.Certificate{}
+	for _, r := range ctx.Recipients {
+		if _, ok := m[r.Permissions]; !ok {
+			pp = append(pp, r.Permissions)
+		}
+		m[r.Permissions] = append(m[r.Permissions], r.Certificate)
+	}
+
+	recipients := [][]byte{}
+	for _, p := range pp {
+		b := make([]byte, 24)
+		copy(b, seed)
+		binary.BigEndian.PutUint32(b[20:], uint32(int32(p)))
+		env, err := cms.Envelope(b, m[p])
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, env)
+	}
+
+	d := newPubSecEncryptDict(ctx.EncryptUsingAES, ctx.EncryptKeyLength, recipients)
+
+	var err error
+	ctx.E, err = supportedEncryption(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.E.P = int(ctx.Recipients[0].Permissions)
+	ctx.EncKey = pubSecKey(seed, ctx.E)
+
+	return d, nil
+}
@@ -82,6 +82,13 @@ func Read(rs io.ReadSeeker, conf *Configuration) (*Context, error) {
 		return nil, err
 	}
 
+	// Check inbound signed documents unless reporting signatures.
+	if ctx.VerifySignatures && ctx.Cmd != LISTSIGNATURES && ctx.Cmd != VERIFYSIGNATURES {
+		if err = ctx.checkSignatures(); err != nil {
+			return nil, err
+		}
+	}
+
 	log.Read.Println("Read: end")
 
 	return ctx, nil
@@ -2329,7 +2336,7 @@ func handleUnencryptedFile(ctx *Context) error {
 
 	// Encrypt subcommand found.
 
-	if ctx.OwnerPW == "" {
+	if ctx.OwnerPW == "" && len(ctx.Recipients) == 0 {
 		return errors.New("Please provide an owner password and an optional user password")
 	}
 
@@ -2394,6 +2401,10 @@ func setupEncryptionKey(ctx *Context, d Dict) (err error) {
 		return err
 	}
 
+	if ctx.E.PubSec {
+		return setupPubSecEncryptionKey(ctx)
+	}
+
 	ctx.E.ID, err = idBytes(ctx)
 	if err != nil {
 		return err
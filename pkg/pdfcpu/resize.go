@@ -0,0 +1,644 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/denisbetsi/pdfcpu/pkg/filter"
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	"github.com/pkg/errors"
+)
+
+var errInvalidResizeConfig = errors.New("Invalid resize configuration string. Please consult pdfcpu help resize")
+
+// ResizeMode defines how page content is scaled to the new page size.
+type ResizeMode int
+
+// These are the supported resize modes.
+const (
+	ResizeFit     ResizeMode = iota // Scale preserving the aspect ratio so that the content fits the page.
+	ResizeFill                      // Scale preserving the aspect ratio so that the content covers the page.
+	ResizeStretch                   // Scale width and height independently to the page size.
+)
+
+func (m ResizeMode) String() string {
+	switch m {
+	case ResizeFit:
+		return "fit"
+	case ResizeFill:
+		return "fill"
+	case ResizeStretch:
+		return "stretch"
+	}
+	return ""
+}
+
+func parseResizeMode(s string) (ResizeMode, error) {
+	switch strings.ToLower(s) {
+	case "fit":
+		return ResizeFit, nil
+	case "fill":
+		return ResizeFill, nil
+	case "stretch":
+		return ResizeStretch, nil
+	}
+	return 0, errors.Errorf("unknown resize mode: %s, please use one of fit, fill, stretch", s)
+}
+
+// Resize represents the command details for the command "Resize".
+type Resize struct {
+	PageSize string       // Paper size eg. A4L, A4P, A4(=default=A4P), see paperSize.go
+	PageDim  *dim         // Page dimensions in user units.
+	Mode     ResizeMode   // One of fit(=default), fill, stretch
+	BgColor  *SimpleColor // Background color, none(=default)
+}
+
+func (r Resize) String() string {
+	bg := "none"
+	if r.BgColor != nil {
+		bg = r.BgColor.String()
+	}
+	return fmt.Sprintf("Resize conf: %s %s, mode=%s, bgcolor=%s\n", r.PageSize, *r.PageDim, r.Mode, bg)
+}
+
+// DefaultResizeConfig returns the default resize configuration.
+func DefaultResizeConfig() *Resize {
+	return &Resize{
+		PageSize: "A4",
+		PageDim:  PaperSize["A4"],
+		Mode:     ResizeFit,
+	}
+}
+
+// ParseResizeConfig parses a resize command string into an internal structure.
+// A plain paper size like "A4L" is accepted as a shortcut for "f: A4L".
+// eg. "f: A4, m: fill, c: 1 1 1" or "d: 500 800, m: stretch"
+func ParseResizeConfig(s string) (*Resize, error) {
+
+	s = strings.TrimSpace(s)
+
+	if s == "" {
+		return nil, errInvalidResizeConfig
+	}
+
+	if !strings.Contains(s, ":") {
+		s = "f: " + s
+	}
+
+	res := DefaultResizeConfig()
+
+	var setDim, setFormat bool
+
+	for _, s := range strings.Split(s, ",") {
+
+		ss := strings.Split(s, ":")
+		if len(ss) != 2 {
+			return nil, errInvalidResizeConfig
+		}
+
+		k := strings.TrimSpace(ss[0])
+		v := strings.TrimSpace(ss[1])
+
+		var err error
+
+		switch k {
+		case "f": // page format
+			res.PageDim, res.PageSize, err = parsePageFormat(v, setDim)
+			setFormat = true
+
+		case "d": // page dimensions
+			res.PageDim, res.PageSize, err = parsePageDim(v, setFormat)
+			setDim = true
+
+		case "m": // resize mode
+			res.Mode, err = parseResizeMode(v)
+
+		case "c": // background color
+			var sc SimpleColor
+			if sc, err = parseColor(v); err == nil {
+				res.BgColor = &sc
+			}
+
+		default:
+			err = errInvalidResizeConfig
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// resizeMatrix returns the transformation matrix mapping r1 onto r2 for mode.
+func resizeMatrix(r1, r2 *Rectangle, mode ResizeMode) matrix {
+
+	sx, sy := r2.Width()/r1.Width(), r2.Height()/r1.Height()
+
+	switch mode {
+	case ResizeFit:
+		sx = math.Min(sx, sy)
+		sy = sx
+	case ResizeFill:
+		sx = math.Max(sx, sy)
+		sy = sx
+	}
+
+	// Center the scaled rectangle within r2.
+	m := identMatrix
+	m[0][0] = sx
+	m[1][1] = sy
+	m[2][0] = r2.LL.X + (r2.Width()-r1.Width()*sx)/2 - r1.LL.X*sx
+	m[2][1] = r2.LL.Y + (r2.Height()-r1.Height()*sy)/2 - r1.LL.Y*sy
+
+	return m
+}
+
+// transformCoords transforms an array of x y coordinate pairs by m.
+func transformCoords(xRefTable *XRefTable, a Array, m matrix) Array {
+
+	ff := numbers(xRefTable, a)
+
+	b := Array{}
+	for i := 0; i+1 < len(ff); i += 2 {
+		x, y := m.transform(ff[i], ff[i+1])
+		b = append(b, Float(x), Float(y))
+	}
+
+	return b
+}
+
+// resizeAnnotations transforms the geometry of the annotations of page d by m.
+func resizeAnnotations(xRefTable *XRefTable, d Dict, m matrix, done IntSet) error {
+
+	a, err := xRefTable.DereferenceArray(d["Annots"])
+	if err != nil || a == nil {
+		return err
+	}
+
+	for _, o := range a {
+
+		if ir, ok := o.(IndirectRef); ok {
+			// Annotations may be shared among pages.
+			if done[ir.ObjectNumber.Value()] {
+				continue
+			}
+			done[ir.ObjectNumber.Value()] = true
+		}
+
+		annot, err := xRefTable.DereferenceDict(o)
+		if err != nil || annot == nil {
+			continue
+		}
+
+		if a, err := xRefTable.DereferenceArray(annot["Rect"]); err == nil && len(a) == 4 {
+			r, err := rect(xRefTable, a)
+			if err != nil {
+				return err
+			}
+			annot.Update("Rect", m.transformRect(r.LL.X, r.LL.Y, r.UR.X, r.UR.Y).Array())
+		}
+
+		for _, k := range []string{"QuadPoints", "Vertices", "L", "CL"} {
+			if a, err := xRefTable.DereferenceArray(annot[k]); err == nil && a != nil {
+				annot.Update(k, transformCoords(xRefTable, a, m))
+			}
+		}
+
+		if a, err := xRefTable.DereferenceArray(annot["InkList"]); err == nil && a != nil {
+			inkList := Array{}
+			for _, o := range a {
+				path, err := xRefTable.DereferenceArray(o)
+				if err != nil {
+					return err
+				}
+				inkList = append(inkList, transformCoords(xRefTable, path, m))
+			}
+			annot.Update("InkList", inkList)
+		}
+	}
+
+	return nil
+}
+
+// visitDest calls f for the explicit destination o, see 12.3.2.2.
+// Named destinations get visited where they are defined.
+func visitDest(xRefTable *XRefTable, o Object, f func(a Array), done IntSet) error {
+
+	if ir, ok := o.(IndirectRef); ok {
+		if done[ir.ObjectNumber.Value()] {
+			return nil
+		}
+		done[ir.ObjectNumber.Value()] = true
+	}
+
+	o, err := xRefTable.Dereference(o)
+	if err != nil || o == nil {
+		return err
+	}
+
+	if d, ok := o.(Dict); ok {
+		return visitDest(xRefTable, d["D"], f, done)
+	}
+
+	if a, ok := o.(Array); ok && len(a) > 0 {
+		f(a)
+	}
+
+	return nil
+}
+
+// visitTarget calls f for the destination of d or of its GoTo action.
+func visitTarget(xRefTable *XRefTable, d Dict, f func(a Array), done IntSet) error {
+
+	if o, found := d.Find("Dest"); found {
+		return visitDest(xRefTable, o, f, done)
+	}
+
+	action, err := xRefTable.DereferenceDict(d["A"])
+	if err != nil || action == nil {
+		return err
+	}
+
+	if s := action.NameEntry("S"); s == nil || *s != "GoTo" {
+		return nil
+	}
+
+	return visitDest(xRefTable, action["D"], f, done)
+}
+
+func visitOutlineDests(xRefTable *XRefTable, ir *IndirectRef, f func(a Array), done IntSet) error {
+
+	for ir != nil {
+
+		if done[ir.ObjectNumber.Value()] {
+			return nil
+		}
+		done[ir.ObjectNumber.Value()] = true
+
+		d, err := xRefTable.DereferenceDict(*ir)
+		if err != nil || d == nil {
+			return err
+		}
+
+		if err = visitTarget(xRefTable, d, f, done); err != nil {
+			return err
+		}
+
+		if err = visitOutlineDests(xRefTable, d.IndirectRefEntry("First"), f, done); err != nil {
+			return err
+		}
+
+		ir = d.IndirectRefEntry("Next")
+	}
+
+	return nil
+}
+
+func visitNamedDests(xRefTable *XRefTable, d Dict, f func(a Array), done IntSet) error {
+
+	names, err := xRefTable.DereferenceArray(d["Names"])
+	if err != nil {
+		return err
+	}
+
+	for i := 1; i < len(names); i += 2 {
+		if err = visitDest(xRefTable, names[i], f, done); err != nil {
+			return err
+		}
+	}
+
+	kids, err := xRefTable.DereferenceArray(d["Kids"])
+	if err != nil {
+		return err
+	}
+
+	for _, o := range kids {
+		d1, err := xRefTable.DereferenceDict(o)
+		if err != nil {
+			return err
+		}
+		if d1 == nil {
+			continue
+		}
+		if err = visitNamedDests(xRefTable, d1, f, done); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// visitDests calls f for all explicit destinations used by links, the open action, outlines and named destinations.
+func visitDests(xRefTable *XRefTable, f func(a Array)) error {
+
+	done := IntSet{}
+
+	irs, err := xRefTable.PageIndRefs()
+	if err != nil {
+		return err
+	}
+
+	for _, ir := range irs {
+
+		d, err := xRefTable.DereferenceDict(ir)
+		if err != nil {
+			return err
+		}
+
+		annots, err := xRefTable.DereferenceArray(d["Annots"])
+		if err != nil {
+			return err
+		}
+
+		for _, o := range annots {
+
+			if ir, ok := o.(IndirectRef); ok {
+				if done[ir.ObjectNumber.Value()] {
+					continue
+				}
+				done[ir.ObjectNumber.Value()] = true
+			}
+
+			annot, err := xRefTable.DereferenceDict(o)
+			if err != nil || annot == nil {
+				continue
+			}
+
+			if err = visitTarget(xRefTable, annot, f, done); err != nil {
+				return err
+			}
+		}
+	}
+
+	rootDict, err := xRefTable.Catalog()
+	if err != nil {
+		return err
+	}
+
+	if o, found := rootDict.Find("OpenAction"); found {
+		if err = visitDest(xRefTable, o, f, done); err != nil {
+			return err
+		}
+	}
+
+	outlines, err := xRefTable.DereferenceDict(rootDict["Outlines"])
+	if err != nil {
+		return err
+	}
+	if outlines != nil {
+		if err = visitOutlineDests(xRefTable, outlines.IndirectRefEntry("First"), f, done); err != nil {
+			return err
+		}
+	}
+
+	// PDF 1.1 named destinations.
+	dests, err := xRefTable.DereferenceDict(rootDict["Dests"])
+	if err != nil {
+		return err
+	}
+	for _, o := range dests {
+		if err = visitDest(xRefTable, o, f, done); err != nil {
+			return err
+		}
+	}
+
+	// PDF 1.2 named destinations.
+	namesDict, err := xRefTable.DereferenceDict(rootDict["Names"])
+	if err != nil || namesDict == nil {
+		return err
+	}
+
+	d, err := xRefTable.DereferenceDict(namesDict["Dests"])
+	if err != nil || d == nil {
+		return err
+	}
+
+	return visitNamedDests(xRefTable, d, f, done)
+}
+
+// resizeDest transforms the coordinates of the explicit destination a by the matrix of its target page.
+// mm maps the object numbers of resized pages to their matrix.
+func resizeDest(xRefTable *XRefTable, a Array, mm map[int]matrix) {
+
+	if len(a) < 3 {
+		return
+	}
+
+	ir, ok := a[0].(IndirectRef)
+	if !ok {
+		return
+	}
+
+	m, ok := mm[ir.ObjectNumber.Value()]
+	if !ok {
+		return
+	}
+
+	typ, ok := a[1].(Name)
+	if !ok {
+		return
+	}
+
+	// The indices of x and y coordinates, null leaves a coordinate unchanged.
+	var xx, yy []int
+
+	switch typ {
+	case "XYZ":
+		xx, yy = []int{2}, []int{3}
+	case "FitH", "FitBH":
+		yy = []int{2}
+	case "FitV", "FitBV":
+		xx = []int{2}
+	case "FitR":
+		xx, yy = []int{2, 4}, []int{3, 5}
+	}
+
+	// Resize matrices only scale and translate.
+	transform := func(ii []int, s, t float64) {
+		for _, i := range ii {
+			if i >= len(a) || a[i] == nil {
+				continue
+			}
+			if f, err := xRefTable.DereferenceNumber(a[i]); err == nil {
+				a[i] = Float(f*s + t)
+			}
+		}
+	}
+
+	transform(xx, m[0][0], m[2][0])
+	transform(yy, m[1][1], m[2][1])
+}
+
+// resizeDests transforms the coordinates of all destinations pointing to resized pages
+// used by links, outlines and named destinations.
+func resizeDests(xRefTable *XRefTable, mm map[int]matrix) error {
+	return visitDests(xRefTable, func(a Array) {
+		resizeDest(xRefTable, a, mm)
+	})
+}
+
+func resizeContent(xRefTable *XRefTable, d Dict, resources Dict, box, mediaBox *Rectangle, m matrix, res *Resize) error {
+
+	var buf bytes.Buffer
+
+	if res.BgColor != nil {
+		c := res.BgColor
+		fmt.Fprintf(&buf, "q %.2f %.2f %.2f rg %.2f %.2f %.2f %.2f re f Q ",
+			c.r, c.g, c.b, mediaBox.LL.X, mediaBox.LL.Y, mediaBox.Width(), mediaBox.Height())
+	}
+
+	resDict := NewDict()
+
+	o, found := d.Find("Contents")
+	if found {
+		bb, err := contentStream(xRefTable, o)
+		if err != nil && err != errNoContent {
+			return err
+		}
+
+		if err == nil {
+			if resources == nil {
+				resources = NewDict()
+			}
+
+			ir, err := xRefTable.IndRefForNewObject(resources)
+			if err != nil {
+				return err
+			}
+
+			// Wrap the original page content into a form XObject clipped to box.
+			formIndRef, err := createNUpFormForPDFResource(xRefTable, ir, bb, box)
+			if err != nil {
+				return err
+			}
+
+			resDict.Insert("XObject", Dict(map[string]Object{"Fm0": *formIndRef}))
+
+			fmt.Fprintf(&buf, "q %.4f %.4f %.4f %.4f %.4f %.4f cm /Fm0 Do Q ",
+				m[0][0], m[0][1], m[1][0], m[1][1], m[2][0], m[2][1])
+		}
+	}
+
+	sd := &StreamDict{Dict: NewDict()}
+	sd.InsertName("Filter", filter.Flate)
+	sd.FilterPipeline = []PDFFilter{{Name: filter.Flate, DecodeParms: nil}}
+	sd.Content = buf.Bytes()
+
+	if err := encodeStream(sd); err != nil {
+		return err
+	}
+
+	ir, err := xRefTable.IndRefForNewObject(*sd)
+	if err != nil {
+		return err
+	}
+
+	d.Update("Contents", *ir)
+	d.Update("Resources", resDict)
+
+	return nil
+}
+
+// resizePage resizes page pageNr and returns the matrix applied.
+func resizePage(xRefTable *XRefTable, pageNr int, res *Resize, done IntSet) (matrix, error) {
+
+	d, inhPAttrs, err := xRefTable.PageDict(pageNr)
+	if err != nil {
+		return identMatrix, err
+	}
+
+	if inhPAttrs.mediaBox == nil {
+		return identMatrix, errors.Errorf("resizePage: page %d: missing MediaBox", pageNr)
+	}
+
+	// The visible region of the page gets resized.
+	box := inhPAttrs.cropBox
+	if box == nil {
+		box = inhPAttrs.mediaBox
+	}
+	if box = intersection(box, inhPAttrs.mediaBox); box == nil {
+		return identMatrix, errors.Errorf("resizePage: page %d: CropBox outside of MediaBox", pageNr)
+	}
+
+	// Rotated pages are displayed with their dimensions swapped.
+	w, h := res.PageDim.w, res.PageDim.h
+	if inhPAttrs.rotate%180 != 0 {
+		w, h = h, w
+	}
+	mediaBox := RectForDim(w, h)
+
+	m := resizeMatrix(box, mediaBox, res.Mode)
+
+	log.Debug.Printf("resizePage: page %d: %s -> %s\n", pageNr, box, mediaBox)
+
+	if err = resizeContent(xRefTable, d, inhPAttrs.resources, box, mediaBox, m, res); err != nil {
+		return identMatrix, err
+	}
+
+	d.Update("MediaBox", mediaBox.Array())
+	d.Delete("CropBox")
+
+	for _, bn := range []string{"TrimBox", "BleedBox", "ArtBox"} {
+		r, err := pageBox(xRefTable, d, bn)
+		if err != nil {
+			return identMatrix, err
+		}
+		if r == nil {
+			continue
+		}
+		if r = intersection(m.transformRect(r.LL.X, r.LL.Y, r.UR.X, r.UR.Y), mediaBox); r == nil {
+			d.Delete(bn)
+			continue
+		}
+		d.Update(bn, r.Array())
+	}
+
+	return m, resizeAnnotations(xRefTable, d, m, done)
+}
+
+// ResizePages resizes selected pages to the paper size or dimensions of res.
+// The page content is scaled according to the resize mode and centered on the new page.
+// Annotations and destinations pointing to resized pages follow the page content.
+func ResizePages(ctx *Context, selectedPages IntSet, res *Resize) error {
+
+	irs, err := ctx.PageIndRefs()
+	if err != nil {
+		return err
+	}
+
+	done := IntSet{}
+	mm := map[int]matrix{}
+
+	for _, k := range sortedPageNrs(selectedPages) {
+		if k < 1 || k > len(irs) {
+			continue
+		}
+		m, err := resizePage(ctx.XRefTable, k, res, done)
+		if err != nil {
+			return err
+		}
+		mm[irs[k-1].ObjectNumber.Value()] = m
+	}
+
+	return resizeDests(ctx.XRefTable, mm)
+}
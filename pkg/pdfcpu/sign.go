@@ -0,0 +1,860 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/denisbetsi/pdfcpu/pkg/filter"
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	"github.com/denisbetsi/pdfcpu/pkg/pdfcpu/cms"
+	"github.com/pkg/errors"
+)
+
+// Signature sub filters, see 12.8.3.
+const (
+	SubFilterPKCS7Detached = "adbe.pkcs7.detached"
+	SubFilterCAdESDetached = "ETSI.CAdES.detached"
+	SubFilterRFC3161       = "ETSI.RFC3161"
+)
+
+// annLocked is the Locked annotation flag, see 12.5.3.
+const annLocked = 1 << 7
+
+// byteRangePlaceholder reserves room for the final ByteRange values.
+const byteRangePlaceholder = 9999999999
+
+// docTimeStampSize is the number of bytes reserved for a time-stamp token.
+const docTimeStampSize = 16384
+
+var errInvalidSignConfig = errors.New("sign: invalid configuration string, please refer to pdfcpu help sign")
+
+// Sign represents the configuration of a digital signature.
+type Sign struct {
+	Signer      *cms.Signer // The signing credentials.
+	FieldName   string      // The partial name of the signature field, defaults to Signature1, Signature2..
+	Page        int         // The page displaying the signature widget, defaults to 1.
+	Rect        *Rectangle  // The position of a visible signature, nil for an invisible signature.
+	Reason      string
+	Location    string
+	ContactInfo string
+	Name        string    // The name of the signer, defaults to the common name of the signer certificate.
+	CAdES       bool      // Use ETSI.CAdES.detached instead of adbe.pkcs7.detached.
+	Time        time.Time // The signing time, defaults to now.
+
+	DocTimeStamp bool          // Add an invisible ETSI.RFC3161 document time-stamp instead of a signature.
+	TSA          cms.TSAClient // The time-stamp authority of a document time-stamp.
+}
+
+// SubFilter returns the signature sub filter.
+func (s Sign) SubFilter() string {
+	if s.DocTimeStamp {
+		return SubFilterRFC3161
+	}
+	if s.CAdES {
+		return SubFilterCAdESDetached
+	}
+	return SubFilterPKCS7Detached
+}
+
+func parseSignRect(s string) (*Rectangle, error) {
+
+	ss := strings.Fields(s)
+	if len(ss) != 4 {
+		return nil, errors.Errorf("sign: invalid rect: %s, please use llx lly urx ury", s)
+	}
+
+	ff := make([]float64, 4)
+	for i, s := range ss {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, errors.Errorf("sign: invalid rect: %s", s)
+		}
+		ff[i] = f
+	}
+
+	r := normalizedRect(ff[0], ff[1], ff[2], ff[3])
+	if r[2]-r[0] <= 0 || r[3]-r[1] <= 0 {
+		return nil, errors.New("sign: empty rect")
+	}
+
+	return Rect(r[0], r[1], r[2], r[3]), nil
+}
+
+// ParseSignConfig parses a sign command string into an internal structure.
+// eg. "reason: Approved, location: Berlin" or "page: 2, rect: 50 50 250 100, format: cades"
+func ParseSignConfig(s string) (*Sign, error) {
+
+	sign := &Sign{Page: 1}
+
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return sign, nil
+	}
+
+	for _, s := range strings.Split(s, ",") {
+
+		ss := strings.SplitN(s, ":", 2)
+		if len(ss) != 2 {
+			return nil, errInvalidSignConfig
+		}
+
+		k := strings.TrimSpace(ss[0])
+		v := strings.TrimSpace(ss[1])
+
+		var err error
+
+		switch k {
+
+		case "field":
+			if v == "" || strings.Contains(v, ".") {
+				return nil, errors.Errorf("sign: invalid field name: %s", v)
+			}
+			sign.FieldName = v
+
+		case "page":
+			if sign.Page, err = strconv.Atoi(v); err != nil || sign.Page < 1 {
+				return nil, errors.Errorf("sign: invalid page: %s", v)
+			}
+
+		case "rect":
+			sign.Rect, err = parseSignRect(v)
+
+		case "reason":
+			sign.Reason = v
+
+		case "location":
+			sign.Location = v
+
+		case "contact":
+			sign.ContactInfo = v
+
+		case "name":
+			sign.Name = v
+
+		case "format":
+			switch strings.ToLower(v) {
+			case "pkcs7":
+				sign.CAdES = false
+			case "cades", "pades":
+				sign.CAdES = true
+			default:
+				return nil, errors.Errorf("sign: unknown format: %s, please use one of pkcs7, cades", v)
+			}
+
+		default:
+			err = errInvalidSignConfig
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sign, nil
+}
+
+// readCredentials reads a certificate and its private key from a PKCS#12 file (.p12, .pfx)
+// or from a PEM encoded certificate chain along with a PEM encoded private key.
+// keyFile defaults to certFile for PEM files holding certificates and key.
+func readCredentials(certFile, keyFile, password string) (*cms.Signer, error) {
+
+	b, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(certFile)) {
+	case ".p12", ".pfx":
+		return cms.DecodePKCS12(b, password)
+	}
+
+	kb := b
+	if keyFile != "" {
+		if kb, err = ioutil.ReadFile(keyFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return cms.ParsePEM(b, kb)
+}
+
+// ReadCredentials sets the signing credentials read from a PKCS#12 file (.p12, .pfx)
+// or from a PEM encoded certificate chain along with a PEM encoded private key.
+// keyFile defaults to certFile for PEM files holding certificates and key.
+func (s *Sign) ReadCredentials(certFile, keyFile, password string) (err error) {
+	s.Signer, err = readCredentials(certFile, keyFile, password)
+	return err
+}
+
+// signerName returns the name of the signer to be displayed.
+func (s Sign) signerName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Signer.Certificate.Subject.CommonName
+}
+
+// contentsSize returns the number of bytes reserved for the CMS signature.
+func (s Sign) contentsSize() int {
+	if s.DocTimeStamp {
+		return docTimeStampSize
+	}
+	n := 8192
+	n += len(s.Signer.Certificate.Raw)
+	for _, c := range s.Signer.Chain {
+		n += len(c.Raw)
+	}
+	return n
+}
+
+// signatureFieldName returns the first unused name SignatureN.
+func (xRefTable *XRefTable) signatureFieldName() (string, error) {
+
+	_, ff, err := xRefTable.formFields()
+	if err != nil {
+		return "", err
+	}
+
+	names := map[string]bool{}
+	for _, f := range ff {
+		names[f.Name] = true
+	}
+
+	for i := 1; ; i++ {
+		if s := fmt.Sprintf("Signature%d", i); !names[s] {
+			return s, nil
+		}
+	}
+}
+
+// signatureAppearanceContent renders signer, date, reason and location into a box of size w x h.
+func (s Sign) signatureAppearanceContent(w, h float64) []byte {
+
+	font := fieldFont{name: defaultFieldFont, baseFont: "Helvetica", color: "0 g"}
+
+	lines := [][]byte{}
+	for _, s := range []string{
+		"Digitally signed by " + s.signerName(),
+		"Date: " + s.Time.Format("2006.01.02 15:04:05 -07:00"),
+		prefixed("Reason: ", s.Reason),
+		prefixed("Location: ", s.Location),
+	} {
+		if s != "" {
+			bb, _ := winAnsiBytes(s)
+			lines = append(lines, bb)
+		}
+	}
+
+	padding := 2.
+
+	// Fit the lines into the box.
+	fontSize := math.Min(12, (h-2*padding)/(float64(len(lines))*1.15))
+	for _, bb := range lines {
+		if tw := font.textWidth(bb, 1); tw > 0 {
+			fontSize = math.Min(fontSize, (w-2*padding)/tw)
+		}
+	}
+	leading := fontSize * 1.15
+
+	var buf bytes.Buffer
+
+	buf.WriteString("q\n")
+	fmt.Fprintf(&buf, "0 0 %.2f %.2f re W n\n", w, h)
+	for i, bb := range lines {
+		y := h - padding - fontSize - float64(i)*leading
+		fmt.Fprintf(&buf, "BT /%s %.2f Tf %s %.2f %.2f Td (%s) Tj ET\n", font.name, fontSize, font.color, padding, y, escapedText(bb))
+	}
+	buf.WriteString("Q\n")
+
+	return buf.Bytes()
+}
+
+func prefixed(prefix, s string) string {
+	if s == "" {
+		return ""
+	}
+	return prefix + s
+}
+
+// signatureAppearance returns the normal appearance of a signature widget.
+func (xRefTable *XRefTable) signatureAppearance(s Sign) (*IndirectRef, error) {
+
+	if s.Rect == nil {
+		// Invisible signatures get an empty appearance.
+		sd := StreamDict{
+			Dict: Dict(
+				map[string]Object{
+					"Type":    Name("XObject"),
+					"Subtype": Name("Form"),
+					"BBox":    NewNumberArray(0, 0, 0, 0),
+				},
+			),
+		}
+		if err := encodeStream(&sd); err != nil {
+			return nil, err
+		}
+		return xRefTable.IndRefForNewObject(sd)
+	}
+
+	d := NewDict()
+	d.InsertName("Type", "Font")
+	d.InsertName("Subtype", "Type1")
+	d.InsertName("BaseFont", "Helvetica")
+	d.InsertName("Encoding", "WinAnsiEncoding")
+
+	fontIndRef, err := xRefTable.IndRefForNewObject(d)
+	if err != nil {
+		return nil, err
+	}
+
+	w, h := s.Rect.Width(), s.Rect.Height()
+
+	return xRefTable.formXObject(s.signatureAppearanceContent(w, h), w, h, defaultFieldFont, *fontIndRef)
+}
+
+// signatureDict returns a new signature dict with placeholders for ByteRange and Contents.
+func signatureDict(s Sign) (Dict, error) {
+
+	d := NewDict()
+	d.InsertName("Type", "Sig")
+	d.InsertName("Filter", "Adobe.PPKLite")
+	d.InsertName("SubFilter", s.SubFilter())
+	d.Insert("ByteRange", Array{Integer(0), Integer(byteRangePlaceholder), Integer(byteRangePlaceholder), Integer(byteRangePlaceholder)})
+	d.Insert("Contents", HexLiteral(strings.Repeat("0", 2*s.contentsSize())))
+
+	if s.DocTimeStamp {
+		// The time-stamp token carries the signing time.
+		d.Update("Type", Name("DocTimeStamp"))
+		return d, nil
+	}
+
+	d.Insert("M", StringLiteral(DateString(s.Time.UTC())))
+
+	for k, v := range map[string]string{"Name": s.signerName(), "Reason": s.Reason, "Location": s.Location, "ContactInfo": s.ContactInfo} {
+		if v == "" {
+			continue
+		}
+		o, err := EncodeText(v)
+		if err != nil {
+			return nil, err
+		}
+		d.Insert(k, o)
+	}
+
+	return d, nil
+}
+
+// addSignatureField adds a signature field for s along with its widget and signature dict.
+// Returns the object number of the signature dict.
+// The object numbers of modified existing objects get recorded in dirty.
+func (ctx *Context) addSignatureField(s *Sign, dirty IntSet) (int, error) {
+
+	if s.Page < 1 || s.Page > ctx.PageCount {
+		return 0, errors.Errorf("sign: invalid page: %d", s.Page)
+	}
+
+	if s.FieldName == "" {
+		name, err := ctx.signatureFieldName()
+		if err != nil {
+			return 0, err
+		}
+		s.FieldName = name
+	} else {
+		_, ff, err := ctx.formFields()
+		if err != nil {
+			return 0, err
+		}
+		for _, f := range ff {
+			if f.Name == s.FieldName {
+				return 0, errors.Errorf("sign: field already exists: %s", s.FieldName)
+			}
+		}
+	}
+
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return 0, err
+	}
+
+	af, err := ctx.createAcroForm()
+	if err != nil {
+		return 0, err
+	}
+
+	if ir, ok := rootDict["AcroForm"].(IndirectRef); ok {
+		dirty[ir.ObjectNumber.Value()] = true
+	} else {
+		dirty[ctx.Root.ObjectNumber.Value()] = true
+	}
+
+	sd, err := signatureDict(*s)
+	if err != nil {
+		return 0, err
+	}
+
+	sigIndRef, err := ctx.IndRefForNewObject(sd)
+	if err != nil {
+		return 0, err
+	}
+
+	apIndRef, err := ctx.signatureAppearance(*s)
+	if err != nil {
+		return 0, err
+	}
+
+	pageIndRefs, err := ctx.PageIndRefs()
+	if err != nil {
+		return 0, err
+	}
+	pageIndRef := pageIndRefs[s.Page-1]
+
+	r := [4]float64{}
+	if s.Rect != nil {
+		r = [4]float64{s.Rect.LL.X, s.Rect.LL.Y, s.Rect.UR.X, s.Rect.UR.Y}
+	}
+
+	// The field dict and its single widget annotation dict are merged.
+	d := NewDict()
+	d.InsertName("FT", "Sig")
+	t, err := EncodeText(s.FieldName)
+	if err != nil {
+		return 0, err
+	}
+	d.Insert("T", t)
+	d.Insert("V", *sigIndRef)
+	d.InsertName("Type", "Annot")
+	d.InsertName("Subtype", "Widget")
+	d.Insert("Rect", NewNumberArray(r[:]...))
+	d.Insert("F", Integer(annPrint|annLocked))
+	d.Insert("P", pageIndRef)
+	d.Insert("AP", Dict(map[string]Object{"N": *apIndRef}))
+
+	fieldIndRef, err := ctx.IndRefForNewObject(d)
+	if err != nil {
+		return 0, err
+	}
+
+	pageDict, _, err := ctx.PageDict(s.Page)
+	if err != nil {
+		return 0, err
+	}
+
+	annots, update, err := ctx.pageAnnots(pageDict)
+	if err != nil {
+		return 0, err
+	}
+	update(append(annots, *fieldIndRef))
+
+	if ir, ok := pageDict["Annots"].(IndirectRef); ok {
+		dirty[ir.ObjectNumber.Value()] = true
+	} else {
+		dirty[pageIndRef.ObjectNumber.Value()] = true
+	}
+
+	fields, err := ctx.DereferenceArray(af["Fields"])
+	if err != nil {
+		return 0, err
+	}
+	af.Update("Fields", append(fields, *fieldIndRef))
+
+	// SignaturesExist, AppendOnly
+	af.Update("SigFlags", Integer(3))
+
+	return sigIndRef.ObjectNumber.Value(), nil
+}
+
+// incrementalWriter writes objects of an incremental update keeping track of their offsets.
+type incrementalWriter struct {
+	buf       bytes.Buffer
+	base      int64         // The size of the original file.
+	offsets   map[int]int64 // Object write offsets.
+	sigObjNr  int           // The object number of the signature dict to record placeholders for, 0 if none.
+	byteRange [2]int64      // Offsets of the ByteRange value of the signature dict.
+	contents  [2]int64      // Offsets of the Contents value of the signature dict.
+}
+
+func (w *incrementalWriter) offset() int64 {
+	return w.base + int64(w.buf.Len())
+}
+
+func (w *incrementalWriter) writeObject(objNr, genNr int, o Object) error {
+
+	w.offsets[objNr] = w.offset()
+
+	fmt.Fprintf(&w.buf, "%d %d obj\n", objNr, genNr)
+
+	switch o := o.(type) {
+
+	case StreamDict:
+		if o.Raw == nil {
+			if err := encodeStream(&o); err != nil {
+				return err
+			}
+		}
+		o.Update("Length", Integer(len(o.Raw)))
+		w.buf.WriteString(o.Dict.PDFString())
+		w.buf.WriteString("\nstream\n")
+		w.buf.Write(o.Raw)
+		w.buf.WriteString("\nendstream")
+
+	case Dict:
+		if objNr == w.sigObjNr {
+			w.writeSignatureDict(o)
+			break
+		}
+		w.buf.WriteString(o.PDFString())
+
+	case nil:
+		w.buf.WriteString("null")
+
+	default:
+		w.buf.WriteString(o.PDFString())
+	}
+
+	w.buf.WriteString("\nendobj\n")
+
+	return nil
+}
+
+// writeSignatureDict writes the signature dict d entry by entry
+// recording the offsets of the ByteRange and Contents values to be filled in on signing.
+func (w *incrementalWriter) writeSignatureDict(d Dict) {
+
+	keys := []string{}
+	for k := range d {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	w.buf.WriteString("<<")
+
+	for _, k := range keys {
+
+		e := Dict(map[string]Object{k: d[k]}).PDFString()
+
+		// Array and hex literal values immediately follow their key.
+		start := w.offset() + int64(len(k)+1)
+		w.buf.WriteString(e[2 : len(e)-2])
+
+		switch k {
+		case "ByteRange":
+			w.byteRange = [2]int64{start, w.offset()}
+		case "Contents":
+			w.contents = [2]int64{start, w.offset()}
+		}
+	}
+
+	w.buf.WriteString(">>")
+}
+
+// xRefSubsections returns the sorted object numbers as subsections of consecutive numbers.
+func xRefSubsections(objNrs []int) [][]int {
+
+	ss := [][]int{}
+
+	for i, objNr := range objNrs {
+		if i == 0 || objNr != objNrs[i-1]+1 {
+			ss = append(ss, []int{})
+		}
+		ss[len(ss)-1] = append(ss[len(ss)-1], objNr)
+	}
+
+	return ss
+}
+
+// incrementalTrailer returns the trailer entries of an incremental update.
+func (ctx *Context) incrementalTrailer(prev int64) Dict {
+
+	d := NewDict()
+	d.Insert("Size", Integer(*ctx.Size))
+	d.Insert("Root", *ctx.Root)
+	if ctx.Info != nil {
+		d.Insert("Info", *ctx.Info)
+	}
+	if ctx.ID != nil {
+		d.Insert("ID", ctx.ID)
+	}
+	d.Insert("Prev", Integer(prev))
+
+	return d
+}
+
+func (ctx *Context) writeIncrementalXRefTable(w *incrementalWriter, objNrs []int, prev int64) {
+
+	xRefOffset := w.offset()
+
+	w.buf.WriteString("xref\n")
+	for _, ss := range xRefSubsections(objNrs) {
+		fmt.Fprintf(&w.buf, "%d %d\n", ss[0], len(ss))
+		for _, objNr := range ss {
+			fmt.Fprintf(&w.buf, "%010d %05d n \n", w.offsets[objNr], ctx.generation(objNr))
+		}
+	}
+
+	fmt.Fprintf(&w.buf, "trailer\n%s\nstartxref\n%d\n%%%%EOF\n", ctx.incrementalTrailer(prev).PDFString(), xRefOffset)
+}
+
+func (ctx *Context) writeIncrementalXRefStream(w *incrementalWriter, objNrs []int, prev int64) error {
+
+	// The xref stream is an object of the update itself.
+	objNr := *ctx.Size
+	*ctx.Size++
+	objNrs = append(objNrs, objNr)
+
+	xRefOffset := w.offset()
+	w.offsets[objNr] = xRefOffset
+
+	// Offset field width
+	ow := 1
+	for xRefOffset>>(8*uint(ow)) > 0 {
+		ow++
+	}
+
+	var content bytes.Buffer
+	index := Array{}
+
+	for _, ss := range xRefSubsections(objNrs) {
+		index = append(index, Integer(ss[0]), Integer(len(ss)))
+		for _, objNr := range ss {
+			content.WriteByte(1)
+			content.Write(int64ToBuf(w.offsets[objNr], ow))
+			content.Write(int64ToBuf(int64(ctx.generation(objNr)), 2))
+		}
+	}
+
+	d := ctx.incrementalTrailer(prev)
+	d.InsertName("Type", "XRef")
+	d.Insert("Index", index)
+	d.Insert("W", Array{Integer(1), Integer(ow), Integer(2)})
+	d.InsertName("Filter", filter.Flate)
+
+	sd := StreamDict{
+		Dict:           d,
+		Content:        content.Bytes(),
+		FilterPipeline: []PDFFilter{{Name: filter.Flate, DecodeParms: nil}},
+	}
+
+	if err := w.writeObject(objNr, 0, sd); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(&w.buf, "startxref\n%d\n%%%%EOF\n", xRefOffset)
+
+	return nil
+}
+
+func (ctx *Context) generation(objNr int) int {
+	if e, found := ctx.FindTableEntryLight(objNr); found && e.Generation != nil {
+		return *e.Generation
+	}
+	return 0
+}
+
+// incrementalUpdate returns an incremental update to the original file of size base
+// holding the modified objects dirty and all objects numbered from size on.
+// The placeholder offsets of the signature dict sigObjNr get recorded unless sigObjNr is 0.
+func (ctx *Context) incrementalUpdate(base int64, size int, dirty IntSet, sigObjNr int, prev int64) (*incrementalWriter, error) {
+
+	for i := size; i < *ctx.Size; i++ {
+		dirty[i] = true
+	}
+
+	objNrs := []int{}
+	for objNr := range dirty {
+		objNrs = append(objNrs, objNr)
+	}
+	sort.Ints(objNrs)
+
+	w := &incrementalWriter{base: base, offsets: map[int]int64{}, sigObjNr: sigObjNr}
+
+	for _, objNr := range objNrs {
+		e, found := ctx.FindTableEntryLight(objNr)
+		if !found || e.Free {
+			return nil, errors.Errorf("sign: missing object %d", objNr)
+		}
+		if err := w.writeObject(objNr, ctx.generation(objNr), e.Object); err != nil {
+			return nil, err
+		}
+	}
+
+	if ctx.Read.UsingXRefStreams && !ctx.Read.Hybrid {
+		if err := ctx.writeIncrementalXRefStream(w, objNrs, prev); err != nil {
+			return nil, err
+		}
+		return w, nil
+	}
+
+	ctx.writeIncrementalXRefTable(w, objNrs, prev)
+
+	return w, nil
+}
+
+// appendIncrementalUpdate returns the original file content orig followed by an incremental update
+// holding the modified objects dirty and all objects numbered from size on
+// along with the writer used, which holds the offsets recorded for the signature dict sigObjNr.
+func (ctx *Context) appendIncrementalUpdate(orig []byte, size int, dirty IntSet, sigObjNr int) ([]byte, *incrementalWriter, error) {
+
+	prev, err := offsetLastXRefSection(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b := orig
+	if len(b) > 0 && b[len(b)-1] != '\n' && b[len(b)-1] != '\r' {
+		b = append(append([]byte{}, b...), '\n')
+	}
+
+	iw, err := ctx.incrementalUpdate(int64(len(b)), size, dirty, sigObjNr, *prev)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return append(b[:len(b):len(b)], iw.buf.Bytes()...), iw, nil
+}
+
+// placeholder returns the offsets of the value recorded at r after checking it is delimited by open and close.
+func placeholder(b []byte, r [2]int64, key string, open, close byte) (int, int, error) {
+
+	start, end := int(r[0]), int(r[1])
+	if start == 0 {
+		return 0, 0, errors.Errorf("sign: missing %s placeholder", key)
+	}
+
+	if end-start < 2 || end > len(b) || b[start] != open || b[end-1] != close {
+		return 0, 0, errors.Errorf("sign: corrupt %s placeholder", key)
+	}
+
+	return start, end, nil
+}
+
+// signBytes sets the ByteRange of the signature dict written by w into b and
+// fills in the signature over the covered bytes.
+func (s *Sign) signBytes(b []byte, w *incrementalWriter) error {
+
+	cStart, cEnd, err := placeholder(b, w.contents, "Contents", '<', '>')
+	if err != nil {
+		return err
+	}
+
+	bStart, bEnd, err := placeholder(b, w.byteRange, "ByteRange", '[', ']')
+	if err != nil {
+		return err
+	}
+
+	br := fmt.Sprintf("[0 %d %d %d]", cStart, cEnd, len(b)-cEnd)
+	if len(br) > bEnd-bStart {
+		return errors.New("sign: ByteRange overflow")
+	}
+	copy(b[bStart:bEnd], br+strings.Repeat(" ", bEnd-bStart-len(br)))
+
+	r := io.MultiReader(bytes.NewReader(b[:cStart]), bytes.NewReader(b[cEnd:]))
+
+	var sig []byte
+
+	if s.DocTimeStamp {
+		sig, err = cms.TimeStamp(s.TSA, r, crypto.SHA256)
+	} else {
+		opts := cms.Options{CAdES: s.CAdES}
+		if !s.CAdES {
+			// PAdES signatures carry the signing time in M only.
+			opts.SigningTime = s.Time
+		}
+		sig, err = cms.SignDetached(r, s.Signer, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	h := hex.EncodeToString(sig)
+	if len(h) > cEnd-cStart-2 {
+		return errors.Errorf("sign: signature exceeds the reserved %d bytes", (cEnd-cStart-2)/2)
+	}
+	copy(b[cStart+1:], h)
+
+	return nil
+}
+
+// AddSignature signs ctx read from the original file content orig using the configuration s.
+// The signature field gets appended to orig as an incremental update and the result is written to w.
+func AddSignature(ctx *Context, orig []byte, w io.Writer, s *Sign) error {
+
+	if ctx.Encrypt != nil {
+		return errors.New("sign: signing encrypted files is not supported")
+	}
+
+	if s.DocTimeStamp {
+		if s.TSA == nil {
+			return errors.New("sign: missing time-stamp authority")
+		}
+		s.Rect = nil
+	} else {
+		if s.Signer == nil {
+			return errors.New("sign: missing credentials")
+		}
+		if err := s.Signer.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if s.Page == 0 {
+		s.Page = 1
+	}
+	if s.Time.IsZero() {
+		s.Time = time.Now()
+	}
+
+	size := *ctx.Size
+	dirty := IntSet{}
+
+	sigObjNr, err := ctx.addSignatureField(s, dirty)
+	if err != nil {
+		return err
+	}
+
+	b, iw, err := ctx.appendIncrementalUpdate(orig, size, dirty, sigObjNr)
+	if err != nil {
+		return err
+	}
+
+	if err := s.signBytes(b, iw); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+
+	if s.DocTimeStamp {
+		log.Info.Printf("time-stamped %s\n", s.FieldName)
+	} else {
+		log.Info.Printf("signed %s as %s using %s\n", s.FieldName, s.signerName(), s.SubFilter())
+	}
+
+	return nil
+}
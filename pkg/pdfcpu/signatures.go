@@ -0,0 +1,529 @@
+/*
+Copyright 2019 The pdfcpu Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfcpu
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/denisbetsi/pdfcpu/pkg/log"
+	"github.com/denisbetsi/pdfcpu/pkg/pdfcpu/cms"
+	"github.com/pkg/errors"
+)
+
+// SubFilterPKCS7SHA1 is the deprecated sub filter signing the SHA-1 digest of the covered bytes.
+const SubFilterPKCS7SHA1 = "adbe.pkcs7.sha1"
+
+// Signature represents a digital signature of a signature field.
+type Signature struct {
+	FieldName   string
+	Pages       []int  // The pages displaying the signature widget.
+	Type        string // Sig or DocTimeStamp
+	SubFilter   string
+	Name        string
+	Reason      string
+	Location    string
+	ContactInfo string
+	Time        time.Time         // M, the signing time claimed by the signer.
+	SigningTime time.Time         // The signing time of the CMS signed attributes or of the time-stamp token.
+	Signer      *x509.Certificate // The signer certificate.
+	ByteRange   [4]int64
+	FileSize    int64
+	Updates     int // The number of incremental updates appended after signing.
+
+	// Set by VerifySignatures.
+	Verified bool                // The digest and the signature value are valid.
+	Trusted  bool                // The signer certificate chains up to a trusted certificate.
+	Chain    []*x509.Certificate // The certificate chain from the signer to the trusted certificate.
+	Err      error               // The reason of a failed verification.
+
+	sd       *cms.SignedData
+	contents []byte // The value of Contents.
+}
+
+// Covered returns the number of bytes covered by the signature.
+func (s Signature) Covered() int64 {
+	return s.ByteRange[1] + s.ByteRange[3]
+}
+
+// Modified returns true if the file has been updated after signing.
+func (s Signature) Modified() bool {
+	return s.Updates > 0
+}
+
+func (s Signature) String() string {
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%s", s.FieldName)
+	if len(s.Pages) > 0 {
+		pp := make([]string, len(s.Pages))
+		for i, p := range s.Pages {
+			pp[i] = strconv.Itoa(p)
+		}
+		fmt.Fprintf(&sb, " (page=%s)", strings.Join(pp, ","))
+	}
+	sb.WriteString(":\n")
+
+	fmt.Fprintf(&sb, "        Type: %s\n", s.Type)
+	fmt.Fprintf(&sb, "   SubFilter: %s\n", s.SubFilter)
+
+	if c := s.Signer; c != nil {
+		fmt.Fprintf(&sb, "      Signer: %s\n", c.Subject)
+		fmt.Fprintf(&sb, "      Issuer: %s\n", c.Issuer)
+		fmt.Fprintf(&sb, "      Serial: %s\n", c.SerialNumber.Text(16))
+		fmt.Fprintf(&sb, "    Validity: %s - %s\n", c.NotBefore.Format(time.RFC3339), c.NotAfter.Format(time.RFC3339))
+	}
+
+	for _, kv := range [][2]string{{"Name", s.Name}, {"Reason", s.Reason}, {"Location", s.Location}, {"ContactInfo", s.ContactInfo}} {
+		if kv[1] != "" {
+			fmt.Fprintf(&sb, "%12s: %s\n", kv[0], kv[1])
+		}
+	}
+
+	if !s.Time.IsZero() {
+		fmt.Fprintf(&sb, "        Time: %s\n", s.Time.Format(time.RFC3339))
+	}
+	if !s.SigningTime.IsZero() {
+		fmt.Fprintf(&sb, " SigningTime: %s\n", s.SigningTime.Format(time.RFC3339))
+	}
+
+	fmt.Fprintf(&sb, "   ByteRange: %v signing %d bytes of a %d bytes revision, file size: %d\n",
+		s.ByteRange, s.Covered(), s.ByteRange[2]+s.ByteRange[3], s.FileSize)
+
+	if s.Modified() {
+		fmt.Fprintf(&sb, "    Modified: %d incremental update(s) after signing\n", s.Updates)
+	} else {
+		sb.WriteString("    Modified: no\n")
+	}
+
+	switch {
+	case s.Err != nil && s.Verified:
+		fmt.Fprintf(&sb, "      Status: intact, not trusted: %v\n", s.Err)
+	case s.Err != nil:
+		fmt.Fprintf(&sb, "      Status: invalid: %v\n", s.Err)
+	case s.Verified:
+		sb.WriteString("      Status: valid, trusted\n")
+	}
+
+	return sb.String()
+}
+
+// parseDate parses a PDF date string, see 7.9.4.
+func parseDate(s string) (time.Time, bool) {
+
+	s = strings.TrimPrefix(strings.TrimSpace(s), "D:")
+	if len(s) < 4 {
+		return time.Time{}, false
+	}
+
+	v := [6]int{0, 1, 1, 0, 0, 0}
+	l := [6]int{4, 2, 2, 2, 2, 2}
+
+	i := 0
+	for j := 0; j < len(v) && i+l[j] <= len(s); j++ {
+		n, err := strconv.Atoi(s[i : i+l[j]])
+		if err != nil {
+			break
+		}
+		v[j] = n
+		i += l[j]
+	}
+
+	loc := time.UTC
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		tz := strings.Replace(s[i+1:], "'", "", -1)
+		h, m := 0, 0
+		if len(tz) >= 2 {
+			h, _ = strconv.Atoi(tz[:2])
+		}
+		if len(tz) >= 4 {
+			m, _ = strconv.Atoi(tz[2:4])
+		}
+		off := h*3600 + m*60
+		if s[i] == '-' {
+			off = -off
+		}
+		loc = time.FixedZone("", off)
+	}
+
+	return time.Date(v[0], time.Month(v[1]), v[2], v[3], v[4], v[5], 0, loc), true
+}
+
+// readRange returns n bytes of rs starting at off.
+func readRange(rs io.ReadSeeker, off, n int64) ([]byte, error) {
+	if _, err := rs.Seek(off, io.SeekStart); err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rs, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// signedContent returns a reader for the bytes covered by the ByteRange of s.
+func (s Signature) signedContent(rs io.ReadSeeker) (io.Reader, error) {
+
+	b1, err := readRange(rs, s.ByteRange[0], s.ByteRange[1])
+	if err != nil {
+		return nil, err
+	}
+
+	b2, err := readRange(rs, s.ByteRange[2], s.ByteRange[3])
+	if err != nil {
+		return nil, err
+	}
+
+	return io.MultiReader(bytes.NewReader(b1), bytes.NewReader(b2)), nil
+}
+
+func (xRefTable *XRefTable) byteRange(d Dict) ([4]int64, error) {
+
+	br := [4]int64{}
+
+	a, err := xRefTable.DereferenceArray(d["ByteRange"])
+	if err != nil {
+		return br, err
+	}
+	if len(a) != 4 {
+		return br, errors.New("signatures: invalid ByteRange")
+	}
+
+	for i, o := range a {
+		o, err := xRefTable.Dereference(o)
+		if err != nil {
+			return br, err
+		}
+		v, ok := o.(Integer)
+		if !ok || v < 0 {
+			return br, errors.New("signatures: invalid ByteRange")
+		}
+		br[i] = int64(v)
+	}
+
+	return br, nil
+}
+
+// checkByteRange verifies that the ByteRange of s covers the whole revision except the signature value
+// and returns the DER encoded signature.
+func (s *Signature) checkByteRange(rs io.ReadSeeker) ([]byte, error) {
+
+	br := s.ByteRange
+
+	if br[0] != 0 || br[1] >= br[2] || br[2]+br[3] > s.FileSize {
+		return nil, errors.Errorf("signatures: ByteRange %v does not cover the file", br)
+	}
+
+	b, err := readRange(rs, br[1], br[2]-br[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) < 2 || b[0] != '<' || b[len(b)-1] != '>' {
+		return nil, errors.Errorf("signatures: ByteRange %v does not exclude exactly the signature value", br)
+	}
+
+	sig, err := hex.DecodeString(strings.Join(strings.Fields(string(b[1:len(b)-1])), ""))
+	if err != nil {
+		return nil, errors.Wrap(err, "signatures: corrupt signature value")
+	}
+
+	// Count the incremental updates appended after signing.
+	if n := s.FileSize - br[2] - br[3]; n > 0 {
+		b, err := readRange(rs, br[2]+br[3], n)
+		if err != nil {
+			return nil, err
+		}
+		s.Updates = bytes.Count(b, []byte("%%EOF"))
+		if s.Updates == 0 && len(bytes.TrimSpace(b)) > 0 {
+			s.Updates = 1
+		}
+	}
+
+	return sig, nil
+}
+
+// Signatures returns the signatures of all signed signature fields of ctx.
+// Signature values are not verified.
+func (ctx *Context) Signatures() ([]*Signature, error) {
+
+	_, ff, err := ctx.formFields()
+	if err != nil {
+		return nil, err
+	}
+
+	rs := ctx.Read.rs
+	fileSize, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	sigs := []*Signature{}
+
+	for _, f := range ff {
+
+		if f.Type != FieldSignature || f.v == nil {
+			continue
+		}
+
+		d, err := ctx.DereferenceDict(f.v)
+		if err != nil {
+			return nil, err
+		}
+		if d == nil {
+			continue
+		}
+
+		s := &Signature{
+			FieldName:   f.Name,
+			Pages:       f.Pages,
+			Type:        "Sig",
+			Name:        ctx.text(d["Name"]),
+			Reason:      ctx.text(d["Reason"]),
+			Location:    ctx.text(d["Location"]),
+			ContactInfo: ctx.text(d["ContactInfo"]),
+			FileSize:    fileSize,
+		}
+
+		if t := d.Type(); t != nil {
+			s.Type = *t
+		}
+
+		if n := d.NameEntry("SubFilter"); n != nil {
+			s.SubFilter = *n
+		}
+
+		if t, ok := parseDate(ctx.text(d["M"])); ok {
+			s.Time = t
+		}
+
+		sigs = append(sigs, s)
+
+		if s.ByteRange, err = ctx.byteRange(d); err != nil {
+			s.Err = err
+			continue
+		}
+
+		b, err := s.checkByteRange(rs)
+		if err != nil {
+			s.Err = err
+			continue
+		}
+
+		s.contents = b
+
+		if s.sd, err = cms.ParseSignedData(b); err != nil {
+			s.Err = err
+			continue
+		}
+
+		s.Signer = s.sd.Signer
+		s.SigningTime = s.sd.SigningTime
+
+		if s.SubFilter == SubFilterRFC3161 {
+			ti, err := s.sd.TimeStampInfo()
+			if err != nil {
+				s.Err = err
+				continue
+			}
+			s.SigningTime = ti.GenTime
+		}
+	}
+
+	return sigs, nil
+}
+
+// verify checks the CMS signature of s against the bytes covered by its ByteRange
+// and the certificate chain of the signer against roots.
+func (s *Signature) verify(rs io.ReadSeeker, roots *x509.CertPool) {
+
+	if s.Err != nil {
+		return
+	}
+
+	r, err := s.signedContent(rs)
+	if err != nil {
+		s.Err = err
+		return
+	}
+
+	switch s.SubFilter {
+
+	case SubFilterPKCS7Detached, SubFilterCAdESDetached:
+		err = s.sd.Verify(r)
+
+	case SubFilterRFC3161:
+		_, err = s.sd.VerifyTimeStamp(r, nil)
+
+	case SubFilterPKCS7SHA1:
+		// The signed content is the SHA-1 digest of the covered bytes.
+		h := sha1.New()
+		if _, err = io.Copy(h, r); err == nil {
+			if err = s.sd.Verify(nil); err == nil && !bytes.Equal(s.sd.Content, h.Sum(nil)) {
+				err = cms.ErrDigestMismatch
+			}
+		}
+
+	default:
+		err = errors.Errorf("signatures: unsupported SubFilter: %s", s.SubFilter)
+	}
+
+	if err != nil {
+		s.Err = err
+		return
+	}
+
+	s.Verified = true
+
+	t := s.SigningTime
+	if t.IsZero() {
+		t = s.Time
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	if s.Chain, err = s.sd.VerifyChain(roots, t); err != nil {
+		s.Err = err
+		return
+	}
+
+	s.Trusted = true
+}
+
+// VerifySignatures returns the signatures of ctx along with their verification results.
+// roots holds the trusted certificates, nil for the system roots.
+func VerifySignatures(ctx *Context, roots *x509.CertPool) ([]*Signature, error) {
+
+	sigs, err := ctx.Signatures()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range sigs {
+		s.verify(ctx.Read.rs, roots)
+	}
+
+	return sigs, nil
+}
+
+// ListSignatures returns a list of the signatures of ctx.
+// If verify is true the signatures are checked against roots.
+func ListSignatures(ctx *Context, verify bool, roots *x509.CertPool) ([]string, error) {
+
+	var (
+		sigs []*Signature
+		err  error
+	)
+
+	if verify {
+		sigs, err = VerifySignatures(ctx, roots)
+	} else {
+		sigs, err = ctx.Signatures()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sigs) == 0 {
+		return []string{"no signatures"}, nil
+	}
+
+	ss := []string{}
+	for _, s := range sigs {
+		ss = append(ss, s.String())
+	}
+
+	return ss, nil
+}
+
+// checkSignatures returns an error if any signature of ctx does not verify against the trusted certificates.
+func (ctx *Context) checkSignatures() error {
+
+	sigs, err := VerifySignatures(ctx, ctx.TrustedCerts)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sigs {
+		if s.Err != nil {
+			return errors.Wrapf(s.Err, "signature %s", s.FieldName)
+		}
+		if s.Modified() {
+			log.Info.Printf("signature %s: file modified after signing by %d incremental update(s)\n", s.FieldName, s.Updates)
+		}
+	}
+
+	return nil
+}
+
+// ReadTrustedCerts returns a certificate pool containing all PEM or DER encoded certificates of the given files.
+func ReadTrustedCerts(fileNames ...string) (*x509.CertPool, error) {
+
+	pool := x509.NewCertPool()
+
+	for _, fn := range fileNames {
+
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return nil, err
+		}
+
+		if !bytes.Contains(b, []byte("-----BEGIN")) {
+			c, err := x509.ParseCertificate(b)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s", fn)
+			}
+			pool.AddCert(c)
+			continue
+		}
+
+		n := 0
+		for {
+			var block *pem.Block
+			block, b = pem.Decode(b)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			c, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s", fn)
+			}
+			pool.AddCert(c)
+			n++
+		}
+
+		if n == 0 {
+			return nil, errors.Errorf("%s: no certificates found", fn)
+		}
+	}
+
+	return pool, nil
+}
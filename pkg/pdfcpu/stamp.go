@@ -447,39 +447,54 @@ func parseScaleFactor(s string) (float64, bool, error) {
 	return sc, scaleAbs, nil
 }
 
-func parseWatermarkColor(s string, wm *Watermark) error {
+// parseColor parses a color string of 3 intensities like "0.5 0.5 1".
+func parseColor(s string) (SimpleColor, error) {
+
+	var sc SimpleColor
 
 	cs := strings.Split(s, " ")
 	if len(cs) != 3 {
-		return errors.Errorf("illegal color string: 3 intensities 0.0 <= i <= 1.0, %s\n", s)
+		return sc, errors.Errorf("illegal color string: 3 intensities 0.0 <= i <= 1.0, %s\n", s)
 	}
 
 	r, err := strconv.ParseFloat(cs[0], 32)
 	if err != nil {
-		return errors.Errorf("red must be a float value: %s\n", cs[0])
+		return sc, errors.Errorf("red must be a float value: %s\n", cs[0])
 	}
 	if r < 0 || r > 1 {
-		return errors.New("red: a color value is an intensity between 0.0 and 1.0")
+		return sc, errors.New("red: a color value is an intensity between 0.0 and 1.0")
 	}
-	wm.Color.r = float32(r)
+	sc.r = float32(r)
 
 	g, err := strconv.ParseFloat(cs[1], 32)
 	if err != nil {
-		return errors.Errorf("green must be a float value: %s\n", cs[1])
+		return sc, errors.Errorf("green must be a float value: %s\n", cs[1])
 	}
 	if g < 0 || g > 1 {
-		return errors.New("green: a color value is an intensity between 0.0 and 1.0")
+		return sc, errors.New("green: a color value is an intensity between 0.0 and 1.0")
 	}
-	wm.Color.g = float32(g)
+	sc.g = float32(g)
 
 	b, err := strconv.ParseFloat(cs[2], 32)
 	if err != nil {
-		return errors.Errorf("blue must be a float value: %s\n", cs[2])
+		return sc, errors.Errorf("blue must be a float value: %s\n", cs[2])
 	}
 	if b < 0 || b > 1 {
-		return errors.New("blue: a color value is an intensity between 0.0 and 1.0")
+		return sc, errors.New("blue: a color value is an intensity between 0.0 and 1.0")
+	}
+	sc.b = float32(b)
+
+	return sc, nil
+}
+
+func parseWatermarkColor(s string, wm *Watermark) error {
+
+	sc, err := parseColor(s)
+	if err != nil {
+		return err
 	}
-	wm.Color.b = float32(b)
+
+	wm.Color = sc
 
 	return nil
 }
@@ -720,6 +735,11 @@ func identifyObjNrs(ctx *Context, o Object, objNrs IntSet) error {
 
 	case IndirectRef:
 
+		if objNrs[o.ObjectNumber.Value()] {
+			// Already visited.
+			return nil
+		}
+
 		objNrs[o.ObjectNumber.Value()] = true
 
 		o1, err := ctx.Dereference(o)
@@ -182,3 +182,31 @@ func HexLiteralToString(hexString string) (string, error) {
 	// if no acceptable UTF16 encoding found, just return decoded hexstring.
 	return string(b), nil
 }
+
+// EncodeText returns s as a PDF text string, see 7.9.2.2 Text String Type.
+// ASCII strings result in a string literal, all other strings in a UTF16BE encoded hex literal.
+func EncodeText(s string) (Object, error) {
+
+	ascii := true
+	for _, r := range s {
+		if r >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+
+	if ascii {
+		esc, err := Escape(s)
+		if err != nil {
+			return nil, err
+		}
+		return StringLiteral(*esc), nil
+	}
+
+	b := []byte{0xFE, 0xFF}
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u>>8), byte(u))
+	}
+
+	return HexLiteral(hex.EncodeToString(b)), nil
+}
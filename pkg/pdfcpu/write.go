@@ -260,14 +260,6 @@ func writeRootObject(ctx *Context) error {
 
 	log.Write.Printf("*** writeRootObject: begin offset=%d *** %s\n", ctx.Write.Offset, catalog)
 
-	// Ensure corresponding and accurate name tree object graphs.
-	if !ctx.ReducedFeatureSet() {
-		err := ctx.BindNameTrees()
-		if err != nil {
-			return err
-		}
-	}
-
 	d, err := xRefTable.DereferenceDict(catalog)
 	if err != nil {
 		return err
@@ -277,13 +269,32 @@ func writeRootObject(ctx *Context) error {
 		return errors.Errorf("writeRootObject: unable to dereference root dict")
 	}
 
+	if len(ctx.Write.SelectedPages) > 0 {
+		// Get rid of any references to pages not being written.
+		undo, err := prunePageReferences(ctx, d)
+		if err != nil {
+			return err
+		}
+		defer undo()
+	}
+
+	// Ensure corresponding and accurate name tree object graphs.
+	if !ctx.ReducedFeatureSet() {
+		err := ctx.BindNameTrees()
+		if err != nil {
+			return err
+		}
+	}
+
 	dictName := "rootDict"
 
 	if ctx.ReducedFeatureSet() {
 		log.Write.Println("writeRootObject - reducedFeatureSet:exclude complex entries.")
 		d.Delete("Names")
 		d.Delete("Dests")
-		d.Delete("Outlines")
+		if !(ctx.Cmd == MERGE && ctx.CreateBookmarks) {
+			d.Delete("Outlines")
+		}
 		d.Delete("OpenAction")
 		d.Delete("AcroForm")
 		d.Delete("StructTreeRoot")
@@ -869,37 +880,49 @@ func setupEncryption(ctx *Context) error {
 		return errors.New("unsupported encryption algorithm")
 	}
 
-	d := newEncryptDict(
-		ctx.EncryptUsingAES,
-		ctx.EncryptKeyLength,
-		ctx.Permissions,
-	)
+	var d Dict
 
-	ctx.E, err = supportedEncryption(ctx, d)
-	if err != nil {
-		return err
-	}
+	if len(ctx.Recipients) > 0 {
 
-	if ctx.ID == nil {
-		return errors.New("encrypt: missing ID")
-	}
+		d, err = newPubSecEncryption(ctx)
+		if err != nil {
+			return err
+		}
 
-	var id []byte
-	id, err = ctx.IDFirstElement()
-	if err != nil {
-		return err
-	}
+	} else {
 
-	ctx.E.ID = id
+		d = newEncryptDict(
+			ctx.EncryptUsingAES,
+			ctx.EncryptKeyLength,
+			ctx.Permissions,
+		)
 
-	err = calcOAndU(ctx, d)
-	if err != nil {
-		return err
-	}
+		ctx.E, err = supportedEncryption(ctx, d)
+		if err != nil {
+			return err
+		}
 
-	err = writePermissions(ctx, d)
-	if err != nil {
-		return err
+		if ctx.ID == nil {
+			return errors.New("encrypt: missing ID")
+		}
+
+		var id []byte
+		id, err = ctx.IDFirstElement()
+		if err != nil {
+			return err
+		}
+
+		ctx.E.ID = id
+
+		err = calcOAndU(ctx, d)
+		if err != nil {
+			return err
+		}
+
+		err = writePermissions(ctx, d)
+		if err != nil {
+			return err
+		}
 	}
 
 	xRefTableEntry := NewXRefTableEntryGen0(d)
@@ -1001,6 +1024,9 @@ func handleEncryption(ctx *Context) error {
 				alg = "AES"
 			}
 			action = fmt.Sprintf("encrypting(%s-%d)", alg, ctx.EncryptKeyLength)
+			if ctx.E.PubSec {
+				action = fmt.Sprintf("encrypting(%s-%d) for %d recipient(s)", alg, ctx.EncryptKeyLength, len(ctx.Recipients))
+			}
 		}
 
 	} else if ctx.UserPWNew != nil || ctx.OwnerPWNew != nil || ctx.Cmd == SETPERMISSIONS {
@@ -1018,6 +1044,10 @@ func handleEncryption(ctx *Context) error {
 		ctx.WriteXRefStream = false
 	}
 
+	if ctx.Write.Quiet {
+		return nil
+	}
+
 	s := filepath.Join(ctx.Write.DirName, ctx.Write.FileName)
 	if len(s) > 0 {
 		s = " " + s
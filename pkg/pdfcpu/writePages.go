@@ -233,11 +233,11 @@ func writePagesDict(ctx *Context, ir *IndirectRef, pageNr *int) (skip bool, writ
 	kidsOrig := d.ArrayEntry("Kids")
 
 	if len(ctx.Write.SelectedPages) > 0 {
-		// EXTRACTPAGES, SPLIT, TRIM, REMOVEPAGES
+		// EXTRACTPAGES, SPLIT, TRIM, REMOVEPAGES, ARRANGEPAGES
 		c := int(countOrig.(Integer))
 		log.Write.Printf("writePagesDict: checking page range %d - %d \n", *pageNr+1, *pageNr+c)
 		if ctx.Cmd == REMOVEPAGES ||
-			((ctx.Cmd == EXTRACTPAGES || ctx.Cmd == TRIM || ctx.Cmd == SPLIT) && containsSelectedPages(ctx, *pageNr+1, *pageNr+c)) {
+			((ctx.Cmd == EXTRACTPAGES || ctx.Cmd == TRIM || ctx.Cmd == SPLIT || ctx.Cmd == ARRANGEPAGES) && containsSelectedPages(ctx, *pageNr+1, *pageNr+c)) {
 			log.Write.Println("writePagesDict: process this subtree")
 		} else {
 			log.Write.Println("writePagesDict: skip this subtree")
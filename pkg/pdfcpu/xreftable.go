@@ -74,6 +74,8 @@ type Enc struct {
 	L, P, R, V int
 	Emd        bool // encrypt meta data
 	ID         []byte
+	PubSec     bool     // public-key security handler
+	Recipients [][]byte // public-key security handler: CMS enveloped seed and permissions
 }
 
 // XRefTable represents a PDF cross reference table plus stats for a PDF file.
@@ -1358,6 +1360,112 @@ func (xRefTable *XRefTable) NamesDict() (Dict, error) {
 	return xRefTable.DereferenceDict(o)
 }
 
+func (xRefTable *XRefTable) nameTreeValue(d Dict, key string) (Object, error) {
+
+	a, err := xRefTable.DereferenceArray(d["Names"])
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i+1 < len(a); i += 2 {
+		k, err := xRefTable.DereferenceText(a[i])
+		if err != nil {
+			return nil, err
+		}
+		if k == key {
+			return a[i+1], nil
+		}
+	}
+
+	kids, err := xRefTable.DereferenceArray(d["Kids"])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, o := range kids {
+
+		d1, err := xRefTable.DereferenceDict(o)
+		if err != nil {
+			return nil, err
+		}
+
+		if d1 == nil {
+			continue
+		}
+
+		v, err := xRefTable.nameTreeValue(d1, key)
+		if err != nil || v != nil {
+			return v, err
+		}
+	}
+
+	return nil, nil
+}
+
+func (xRefTable *XRefTable) namedDestination(o Object) (Object, error) {
+
+	rootDict, err := xRefTable.Catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	if n, ok := o.(Name); ok {
+		// PDF 1.1 named destination.
+		d, err := xRefTable.DereferenceDict(rootDict["Dests"])
+		if err != nil || d == nil {
+			return nil, err
+		}
+		return d[n.Value()], nil
+	}
+
+	// PDF 1.2 named destination.
+	s, err := xRefTable.DereferenceText(o)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := xRefTable.DereferenceDict(rootDict["Names"])
+	if err != nil || d == nil {
+		return nil, err
+	}
+
+	d, err = xRefTable.DereferenceDict(d["Dests"])
+	if err != nil || d == nil {
+		return nil, err
+	}
+
+	return xRefTable.nameTreeValue(d, s)
+}
+
+// ResolveDestination returns the explicit destination array for a destination.
+// Named destinations are looked up in the catalog's Dests dict or in the Dests name tree.
+// Returns nil for a named destination that cannot be resolved.
+func (xRefTable *XRefTable) ResolveDestination(o Object) (Array, error) {
+
+	o, err := xRefTable.Dereference(o)
+	if err != nil || o == nil {
+		return nil, err
+	}
+
+	switch o.(type) {
+
+	case Name, StringLiteral, HexLiteral:
+		if o, err = xRefTable.namedDestination(o); err != nil {
+			return nil, err
+		}
+		if o, err = xRefTable.Dereference(o); err != nil || o == nil {
+			return nil, err
+		}
+
+	}
+
+	if d, ok := o.(Dict); ok {
+		o = d["D"]
+	}
+
+	return xRefTable.DereferenceArray(o)
+}
+
 // RemoveNameTree removes a specific name tree.
 // Also removes a resulting empty names dict.
 func (xRefTable *XRefTable) RemoveNameTree(nameTreeName string) error {
@@ -1712,6 +1820,59 @@ func (xRefTable *XRefTable) PageMediaBox(i int) (*Rectangle, error) {
 	return inhPAttrs.mediaBox, err
 }
 
+func (xRefTable *XRefTable) collectPageIndRefs(root IndirectRef, irs *[]IndirectRef) error {
+
+	d, err := xRefTable.DereferenceDict(root)
+	if err != nil {
+		return err
+	}
+
+	kids := d.ArrayEntry("Kids")
+	if kids == nil {
+		*irs = append(*irs, root)
+		return nil
+	}
+
+	for _, o := range kids {
+
+		if o == nil {
+			continue
+		}
+
+		ir, ok := o.(IndirectRef)
+		if !ok {
+			return errors.Errorf("collectPageIndRefs: corrupt page node dict")
+		}
+
+		if err = xRefTable.collectPageIndRefs(ir, irs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PageIndRefs returns the indirect references of all page dicts in page order.
+func (xRefTable *XRefTable) PageIndRefs() ([]IndirectRef, error) {
+
+	root, err := xRefTable.Pages()
+	if err != nil {
+		return nil, err
+	}
+
+	if root == nil {
+		return nil, errors.New("PageIndRefs: missing page tree root")
+	}
+
+	irs := []IndirectRef{}
+
+	if err = xRefTable.collectPageIndRefs(*root, &irs); err != nil {
+		return nil, err
+	}
+
+	return irs, nil
+}
+
 func (xRefTable *XRefTable) emptyPage(parentIndRef *IndirectRef, mediaBox *Rectangle) (*IndirectRef, error) {
 
 	contents := &StreamDict{Dict: NewDict()}
@@ -1756,14 +1917,23 @@ func (xRefTable *XRefTable) pageMediaBox(d *Dict) (*Rectangle, error) {
 	return rect(xRefTable, a)
 }
 
-func (xRefTable *XRefTable) insertIntoPageTree(root *IndirectRef, pAttrs *InheritedPageAttrs, p *int, selectedPages IntSet) (int, error) {
+// pageReplacement returns the pages taking the place of page pageNr referenced by ir,
+// a kid of the page tree node parent inheriting pAttrs from its ancestors.
+type pageReplacement func(parent *IndirectRef, ir IndirectRef, pageDict Dict, pAttrs InheritedPageAttrs, pageNr int) ([]IndirectRef, error)
+
+// insertIntoPageTree puts the pages returned by f in place of each page of the page tree rooted at root
+// and returns the resulting change in page count. Page tree nodes left without kids get dropped.
+func (xRefTable *XRefTable) insertIntoPageTree(root *IndirectRef, pAttrs *InheritedPageAttrs, p *int, f pageReplacement) (int, error) {
 
 	d, err := xRefTable.DereferenceDict(*root)
 	if err != nil {
 		return 0, err
 	}
 
-	err = xRefTable.checkInheritedPageAttrs(d, pAttrs)
+	// Sibling subtrees must not see each other's attributes.
+	attrs := *pAttrs
+
+	err = xRefTable.checkInheritedPageAttrs(d, &attrs)
 	if err != nil {
 		return 0, err
 	}
@@ -1797,35 +1967,25 @@ func (xRefTable *XRefTable) insertIntoPageTree(root *IndirectRef, pAttrs *Inheri
 
 		case "Pages":
 			// Recurse over sub pagetree.
-			j, err := xRefTable.insertIntoPageTree(&ir, pAttrs, p, selectedPages)
+			j, err := xRefTable.insertIntoPageTree(&ir, &attrs, p, f)
 			if err != nil {
 				return 0, err
 			}
-			a = append(a, ir)
+			if len(pageNodeDict.ArrayEntry("Kids")) > 0 {
+				a = append(a, ir)
+			}
 			i += j
 
 		case "Page":
 			*p++
-			if selectedPages[*p] {
-				// Insert empty page.
-				mediaBox := pAttrs.mediaBox
-				if mediaBox == nil {
-					mediaBox, err = xRefTable.pageMediaBox(&pageNodeDict)
-					if err != nil {
-						return 0, err
-					}
-				}
-
-				indRef, err := xRefTable.emptyPage(root, mediaBox)
-				if err != nil {
-					return 0, err
-				}
-
-				a = append(a, *indRef)
-				i++
+			irs, err := f(root, ir, pageNodeDict, attrs, *p)
+			if err != nil {
+				return 0, err
 			}
-			a = append(a, ir)
-			i++
+			for _, ir := range irs {
+				a = append(a, ir)
+			}
+			i += len(irs) - 1
 
 		}
 
@@ -1847,7 +2007,214 @@ func (xRefTable *XRefTable) InsertPages(pages IntSet) error {
 	var inhPAttrs InheritedPageAttrs
 	p := 0
 
-	_, err = xRefTable.insertIntoPageTree(root, &inhPAttrs, &p, pages)
+	_, err = xRefTable.insertIntoPageTree(root, &inhPAttrs, &p, func(parent *IndirectRef, ir IndirectRef, pageDict Dict, pAttrs InheritedPageAttrs, pageNr int) ([]IndirectRef, error) {
+
+		if !pages[pageNr] {
+			return []IndirectRef{ir}, nil
+		}
+
+		// Insert empty page.
+		mediaBox := pAttrs.mediaBox
+		if mediaBox == nil {
+			var err error
+			if mediaBox, err = xRefTable.pageMediaBox(&pageDict); err != nil {
+				return nil, err
+			}
+		}
+
+		indRef, err := xRefTable.emptyPage(parent, mediaBox)
+		if err != nil {
+			return nil, err
+		}
+
+		return []IndirectRef{*indRef, ir}, nil
+	})
+
+	return err
+}
+
+// inheritPageAttrs makes the page attributes d inherits from its ancestors explicit.
+func inheritPageAttrs(d Dict, pAttrs InheritedPageAttrs) {
+
+	if _, found := d.Find("Resources"); !found && pAttrs.resources != nil {
+		d.Insert("Resources", pAttrs.resources)
+	}
+
+	if _, found := d.Find("MediaBox"); !found && pAttrs.mediaBox != nil {
+		d.Insert("MediaBox", pAttrs.mediaBox.Array())
+	}
+
+	if _, found := d.Find("CropBox"); !found && pAttrs.cropBox != nil {
+		d.Insert("CropBox", pAttrs.cropBox.Array())
+	}
+
+	if _, found := d.Find("Rotate"); !found && pAttrs.rotate != 0 {
+		d.Insert("Rotate", Integer(pAttrs.rotate))
+	}
+}
+
+// detachPage makes the attributes page d inherits from its ancestors explicit and removes them from the ancestors
+// so d may be moved anywhere within the page tree.
+func (xRefTable *XRefTable) detachPage(parent *IndirectRef, d Dict, pAttrs InheritedPageAttrs) error {
+
+	inheritPageAttrs(d, pAttrs)
+
+	for ir := parent; ir != nil; {
+		d1, err := xRefTable.DereferenceDict(*ir)
+		if err != nil {
+			return err
+		}
+		for _, k := range []string{"Resources", "MediaBox", "CropBox", "Rotate"} {
+			d1.Delete(k)
+		}
+		ir = d1.IndirectRefEntry("Parent")
+	}
+
+	return nil
+}
+
+// blankPageFor creates a blank page for position j of pageNrs.
+func (xRefTable *XRefTable) blankPageFor(root *IndirectRef, irs []IndirectRef, pageNrs []int, j int) (*IndirectRef, error) {
+
+	// Look for the preceding page first, then for the following page.
+	i := 0
+	for k := j - 1; k >= 0 && i == 0; k-- {
+		i = pageNrs[k]
+	}
+	for k := j + 1; k < len(pageNrs) && i == 0; k++ {
+		i = pageNrs[k]
+	}
+
+	if i == 0 {
+		return nil, errors.New("ArrangePages: missing page for blank page dimensions")
+	}
+
+	d, err := xRefTable.DereferenceDict(irs[i-1])
+	if err != nil {
+		return nil, err
+	}
+
+	mediaBox, err := xRefTable.pageMediaBox(&d)
+	if err != nil {
+		return nil, err
+	}
+
+	return xRefTable.emptyPage(root, mediaBox)
+}
+
+// copyPage returns a copy of pageDict along with copies of its annotations as a kid of parent.
+func (xRefTable *XRefTable) copyPage(parent *IndirectRef, pageDict Dict) (*IndirectRef, error) {
+
+	d := NewDict()
+	for k, v := range pageDict {
+		if k != "Annots" {
+			d.Insert(k, v)
+		}
+	}
+	d.Update("Parent", *parent)
+
+	indRef, err := xRefTable.IndRefForNewObject(d)
+	if err != nil {
+		return nil, err
+	}
+
+	annots, _, err := xRefTable.pageAnnots(pageDict)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := xRefTable.copyAnnotations(annots, *indRef, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(a) > 0 {
+		d.Insert("Annots", a)
+	}
+
+	return indRef, nil
+}
+
+// arrangedPage returns the page for position j of pageNrs as a kid of parent.
+// The first occurrence of a page is the page itself, any further occurrence a copy.
+func (xRefTable *XRefTable) arrangedPage(parent *IndirectRef, irs []IndirectRef, pageNrs []int, j int, used IntSet) (*IndirectRef, error) {
+
+	i := pageNrs[j]
+	if i == 0 {
+		return xRefTable.blankPageFor(parent, irs, pageNrs, j)
+	}
+
+	ir := irs[i-1]
+
+	pageDict, err := xRefTable.DereferenceDict(ir)
+	if err != nil {
+		return nil, err
+	}
+
+	if used[i] {
+		return xRefTable.copyPage(parent, pageDict)
+	}
+
+	used[i] = true
+	pageDict.Update("Parent", *parent)
+
+	return &ir, nil
+}
+
+// ArrangePages rearranges the pages in the order of pageNrs preserving the structure of the page tree.
+// Pages may occur more than once and pages not mentioned get dropped.
+// Repeated pages are written as copies of the original page including its annotations.
+// A 0 inserts a blank page sized like the preceding page or else the following page.
+func (xRefTable *XRefTable) ArrangePages(pageNrs []int) error {
+
+	root, err := xRefTable.Pages()
+	if err != nil {
+		return err
+	}
+
+	// Collect all pages while making them independent of their position within the page tree.
+	irs := []IndirectRef{}
+	p := 0
+
+	_, err = xRefTable.insertIntoPageTree(root, &InheritedPageAttrs{}, &p, func(parent *IndirectRef, ir IndirectRef, pageDict Dict, pAttrs InheritedPageAttrs, _ int) ([]IndirectRef, error) {
+		if err := xRefTable.detachPage(parent, pageDict, pAttrs); err != nil {
+			return nil, err
+		}
+		irs = append(irs, ir)
+		return []IndirectRef{ir}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, i := range pageNrs {
+		if i < 0 || i > len(irs) {
+			return errors.Errorf("ArrangePages: invalid page number: %d", i)
+		}
+	}
+
+	// Page k makes room for the arranged page k, the last page for all remaining arranged pages.
+	used := IntSet{}
+	p = 0
+
+	_, err = xRefTable.insertIntoPageTree(root, &InheritedPageAttrs{}, &p, func(parent *IndirectRef, _ IndirectRef, _ Dict, _ InheritedPageAttrs, pageNr int) ([]IndirectRef, error) {
+
+		thru := pageNr
+		if pageNr == len(irs) {
+			thru = len(pageNrs)
+		}
+
+		a := []IndirectRef{}
+		for j := pageNr - 1; j < thru && j < len(pageNrs); j++ {
+			ir, err := xRefTable.arrangedPage(parent, irs, pageNrs, j, used)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, *ir)
+		}
+
+		return a, nil
+	})
 
 	return err
 }